@@ -15,9 +15,29 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/.well-known/jwks.json": {
+            "get": {
+                "description": "Ключи в формате RFC 7517 для проверки наших JWT другими сервисами. Ключ выбирается по заголовку 'kid' токена. Ответ не обернут в стандартный формат API",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "JWT"
+                ],
+                "summary": "Публичные ключи JWT (JWKS)",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/jwtutils.JwkSet"
+                        }
+                    }
+                }
+            }
+        },
         "/api/admin/flight-ticket/create": {
             "post": {
-                "description": "Создаёт авиабилет с переданными параметрами",
+                "description": "Создаёт авиабилет с переданными параметрами. Билеты, созданные оператором авиакомпании, принадлежат ему",
                 "consumes": [
                     "application/json"
                 ],
@@ -27,7 +47,7 @@ const docTemplate = `{
                 "tags": [
                     "Авиабилет"
                 ],
-                "summary": "Создать авиабилет (Admin only)",
+                "summary": "Создать авиабилет (Admin, Airline operator)",
                 "parameters": [
                     {
                         "type": "string",
@@ -70,6 +90,12 @@ const docTemplate = `{
                         "name": "arrival",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID оператора авиакомпании (только для админа) (необязательно)",
+                        "name": "operator-id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -97,6 +123,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/httputils.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -108,7 +140,7 @@ const docTemplate = `{
         },
         "/api/admin/flight-ticket/delete": {
             "delete": {
-                "description": "Удаляет авиабилет по ID",
+                "description": "Удаляет авиабилет по ID. Оператор авиакомпании может удалять только свои билеты",
                 "consumes": [
                     "application/json"
                 ],
@@ -118,7 +150,7 @@ const docTemplate = `{
                 "tags": [
                     "Авиабилет"
                 ],
-                "summary": "Удалить авиабилет (Admin only)",
+                "summary": "Удалить авиабилет (Admin, Airline operator)",
                 "parameters": [
                     {
                         "type": "string",
@@ -141,6 +173,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/httputils.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -152,7 +190,7 @@ const docTemplate = `{
         },
         "/api/admin/flight-ticket/update": {
             "put": {
-                "description": "Изменяет авиабилет переданными параметрами",
+                "description": "Изменяет авиабилет переданными параметрами. Оператор авиакомпании может изменять только свои билеты",
                 "consumes": [
                     "application/json"
                 ],
@@ -162,7 +200,7 @@ const docTemplate = `{
                 "tags": [
                     "Авиабилет"
                 ],
-                "summary": "Изменить авиабилет (Admin only)",
+                "summary": "Изменить авиабилет (Admin, Airline operator)",
                 "parameters": [
                     {
                         "type": "string",
@@ -206,6 +244,12 @@ const docTemplate = `{
                         "description": "Новые дата и время посадки (формат: 2006-01-02T15:04:06Z) (необязательно)",
                         "name": "arrival",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Новый ID оператора авиакомпании (только для админа) (необязательно)",
+                        "name": "operator-id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -221,6 +265,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/httputils.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -232,7 +282,7 @@ const docTemplate = `{
         },
         "/api/admin/hotel-room/create": {
             "post": {
-                "description": "Создаёт номер отеля с переданными параметрами",
+                "description": "Создаёт номер отеля с переданными параметрами. Менеджер отеля может создавать номера только в своих отелях",
                 "consumes": [
                     "application/json"
                 ],
@@ -242,7 +292,7 @@ const docTemplate = `{
                 "tags": [
                     "Номер отеля"
                 ],
-                "summary": "Создать номер отеля (Admin only)",
+                "summary": "Создать номер отеля (Admin, Hotel manager)",
                 "parameters": [
                     {
                         "type": "string",
@@ -305,6 +355,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/httputils.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -316,7 +372,7 @@ const docTemplate = `{
         },
         "/api/admin/hotel-room/delete": {
             "delete": {
-                "description": "Удаляет номер отеля по ID",
+                "description": "Удаляет номер отеля по ID. Менеджер отеля может удалять номера только своих отелей. Номер остается в истории аренд, но не находится в поиске. Если у номера есть незакончившиеся аренды, удаление отклоняется, а с 'force=true' аренды отменяются и арендаторам отправляется письмо",
                 "consumes": [
                     "application/json"
                 ],
@@ -326,7 +382,7 @@ const docTemplate = `{
                 "tags": [
                     "Номер отеля"
                 ],
-                "summary": "Удалить номер отеля (Admin only)",
+                "summary": "Удалить номер отеля (Admin, Hotel manager)",
                 "parameters": [
                     {
                         "type": "string",
@@ -334,6 +390,12 @@ const docTemplate = `{
                         "name": "id",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Отменить незакончившиеся аренды",
+                        "name": "force",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -349,6 +411,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/httputils.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -360,7 +440,7 @@ const docTemplate = `{
         },
         "/api/admin/hotel-room/update": {
             "put": {
-                "description": "Изменяет номер отеля переданными параметрами",
+                "description": "Изменяет номер отеля переданными параметрами. Менеджер отеля может изменять номера только своих отелей",
                 "consumes": [
                     "application/json"
                 ],
@@ -370,7 +450,7 @@ const docTemplate = `{
                 "tags": [
                     "Номер отеля"
                 ],
-                "summary": "Изменить номер отеля (Admin only)",
+                "summary": "Изменить номер отеля (Admin, Hotel manager)",
                 "parameters": [
                     {
                         "type": "string",
@@ -435,6 +515,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/httputils.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -477,6 +563,12 @@ const docTemplate = `{
                         "description": "Название отеля (необязательно)",
                         "name": "name",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID менеджера отеля (необязательно)",
+                        "name": "owner-id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -504,6 +596,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/httputils.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -515,7 +613,7 @@ const docTemplate = `{
         },
         "/api/admin/hotel/delete": {
             "delete": {
-                "description": "Удаляет отель по ID",
+                "description": "Удаляет отель и все его номера по ID. Отель и номера остаются в истории аренд, но не находятся в поиске. Если у номеров есть незакончившиеся аренды, удаление отклоняется, а с 'force=true' аренды отменяются и арендаторам отправляется письмо",
                 "consumes": [
                     "application/json"
                 ],
@@ -533,6 +631,12 @@ const docTemplate = `{
                         "name": "id",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Отменить незакончившиеся аренды",
+                        "name": "force",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -548,6 +652,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/httputils.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -559,7 +681,7 @@ const docTemplate = `{
         },
         "/api/admin/hotel/update": {
             "put": {
-                "description": "Изменяет отель переданными параметрами",
+                "description": "Изменяет отель переданными параметрами. Менеджер отеля может изменять только свои отели",
                 "consumes": [
                     "application/json"
                 ],
@@ -569,7 +691,7 @@ const docTemplate = `{
                 "tags": [
                     "Отель"
                 ],
-                "summary": "Изменить отель (Admin only)",
+                "summary": "Изменить отель (Admin, Hotel manager)",
                 "parameters": [
                     {
                         "type": "string",
@@ -595,6 +717,12 @@ const docTemplate = `{
                         "description": "Новое название отеля (необязательно)",
                         "name": "name",
                         "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Новый ID менеджера отеля (только для админа) (необязательно)",
+                        "name": "owner-id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -622,6 +750,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/httputils.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -631,9 +765,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/flight-ticket/buy": {
-            "post": {
-                "description": "Покупает авиабилеты по ID и количеству пассажиров. Требуется авторизация",
+        "/api/admin/rent/find": {
+            "get": {
+                "description": "Находит брони по фильтрам. Менеджер отеля видит только брони своих отелей и должен указать 'hotel-id'",
                 "consumes": [
                     "application/json"
                 ],
@@ -641,23 +775,27 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Авиабилет"
+                    "Номер отеля"
                 ],
-                "summary": "Купить авиабилет",
+                "summary": "Найти брони (Admin, Support agent, Hotel manager)",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID авиабилета",
-                        "name": "id",
-                        "in": "query",
-                        "required": true
+                        "description": "ID отеля (обязательно для менеджера отеля)",
+                        "name": "hotel-id",
+                        "in": "query"
                     },
                     {
-                        "type": "integer",
-                        "description": "Количество пассажиров",
-                        "name": "quantity",
-                        "in": "query",
-                        "required": true
+                        "type": "string",
+                        "description": "ID номера отеля (необязательно)",
+                        "name": "hotel-room-id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID арендатора (необязательно)",
+                        "name": "renter-id",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -672,7 +810,10 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/flightticket.FlightTicket"
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/rent.Rent"
+                                            }
                                         }
                                     }
                                 }
@@ -685,6 +826,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/httputils.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -694,9 +841,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/flight-ticket/find": {
-            "get": {
-                "description": "Находит авиабилет по фильтрам. Можно использовать 'city-from' и 'city-to' вместе с 'city-via' для поиска билетов с пересадкой в этом городе или без него. Если указать 'arrange', то билеты будут отсортированы по цене в указанном порядке (asc - по возрастанию, desc - по убыванию). Самый быстрый и самый дешевый авиабилеты/рейсы отмечаются категориями 'самый быстрый', 'самый дешевый', исключение если нет прямого пути без пересадок - в таком случае выдается самый быстрый путь. При поиске по нужным датам у билетов учитывается только дата взлета, то есть если дата посадки будет выходить из границ дат, то билет все равно выдаст как доступный",
+        "/api/admin/user/disable": {
+            "post": {
+                "description": "Завершает все сессии пользователя. Пока аккаунт заблокирован, вход, токены и API ключи пользователя не принимаются",
                 "consumes": [
                     "application/json"
                 ],
@@ -704,52 +851,66 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Авиабилет"
+                    "Пользователь"
                 ],
-                "summary": "Найти авиабилет",
+                "summary": "Заблокировать пользователя (Admin only)",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Из какого города",
-                        "name": "city-from",
+                        "description": "ID пользователя",
+                        "name": "id",
                         "in": "query",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.SuccessResponse"
+                        }
                     },
-                    {
-                        "type": "string",
-                        "description": "Через какой город (по выбору)",
-                        "name": "city-via",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "В какой город (по выбору)",
-                        "name": "city-to",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Количество билетов/мест (необязательно)",
-                        "name": "quantity",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Дата и время взлета (формат: 2006-01-02T15:04:05Z) (необязательно)",
-                        "name": "take-off",
-                        "in": "query"
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
                     },
-                    {
-                        "type": "string",
-                        "description": "Дата и время посадки (формат: 2006-01-02T15:04:06Z) (необязательно)",
-                        "name": "arrival",
-                        "in": "query"
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
                     },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/user/enable": {
+            "post": {
+                "description": "Снимает блокировку аккаунта, после этого пользователь может снова войти",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Разблокировать пользователя (Admin only)",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "Упорядочить по цене ('asc' возрастание, 'desc' убывание) (необязательно)",
-                        "name": "arrange",
-                        "in": "query"
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -765,6 +926,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/httputils.ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -774,9 +941,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/hotel-room/find": {
+        "/api/admin/user/find": {
             "get": {
-                "description": "Находит номер отеля по фильтрам. Можно искать без фильтров. Нельзя использовать одновременно 'date-to' и 'days'.  Если указать 'arrange', то билеты будут отсортированы по цене в указанном порядке (asc - по возрастанию, desc - по убыванию)",
+                "description": "Ищет пользователей по части email или имени (без учета регистра), роли и блокировке. Удаленные аккаунты не возвращаются. Результат отсортирован по email",
                 "consumes": [
                     "application/json"
                 ],
@@ -784,121 +951,109 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Номер отеля"
+                    "Пользователь"
                 ],
-                "summary": "Найти номер отеля",
+                "summary": "Найти пользователей (Admin only)",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID номера отеля (необязательно)",
-                        "name": "id",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "ID отеля (необязательно)",
-                        "name": "hotel-id",
+                        "description": "Часть email или имени",
+                        "name": "query",
                         "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Дата заселения/свободен ли (прим. 2016-10-06, год-месяц-день) (необязательно)",
-                        "name": "date-from",
+                        "description": "Роль",
+                        "name": "role",
                         "in": "query"
                     },
                     {
-                        "type": "string",
-                        "description": "Дата выезда/свободен ли (прим. 2016-10-06, год-месяц-день) (необязательно)",
-                        "name": "date-to",
+                        "type": "boolean",
+                        "description": "Только заблокированные (true) или только активные (false)",
+                        "name": "disabled",
                         "in": "query"
                     },
                     {
                         "type": "integer",
-                        "description": "Количество дней (необязательно)",
-                        "name": "days",
+                        "description": "Сколько пропустить (по умолчанию 0)",
+                        "name": "offset",
                         "in": "query"
                     },
                     {
                         "type": "integer",
-                        "description": "Минимальное количество комнат (необязательно)",
-                        "name": "rooms-from",
+                        "description": "Размер страницы (по умолчанию 20, максимум 100)",
+                        "name": "limit",
                         "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httputils.SuccessResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/userusecases.UserPage"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
                     },
-                    {
-                        "type": "integer",
-                        "description": "Максимальное количество комнат (необязательно)",
-                        "name": "rooms-to",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Первый тип (standard, large, premium) (необязательно)",
-                        "name": "type-first",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Второй тип (standard, large, premium) (необязательно)",
-                        "name": "type-second",
-                        "in": "query"
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
                     },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/admin/user/grant-role": {
+            "post": {
+                "description": "Выдает роль другому пользователю. Каждая выдача записывается в историю с ID выдавшего админа",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Выдать роль пользователю (Admin only)",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "Единый тип (standard, large, premium) (при использовании перекрывает первый тип) (необязательно)",
-                        "name": "type",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Минимальное количество человек (необязательно)",
-                        "name": "amount-people-from",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Максимальное количество человек (необязательно)",
-                        "name": "amount-people-to",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Минимальная цена за номер (необязательно)",
-                        "name": "value-from",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Максимальная цена за номер (необязательно)",
-                        "name": "value-to",
-                        "in": "query"
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Упорядочить по цене ('asc' возрастание, 'desc' убывание) (необязательно)",
-                        "name": "arrange",
-                        "in": "query"
+                        "description": "Роль (user, admin, hotel_manager, airline_operator, support_agent)",
+                        "name": "role",
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "allOf": [
-                                {
-                                    "$ref": "#/definitions/httputils.SuccessResponse"
-                                },
-                                {
-                                    "type": "object",
-                                    "properties": {
-                                        "data": {
-                                            "type": "array",
-                                            "items": {
-                                                "$ref": "#/definitions/hotelroom.HotelRoom"
-                                            }
-                                        }
-                                    }
-                                }
-                            ]
+                            "$ref": "#/definitions/httputils.SuccessResponse"
                         }
                     },
                     "400": {
@@ -907,6 +1062,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/httputils.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -916,9 +1089,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/hotel-room/rent": {
+        "/api/admin/user/logout": {
             "post": {
-                "description": "Бронирует номер отеля на заданные даты. Дата выезда считается свободной для другого бронирования. 'days' нельзя использовать вместе с 'date-to'. Требуется авторизация",
+                "description": "Все выданные пользователю токены перестают действовать, войти заново он может сразу",
                 "consumes": [
                     "application/json"
                 ],
@@ -926,54 +1099,23 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Номер отеля"
+                    "Пользователь"
                 ],
-                "summary": "Забронировать номер отеля",
+                "summary": "Завершить все сессии пользователя (Admin only)",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID номера отеля",
+                        "description": "ID пользователя",
                         "name": "id",
                         "in": "query",
                         "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Дата заселения (прим. 2016-10-06, год-месяц-день)",
-                        "name": "date-from",
-                        "in": "query",
-                        "required": true
-                    },
-                    {
-                        "type": "string",
-                        "description": "Дата выезда (прим. 2016-10-06, год-месяц-день) (по выбору)",
-                        "name": "date-to",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Количество дней (по выбору)",
-                        "name": "days",
-                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "allOf": [
-                                {
-                                    "$ref": "#/definitions/httputils.SuccessResponse"
-                                },
-                                {
-                                    "type": "object",
-                                    "properties": {
-                                        "data": {
-                                            "$ref": "#/definitions/rent.Rent"
-                                        }
-                                    }
-                                }
-                            ]
+                            "$ref": "#/definitions/httputils.SuccessResponse"
                         }
                     },
                     "400": {
@@ -982,6 +1124,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/httputils.ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -991,9 +1139,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/hotel-room/unrent": {
+        "/api/admin/user/revoke-role": {
             "post": {
-                "description": "Отменяет бронь номер отеля по ID брони. Админ может удалить бронь у любого пользователя. Требуется авторизация",
+                "description": "Возвращает пользователю роль 'user'. Отзыв записывается в историю с ID админа",
                 "consumes": [
                     "application/json"
                 ],
@@ -1001,14 +1149,14 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Номер отеля"
+                    "Пользователь"
                 ],
-                "summary": "Отменить бронь номера отеля",
+                "summary": "Отозвать роль у пользователя (Admin only)",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID брони",
-                        "name": "rent-id",
+                        "description": "ID пользователя",
+                        "name": "id",
                         "in": "query",
                         "required": true
                     }
@@ -1026,6 +1174,24 @@ const docTemplate = `{
                             "$ref": "#/definitions/httputils.ErrorResponse"
                         }
                     },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1035,9 +1201,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/hotel/find": {
+        "/api/admin/user/role-grants": {
             "get": {
-                "description": "Находит отель по фильтрам. Можно искать без фильтров. Нельзя использовать одновременно 'stars' и 'stars-from' + 'stars-to'. Если указать 'arrange', то билеты будут отсортированы по цене в указанном порядке (asc - по возрастанию, desc - по убыванию)",
+                "description": "Возвращает все выдачи и отзывы ролей пользователя, начиная с последних",
                 "consumes": [
                     "application/json"
                 ],
@@ -1045,45 +1211,16 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Отель"
+                    "Пользователь"
                 ],
-                "summary": "Найти отель по параметрам",
+                "summary": "История ролей пользователя (Admin only)",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "ID отеля (необязательно)",
+                        "description": "ID пользователя",
                         "name": "id",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Город (необязательно)",
-                        "name": "city",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Количество звёзд (необязательно)",
-                        "name": "stars",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Минимальное кол-во звезд (необязательно)",
-                        "name": "stars-from",
-                        "in": "query"
-                    },
-                    {
-                        "type": "integer",
-                        "description": "Максимальное кол-во звезд (необязательно)",
-                        "name": "stars-to",
-                        "in": "query"
-                    },
-                    {
-                        "type": "string",
-                        "description": "Упорядочить по звездам ('asc' возрастание, 'desc' убывание) (необязательно)",
-                        "name": "arrange",
-                        "in": "query"
+                        "in": "query",
+                        "required": true
                     }
                 ],
                 "responses": {
@@ -1100,7 +1237,7 @@ const docTemplate = `{
                                         "data": {
                                             "type": "array",
                                             "items": {
-                                                "$ref": "#/definitions/hotel.Hotel"
+                                                "$ref": "#/definitions/rolegrant.RoleGrant"
                                             }
                                         }
                                     }
@@ -1123,9 +1260,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/user/get-admin": {
+        "/api/admin/user/unlock": {
             "post": {
-                "description": "Выдает роль админа по паролю. Требуется авторизация",
+                "description": "Сбрасывает счетчик неудачных попыток входа для email пользователя и, если указан, для IP адреса",
                 "consumes": [
                     "application/json"
                 ],
@@ -1135,14 +1272,20 @@ const docTemplate = `{
                 "tags": [
                     "Пользователь"
                 ],
-                "summary": "Выдать роль админа",
+                "summary": "Разблокировать вход (Admin only)",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Пароль админки",
-                        "name": "password",
+                        "description": "ID пользователя",
+                        "name": "id",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "IP адрес",
+                        "name": "ip",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -1167,9 +1310,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/user/get-user": {
-            "post": {
-                "description": "Выдает роль пользователя. Требуется авторизация",
+        "/api/admin/user/view": {
+            "get": {
+                "description": "Возвращает пользователя, его брони номеров и покупки авиабилетов",
                 "consumes": [
                     "application/json"
                 ],
@@ -1179,12 +1322,33 @@ const docTemplate = `{
                 "tags": [
                     "Пользователь"
                 ],
-                "summary": "Выдать роль пользователя",
+                "summary": "Пользователь с бронями и покупками (Admin only)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID пользователя",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/httputils.SuccessResponse"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httputils.SuccessResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/userusecases.UserDetails"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "400": {
@@ -1193,6 +1357,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/httputils.ErrorResponse"
                         }
                     },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1202,9 +1372,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/user/login": {
+        "/api/flight-ticket/buy": {
             "post": {
-                "description": "Вход в аккаунт по email и паролю",
+                "description": "Покупает авиабилеты по ID и количеству пассажиров. Покупка сохраняется в истории пользователя. Требуется авторизация",
                 "consumes": [
                     "application/json"
                 ],
@@ -1212,21 +1382,21 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Пользователь"
+                    "Авиабилет"
                 ],
-                "summary": "Войти в аккаунт",
+                "summary": "Купить авиабилет",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Email",
-                        "name": "email",
+                        "description": "ID авиабилета",
+                        "name": "id",
                         "in": "query",
                         "required": true
                     },
                     {
-                        "type": "string",
-                        "description": "Пароль",
-                        "name": "password",
+                        "type": "integer",
+                        "description": "Количество пассажиров",
+                        "name": "quantity",
                         "in": "query",
                         "required": true
                     }
@@ -1243,7 +1413,7 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/user.User"
+                                            "$ref": "#/definitions/flightticket.FlightTicket"
                                         }
                                     }
                                 }
@@ -1256,6 +1426,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/httputils.ErrorResponse"
                         }
                     },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1265,9 +1441,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/user/register": {
-            "post": {
-                "description": "Регистрирует пользователя по email, имени и паролю",
+        "/api/flight-ticket/find": {
+            "get": {
+                "description": "Находит авиабилет по фильтрам. Можно использовать 'city-from' и 'city-to' вместе с 'city-via' для поиска билетов с пересадкой в этом городе или без него. Если указать 'arrange', то билеты будут отсортированы по цене в указанном порядке (asc - по возрастанию, desc - по убыванию). Самый быстрый и самый дешевый авиабилеты/рейсы отмечаются категориями 'самый быстрый', 'самый дешевый', исключение если нет прямого пути без пересадок - в таком случае выдается самый быстрый путь. При поиске по нужным датам у билетов учитывается только дата взлета, то есть если дата посадки будет выходить из границ дат, то билет все равно выдаст как доступный",
                 "consumes": [
                     "application/json"
                 ],
@@ -1275,30 +1451,179 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Пользователь"
+                    "Авиабилет"
                 ],
-                "summary": "Зарегистрироваться",
+                "summary": "Найти авиабилет",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Имя пользователя",
-                        "name": "name",
+                        "description": "Из какого города",
+                        "name": "city-from",
                         "in": "query",
                         "required": true
                     },
                     {
                         "type": "string",
-                        "description": "Email пользователя",
-                        "name": "email",
-                        "in": "query",
-                        "required": true
+                        "description": "Через какой город (по выбору)",
+                        "name": "city-via",
+                        "in": "query"
                     },
                     {
                         "type": "string",
-                        "description": "Пароль пользователя",
-                        "name": "password",
-                        "in": "query",
-                        "required": true
+                        "description": "В какой город (по выбору)",
+                        "name": "city-to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество билетов/мест (необязательно)",
+                        "name": "quantity",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата и время взлета (формат: 2006-01-02T15:04:05Z) (необязательно)",
+                        "name": "take-off",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата и время посадки (формат: 2006-01-02T15:04:06Z) (необязательно)",
+                        "name": "arrival",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Упорядочить по цене ('asc' возрастание, 'desc' убывание) (необязательно)",
+                        "name": "arrange",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/hotel-room/find": {
+            "get": {
+                "description": "Находит номер отеля по фильтрам. Можно искать без фильтров. Нельзя использовать одновременно 'date-to' и 'days'.  Если указать 'arrange', то билеты будут отсортированы по цене в указанном порядке (asc - по возрастанию, desc - по убыванию)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Номер отеля"
+                ],
+                "summary": "Найти номер отеля",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID номера отеля (необязательно)",
+                        "name": "id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID отеля (необязательно)",
+                        "name": "hotel-id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата заселения/свободен ли (прим. 2016-10-06, год-месяц-день) (необязательно)",
+                        "name": "date-from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата выезда/свободен ли (прим. 2016-10-06, год-месяц-день) (необязательно)",
+                        "name": "date-to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество дней (необязательно)",
+                        "name": "days",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Минимальное количество комнат (необязательно)",
+                        "name": "rooms-from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальное количество комнат (необязательно)",
+                        "name": "rooms-to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Первый тип (standard, large, premium) (необязательно)",
+                        "name": "type-first",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Второй тип (standard, large, premium) (необязательно)",
+                        "name": "type-second",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Единый тип (standard, large, premium) (при использовании перекрывает первый тип) (необязательно)",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Минимальное количество человек (необязательно)",
+                        "name": "amount-people-from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальное количество человек (необязательно)",
+                        "name": "amount-people-to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Минимальная цена за номер (необязательно)",
+                        "name": "value-from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальная цена за номер (необязательно)",
+                        "name": "value-to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Упорядочить по цене ('asc' возрастание, 'desc' убывание) (необязательно)",
+                        "name": "arrange",
+                        "in": "query"
                     }
                 ],
                 "responses": {
@@ -1313,7 +1638,10 @@ const docTemplate = `{
                                     "type": "object",
                                     "properties": {
                                         "data": {
-                                            "$ref": "#/definitions/user.User"
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/hotelroom.HotelRoom"
+                                            }
                                         }
                                     }
                                 }
@@ -1335,9 +1663,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/api/user/rename": {
-            "put": {
-                "description": "Изменяет имя пользователя. Требуется авторизация",
+        "/api/hotel-room/rent": {
+            "post": {
+                "description": "Бронирует номер отеля на заданные даты. Дата выезда считается свободной для другого бронирования. 'days' нельзя использовать вместе с 'date-to'. Требуется авторизация",
                 "consumes": [
                     "application/json"
                 ],
@@ -1345,23 +1673,54 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Пользователь"
+                    "Номер отеля"
                 ],
-                "summary": "Изменить имя",
+                "summary": "Забронировать номер отеля",
                 "parameters": [
                     {
                         "type": "string",
-                        "description": "Новое имя пользователя",
-                        "name": "name",
+                        "description": "ID номера отеля",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата заселения (прим. 2016-10-06, год-месяц-день)",
+                        "name": "date-from",
                         "in": "query",
                         "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Дата выезда (прим. 2016-10-06, год-месяц-день) (по выбору)",
+                        "name": "date-to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество дней (по выбору)",
+                        "name": "days",
+                        "in": "query"
                     }
                 ],
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/httputils.SuccessResponse"
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httputils.SuccessResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/rent.Rent"
+                                        }
+                                    }
+                                }
+                            ]
                         }
                     },
                     "400": {
@@ -1370,6 +1729,12 @@ const docTemplate = `{
                             "$ref": "#/definitions/httputils.ErrorResponse"
                         }
                     },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
                     "500": {
                         "description": "Internal Server Error",
                         "schema": {
@@ -1378,65 +1743,2189 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "flightticket.FlightTicket": {
+        },
+        "/api/hotel-room/unrent": {
+            "post": {
+                "description": "Отменяет бронь номер отеля по ID брони. Отмененная бронь остается в истории с полями cancelled_at и cancel_reason, а ее даты снова свободны. Админ и агент поддержки могут отменить бронь любого пользователя, менеджер отеля - брони своих отелей. Требуется авторизация",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Номер отеля"
+                ],
+                "summary": "Отменить бронь номера отеля",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID брони",
+                        "name": "rent-id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/hotel/find": {
+            "get": {
+                "description": "Находит отель по фильтрам. Можно искать без фильтров. Нельзя использовать одновременно 'stars' и 'stars-from' + 'stars-to'. Если указать 'arrange', то билеты будут отсортированы по цене в указанном порядке (asc - по возрастанию, desc - по убыванию)",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Отель"
+                ],
+                "summary": "Найти отель по параметрам",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID отеля (необязательно)",
+                        "name": "id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Город (необязательно)",
+                        "name": "city",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество звёзд (необязательно)",
+                        "name": "stars",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Минимальное кол-во звезд (необязательно)",
+                        "name": "stars-from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Максимальное кол-во звезд (необязательно)",
+                        "name": "stars-to",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Упорядочить по звездам ('asc' возрастание, 'desc' убывание) (необязательно)",
+                        "name": "arrange",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httputils.SuccessResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/hotel.Hotel"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/partner/hotel-room/create": {
+            "post": {
+                "description": "Создаёт номер отеля с переданными параметрами. Менеджер отеля может создавать номера только в своих отелях",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Номер отеля"
+                ],
+                "summary": "Создать номер отеля (Admin, Hotel manager)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID отеля",
+                        "name": "hotel-id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество комнат",
+                        "name": "rooms",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Тип номера (standard, large, premium)",
+                        "name": "type",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Количество человек",
+                        "name": "amount-people",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Цена за номер",
+                        "name": "value",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httputils.SuccessResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/hotelroom.HotelRoom"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/partner/hotel-room/delete": {
+            "delete": {
+                "description": "Удаляет номер отеля по ID. Менеджер отеля может удалять номера только своих отелей. Номер остается в истории аренд, но не находится в поиске. Если у номера есть незакончившиеся аренды, удаление отклоняется, а с 'force=true' аренды отменяются и арендаторам отправляется письмо",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Номер отеля"
+                ],
+                "summary": "Удалить номер отеля (Admin, Hotel manager)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID номера отеля",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "boolean",
+                        "description": "Отменить незакончившиеся аренды",
+                        "name": "force",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/partner/hotel-room/price": {
+            "put": {
+                "description": "Изменяет цену номера отеля. Партнер может менять цены только в своих отелях. Требуется авторизация",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Партнер"
+                ],
+                "summary": "Изменить цену номера (Partner)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID номера отеля",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Новая цена за номер",
+                        "name": "value",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httputils.SuccessResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/hotelroom.HotelRoom"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/partner/hotel-room/update": {
+            "put": {
+                "description": "Изменяет номер отеля переданными параметрами. Менеджер отеля может изменять номера только своих отелей",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Номер отеля"
+                ],
+                "summary": "Изменить номер отеля (Admin, Hotel manager)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID номера отеля",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Новый ID отеля (необязательно)",
+                        "name": "hotel-id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Новое количество комнат (необязательно)",
+                        "name": "rooms",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Новый тип номера (standard, large, premium) (необязательно)",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Новое количество человек (необязательно)",
+                        "name": "amount-people",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Новая цена за номер (необязательно)",
+                        "name": "value",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httputils.SuccessResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/hotelroom.HotelRoom"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/partner/hotel/list": {
+            "get": {
+                "description": "Возвращает отели, владельцем которых является партнер (менеджер отеля). Требуется авторизация",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Партнер"
+                ],
+                "summary": "Мои отели (Partner)",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httputils.SuccessResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/hotel.Hotel"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/partner/rent/find": {
+            "get": {
+                "description": "Находит брони по фильтрам. Менеджер отеля видит только брони своих отелей и должен указать 'hotel-id'",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Номер отеля"
+                ],
+                "summary": "Найти брони (Admin, Support agent, Hotel manager)",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID отеля (обязательно для менеджера отеля)",
+                        "name": "hotel-id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID номера отеля (необязательно)",
+                        "name": "hotel-room-id",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID арендатора (необязательно)",
+                        "name": "renter-id",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httputils.SuccessResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/rent.Rent"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/2fa/disable": {
+            "post": {
+                "description": "Выключает 2FA после проверки пароля и кода из приложения или кода восстановления. Неверный пароль считается неудачной попыткой входа. Требуется авторизация",
+                "consumes": [
+                    "application/json",
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Выключить 2FA",
+                "parameters": [
+                    {
+                        "description": "Текущий пароль и код",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.TotpDisableRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/2fa/enable": {
+            "post": {
+                "description": "Подтверждает код из приложения и включает 2FA. Возвращает коды восстановления, они показываются только один раз. Все сессии, начатые до включения 2FA, завершаются, текущая получает новые cookie и токены в теле ответа. Требуется авторизация",
+                "consumes": [
+                    "application/json",
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Включить 2FA",
+                "parameters": [
+                    {
+                        "description": "Код из приложения",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.TotpCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httputils.SuccessResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/userusecases.TotpEnabled"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/2fa/recovery-codes": {
+            "post": {
+                "description": "Заменяет все коды восстановления новыми. Нужен код из приложения. Требуется авторизация",
+                "consumes": [
+                    "application/json",
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Новые коды восстановления 2FA",
+                "parameters": [
+                    {
+                        "description": "Код из приложения",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.TotpCodeRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httputils.SuccessResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "type": "string"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/2fa/setup": {
+            "post": {
+                "description": "Создает секрет TOTP и ссылку otpauth:// для QR-кода. 2FA включается только после подтверждения кода через /api/user/2fa/enable. Неверный пароль считается неудачной попыткой входа. Требуется авторизация",
+                "consumes": [
+                    "application/json",
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Начать настройку 2FA",
+                "parameters": [
+                    {
+                        "description": "Текущий пароль",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.TotpSetupRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httputils.SuccessResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/userusecases.TotpSetup"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/api-key/create": {
+            "post": {
+                "description": "Создает долгоживущий ключ для интеграций сервер-сервер. Ключ действует от имени владельца, но только с указанными правами, которые есть у его роли. Передается в заголовке 'X-API-Key' или 'Authorization: Bearer'. Сам ключ показывается только один раз. Требуется авторизация",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Создать API ключ",
+                "parameters": [
+                    {
+                        "description": "Название и права ключа (users:manage, hotels:manage, hotel_rooms:manage, flights:manage, rents:read, rents:cancel)",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.CreateApiKeyRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httputils.SuccessResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/handler.CreatedApiKey"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/api-key/list": {
+            "get": {
+                "description": "Возвращает все API ключи пользователя, включая отозванные, с временем последнего использования. Требуется авторизация",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Мои API ключи",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httputils.SuccessResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "type": "array",
+                                            "items": {
+                                                "$ref": "#/definitions/apikey.ApiKey"
+                                            }
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/api-key/revoke": {
+            "post": {
+                "description": "Ключ перестает работать сразу. Требуется авторизация",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Отозвать API ключ",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID ключа",
+                        "name": "id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/change-email": {
+            "post": {
+                "description": "Отправляет ссылку подтверждения на новый email. Email меняется только после перехода по ссылке. Неверный пароль считается неудачной попыткой входа. Требуется авторизация",
+                "consumes": [
+                    "application/json",
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Сменить email",
+                "parameters": [
+                    {
+                        "description": "Новый email и текущий пароль",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ChangeEmailRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/change-email/confirm": {
+            "get": {
+                "description": "Меняет email на новый по одноразовому токену из письма",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Подтвердить новый email",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Токен из письма",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/change-password": {
+            "put": {
+                "description": "Меняет пароль после проверки старого. Все остальные сессии пользователя завершаются, а неиспользованные коды сброса пароля перестают действовать. Текущая сессия получает новые cookie, те же токены возвращаются в теле ответа для клиентов с 'Authorization: Bearer'. Неверный старый пароль считается неудачной попыткой входа. Требуется авторизация",
+                "consumes": [
+                    "application/json",
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Сменить пароль",
+                "parameters": [
+                    {
+                        "description": "Текущий и новый пароль",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.ChangePasswordRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httputils.SuccessResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/userusecases.TokenPair"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/delete": {
+            "delete": {
+                "description": "Обезличивает аккаунт: имя, email и пароль стираются, все сессии завершаются. Брони и билеты сохраняются для отчетности. Если включена 2FA, нужен код из приложения или код восстановления. Неверный пароль или код считается неудачной попыткой входа. Требуется авторизация",
+                "consumes": [
+                    "application/json",
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Удалить аккаунт",
+                "parameters": [
+                    {
+                        "description": "Текущий пароль и код 2FA",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.DeleteAccountRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/login": {
+            "post": {
+                "description": "Вход в аккаунт по email и паролю. Данные принимаются в теле запроса (JSON или форма). После нескольких неудачных попыток вход замедляется, а затем временно блокируется (ответ 429 с заголовком Retry-After). Неизвестный email и неверный пароль дают одинаковый ответ 401. Если включена 2FA, нужен код из приложения или код восстановления, без него вернется 401",
+                "consumes": [
+                    "application/json",
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Войти в аккаунт",
+                "parameters": [
+                    {
+                        "description": "Email и пароль",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httputils.SuccessResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/user.User"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/me": {
+            "get": {
+                "description": "Возвращает текущего пользователя, его настройки и количество бронирований. Требуется авторизация",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Мой профиль",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httputils.SuccessResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/userusecases.Profile"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Изменяет имя, телефон, язык и валюту. Меняются только переданные поля, пустая строка очищает телефон, язык или валюту. Требуется авторизация",
+                "consumes": [
+                    "application/json",
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Изменить профиль",
+                "parameters": [
+                    {
+                        "description": "Поля профиля",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.UpdateProfileRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httputils.SuccessResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/userusecases.Profile"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/password-reset/confirm": {
+            "post": {
+                "description": "Устанавливает новый пароль по одноразовому коду из письма. Если новый пароль не подходит под политику паролей, вернется 400, а код останется действительным",
+                "consumes": [
+                    "application/json",
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Сбросить пароль",
+                "parameters": [
+                    {
+                        "description": "Код из письма и новый пароль",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.PasswordResetConfirmRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/password-reset/request": {
+            "post": {
+                "description": "Отправляет на email одноразовый код для сброса пароля. Ответ одинаковый независимо от того, существует ли аккаунт",
+                "consumes": [
+                    "application/json",
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Запросить сброс пароля",
+                "parameters": [
+                    {
+                        "description": "Email",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.PasswordResetRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/register": {
+            "post": {
+                "description": "Регистрирует пользователя по email, имени и паролю. Данные принимаются в теле запроса (JSON или форма). Пароль должен соответствовать политике паролей и не встречаться в утечках, иначе вернется 400. Если email уже занят, вернется 409",
+                "consumes": [
+                    "application/json",
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Зарегистрироваться",
+                "parameters": [
+                    {
+                        "description": "Данные для регистрации",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.RegisterRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httputils.SuccessResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/user.User"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/rename": {
+            "put": {
+                "description": "Изменяет имя пользователя. Требуется авторизация",
+                "consumes": [
+                    "application/json",
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Изменить имя",
+                "parameters": [
+                    {
+                        "description": "Новое имя пользователя",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.RenameRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/token": {
+            "post": {
+                "description": "Вход для мобильных приложений и скриптов: возвращает access и refresh токены в JSON вместо cookie. Access токен передается в заголовке 'Authorization: Bearer \u003ctoken\u003e'. Неизвестный email и неверный пароль дают одинаковый ответ 401",
+                "consumes": [
+                    "application/json",
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Получить токены",
+                "parameters": [
+                    {
+                        "description": "Email, пароль и код 2FA",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.LoginRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httputils.SuccessResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/userusecases.TokenPair"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "403": {
+                        "description": "Forbidden",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/token/refresh": {
+            "post": {
+                "description": "Выдает новый access токен по refresh токену из /api/user/token",
+                "consumes": [
+                    "application/json",
+                    "application/x-www-form-urlencoded"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Обновить access токен",
+                "parameters": [
+                    {
+                        "description": "Refresh токен",
+                        "name": "request",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/handler.RefreshTokenRequest"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "allOf": [
+                                {
+                                    "$ref": "#/definitions/httputils.SuccessResponse"
+                                },
+                                {
+                                    "type": "object",
+                                    "properties": {
+                                        "data": {
+                                            "$ref": "#/definitions/userusecases.TokenPair"
+                                        }
+                                    }
+                                }
+                            ]
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/verify-email/confirm": {
+            "get": {
+                "description": "Подтверждает email по одноразовому токену из письма",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Подтвердить email",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Токен из письма",
+                        "name": "token",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.SuccessResponse"
+                        }
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/user/verify-email/send": {
+            "post": {
+                "description": "Отправляет на email пользователя ссылку для подтверждения. Предыдущие ссылки перестают действовать. Требуется авторизация",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Пользователь"
+                ],
+                "summary": "Отправить письмо для подтверждения email",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.SuccessResponse"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/httputils.ErrorResponse"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "apikey.ApiKey": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "integer"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "last_used_at": {
+                    "type": "integer"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "owner_id": {
+                    "type": "string"
+                },
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/user.Permission"
+                    }
+                },
+                "prefix": {
+                    "description": "first characters of the key, to recognize it in the list",
+                    "type": "string"
+                },
+                "revoked_at": {
+                    "type": "integer"
+                }
+            }
+        },
+        "flightticket.FlightTicket": {
+            "type": "object",
+            "properties": {
+                "arrival": {
+                    "type": "integer"
+                },
+                "category": {
+                    "$ref": "#/definitions/flightticket.FlightTicketCategories"
+                },
+                "city_from": {
+                    "type": "string"
+                },
+                "city_to": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "operator_id": {
+                    "description": "airline operator, uuid.Nil if managed only by admins",
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "take_off": {
+                    "type": "integer"
+                },
+                "value": {
+                    "type": "integer"
+                }
+            }
+        },
+        "flightticket.FlightTicketCategories": {
+            "type": "string",
+            "enum": [
+                "самый быстрый",
+                "самый дешевый",
+                "самый дешевый, самый быстрый",
+                ""
+            ],
+            "x-enum-varnames": [
+                "Fastest",
+                "Cheapest",
+                "CheapestFastest",
+                "None"
+            ]
+        },
+        "flightticket.Purchase": {
+            "type": "object",
+            "properties": {
+                "buyer_id": {
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "integer"
+                },
+                "flight_ticket_id": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "quantity": {
+                    "type": "integer"
+                },
+                "value": {
+                    "description": "total for all passengers",
+                    "type": "integer"
+                }
+            }
+        },
+        "handler.ChangeEmailRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "new-ivan@example.com"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "strong-password"
+                }
+            }
+        },
+        "handler.ChangePasswordRequest": {
+            "type": "object",
+            "properties": {
+                "new_password": {
+                    "type": "string",
+                    "example": "new-strong-password"
+                },
+                "old_password": {
+                    "type": "string",
+                    "example": "strong-password"
+                }
+            }
+        },
+        "handler.CreateApiKeyRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "channel manager"
+                },
+                "permissions": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "hotel_rooms:manage"
+                    ]
+                }
+            }
+        },
+        "handler.CreatedApiKey": {
+            "type": "object",
+            "properties": {
+                "api_key": {
+                    "$ref": "#/definitions/apikey.ApiKey"
+                },
+                "key": {
+                    "type": "string",
+                    "example": "otk_..."
+                }
+            }
+        },
+        "handler.DeleteAccountRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "TOTP or recovery code, only when 2FA is enabled",
+                    "type": "string",
+                    "example": "123456"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "strong-password"
+                }
+            }
+        },
+        "handler.LoginRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "TOTP or recovery code, only when 2FA is enabled",
+                    "type": "string",
+                    "example": "123456"
+                },
+                "email": {
+                    "type": "string",
+                    "example": "ivan@example.com"
+                },
+                "password": {
+                    "type": "string",
+                    "example": "strong-password"
+                }
+            }
+        },
+        "handler.PasswordResetConfirmRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "new-strong-password"
+                },
+                "token": {
+                    "type": "string"
+                }
+            }
+        },
+        "handler.PasswordResetRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "ivan@example.com"
+                }
+            }
+        },
+        "handler.RefreshTokenRequest": {
             "type": "object",
             "properties": {
-                "arrival": {
-                    "type": "integer"
-                },
-                "category": {
-                    "$ref": "#/definitions/flightticket.FlightTicketCategories"
-                },
-                "city_from": {
+                "refresh_token": {
                     "type": "string"
+                }
+            }
+        },
+        "handler.RegisterRequest": {
+            "type": "object",
+            "properties": {
+                "email": {
+                    "type": "string",
+                    "example": "ivan@example.com"
                 },
-                "city_to": {
-                    "type": "string"
+                "name": {
+                    "type": "string",
+                    "example": "Иван"
                 },
-                "id": {
-                    "type": "string"
+                "password": {
+                    "type": "string",
+                    "example": "strong-password"
+                }
+            }
+        },
+        "handler.RenameRequest": {
+            "type": "object",
+            "properties": {
+                "name": {
+                    "type": "string",
+                    "example": "Иван"
+                }
+            }
+        },
+        "handler.TotpCodeRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
+                }
+            }
+        },
+        "handler.TotpDisableRequest": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "type": "string",
+                    "example": "123456"
                 },
-                "quantity": {
-                    "type": "integer"
+                "password": {
+                    "type": "string",
+                    "example": "strong-password"
+                }
+            }
+        },
+        "handler.TotpSetupRequest": {
+            "type": "object",
+            "properties": {
+                "password": {
+                    "type": "string",
+                    "example": "strong-password"
+                }
+            }
+        },
+        "handler.UpdateProfileRequest": {
+            "type": "object",
+            "properties": {
+                "currency": {
+                    "type": "string",
+                    "example": "RUB"
                 },
-                "take_off": {
-                    "type": "integer"
+                "language": {
+                    "type": "string",
+                    "example": "ru"
                 },
-                "value": {
-                    "type": "integer"
+                "name": {
+                    "type": "string",
+                    "example": "Иван"
+                },
+                "phone": {
+                    "type": "string",
+                    "example": "+79991234567"
                 }
             }
         },
-        "flightticket.FlightTicketCategories": {
-            "type": "string",
-            "enum": [
-                "самый быстрый",
-                "самый дешевый",
-                "самый дешевый, самый быстрый",
-                ""
-            ],
-            "x-enum-varnames": [
-                "Fastest",
-                "Cheapest",
-                "CheapestFastest",
-                "None"
-            ]
-        },
         "hotel.Hotel": {
             "type": "object",
             "properties": {
                 "city": {
                     "type": "string"
                 },
+                "deleted_at": {
+                    "description": "set when deleted, the record is kept for the history of rents",
+                    "type": "integer"
+                },
                 "id": {
                     "type": "string"
                 },
                 "name": {
                     "type": "string"
                 },
+                "owner_id": {
+                    "description": "hotel manager, uuid.Nil if managed only by admins",
+                    "type": "string"
+                },
                 "stars": {
                     "type": "integer"
                 }
@@ -1449,6 +3938,10 @@ const docTemplate = `{
                     "description": "can have 2 values (from, to)",
                     "type": "integer"
                 },
+                "deleted_at": {
+                    "description": "set when deleted, the record is kept for the history of rents",
+                    "type": "integer"
+                },
                 "hotel_uuid": {
                     "type": "string"
                 },
@@ -1517,9 +4010,56 @@ const docTemplate = `{
                 }
             }
         },
+        "jwtutils.Jwk": {
+            "type": "object",
+            "properties": {
+                "alg": {
+                    "type": "string"
+                },
+                "crv": {
+                    "type": "string"
+                },
+                "e": {
+                    "type": "string"
+                },
+                "kid": {
+                    "type": "string"
+                },
+                "kty": {
+                    "type": "string"
+                },
+                "n": {
+                    "type": "string"
+                },
+                "use": {
+                    "type": "string"
+                },
+                "x": {
+                    "type": "string"
+                }
+            }
+        },
+        "jwtutils.JwkSet": {
+            "type": "object",
+            "properties": {
+                "keys": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/jwtutils.Jwk"
+                    }
+                }
+            }
+        },
         "rent.Rent": {
             "type": "object",
             "properties": {
+                "cancel_reason": {
+                    "type": "string"
+                },
+                "cancelled_at": {
+                    "description": "set when cancelled, the record is kept for the history and its dates are free again",
+                    "type": "integer"
+                },
                 "date_from": {
                     "type": "integer"
                 },
@@ -1537,23 +4077,107 @@ const docTemplate = `{
                 }
             }
         },
+        "rolegrant.GrantAction": {
+            "type": "string",
+            "enum": [
+                "grant",
+                "revoke",
+                "bootstrap"
+            ],
+            "x-enum-varnames": [
+                "ActionGrant",
+                "ActionRevoke",
+                "ActionBootstrap"
+            ]
+        },
+        "rolegrant.RoleGrant": {
+            "type": "object",
+            "properties": {
+                "action": {
+                    "$ref": "#/definitions/rolegrant.GrantAction"
+                },
+                "created_at": {
+                    "type": "integer"
+                },
+                "granted_by": {
+                    "description": "uuid.Nil when bootstrapped from CLI or env",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "previous_role": {
+                    "$ref": "#/definitions/user.UserRole"
+                },
+                "role": {
+                    "$ref": "#/definitions/user.UserRole"
+                },
+                "user_id": {
+                    "type": "string"
+                }
+            }
+        },
+        "user.Permission": {
+            "type": "string",
+            "enum": [
+                "users:manage",
+                "hotels:manage",
+                "hotel_rooms:manage",
+                "flights:manage",
+                "rents:read",
+                "rents:cancel"
+            ],
+            "x-enum-varnames": [
+                "PermManageUsers",
+                "PermManageHotels",
+                "PermManageHotelRooms",
+                "PermManageFlights",
+                "PermReadRents",
+                "PermCancelRents"
+            ]
+        },
         "user.User": {
             "type": "object",
             "properties": {
+                "currency": {
+                    "description": "ISO 4217, e.g. RUB",
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "integer"
+                },
+                "disabled_at": {
+                    "description": "disabled by an admin, login and sessions are rejected",
+                    "type": "integer"
+                },
                 "email": {
                     "type": "string"
                 },
+                "email_verified": {
+                    "type": "boolean"
+                },
                 "id": {
                     "type": "string"
                 },
+                "language": {
+                    "description": "ISO 639-1, e.g. ru",
+                    "type": "string"
+                },
                 "name": {
                     "type": "string"
                 },
-                "password": {
+                "pending_email": {
+                    "type": "string"
+                },
+                "phone": {
+                    "description": "E.164, e.g. +79991234567",
                     "type": "string"
                 },
                 "role": {
                     "$ref": "#/definitions/user.UserRole"
+                },
+                "totp_enabled": {
+                    "type": "boolean"
                 }
             }
         },
@@ -1561,12 +4185,162 @@ const docTemplate = `{
             "type": "string",
             "enum": [
                 "admin",
-                "user"
+                "user",
+                "hotel_manager",
+                "airline_operator",
+                "support_agent"
             ],
             "x-enum-varnames": [
                 "RoleAdmin",
-                "RoleUser"
+                "RoleUser",
+                "RoleHotelManager",
+                "RoleAirlineOperator",
+                "RoleSupportAgent"
             ]
+        },
+        "userusecases.BookingCounts": {
+            "type": "object",
+            "properties": {
+                "rents": {
+                    "type": "integer"
+                },
+                "upcoming_rents": {
+                    "description": "not ended yet",
+                    "type": "integer"
+                }
+            }
+        },
+        "userusecases.Profile": {
+            "type": "object",
+            "properties": {
+                "bookings": {
+                    "$ref": "#/definitions/userusecases.BookingCounts"
+                },
+                "currency": {
+                    "description": "ISO 4217, e.g. RUB",
+                    "type": "string"
+                },
+                "deleted_at": {
+                    "type": "integer"
+                },
+                "disabled_at": {
+                    "description": "disabled by an admin, login and sessions are rejected",
+                    "type": "integer"
+                },
+                "email": {
+                    "type": "string"
+                },
+                "email_verified": {
+                    "type": "boolean"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "language": {
+                    "description": "ISO 639-1, e.g. ru",
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string"
+                },
+                "pending_email": {
+                    "type": "string"
+                },
+                "phone": {
+                    "description": "E.164, e.g. +79991234567",
+                    "type": "string"
+                },
+                "role": {
+                    "$ref": "#/definitions/user.UserRole"
+                },
+                "totp_enabled": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "userusecases.TokenPair": {
+            "type": "object",
+            "properties": {
+                "access_token": {
+                    "type": "string"
+                },
+                "expires_in": {
+                    "type": "integer",
+                    "example": 600
+                },
+                "refresh_token": {
+                    "type": "string"
+                },
+                "token_type": {
+                    "type": "string",
+                    "example": "Bearer"
+                }
+            }
+        },
+        "userusecases.TotpEnabled": {
+            "type": "object",
+            "properties": {
+                "recovery_codes": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "tokens": {
+                    "$ref": "#/definitions/userusecases.TokenPair"
+                }
+            }
+        },
+        "userusecases.TotpSetup": {
+            "type": "object",
+            "properties": {
+                "provisioning_uri": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                }
+            }
+        },
+        "userusecases.UserDetails": {
+            "type": "object",
+            "properties": {
+                "flight_ticket_purchases": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/flightticket.Purchase"
+                    }
+                },
+                "rents": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/rent.Rent"
+                    }
+                },
+                "user": {
+                    "$ref": "#/definitions/user.User"
+                }
+            }
+        },
+        "userusecases.UserPage": {
+            "type": "object",
+            "properties": {
+                "limit": {
+                    "type": "integer"
+                },
+                "offset": {
+                    "type": "integer"
+                },
+                "total": {
+                    "type": "integer"
+                },
+                "users": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/user.User"
+                    }
+                }
+            }
         }
     }
 }`
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"fmt"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/application/usecases/apikeyusecases"
+	"github.com/rom6n/otello/internal/app/domain/apikey"
+	"github.com/rom6n/otello/internal/app/domain/user"
+	"github.com/rom6n/otello/internal/utils/httputils"
+)
+
+type ApiKeyHandler struct {
+	ApiKeyUsecase apikeyusecases.ApiKeyUsecases
+}
+
+type CreateApiKeyRequest struct {
+	Name        string   `json:"name" example:"channel manager"`
+	Permissions []string `json:"permissions" example:"hotel_rooms:manage"`
+}
+
+type CreatedApiKey struct {
+	ApiKey *apikey.ApiKey `json:"api_key"`
+	Key    string         `json:"key" example:"otk_..."`
+}
+
+// @Summary Создать API ключ
+// @Description Создает долгоживущий ключ для интеграций сервер-сервер. Ключ действует от имени владельца, но только с указанными правами, которые есть у его роли. Передается в заголовке 'X-API-Key' или 'Authorization: Bearer'. Сам ключ показывается только один раз. Требуется авторизация
+// @Tags Пользователь
+// @Accept json
+// @Produce json
+// @Param request body CreateApiKeyRequest true "Название и права ключа (users:manage, hotels:manage, hotel_rooms:manage, flights:manage, rents:read, rents:cancel)"
+// @Success 200 {object} httputils.SuccessResponse{data=CreatedApiKey}
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/user/api-key/create [post]
+func (v *ApiKeyHandler) Create() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to create api key"
+
+		var req CreateApiKeyRequest
+		if err := c.BodyParser(&req); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("failed to parse request body: %v", err), nil, fiber.StatusBadRequest)
+		}
+
+		if req.Name == "" || len(req.Permissions) == 0 {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, "fields 'name' and 'permissions' are required", nil, fiber.StatusBadRequest)
+		}
+
+		permissions := make([]user.Permission, len(req.Permissions))
+		for i, permission := range req.Permissions {
+			permissions[i] = user.Permission(permission)
+		}
+
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
+		newApiKey, rawKey, err := v.ApiKeyUsecase.Create(ctx, actor, req.Name, permissions)
+		if err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		return httputils.HandleSuccess(c, "successfully created api key. save the key, it is shown only once", CreatedApiKey{ApiKey: newApiKey, Key: rawKey})
+	}
+}
+
+// @Summary Мои API ключи
+// @Description Возвращает все API ключи пользователя, включая отозванные, с временем последнего использования. Требуется авторизация
+// @Tags Пользователь
+// @Accept json
+// @Produce json
+// @Success 200 {object} httputils.SuccessResponse{data=[]apikey.ApiKey}
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/user/api-key/list [get]
+func (v *ApiKeyHandler) FindOwned() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to find api keys"
+
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
+		apiKeys, err := v.ApiKeyUsecase.GetOwned(ctx, actor)
+		if err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		return httputils.HandleSuccess(c, "successfully found api keys", apiKeys)
+	}
+}
+
+// @Summary Отозвать API ключ
+// @Description Ключ перестает работать сразу. Требуется авторизация
+// @Tags Пользователь
+// @Accept json
+// @Produce json
+// @Param id query string true "ID ключа"
+// @Success 200 {object} httputils.SuccessResponse
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/user/api-key/revoke [post]
+func (v *ApiKeyHandler) Revoke() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to revoke api key"
+
+		keyUuidStr := c.Query("id")
+		if keyUuidStr == "" {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, "query value 'id' is required", nil, fiber.StatusBadRequest)
+		}
+
+		keyUuid, parseErr := uuid.Parse(keyUuidStr)
+		if parseErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("failed to parse query value 'id': %v", parseErr), nil, fiber.StatusBadRequest)
+		}
+
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
+		if err := v.ApiKeyUsecase.Revoke(ctx, actor, keyUuid); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		return httputils.HandleSuccess(c, "successfully revoked api key", nil)
+	}
+}
@@ -0,0 +1,89 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/hotelrepository"
+	hotelroomrepository "github.com/rom6n/otello/internal/app/adapters/repository/hotelroomrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/rentrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/userrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/usertokenrepository"
+	"github.com/rom6n/otello/internal/app/application/usecases/userusecases"
+	"github.com/rom6n/otello/internal/app/domain/hotel"
+	"github.com/rom6n/otello/internal/app/domain/hotelroom"
+	"github.com/rom6n/otello/internal/app/domain/rent"
+	"github.com/rom6n/otello/internal/app/domain/user"
+	"github.com/rom6n/otello/internal/pkg/logger"
+)
+
+func getActor(c *fiber.Ctx) (user.Actor, error) {
+	userUuidStr := c.Locals("id").(string)
+	userUuid, parseErr := uuid.Parse(userUuidStr)
+	if parseErr != nil {
+		logger.FromContext(c.UserContext()).Warn("failed to parse user uuid from locals", "error", parseErr)
+		return user.Actor{}, fmt.Errorf("uuid parse error: %v", parseErr)
+	}
+
+	// set only for API keys, usecases check them together with the role
+	keyPermissions, _ := c.Locals("keyPermissions").([]user.Permission)
+
+	return user.Actor{Uuid: userUuid, Role: user.UserRole(c.Locals("role").(string)), KeyPermissions: keyPermissions}, nil
+}
+
+func isMfaSession(c *fiber.Ctx) bool {
+	mfa, _ := c.Locals("mfa").(bool)
+	return mfa
+}
+
+func parseOptionalUuidQuery(c *fiber.Ctx, key string, parseTo *uuid.UUID) error {
+	uuidStr := c.Query(key)
+	if uuidStr == "" {
+		return nil
+	}
+
+	parsedUuid, parseErr := uuid.Parse(uuidStr)
+	if parseErr != nil {
+		return fmt.Errorf("failed to parse query value '%v': %v", key, parseErr)
+	}
+	*parseTo = parsedUuid
+
+	return nil
+}
+
+// parseForceQuery reads the 'force' flag of deletions that cancel active rents
+func parseForceQuery(c *fiber.Ctx) (bool, error) {
+	forceStr := c.Query("force")
+	if forceStr == "" {
+		return false, nil
+	}
+
+	force, parseErr := strconv.ParseBool(forceStr)
+	if parseErr != nil {
+		return false, fmt.Errorf("failed to parse query value 'force': %v", parseErr)
+	}
+
+	return force, nil
+}
+
+func usecaseErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, user.ErrNoPermission), errors.Is(err, user.ErrWrongPassword), errors.Is(err, user.ErrInvalidTwoFactorCode), errors.Is(err, user.ErrAccountDisabled),
+		errors.Is(err, userusecases.ErrOwnRole):
+		return fiber.StatusForbidden
+	case errors.Is(err, user.ErrInvalidCredentials), errors.Is(err, user.ErrTwoFactorRequired), errors.Is(err, user.ErrSessionRevoked):
+		return fiber.StatusUnauthorized
+	case errors.Is(err, usertokenrepository.ErrInvalidToken), errors.Is(err, user.ErrWeakPassword), errors.Is(err, user.ErrBreachedPassword):
+		return fiber.StatusBadRequest
+	case errors.Is(err, userrepository.ErrUserNotFound), errors.Is(err, hotelrepository.ErrHotelNotFound), errors.Is(err, hotelroomrepository.ErrHotelRoomNotFound), errors.Is(err, rentrepository.ErrRentNotFound),
+		errors.Is(err, hotel.ErrDeleted), errors.Is(err, hotelroom.ErrDeleted):
+		return fiber.StatusNotFound
+	case errors.Is(err, userrepository.ErrEmailTaken), errors.Is(err, rent.ErrAlreadyRented), errors.Is(err, rent.ErrHasActiveRents),
+		errors.Is(err, userusecases.ErrRoleAlreadyGiven), errors.Is(err, userusecases.ErrNoRoleToRevoke):
+		return fiber.StatusConflict
+	}
+	return fiber.StatusInternalServerError
+}
@@ -129,8 +129,8 @@ func parseFlightTicketParams(c *fiber.Ctx, parseTo *flightticket.FlightTicket, a
 	return arrange, cityVia, nil
 }
 
-// @Summary Создать авиабилет (Admin only)
-// @Description Создаёт авиабилет с переданными параметрами
+// @Summary Создать авиабилет (Admin, Airline operator)
+// @Description Создаёт авиабилет с переданными параметрами. Билеты, созданные оператором авиакомпании, принадлежат ему
 // @Tags Авиабилет
 // @Accept json
 // @Produce json
@@ -140,32 +140,43 @@ func parseFlightTicketParams(c *fiber.Ctx, parseTo *flightticket.FlightTicket, a
 // @Param value query int true "Цена за билет"
 // @Param take-off query string true "Дата и время взлета (формат: 2006-01-02T15:04:05Z)"
 // @Param arrival query string true "Дата и время посадки (формат: 2006-01-02T15:04:06Z)"
+// @Param operator-id query string false "ID оператора авиакомпании (только для админа) (необязательно)"
 // @Success 200 {object} httputils.SuccessResponse{data=flightticket.FlightTicket}
 // @Failure 400 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
 // @Failure 500 {object} httputils.ErrorResponse
 // @Router /api/admin/flight-ticket/create [post]
 func (v *FlightTicketHandler) Create() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
+		ctx := c.UserContext()
 		unsuccessMessage := "failed to create a flight ticket"
 
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
 		var flightTicket flightticket.FlightTicket
 		_, _, parseErr := parseFlightTicketParams(c, &flightTicket, true, false)
 		if parseErr != nil {
 			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parseErr), nil, fiber.StatusBadRequest)
 		}
 
-		err := v.FlightTicketUsecase.Create(ctx, &flightTicket)
+		if parseOperatorErr := parseOptionalUuidQuery(c, "operator-id", &flightTicket.OperatorUuid); parseOperatorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parseOperatorErr), nil, fiber.StatusBadRequest)
+		}
+
+		err := v.FlightTicketUsecase.Create(ctx, actor, &flightTicket)
 		if err != nil {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusInternalServerError)
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
 		}
 
 		return httputils.HandleSuccess(c, "successfully created a flight ticket", flightTicket)
 	}
 }
 
-// @Summary Изменить авиабилет (Admin only)
-// @Description Изменяет авиабилет переданными параметрами
+// @Summary Изменить авиабилет (Admin, Airline operator)
+// @Description Изменяет авиабилет переданными параметрами. Оператор авиакомпании может изменять только свои билеты
 // @Tags Авиабилет
 // @Accept json
 // @Produce json
@@ -176,18 +187,26 @@ func (v *FlightTicketHandler) Create() fiber.Handler {
 // @Param value query int false "Новая цена за билет (необязательно)"
 // @Param take-off query string false "Новые дата и время взлета (формат: 2006-01-02T15:04:05Z) (необязательно)"
 // @Param arrival query string false "Новые дата и время посадки (формат: 2006-01-02T15:04:06Z) (необязательно)"
+// @Param operator-id query string false "Новый ID оператора авиакомпании (только для админа) (необязательно)"
 // @Success 200 {object} httputils.SuccessResponse
 // @Failure 400 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
 // @Failure 500 {object} httputils.ErrorResponse
 // @Router /api/admin/flight-ticket/update [put]
 func (v *FlightTicketHandler) Update() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
+		ctx := c.UserContext()
 		unsuccessMessage := "failed to update the flight ticket"
 
 		if c.Query("id") == "" {
 			return httputils.HandleUnsuccess(c, unsuccessMessage, "query value 'id' is required", nil, fiber.StatusBadRequest)
 		}
+
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
 		var flightTicketFilter flightticket.FlightTicket
 		_, _, parseErr := parseFlightTicketParams(c, &flightTicketFilter, false, true)
 		if parseErr != nil {
@@ -204,28 +223,33 @@ func (v *FlightTicketHandler) Update() fiber.Handler {
 			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parse2Err), nil, fiber.StatusBadRequest)
 		}
 
-		err := v.FlightTicketUsecase.Update(ctx, foundFlightTicket)
+		if parseOperatorErr := parseOptionalUuidQuery(c, "operator-id", &foundFlightTicket.OperatorUuid); parseOperatorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parseOperatorErr), nil, fiber.StatusBadRequest)
+		}
+
+		err := v.FlightTicketUsecase.Update(ctx, actor, foundFlightTicket)
 		if err != nil {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusInternalServerError)
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
 		}
 
 		return httputils.HandleSuccess(c, "successfully updated the flight ticket", foundFlightTicket)
 	}
 }
 
-// @Summary Удалить авиабилет (Admin only)
-// @Description Удаляет авиабилет по ID
+// @Summary Удалить авиабилет (Admin, Airline operator)
+// @Description Удаляет авиабилет по ID. Оператор авиакомпании может удалять только свои билеты
 // @Tags Авиабилет
 // @Accept json
 // @Produce json
 // @Param id query string true "ID авиабилета"
 // @Success 200 {object} httputils.SuccessResponse
 // @Failure 400 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
 // @Failure 500 {object} httputils.ErrorResponse
 // @Router /api/admin/flight-ticket/delete [delete]
 func (v *FlightTicketHandler) Delete() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
+		ctx := c.UserContext()
 		unsuccessMessage := "failed to delete the flight ticket"
 
 		uuidStr := c.Query("id")
@@ -239,9 +263,14 @@ func (v *FlightTicketHandler) Delete() fiber.Handler {
 			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("failed to parse ticket uuid: %v", parseTicketUuidErr), nil, fiber.StatusBadRequest)
 		}
 
-		err := v.FlightTicketUsecase.Delete(ctx, uuidParsed)
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
+		err := v.FlightTicketUsecase.Delete(ctx, actor, uuidParsed)
 		if err != nil {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusInternalServerError)
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
 		}
 
 		return httputils.HandleSuccess(c, "successfully deleted the flight ticket", nil)
@@ -266,7 +295,7 @@ func (v *FlightTicketHandler) Delete() fiber.Handler {
 // @Router /api/flight-ticket/find [get]
 func (v *FlightTicketHandler) Find() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
+		ctx := c.UserContext()
 		unsuccessMessage := "failed to find flight tickets"
 
 		var flightTicketFilter flightticket.FlightTicket
@@ -296,7 +325,7 @@ func (v *FlightTicketHandler) Find() fiber.Handler {
 }
 
 // @Summary Купить авиабилет
-// @Description Покупает авиабилеты по ID и количеству пассажиров. Требуется авторизация
+// @Description Покупает авиабилеты по ID и количеству пассажиров. Покупка сохраняется в истории пользователя. Требуется авторизация
 // @Tags Авиабилет
 // @Accept json
 // @Produce json
@@ -304,11 +333,12 @@ func (v *FlightTicketHandler) Find() fiber.Handler {
 // @Param quantity query int true "Количество пассажиров"
 // @Success 200 {object} httputils.SuccessResponse{data=flightticket.FlightTicket}
 // @Failure 400 {object} httputils.ErrorResponse
+// @Failure 401 {object} httputils.ErrorResponse
 // @Failure 500 {object} httputils.ErrorResponse
 // @Router /api/flight-ticket/buy [post]
 func (v *FlightTicketHandler) Buy() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
+		ctx := c.UserContext()
 		unsuccessMessage := "failed to buy flight tickets"
 
 		uuidStr := c.Query("id")
@@ -334,7 +364,12 @@ func (v *FlightTicketHandler) Buy() fiber.Handler {
 
 		amountPassengers := uint32(amountPassengersParsed)
 
-		boughtFlightTicket, err := v.FlightTicketUsecase.Buy(ctx, uuidParsed, amountPassengers)
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
+		boughtFlightTicket, err := v.FlightTicketUsecase.Buy(ctx, actor.Uuid, uuidParsed, amountPassengers)
 		if err != nil {
 			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusInternalServerError)
 		}
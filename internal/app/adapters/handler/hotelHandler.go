@@ -112,31 +112,42 @@ func parseHotelParams(c *fiber.Ctx, allRequired bool, parseTo *hotel.Hotel) (*st
 // @Param city query string true "Город"
 // @Param stars query int true "Количество звёзд"
 // @Param name query string false "Название отеля (необязательно)"
+// @Param owner-id query string false "ID менеджера отеля (необязательно)"
 // @Success 200 {object} httputils.SuccessResponse{data=hotel.Hotel}
 // @Failure 400 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
 // @Failure 500 {object} httputils.ErrorResponse
 // @Router /api/admin/hotel/create [post]
 func (v *HotelHandler) Create() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
+		ctx := c.UserContext()
 		unsuccessMessage := "failed to create a hotel"
 
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
 		var parsedHotel hotel.Hotel
 
 		if _, _, _, parseErr := parseHotelParams(c, true, &parsedHotel); parseErr != nil {
 			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parseErr), nil, fiber.StatusBadRequest)
 		}
 
-		if err := v.HotelUsecase.Create(ctx, &parsedHotel); err != nil {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusInternalServerError)
+		if parseErr := parseOptionalUuidQuery(c, "owner-id", &parsedHotel.OwnerUuid); parseErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parseErr), nil, fiber.StatusBadRequest)
+		}
+
+		if err := v.HotelUsecase.Create(ctx, actor, &parsedHotel); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
 		}
 
 		return httputils.HandleSuccess(c, "successfully created the hotel", &parsedHotel)
 	}
 }
 
-// @Summary Изменить отель (Admin only)
-// @Description Изменяет отель переданными параметрами
+// @Summary Изменить отель (Admin, Hotel manager)
+// @Description Изменяет отель переданными параметрами. Менеджер отеля может изменять только свои отели
 // @Tags Отель
 // @Accept json
 // @Produce json
@@ -144,19 +155,26 @@ func (v *HotelHandler) Create() fiber.Handler {
 // @Param city query string false "Новый город (необязательно)"
 // @Param stars query int false "Новое количество звёзд (необязательно)"
 // @Param name query string false "Новое название отеля (необязательно)"
+// @Param owner-id query string false "Новый ID менеджера отеля (только для админа) (необязательно)"
 // @Success 200 {object} httputils.SuccessResponse{data=hotel.Hotel}
 // @Failure 400 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
 // @Failure 500 {object} httputils.ErrorResponse
 // @Router /api/admin/hotel/update [put]
 func (v *HotelHandler) Update() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
+		ctx := c.UserContext()
 		unsuccessMessage := "failed to update the hotel"
 
 		if c.Query("id") == "" {
 			return httputils.HandleUnsuccess(c, unsuccessMessage, "query value 'id' is required", nil, fiber.StatusBadRequest)
 		}
 
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
 		var parsedHotel hotel.Hotel
 		if _, _, _, parseErr := parseHotelParams(c, false, &parsedHotel); parseErr != nil {
 			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parseErr), nil, fiber.StatusBadRequest)
@@ -171,8 +189,12 @@ func (v *HotelHandler) Update() fiber.Handler {
 			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parse2Err), nil, fiber.StatusBadRequest)
 		}
 
-		if err := v.HotelUsecase.Update(ctx, foundHotel); err != nil {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusInternalServerError)
+		if parseErr := parseOptionalUuidQuery(c, "owner-id", &foundHotel.OwnerUuid); parseErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parseErr), nil, fiber.StatusBadRequest)
+		}
+
+		if err := v.HotelUsecase.Update(ctx, actor, foundHotel); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
 		}
 
 		return httputils.HandleSuccess(c, "successfully updated the hotel", foundHotel)
@@ -180,18 +202,22 @@ func (v *HotelHandler) Update() fiber.Handler {
 }
 
 // @Summary Удалить отель (Admin only)
-// @Description Удаляет отель по ID
+// @Description Удаляет отель и все его номера по ID. Отель и номера остаются в истории аренд, но не находятся в поиске. Если у номеров есть незакончившиеся аренды, удаление отклоняется, а с 'force=true' аренды отменяются и арендаторам отправляется письмо
 // @Tags Отель
 // @Accept json
 // @Produce json
 // @Param id query string true "ID отеля"
+// @Param force query bool false "Отменить незакончившиеся аренды"
 // @Success 200 {object} httputils.SuccessResponse
 // @Failure 400 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
+// @Failure 404 {object} httputils.ErrorResponse
+// @Failure 409 {object} httputils.ErrorResponse
 // @Failure 500 {object} httputils.ErrorResponse
 // @Router /api/admin/hotel/delete [delete]
 func (v *HotelHandler) Delete() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
+		ctx := c.UserContext()
 		unsuccessMessage := "failed to delete the hotel"
 
 		if c.Query("id") == "" {
@@ -209,8 +235,18 @@ func (v *HotelHandler) Delete() fiber.Handler {
 			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("failed to parse query value 'id': %v", parseErr), nil, fiber.StatusBadRequest)
 		}
 
-		if err := v.HotelUsecase.Delete(ctx, hotelUuid); err != nil {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusInternalServerError)
+		force, forceErr := parseForceQuery(c)
+		if forceErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", forceErr), nil, fiber.StatusBadRequest)
+		}
+
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
+		if err := v.HotelUsecase.Delete(ctx, actor, hotelUuid, force); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
 		}
 
 		return httputils.HandleSuccess(c, "successfully deleted the hotel", nil)
@@ -234,7 +270,7 @@ func (v *HotelHandler) Delete() fiber.Handler {
 // @Router /api/hotel/find [get]
 func (v *HotelHandler) Find() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
+		ctx := c.UserContext()
 		unsuccessMessage := "failed to find the hotel"
 
 		var parsedHotel hotel.Hotel
@@ -256,3 +292,35 @@ func (v *HotelHandler) Find() fiber.Handler {
 		return httputils.HandleSuccess(c, "successfully found hotels", foundHotels)
 	}
 }
+
+// @Summary Мои отели (Partner)
+// @Description Возвращает отели, владельцем которых является партнер (менеджер отеля). Требуется авторизация
+// @Tags Партнер
+// @Accept json
+// @Produce json
+// @Success 200 {object} httputils.SuccessResponse{data=[]hotel.Hotel}
+// @Failure 403 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/partner/hotel/list [get]
+func (v *HotelHandler) FindOwned() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to find your hotels"
+
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
+		foundHotels, err := v.HotelUsecase.GetOwned(ctx, actor)
+		if err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		if len(foundHotels) == 0 {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, "hotels not found", nil, fiber.StatusNotFound)
+		}
+
+		return httputils.HandleSuccess(c, "successfully found your hotels", foundHotels)
+	}
+}
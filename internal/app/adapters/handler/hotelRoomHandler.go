@@ -18,7 +18,6 @@ type HotelRoomHandler struct {
 func parseBothFindHotelRoomParams(dateStr, roomsStr, typeStr, amountPeopleStr, valueStr string, parseTo *hotelroom.FindHotelRoomDTO) error {
 	if dateStr != "" {
 		dateFr, parseDateErr := httputils.ParseTimeDate(dateStr)
-		fmt.Printf("date from: %v\n", dateFr)
 		if parseDateErr != nil {
 			return fmt.Errorf("failed to parse 'date-*' query value, must match pattern 2016-10-06: %v", parseDateErr)
 		}
@@ -146,7 +145,6 @@ func ParseHotelRoomFindRequest(c *fiber.Ctx, parseTo *hotelroom.FindHotelRoomFil
 
 	if daysStr != "" {
 		daysParsed, parseErr := strconv.ParseUint(daysStr, 0, 64)
-		fmt.Printf("days: %v\n", daysParsed)
 		if parseErr != nil {
 			return fmt.Errorf("failed to parse query value 'days': %v", parseErr)
 		}
@@ -316,8 +314,8 @@ func parseHotelRoomType(typeStr string) (hotelroom.HotelType, error) {
 	return Type, nil
 }
 
-// @Summary Создать номер отеля (Admin only)
-// @Description Создаёт номер отеля с переданными параметрами
+// @Summary Создать номер отеля (Admin, Hotel manager)
+// @Description Создаёт номер отеля с переданными параметрами. Менеджер отеля может создавать номера только в своих отелях
 // @Tags Номер отеля
 // @Accept json
 // @Produce json
@@ -328,29 +326,36 @@ func parseHotelRoomType(typeStr string) (hotelroom.HotelType, error) {
 // @Param value query int true "Цена за номер"
 // @Success 200 {object} httputils.SuccessResponse{data=hotelroom.HotelRoom}
 // @Failure 400 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
 // @Failure 500 {object} httputils.ErrorResponse
 // @Router /api/admin/hotel-room/create [post]
+// @Router /api/partner/hotel-room/create [post]
 func (v *HotelRoomHandler) Create() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
+		ctx := c.UserContext()
 
 		unsuccessMessage := "failed to create hotel room"
 
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
 		var hotelRoom hotelroom.HotelRoom
 		if parseErr := parseHotelRoomCreateRequest(c, &hotelRoom); parseErr != nil {
 			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parseErr), nil, fiber.StatusBadRequest)
 		}
 
-		if err := v.HotelRoomUsecase.Create(ctx, &hotelRoom); err != nil {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusInternalServerError)
+		if err := v.HotelRoomUsecase.Create(ctx, actor, &hotelRoom); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
 		}
 
 		return httputils.HandleSuccess(c, "successfully created hotel room", &hotelRoom)
 	}
 }
 
-// @Summary Изменить номер отеля (Admin only)
-// @Description Изменяет номер отеля переданными параметрами
+// @Summary Изменить номер отеля (Admin, Hotel manager)
+// @Description Изменяет номер отеля переданными параметрами. Менеджер отеля может изменять номера только своих отелей
 // @Tags Номер отеля
 // @Accept json
 // @Produce json
@@ -362,11 +367,13 @@ func (v *HotelRoomHandler) Create() fiber.Handler {
 // @Param value query int false "Новая цена за номер (необязательно)"
 // @Success 200 {object} httputils.SuccessResponse{data=hotelroom.HotelRoom}
 // @Failure 400 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
 // @Failure 500 {object} httputils.ErrorResponse
 // @Router /api/admin/hotel-room/update [put]
+// @Router /api/partner/hotel-room/update [put]
 func (v *HotelRoomHandler) Update() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
+		ctx := c.UserContext()
 
 		unsuccessMessage := "failed to update the hotel room"
 
@@ -380,6 +387,11 @@ func (v *HotelRoomHandler) Update() fiber.Handler {
 			return fmt.Errorf("failed to parse query value 'id': %v", parseUuidErr)
 		}
 
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
 		foundHotelRoom, getErr := v.HotelRoomUsecase.Get(ctx, roomUuid)
 		if getErr != nil {
 			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", getErr), nil, fiber.StatusInternalServerError)
@@ -390,27 +402,32 @@ func (v *HotelRoomHandler) Update() fiber.Handler {
 			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parseErr), nil, fiber.StatusBadRequest)
 		}
 
-		if err := v.HotelRoomUsecase.Update(ctx, foundHotelRoom); err != nil {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parseErr), nil, fiber.StatusInternalServerError)
+		if err := v.HotelRoomUsecase.Update(ctx, actor, foundHotelRoom); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
 		}
 
 		return httputils.HandleSuccess(c, "successfully updated the hotel room", &foundHotelRoom)
 	}
 }
 
-// @Summary Удалить номер отеля (Admin only)
-// @Description Удаляет номер отеля по ID
+// @Summary Удалить номер отеля (Admin, Hotel manager)
+// @Description Удаляет номер отеля по ID. Менеджер отеля может удалять номера только своих отелей. Номер остается в истории аренд, но не находится в поиске. Если у номера есть незакончившиеся аренды, удаление отклоняется, а с 'force=true' аренды отменяются и арендаторам отправляется письмо
 // @Tags Номер отеля
 // @Accept json
 // @Produce json
 // @Param id query string true "ID номера отеля"
+// @Param force query bool false "Отменить незакончившиеся аренды"
 // @Success 200 {object} httputils.SuccessResponse
 // @Failure 400 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
+// @Failure 404 {object} httputils.ErrorResponse
+// @Failure 409 {object} httputils.ErrorResponse
 // @Failure 500 {object} httputils.ErrorResponse
 // @Router /api/admin/hotel-room/delete [delete]
+// @Router /api/partner/hotel-room/delete [delete]
 func (v *HotelRoomHandler) Delete() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
+		ctx := c.UserContext()
 
 		unsuccessMessage := "failed to delete the hotel room"
 
@@ -425,14 +442,76 @@ func (v *HotelRoomHandler) Delete() fiber.Handler {
 			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("failed to parse query valie 'id': %v", parseErr), nil, fiber.StatusInternalServerError)
 		}
 
-		if err := v.HotelRoomUsecase.Delete(ctx, hotelRoomUuid); err != nil {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parseErr), nil, fiber.StatusInternalServerError)
+		force, forceErr := parseForceQuery(c)
+		if forceErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", forceErr), nil, fiber.StatusBadRequest)
+		}
+
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
+		if err := v.HotelRoomUsecase.Delete(ctx, actor, hotelRoomUuid, force); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
 		}
 
 		return httputils.HandleSuccess(c, "successfully deleted the hotel room", nil)
 	}
 }
 
+// @Summary Изменить цену номера (Partner)
+// @Description Изменяет цену номера отеля. Партнер может менять цены только в своих отелях. Требуется авторизация
+// @Tags Партнер
+// @Accept json
+// @Produce json
+// @Param id query string true "ID номера отеля"
+// @Param value query int true "Новая цена за номер"
+// @Success 200 {object} httputils.SuccessResponse{data=hotelroom.HotelRoom}
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/partner/hotel-room/price [put]
+func (v *HotelRoomHandler) UpdatePrice() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+
+		unsuccessMessage := "failed to update the hotel room price"
+
+		hotelRoomUuidStr := c.Query("id")
+		valueStr := c.Query("value")
+
+		if hotelRoomUuidStr == "" || valueStr == "" {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, "query values 'id' and 'value' are required", nil, fiber.StatusBadRequest)
+		}
+
+		hotelRoomUuid, parseErr := uuid.Parse(hotelRoomUuidStr)
+		if parseErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("failed to parse query value 'id': %v", parseErr), nil, fiber.StatusBadRequest)
+		}
+
+		value, parseValueErr := strconv.ParseInt(valueStr, 0, 64)
+		if parseValueErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("failed to parse query value 'value': %v", parseValueErr), nil, fiber.StatusBadRequest)
+		}
+		if value < 0 {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, "query value 'value' must be greater than zero", nil, fiber.StatusBadRequest)
+		}
+
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
+		updatedHotelRoom, err := v.HotelRoomUsecase.UpdatePrice(ctx, actor, hotelRoomUuid, value)
+		if err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		return httputils.HandleSuccess(c, "successfully updated the hotel room price", updatedHotelRoom)
+	}
+}
+
 // @Summary Найти номер отеля
 // @Description Находит номер отеля по фильтрам. Можно искать без фильтров. Нельзя использовать одновременно 'date-to' и 'days'.  Если указать 'arrange', то билеты будут отсортированы по цене в указанном порядке (asc - по возрастанию, desc - по убыванию)
 // @Tags Номер отеля
@@ -459,7 +538,7 @@ func (v *HotelRoomHandler) Delete() fiber.Handler {
 // @Router /api/hotel-room/find [get]
 func (v *HotelRoomHandler) Find() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
+		ctx := c.UserContext()
 
 		unsuccessMessage := "failed to find hotel rooms"
 
@@ -0,0 +1,25 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/rom6n/otello/internal/utils/jwtutils"
+)
+
+const jwksCacheControl = "public, max-age=300"
+
+type JwksHandler struct {
+	JwtRepo jwtutils.JwtRepository
+}
+
+// @Summary Публичные ключи JWT (JWKS)
+// @Description Ключи в формате RFC 7517 для проверки наших JWT другими сервисами. Ключ выбирается по заголовку 'kid' токена. Ответ не обернут в стандартный формат API
+// @Tags JWT
+// @Produce json
+// @Success 200 {object} jwtutils.JwkSet
+// @Router /.well-known/jwks.json [get]
+func (v *JwksHandler) Jwks() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		c.Set(fiber.HeaderCacheControl, jwksCacheControl)
+		return c.JSON(v.JwtRepo.Jwks())
+	}
+}
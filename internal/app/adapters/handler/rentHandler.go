@@ -5,10 +5,10 @@ import (
 	"strconv"
 
 	"github.com/gofiber/fiber/v2"
-	flog "github.com/gofiber/fiber/v2/log"
 	"github.com/google/uuid"
 	"github.com/rom6n/otello/internal/app/application/usecases/rentusecases"
 	"github.com/rom6n/otello/internal/app/domain/rent"
+	"github.com/rom6n/otello/internal/pkg/logger"
 	"github.com/rom6n/otello/internal/utils/httputils"
 )
 
@@ -45,7 +45,7 @@ func parseRentCreateRequest(c *fiber.Ctx, parseTo *rent.Rent) error {
 	userUuidStr := c.Locals("id").(string)
 	userUuid, parseUserUuidErr := uuid.Parse(userUuidStr)
 	if parseUserUuidErr != nil {
-		flog.Warnf("Error parsing user UUID from JWT: %v\n", parseUserUuidErr)
+		logger.FromContext(c.UserContext()).Warn("failed to parse user uuid from jwt", "error", parseUserUuidErr)
 		return fmt.Errorf("failed to parse user uuid from jwt: %v", parseUserUuidErr)
 	}
 
@@ -104,7 +104,7 @@ func parseRentDeleteRequest(c *fiber.Ctx, parseTo *rent.DeleteDTO) error {
 
 	userUuid, parseUserUuidErr := uuid.Parse(userUuidStr)
 	if parseUserUuidErr != nil {
-		flog.Warnf("Error parsing user UUID from JWT: %v\n", parseUserUuidErr)
+		logger.FromContext(c.UserContext()).Warn("failed to parse user uuid from jwt", "error", parseUserUuidErr)
 		return fmt.Errorf("failed to parse user uuid from jwt: %v", parseUserUuidErr)
 	}
 
@@ -135,11 +135,12 @@ func parseRentDeleteRequest(c *fiber.Ctx, parseTo *rent.DeleteDTO) error {
 // @Param days query int false "Количество дней (по выбору)"
 // @Success 200 {object} httputils.SuccessResponse{data=rent.Rent}
 // @Failure 400 {object} httputils.ErrorResponse
+// @Failure 409 {object} httputils.ErrorResponse
 // @Failure 500 {object} httputils.ErrorResponse
 // @Router /api/hotel-room/rent [post]
 func (v *RentHandler) Create() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
+		ctx := c.UserContext()
 
 		unsuccessMessage := "failed to rent"
 
@@ -149,7 +150,7 @@ func (v *RentHandler) Create() fiber.Handler {
 		}
 
 		if err := v.RentUsecase.Create(ctx, &hotelRoomRent); err != nil {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusInternalServerError)
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
 		}
 
 		return httputils.HandleSuccess(c, "successfully rented the hotel room", &hotelRoomRent)
@@ -157,18 +158,20 @@ func (v *RentHandler) Create() fiber.Handler {
 }
 
 // @Summary Отменить бронь номера отеля
-// @Description Отменяет бронь номер отеля по ID брони. Админ может удалить бронь у любого пользователя. Требуется авторизация
+// @Description Отменяет бронь номер отеля по ID брони. Отмененная бронь остается в истории с полями cancelled_at и cancel_reason, а ее даты снова свободны. Админ и агент поддержки могут отменить бронь любого пользователя, менеджер отеля - брони своих отелей. Требуется авторизация
 // @Tags Номер отеля
 // @Accept json
 // @Produce json
 // @Param rent-id query string true "ID брони"
 // @Success 200 {object} httputils.SuccessResponse
 // @Failure 400 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
+// @Failure 404 {object} httputils.ErrorResponse
 // @Failure 500 {object} httputils.ErrorResponse
 // @Router /api/hotel-room/unrent [post]
 func (v *RentHandler) Delete() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
+		ctx := c.UserContext()
 
 		unsuccessMessage := "failed to delete the rent"
 
@@ -178,9 +181,72 @@ func (v *RentHandler) Delete() fiber.Handler {
 		}
 
 		if err := v.RentUsecase.Delete(ctx, deleteDto); err != nil {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusInternalServerError)
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
 		}
 
 		return httputils.HandleSuccess(c, "successfully deleted the rent", nil)
 	}
 }
+
+func parseRentFindRequest(c *fiber.Ctx, parseTo *rent.FindRentFilterDTO) error {
+	if err := parseOptionalUuidQuery(c, "hotel-id", &parseTo.HotelUuid); err != nil {
+		return err
+	}
+
+	if err := parseOptionalUuidQuery(c, "renter-id", &parseTo.RenterUuid); err != nil {
+		return err
+	}
+
+	var hotelRoomUuid uuid.UUID
+	if err := parseOptionalUuidQuery(c, "hotel-room-id", &hotelRoomUuid); err != nil {
+		return err
+	}
+	if hotelRoomUuid != uuid.Nil {
+		parseTo.RoomUuids = []uuid.UUID{hotelRoomUuid}
+	}
+
+	return nil
+}
+
+// @Summary Найти брони (Admin, Support agent, Hotel manager)
+// @Description Находит брони по фильтрам. Менеджер отеля видит только брони своих отелей и должен указать 'hotel-id'
+// @Tags Номер отеля
+// @Accept json
+// @Produce json
+// @Param hotel-id query string false "ID отеля (обязательно для менеджера отеля)"
+// @Param hotel-room-id query string false "ID номера отеля (необязательно)"
+// @Param renter-id query string false "ID арендатора (необязательно)"
+// @Success 200 {object} httputils.SuccessResponse{data=[]rent.Rent}
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/admin/rent/find [get]
+// @Router /api/partner/rent/find [get]
+func (v *RentHandler) Find() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+
+		unsuccessMessage := "failed to find rents"
+
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
+		var rentFilter rent.FindRentFilterDTO
+		if err := parseRentFindRequest(c, &rentFilter); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusBadRequest)
+		}
+
+		foundRents, err := v.RentUsecase.GetWithParams(ctx, actor, &rentFilter)
+		if err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		if len(foundRents) == 0 {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, "rents not found", nil, fiber.StatusNotFound)
+		}
+
+		return httputils.HandleSuccess(c, "successfully found rents", foundRents)
+	}
+}
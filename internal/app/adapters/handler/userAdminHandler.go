@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rom6n/otello/internal/app/domain/user"
+	"github.com/rom6n/otello/internal/utils/httputils"
+)
+
+const (
+	defaultUsersPageLimit = 20
+	maxUsersPageLimit     = 100
+)
+
+// @Summary Найти пользователей (Admin only)
+// @Description Ищет пользователей по части email или имени (без учета регистра), роли и блокировке. Удаленные аккаунты не возвращаются. Результат отсортирован по email
+// @Tags Пользователь
+// @Accept json
+// @Produce json
+// @Param query query string false "Часть email или имени"
+// @Param role query string false "Роль"
+// @Param disabled query bool false "Только заблокированные (true) или только активные (false)"
+// @Param offset query int false "Сколько пропустить (по умолчанию 0)"
+// @Param limit query int false "Размер страницы (по умолчанию 20, максимум 100)"
+// @Success 200 {object} httputils.SuccessResponse{data=userusecases.UserPage}
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/admin/user/find [get]
+func (v *UserHandler) FindUsers() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to find users"
+
+		filter, parseErr := parseFindUserFilter(c)
+		if parseErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parseErr), nil, fiber.StatusBadRequest)
+		}
+
+		usersPage, err := v.UserUsecase.FindUsers(ctx, filter)
+		if err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		return httputils.HandleSuccess(c, "successfully found users", usersPage)
+	}
+}
+
+// @Summary Пользователь с бронями и покупками (Admin only)
+// @Description Возвращает пользователя, его брони номеров и покупки авиабилетов
+// @Tags Пользователь
+// @Accept json
+// @Produce json
+// @Param id query string true "ID пользователя"
+// @Success 200 {object} httputils.SuccessResponse{data=userusecases.UserDetails}
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 404 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/admin/user/view [get]
+func (v *UserHandler) GetUserDetails() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to get the user"
+
+		_, userUuid, parseErr := parseAdminAndUserUuids(c)
+		if parseErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parseErr), nil, fiber.StatusBadRequest)
+		}
+
+		userDetails, err := v.UserUsecase.GetUserDetails(ctx, userUuid)
+		if err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		return httputils.HandleSuccess(c, "successfully got the user", userDetails)
+	}
+}
+
+// @Summary Заблокировать пользователя (Admin only)
+// @Description Завершает все сессии пользователя. Пока аккаунт заблокирован, вход, токены и API ключи пользователя не принимаются
+// @Tags Пользователь
+// @Accept json
+// @Produce json
+// @Param id query string true "ID пользователя"
+// @Success 200 {object} httputils.SuccessResponse
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 404 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/admin/user/disable [post]
+func (v *UserHandler) DisableUser() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to disable the user"
+
+		adminUuid, userUuid, parseErr := parseAdminAndUserUuids(c)
+		if parseErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parseErr), nil, fiber.StatusBadRequest)
+		}
+
+		if err := v.UserUsecase.DisableUser(ctx, adminUuid, userUuid); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		return httputils.HandleSuccess(c, "successfully disabled the user", nil)
+	}
+}
+
+// @Summary Разблокировать пользователя (Admin only)
+// @Description Снимает блокировку аккаунта, после этого пользователь может снова войти
+// @Tags Пользователь
+// @Accept json
+// @Produce json
+// @Param id query string true "ID пользователя"
+// @Success 200 {object} httputils.SuccessResponse
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 404 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/admin/user/enable [post]
+func (v *UserHandler) EnableUser() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to enable the user"
+
+		_, userUuid, parseErr := parseAdminAndUserUuids(c)
+		if parseErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parseErr), nil, fiber.StatusBadRequest)
+		}
+
+		if err := v.UserUsecase.EnableUser(ctx, userUuid); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		return httputils.HandleSuccess(c, "successfully enabled the user", nil)
+	}
+}
+
+// @Summary Завершить все сессии пользователя (Admin only)
+// @Description Все выданные пользователю токены перестают действовать, войти заново он может сразу
+// @Tags Пользователь
+// @Accept json
+// @Produce json
+// @Param id query string true "ID пользователя"
+// @Success 200 {object} httputils.SuccessResponse
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 404 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/admin/user/logout [post]
+func (v *UserHandler) ForceLogout() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to logout the user"
+
+		_, userUuid, parseErr := parseAdminAndUserUuids(c)
+		if parseErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parseErr), nil, fiber.StatusBadRequest)
+		}
+
+		if err := v.UserUsecase.ForceLogout(ctx, userUuid); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		return httputils.HandleSuccess(c, "successfully ended all sessions of the user", nil)
+	}
+}
+
+func parseFindUserFilter(c *fiber.Ctx) (*user.FindUserFilterDTO, error) {
+	filter := &user.FindUserFilterDTO{
+		Query: c.Query("query"),
+		Limit: defaultUsersPageLimit,
+	}
+
+	if roleStr := c.Query("role"); roleStr != "" {
+		role, roleErr := parseUserRole(roleStr)
+		if roleErr != nil {
+			return nil, roleErr
+		}
+		filter.Role = role
+	}
+
+	if disabledStr := c.Query("disabled"); disabledStr != "" {
+		disabled, parseErr := strconv.ParseBool(disabledStr)
+		if parseErr != nil {
+			return nil, fmt.Errorf("failed to parse query value 'disabled': %v", parseErr)
+		}
+		filter.Disabled = &disabled
+	}
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		offset, parseErr := strconv.ParseInt(offsetStr, 10, 64)
+		if parseErr != nil || offset < 0 {
+			return nil, fmt.Errorf("query value 'offset' must be a non-negative number")
+		}
+		filter.Offset = offset
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, parseErr := strconv.ParseInt(limitStr, 10, 64)
+		if parseErr != nil || limit < 1 || limit > maxUsersPageLimit {
+			return nil, fmt.Errorf("query value 'limit' must be a number from 1 to %v", maxUsersPageLimit)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
@@ -1,54 +1,51 @@
 package handler
 
 import (
+	"errors"
 	"fmt"
+	"strconv"
 
 	"github.com/gofiber/fiber/v2"
-	flog "github.com/gofiber/fiber/v2/log"
 	"github.com/google/uuid"
 	"github.com/rom6n/otello/internal/app/application/usecases/userusecases"
+	"github.com/rom6n/otello/internal/app/domain/loginattempt"
 	"github.com/rom6n/otello/internal/app/domain/user"
+	"github.com/rom6n/otello/internal/pkg/logger"
 	"github.com/rom6n/otello/internal/utils/httputils"
 )
 
 type UserHandler struct {
-	UserUsecase userusecases.UserUsecases
+	UserUsecase           userusecases.UserUsecases
+	Cookies               httputils.Cookies
+	AllowQueryCredentials bool
 }
 
 // @Summary Зарегистрироваться
-// @Description Регистрирует пользователя по email, имени и паролю
+// @Description Регистрирует пользователя по email, имени и паролю. Данные принимаются в теле запроса (JSON или форма). Пароль должен соответствовать политике паролей и не встречаться в утечках, иначе вернется 400. Если email уже занят, вернется 409
 // @Tags Пользователь
-// @Accept json
+// @Accept json,x-www-form-urlencoded
 // @Produce json
-// @Param name query string true "Имя пользователя"
-// @Param email query string true "Email пользователя"
-// @Param password query string true "Пароль пользователя"
+// @Param request body RegisterRequest true "Данные для регистрации"
 // @Success 200 {object} httputils.SuccessResponse{data=user.User}
 // @Failure 400 {object} httputils.ErrorResponse
+// @Failure 409 {object} httputils.ErrorResponse
 // @Failure 500 {object} httputils.ErrorResponse
 // @Router /api/user/register [post]
 func (v *UserHandler) Register() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
-		name := c.Query("name")
-		email := c.Query("email")
-		password := c.Query("password")
-
+		ctx := c.UserContext()
 		unsuccessMessage := "failed to register"
 
-		if name == "" || email == "" || password == "" {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, "query values 'name', 'email' and 'password' are required", nil, fiber.StatusBadRequest)
+		var req RegisterRequest
+		if err := v.parseUserRequest(c, &req); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusBadRequest)
 		}
 
-		if !httputils.IsEmailCorrect(email) {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, "invalid email address", nil, fiber.StatusBadRequest)
-		}
-
-		newUser := user.NewUser(name, email, password)
+		newUser := user.NewUser(req.Name, req.Email, req.Password)
 
 		jwtRefreshCookie, jwtAccessCookie, err := v.UserUsecase.Register(ctx, newUser)
 		if err != nil {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusInternalServerError)
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
 		}
 
 		c.Cookie(jwtRefreshCookie)
@@ -59,35 +56,36 @@ func (v *UserHandler) Register() fiber.Handler {
 }
 
 // @Summary Войти в аккаунт
-// @Description Вход в аккаунт по email и паролю
+// @Description Вход в аккаунт по email и паролю. Данные принимаются в теле запроса (JSON или форма). После нескольких неудачных попыток вход замедляется, а затем временно блокируется (ответ 429 с заголовком Retry-After). Неизвестный email и неверный пароль дают одинаковый ответ 401. Если включена 2FA, нужен код из приложения или код восстановления, без него вернется 401
 // @Tags Пользователь
-// @Accept json
+// @Accept json,x-www-form-urlencoded
 // @Produce json
-// @Param email query string true "Email"
-// @Param password query string true "Пароль"
+// @Param request body LoginRequest true "Email и пароль"
 // @Success 200 {object} httputils.SuccessResponse{data=user.User}
 // @Failure 400 {object} httputils.ErrorResponse
+// @Failure 401 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
+// @Failure 429 {object} httputils.ErrorResponse
 // @Failure 500 {object} httputils.ErrorResponse
 // @Router /api/user/login [post]
 func (v *UserHandler) Login() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
-		email := c.Query("email")
-		password := c.Query("password")
-
+		ctx := c.UserContext()
 		unsuccessMessage := "failed to login"
 
-		if email == "" || password == "" {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, "query values 'email' and 'password' are required", nil, fiber.StatusBadRequest)
+		var req LoginRequest
+		if err := v.parseUserRequest(c, &req); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusBadRequest)
 		}
 
-		if !httputils.IsEmailCorrect(email) {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, "invalid email address", nil, fiber.StatusBadRequest)
+		jwtRefreshCookie, jwtAccessCookie, foundUser, err := v.UserUsecase.Login(ctx, req.Email, req.Password, req.Code, c.IP())
+		var throttledErr *loginattempt.ThrottledError
+		if errors.As(err, &throttledErr) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(throttledErr.RetryAfter.Seconds())))
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusTooManyRequests)
 		}
-
-		jwtRefreshCookie, jwtAccessCookie, foundUser, err := v.UserUsecase.Login(ctx, email, password)
 		if err != nil {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusInternalServerError)
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
 		}
 
 		c.Cookie(jwtRefreshCookie)
@@ -97,111 +95,765 @@ func (v *UserHandler) Login() fiber.Handler {
 	}
 }
 
+// @Summary Получить токены
+// @Description Вход для мобильных приложений и скриптов: возвращает access и refresh токены в JSON вместо cookie. Access токен передается в заголовке 'Authorization: Bearer <token>'. Неизвестный email и неверный пароль дают одинаковый ответ 401
+// @Tags Пользователь
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body LoginRequest true "Email, пароль и код 2FA"
+// @Success 200 {object} httputils.SuccessResponse{data=userusecases.TokenPair}
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 401 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
+// @Failure 429 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/user/token [post]
+func (v *UserHandler) Token() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to issue tokens"
+
+		var req LoginRequest
+		if err := v.parseUserRequest(c, &req); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusBadRequest)
+		}
+
+		tokenPair, err := v.UserUsecase.IssueTokens(ctx, req.Email, req.Password, req.Code, c.IP())
+		var throttledErr *loginattempt.ThrottledError
+		if errors.As(err, &throttledErr) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(throttledErr.RetryAfter.Seconds())))
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusTooManyRequests)
+		}
+		if err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		c.Set(fiber.HeaderCacheControl, "no-store")
+
+		return httputils.HandleSuccess(c, "successfully issued tokens", tokenPair)
+	}
+}
+
+// @Summary Обновить access токен
+// @Description Выдает новый access токен по refresh токену из /api/user/token
+// @Tags Пользователь
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body RefreshTokenRequest true "Refresh токен"
+// @Success 200 {object} httputils.SuccessResponse{data=userusecases.TokenPair}
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 401 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/user/token/refresh [post]
+func (v *UserHandler) RefreshToken() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to refresh token"
+
+		var req RefreshTokenRequest
+		if err := v.parseUserRequest(c, &req); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusBadRequest)
+		}
+
+		tokenPair, err := v.UserUsecase.RefreshAccessToken(ctx, req.RefreshToken)
+		if err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		c.Set(fiber.HeaderCacheControl, "no-store")
+
+		return httputils.HandleSuccess(c, "successfully refreshed token", tokenPair)
+	}
+}
+
 // @Summary Изменить имя
 // @Description Изменяет имя пользователя. Требуется авторизация
 // @Tags Пользователь
-// @Accept json
+// @Accept json,x-www-form-urlencoded
 // @Produce json
-// @Param name query string true "Новое имя пользователя"
+// @Param request body RenameRequest true "Новое имя пользователя"
 // @Success 200 {object} httputils.SuccessResponse
 // @Failure 400 {object} httputils.ErrorResponse
 // @Failure 500 {object} httputils.ErrorResponse
 // @Router /api/user/rename [put]
 func (v *UserHandler) ChangeName() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
-		newName := c.Query("name")
-
+		ctx := c.UserContext()
 		unsuccessMessage := "failed to change the name"
 
 		userIdStr := c.Locals("id").(string)
 		userId, parseUuidErr := uuid.Parse(userIdStr)
 		if parseUuidErr != nil {
-			flog.Warnf("Error parsing user UUID from Locals: %v\n", parseUuidErr)
+			logger.FromContext(ctx).Warn("failed to parse user uuid from locals", "error", parseUuidErr)
 			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("uuid parse error: %v", parseUuidErr), nil, fiber.StatusInternalServerError)
 		}
 
-		if newName == "" {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, "new name is required", nil, fiber.StatusBadRequest)
+		var req RenameRequest
+		if err := v.parseUserRequest(c, &req); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusBadRequest)
+		}
+
+		if err := v.UserUsecase.ChangeName(ctx, userId, req.Name); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		return httputils.HandleSuccess(c, fmt.Sprintf("successfully changed the name to '%v'", req.Name), nil)
+	}
+}
+
+// @Summary Мой профиль
+// @Description Возвращает текущего пользователя, его настройки и количество бронирований. Требуется авторизация
+// @Tags Пользователь
+// @Produce json
+// @Success 200 {object} httputils.SuccessResponse{data=userusecases.Profile}
+// @Failure 401 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/user/me [get]
+func (v *UserHandler) GetProfile() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to get the profile"
+
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
+		profile, err := v.UserUsecase.GetProfile(ctx, actor.Uuid)
+		if err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		return httputils.HandleSuccess(c, "successfully got the profile", profile)
+	}
+}
+
+// @Summary Изменить профиль
+// @Description Изменяет имя, телефон, язык и валюту. Меняются только переданные поля, пустая строка очищает телефон, язык или валюту. Требуется авторизация
+// @Tags Пользователь
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body UpdateProfileRequest true "Поля профиля"
+// @Success 200 {object} httputils.SuccessResponse{data=userusecases.Profile}
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 401 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/user/me [put]
+func (v *UserHandler) UpdateProfile() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to update the profile"
+
+		var req UpdateProfileRequest
+		if err := v.parseUserRequest(c, &req); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusBadRequest)
+		}
+
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
+		profile, err := v.UserUsecase.UpdateProfile(ctx, actor.Uuid, user.ProfileUpdate{
+			Name:     req.Name,
+			Phone:    req.Phone,
+			Language: req.Language,
+			Currency: req.Currency,
+		})
+		if err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		return httputils.HandleSuccess(c, "successfully updated the profile", profile)
+	}
+}
+
+func parseUserRole(roleStr string) (user.UserRole, error) {
+	role, ok := user.ParseUserRole(roleStr)
+	if !ok {
+		return user.RoleUser, fmt.Errorf("value of query value 'role' is incorrect. pick any of: 'user', 'admin', 'hotel_manager', 'airline_operator', 'support_agent'")
+	}
+
+	return role, nil
+}
+
+func parseAdminAndUserUuids(c *fiber.Ctx) (uuid.UUID, uuid.UUID, error) {
+	userUuidStr := c.Query("id")
+	if userUuidStr == "" {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("query value 'id' is required")
+	}
+
+	userUuid, parseErr := uuid.Parse(userUuidStr)
+	if parseErr != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("failed to parse query value 'id': %v", parseErr)
+	}
+
+	adminUuid, parseAdminErr := uuid.Parse(c.Locals("id").(string))
+	if parseAdminErr != nil {
+		logger.FromContext(c.UserContext()).Warn("failed to parse user uuid from locals", "error", parseAdminErr)
+		return uuid.Nil, uuid.Nil, fmt.Errorf("uuid parse error: %v", parseAdminErr)
+	}
+
+	return adminUuid, userUuid, nil
+}
+
+// @Summary Выдать роль пользователю (Admin only)
+// @Description Выдает роль другому пользователю. Каждая выдача записывается в историю с ID выдавшего админа
+// @Tags Пользователь
+// @Accept json
+// @Produce json
+// @Param id query string true "ID пользователя"
+// @Param role query string true "Роль (user, admin, hotel_manager, airline_operator, support_agent)"
+// @Success 200 {object} httputils.SuccessResponse
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
+// @Failure 404 {object} httputils.ErrorResponse
+// @Failure 409 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/admin/user/grant-role [post]
+func (v *UserHandler) GrantRole() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to grant the role"
+
+		adminUuid, userUuid, parseErr := parseAdminAndUserUuids(c)
+		if parseErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parseErr), nil, fiber.StatusBadRequest)
+		}
+
+		roleStr := c.Query("role")
+		if roleStr == "" {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, "query value 'role' is required", nil, fiber.StatusBadRequest)
 		}
 
-		if err := v.UserUsecase.ChangeName(ctx, userId, newName); err != nil {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusInternalServerError)
+		newRole, parseRoleErr := parseUserRole(roleStr)
+		if parseRoleErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parseRoleErr), nil, fiber.StatusBadRequest)
 		}
 
-		return httputils.HandleSuccess(c, fmt.Sprintf("successfully changed the name to '%v'", newName), nil)
+		if err := v.UserUsecase.GrantRole(ctx, adminUuid, userUuid, newRole); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		return httputils.HandleSuccess(c, fmt.Sprintf("successfully granted role '%v'", newRole), nil)
 	}
 }
 
-// @Summary Выдать роль админа
-// @Description Выдает роль админа по паролю. Требуется авторизация
+// @Summary Отозвать роль у пользователя (Admin only)
+// @Description Возвращает пользователю роль 'user'. Отзыв записывается в историю с ID админа
 // @Tags Пользователь
 // @Accept json
 // @Produce json
-// @Param password query string true "Пароль админки"
+// @Param id query string true "ID пользователя"
 // @Success 200 {object} httputils.SuccessResponse
 // @Failure 400 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
+// @Failure 404 {object} httputils.ErrorResponse
+// @Failure 409 {object} httputils.ErrorResponse
 // @Failure 500 {object} httputils.ErrorResponse
-// @Router /api/user/get-admin [post]
-func (v *UserHandler) GetAdminRole() fiber.Handler {
+// @Router /api/admin/user/revoke-role [post]
+func (v *UserHandler) RevokeRole() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
-		password := c.Query("password")
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to revoke the role"
 
-		unsuccessMessage := "failed to get admin role"
+		adminUuid, userUuid, parseErr := parseAdminAndUserUuids(c)
+		if parseErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parseErr), nil, fiber.StatusBadRequest)
+		}
 
-		if password == "" {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, "query value 'password' is required", nil, fiber.StatusBadRequest)
+		if err := v.UserUsecase.RevokeRole(ctx, adminUuid, userUuid); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
 		}
 
-		userUuidStr := c.Locals("id").(string)
-		userId, parseErr := uuid.Parse(userUuidStr)
+		return httputils.HandleSuccess(c, "successfully revoked the role", nil)
+	}
+}
+
+// @Summary История ролей пользователя (Admin only)
+// @Description Возвращает все выдачи и отзывы ролей пользователя, начиная с последних
+// @Tags Пользователь
+// @Accept json
+// @Produce json
+// @Param id query string true "ID пользователя"
+// @Success 200 {object} httputils.SuccessResponse{data=[]rolegrant.RoleGrant}
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/admin/user/role-grants [get]
+func (v *UserHandler) GetRoleGrants() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to get role grants"
+
+		_, userUuid, parseErr := parseAdminAndUserUuids(c)
 		if parseErr != nil {
-			flog.Warnf("Error parsing user UUID from Locals: %v\n", parseErr)
-			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("uuid parse error: %v", parseErr), nil, fiber.StatusInternalServerError)
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parseErr), nil, fiber.StatusBadRequest)
 		}
 
-		newJwtRefreshCookie, newJwtAccessCookie, err := v.UserUsecase.ChangeRole(ctx, userId, user.RoleAdmin, password)
+		roleGrants, err := v.UserUsecase.GetRoleGrants(ctx, userUuid)
 		if err != nil {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusInternalServerError)
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
 		}
 
-		c.Cookie(newJwtRefreshCookie)
-		c.Cookie(newJwtAccessCookie)
+		return httputils.HandleSuccess(c, "successfully found role grants", roleGrants)
+	}
+}
 
-		return httputils.HandleSuccess(c, "successfully changed role to 'admin'", nil)
+// @Summary Отправить письмо для подтверждения email
+// @Description Отправляет на email пользователя ссылку для подтверждения. Предыдущие ссылки перестают действовать. Требуется авторизация
+// @Tags Пользователь
+// @Accept json
+// @Produce json
+// @Success 200 {object} httputils.SuccessResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/user/verify-email/send [post]
+func (v *UserHandler) SendEmailVerification() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to send verification email"
+
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
+		if err := v.UserUsecase.SendEmailVerification(ctx, actor.Uuid); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		return httputils.HandleSuccess(c, "successfully sent verification email", nil)
 	}
 }
 
-// @Summary Выдать роль пользователя
-// @Description Выдает роль пользователя. Требуется авторизация
+// @Summary Подтвердить email
+// @Description Подтверждает email по одноразовому токену из письма
 // @Tags Пользователь
 // @Accept json
 // @Produce json
+// @Param token query string true "Токен из письма"
+// @Success 200 {object} httputils.SuccessResponse
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/user/verify-email/confirm [get]
+func (v *UserHandler) VerifyEmail() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		token := c.Query("token")
+
+		unsuccessMessage := "failed to verify email"
+
+		if token == "" {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, "query value 'token' is required", nil, fiber.StatusBadRequest)
+		}
+
+		if err := v.UserUsecase.VerifyEmail(ctx, token); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		return httputils.HandleSuccess(c, "successfully verified email", nil)
+	}
+}
+
+// @Summary Запросить сброс пароля
+// @Description Отправляет на email одноразовый код для сброса пароля. Ответ одинаковый независимо от того, существует ли аккаунт
+// @Tags Пользователь
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body PasswordResetRequest true "Email"
 // @Success 200 {object} httputils.SuccessResponse
 // @Failure 400 {object} httputils.ErrorResponse
 // @Failure 500 {object} httputils.ErrorResponse
-// @Router /api/user/get-user [post]
-func (v *UserHandler) GetUserRole() fiber.Handler {
+// @Router /api/user/password-reset/request [post]
+func (v *UserHandler) RequestPasswordReset() fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		ctx := c.Context()
-		unsuccessMessage := "failed to get user role"
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to request password reset"
+
+		var req PasswordResetRequest
+		if err := v.parseUserRequest(c, &req); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusBadRequest)
+		}
+
+		if err := v.UserUsecase.RequestPasswordReset(ctx, req.Email); err != nil {
+			logger.FromContext(ctx).Error("failed to request password reset", "error", err)
+			return httputils.HandleUnsuccess(c, unsuccessMessage, "internal error", nil, fiber.StatusInternalServerError)
+		}
 
-		userUuidStr := c.Locals("id").(string)
-		userId, parseErr := uuid.Parse(userUuidStr)
+		return httputils.HandleSuccess(c, "if the account exists, a password reset email has been sent", nil)
+	}
+}
+
+// @Summary Сбросить пароль
+// @Description Устанавливает новый пароль по одноразовому коду из письма. Если новый пароль не подходит под политику паролей, вернется 400, а код останется действительным
+// @Tags Пользователь
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body PasswordResetConfirmRequest true "Код из письма и новый пароль"
+// @Success 200 {object} httputils.SuccessResponse
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/user/password-reset/confirm [post]
+func (v *UserHandler) ResetPassword() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to reset password"
+
+		var req PasswordResetConfirmRequest
+		if err := v.parseUserRequest(c, &req); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusBadRequest)
+		}
+
+		if err := v.UserUsecase.ResetPassword(ctx, req.Token, req.Password); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		return httputils.HandleSuccess(c, "successfully reset password", nil)
+	}
+}
+
+// @Summary Сменить пароль
+// @Description Меняет пароль после проверки старого. Все остальные сессии пользователя завершаются, а неиспользованные коды сброса пароля перестают действовать. Текущая сессия получает новые cookie, те же токены возвращаются в теле ответа для клиентов с 'Authorization: Bearer'. Неверный старый пароль считается неудачной попыткой входа. Требуется авторизация
+// @Tags Пользователь
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body ChangePasswordRequest true "Текущий и новый пароль"
+// @Success 200 {object} httputils.SuccessResponse{data=userusecases.TokenPair}
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
+// @Failure 429 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/user/change-password [put]
+func (v *UserHandler) ChangePassword() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to change the password"
+
+		var req ChangePasswordRequest
+		if err := v.parseUserRequest(c, &req); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusBadRequest)
+		}
+
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
+		tokenPair, err := v.UserUsecase.ChangePassword(ctx, actor.Uuid, req.OldPassword, req.NewPassword, c.IP(), isMfaSession(c))
+		var throttledErr *loginattempt.ThrottledError
+		if errors.As(err, &throttledErr) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(throttledErr.RetryAfter.Seconds())))
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusTooManyRequests)
+		}
+		if err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		c.Cookie(v.Cookies.Build(tokenPair.RefreshToken, httputils.JwtRefreshToken))
+		c.Cookie(v.Cookies.Build(tokenPair.AccessToken, httputils.JwtAccessToken))
+		c.Set(fiber.HeaderCacheControl, "no-store")
+
+		return httputils.HandleSuccess(c, "successfully changed the password", tokenPair)
+	}
+}
+
+// @Summary Сменить email
+// @Description Отправляет ссылку подтверждения на новый email. Email меняется только после перехода по ссылке. Неверный пароль считается неудачной попыткой входа. Требуется авторизация
+// @Tags Пользователь
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body ChangeEmailRequest true "Новый email и текущий пароль"
+// @Success 200 {object} httputils.SuccessResponse
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
+// @Failure 409 {object} httputils.ErrorResponse
+// @Failure 429 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/user/change-email [post]
+func (v *UserHandler) RequestEmailChange() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to change the email"
+
+		var req ChangeEmailRequest
+		if err := v.parseUserRequest(c, &req); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusBadRequest)
+		}
+
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
+		err := v.UserUsecase.RequestEmailChange(ctx, actor.Uuid, req.Password, req.Email, c.IP())
+		var throttledErr *loginattempt.ThrottledError
+		if errors.As(err, &throttledErr) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(throttledErr.RetryAfter.Seconds())))
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusTooManyRequests)
+		}
+		if err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		return httputils.HandleSuccess(c, "confirmation link has been sent to the new email", nil)
+	}
+}
+
+// @Summary Подтвердить новый email
+// @Description Меняет email на новый по одноразовому токену из письма
+// @Tags Пользователь
+// @Accept json
+// @Produce json
+// @Param token query string true "Токен из письма"
+// @Success 200 {object} httputils.SuccessResponse
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 409 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/user/change-email/confirm [get]
+func (v *UserHandler) ConfirmEmailChange() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		token := c.Query("token")
+
+		unsuccessMessage := "failed to confirm the new email"
+
+		if token == "" {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, "query value 'token' is required", nil, fiber.StatusBadRequest)
+		}
+
+		if err := v.UserUsecase.ConfirmEmailChange(ctx, token); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		return httputils.HandleSuccess(c, "successfully changed the email", nil)
+	}
+}
+
+// @Summary Удалить аккаунт
+// @Description Обезличивает аккаунт: имя, email и пароль стираются, все сессии завершаются. Брони и билеты сохраняются для отчетности. Если включена 2FA, нужен код из приложения или код восстановления. Неверный пароль или код считается неудачной попыткой входа. Требуется авторизация
+// @Tags Пользователь
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body DeleteAccountRequest true "Текущий пароль и код 2FA"
+// @Success 200 {object} httputils.SuccessResponse
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 401 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
+// @Failure 429 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/user/delete [delete]
+func (v *UserHandler) DeleteAccount() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to delete the account"
+
+		var req DeleteAccountRequest
+		if err := v.parseUserRequest(c, &req); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusBadRequest)
+		}
+
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
+		err := v.UserUsecase.DeleteAccount(ctx, actor.Uuid, req.Password, req.Code, c.IP())
+		var throttledErr *loginattempt.ThrottledError
+		if errors.As(err, &throttledErr) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(throttledErr.RetryAfter.Seconds())))
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusTooManyRequests)
+		}
+		if err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		c.Cookie(v.Cookies.BuildExpired(httputils.JwtAccessToken))
+		c.Cookie(v.Cookies.BuildExpired(httputils.JwtRefreshToken))
+
+		return httputils.HandleSuccess(c, "successfully deleted the account", nil)
+	}
+}
+
+// @Summary Разблокировать вход (Admin only)
+// @Description Сбрасывает счетчик неудачных попыток входа для email пользователя и, если указан, для IP адреса
+// @Tags Пользователь
+// @Accept json
+// @Produce json
+// @Param id query string true "ID пользователя"
+// @Param ip query string false "IP адрес"
+// @Success 200 {object} httputils.SuccessResponse
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/admin/user/unlock [post]
+func (v *UserHandler) UnlockLogin() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to unlock login"
+
+		_, userUuid, parseErr := parseAdminAndUserUuids(c)
 		if parseErr != nil {
-			flog.Warnf("Error parsing user UUID from Locals: %v\n", parseErr)
-			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("uuid parse error: %v", parseErr), nil, fiber.StatusInternalServerError)
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", parseErr), nil, fiber.StatusBadRequest)
+		}
+
+		if err := v.UserUsecase.UnlockLogin(ctx, userUuid, c.Query("ip")); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		return httputils.HandleSuccess(c, "successfully unlocked login", nil)
+	}
+}
+
+// @Summary Начать настройку 2FA
+// @Description Создает секрет TOTP и ссылку otpauth:// для QR-кода. 2FA включается только после подтверждения кода через /api/user/2fa/enable. Неверный пароль считается неудачной попыткой входа. Требуется авторизация
+// @Tags Пользователь
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body TotpSetupRequest true "Текущий пароль"
+// @Success 200 {object} httputils.SuccessResponse{data=userusecases.TotpSetup}
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
+// @Failure 429 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/user/2fa/setup [post]
+func (v *UserHandler) SetupTotp() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to setup two-factor authentication"
+
+		var req TotpSetupRequest
+		if err := v.parseUserRequest(c, &req); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusBadRequest)
+		}
+
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
+		totpSetup, err := v.UserUsecase.SetupTotp(ctx, actor.Uuid, req.Password, c.IP())
+		var throttledErr *loginattempt.ThrottledError
+		if errors.As(err, &throttledErr) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(throttledErr.RetryAfter.Seconds())))
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusTooManyRequests)
+		}
+		if err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		return httputils.HandleSuccess(c, "scan the provisioning uri and confirm a code to enable two-factor authentication", totpSetup)
+	}
+}
+
+// @Summary Включить 2FA
+// @Description Подтверждает код из приложения и включает 2FA. Возвращает коды восстановления, они показываются только один раз. Все сессии, начатые до включения 2FA, завершаются, текущая получает новые cookie и токены в теле ответа. Требуется авторизация
+// @Tags Пользователь
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body TotpCodeRequest true "Код из приложения"
+// @Success 200 {object} httputils.SuccessResponse{data=userusecases.TotpEnabled}
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/user/2fa/enable [post]
+func (v *UserHandler) EnableTotp() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to enable two-factor authentication"
+
+		var req TotpCodeRequest
+		if err := v.parseUserRequest(c, &req); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusBadRequest)
+		}
+
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
+		totpEnabled, err := v.UserUsecase.EnableTotp(ctx, actor.Uuid, req.Code)
+		if err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
+
+		c.Cookie(v.Cookies.Build(totpEnabled.Tokens.RefreshToken, httputils.JwtRefreshToken))
+		c.Cookie(v.Cookies.Build(totpEnabled.Tokens.AccessToken, httputils.JwtAccessToken))
+		c.Set(fiber.HeaderCacheControl, "no-store")
+
+		return httputils.HandleSuccess(c, "successfully enabled two-factor authentication. save the recovery codes", totpEnabled)
+	}
+}
+
+// @Summary Выключить 2FA
+// @Description Выключает 2FA после проверки пароля и кода из приложения или кода восстановления. Неверный пароль считается неудачной попыткой входа. Требуется авторизация
+// @Tags Пользователь
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body TotpDisableRequest true "Текущий пароль и код"
+// @Success 200 {object} httputils.SuccessResponse
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
+// @Failure 429 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/user/2fa/disable [post]
+func (v *UserHandler) DisableTotp() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to disable two-factor authentication"
+
+		var req TotpDisableRequest
+		if err := v.parseUserRequest(c, &req); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusBadRequest)
+		}
+
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
 		}
 
-		newJwtRefreshCookie, newJwtAccessCookie, err := v.UserUsecase.ChangeRole(ctx, userId, user.RoleUser, "")
+		err := v.UserUsecase.DisableTotp(ctx, actor.Uuid, req.Password, req.Code, c.IP())
+		var throttledErr *loginattempt.ThrottledError
+		if errors.As(err, &throttledErr) {
+			c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(throttledErr.RetryAfter.Seconds())))
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusTooManyRequests)
+		}
 		if err != nil {
-			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusInternalServerError)
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
 		}
 
-		c.Cookie(newJwtRefreshCookie)
-		c.Cookie(newJwtAccessCookie)
+		return httputils.HandleSuccess(c, "successfully disabled two-factor authentication", nil)
+	}
+}
+
+// @Summary Новые коды восстановления 2FA
+// @Description Заменяет все коды восстановления новыми. Нужен код из приложения. Требуется авторизация
+// @Tags Пользователь
+// @Accept json,x-www-form-urlencoded
+// @Produce json
+// @Param request body TotpCodeRequest true "Код из приложения"
+// @Success 200 {object} httputils.SuccessResponse{data=[]string}
+// @Failure 400 {object} httputils.ErrorResponse
+// @Failure 403 {object} httputils.ErrorResponse
+// @Failure 500 {object} httputils.ErrorResponse
+// @Router /api/user/2fa/recovery-codes [post]
+func (v *UserHandler) RegenerateRecoveryCodes() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := c.UserContext()
+		unsuccessMessage := "failed to regenerate recovery codes"
+
+		var req TotpCodeRequest
+		if err := v.parseUserRequest(c, &req); err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, fiber.StatusBadRequest)
+		}
+
+		actor, actorErr := getActor(c)
+		if actorErr != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", actorErr), nil, fiber.StatusInternalServerError)
+		}
+
+		recoveryCodes, err := v.UserUsecase.RegenerateRecoveryCodes(ctx, actor.Uuid, req.Code)
+		if err != nil {
+			return httputils.HandleUnsuccess(c, unsuccessMessage, fmt.Sprintf("%v", err), nil, usecaseErrorStatus(err))
+		}
 
-		return httputils.HandleSuccess(c, "successfully changed role to 'user'", nil)
+		return httputils.HandleSuccess(c, "successfully regenerated recovery codes", recoveryCodes)
 	}
 }
@@ -0,0 +1,231 @@
+package handler
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rom6n/otello/internal/pkg/logger"
+	"github.com/rom6n/otello/internal/utils/httputils"
+)
+
+var (
+	phoneRegexp    = regexp.MustCompile(`^\+[1-9][0-9]{6,14}$`)
+	languageRegexp = regexp.MustCompile(`^[a-z]{2}$`)
+	currencyRegexp = regexp.MustCompile(`^[A-Z]{3}$`)
+)
+
+type userRequest interface {
+	validate() error
+}
+
+type RegisterRequest struct {
+	Name     string `json:"name" form:"name" query:"name" example:"Иван"`
+	Email    string `json:"email" form:"email" query:"email" example:"ivan@example.com"`
+	Password string `json:"password" form:"password" query:"password" example:"strong-password"`
+}
+
+type LoginRequest struct {
+	Email    string `json:"email" form:"email" query:"email" example:"ivan@example.com"`
+	Password string `json:"password" form:"password" query:"password" example:"strong-password"`
+	Code     string `json:"code,omitempty" form:"code" query:"code" example:"123456"` // TOTP or recovery code, only when 2FA is enabled
+}
+
+type RenameRequest struct {
+	Name string `json:"name" form:"name" query:"name" example:"Иван"`
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email" form:"email" query:"email" example:"ivan@example.com"`
+}
+
+type PasswordResetConfirmRequest struct {
+	Token    string `json:"token" form:"token" query:"token"`
+	Password string `json:"password" form:"password" query:"password" example:"new-strong-password"`
+}
+
+type ChangePasswordRequest struct {
+	OldPassword string `json:"old_password" form:"old_password" query:"old_password" example:"strong-password"`
+	NewPassword string `json:"new_password" form:"new_password" query:"new_password" example:"new-strong-password"`
+}
+
+type ChangeEmailRequest struct {
+	Email    string `json:"email" form:"email" query:"email" example:"new-ivan@example.com"`
+	Password string `json:"password" form:"password" query:"password" example:"strong-password"`
+}
+
+type DeleteAccountRequest struct {
+	Password string `json:"password" form:"password" query:"password" example:"strong-password"`
+	Code     string `json:"code,omitempty" form:"code" query:"code" example:"123456"` // TOTP or recovery code, only when 2FA is enabled
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" form:"refresh_token" query:"refresh_token"`
+}
+
+type TotpSetupRequest struct {
+	Password string `json:"password" form:"password" query:"password" example:"strong-password"`
+}
+
+type TotpCodeRequest struct {
+	Code string `json:"code" form:"code" query:"code" example:"123456"`
+}
+
+// UpdateProfileRequest changes only the sent fields, an empty string clears phone, language or currency
+type UpdateProfileRequest struct {
+	Name     *string `json:"name,omitempty" form:"name" query:"name" example:"Иван"`
+	Phone    *string `json:"phone,omitempty" form:"phone" query:"phone" example:"+79991234567"`
+	Language *string `json:"language,omitempty" form:"language" query:"language" example:"ru"`
+	Currency *string `json:"currency,omitempty" form:"currency" query:"currency" example:"RUB"`
+}
+
+type TotpDisableRequest struct {
+	Password string `json:"password" form:"password" query:"password" example:"strong-password"`
+	Code     string `json:"code" form:"code" query:"code" example:"123456"`
+}
+
+func (r *RegisterRequest) validate() error {
+	if r.Name == "" || r.Email == "" || r.Password == "" {
+		return fmt.Errorf("fields 'name', 'email' and 'password' are required")
+	}
+	return validateEmail(r.Email)
+}
+
+func (r *LoginRequest) validate() error {
+	if r.Email == "" || r.Password == "" {
+		return fmt.Errorf("fields 'email' and 'password' are required")
+	}
+	return validateEmail(r.Email)
+}
+
+func (r *RenameRequest) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("field 'name' is required")
+	}
+	return nil
+}
+
+func (r *PasswordResetRequest) validate() error {
+	if r.Email == "" {
+		return fmt.Errorf("field 'email' is required")
+	}
+	return validateEmail(r.Email)
+}
+
+func (r *PasswordResetConfirmRequest) validate() error {
+	if r.Token == "" || r.Password == "" {
+		return fmt.Errorf("fields 'token' and 'password' are required")
+	}
+	return nil
+}
+
+func (r *ChangePasswordRequest) validate() error {
+	if r.OldPassword == "" || r.NewPassword == "" {
+		return fmt.Errorf("fields 'old_password' and 'new_password' are required")
+	}
+	return nil
+}
+
+func (r *ChangeEmailRequest) validate() error {
+	if r.Email == "" || r.Password == "" {
+		return fmt.Errorf("fields 'email' and 'password' are required")
+	}
+	return validateEmail(r.Email)
+}
+
+func (r *DeleteAccountRequest) validate() error {
+	if r.Password == "" {
+		return fmt.Errorf("field 'password' is required")
+	}
+	return nil
+}
+
+func (r *RefreshTokenRequest) validate() error {
+	if r.RefreshToken == "" {
+		return fmt.Errorf("field 'refresh_token' is required")
+	}
+	return nil
+}
+
+func (r *TotpSetupRequest) validate() error {
+	if r.Password == "" {
+		return fmt.Errorf("field 'password' is required")
+	}
+	return nil
+}
+
+func (r *TotpCodeRequest) validate() error {
+	if r.Code == "" {
+		return fmt.Errorf("field 'code' is required")
+	}
+	return nil
+}
+
+func (r *TotpDisableRequest) validate() error {
+	if r.Password == "" || r.Code == "" {
+		return fmt.Errorf("fields 'password' and 'code' are required")
+	}
+	return nil
+}
+
+func (r *UpdateProfileRequest) validate() error {
+	if r.Name == nil && r.Phone == nil && r.Language == nil && r.Currency == nil {
+		return fmt.Errorf("at least one of fields 'name', 'phone', 'language' and 'currency' is required")
+	}
+
+	if r.Name != nil {
+		*r.Name = strings.TrimSpace(*r.Name)
+		if *r.Name == "" {
+			return fmt.Errorf("field 'name' can not be empty")
+		}
+	}
+
+	if r.Phone != nil {
+		*r.Phone = strings.NewReplacer(" ", "", "-", "", "(", "", ")", "").Replace(*r.Phone)
+		if *r.Phone != "" && !phoneRegexp.MatchString(*r.Phone) {
+			return fmt.Errorf("field 'phone' must be in international format, e.g. +79991234567")
+		}
+	}
+
+	if r.Language != nil {
+		*r.Language = strings.ToLower(strings.TrimSpace(*r.Language))
+		if *r.Language != "" && !languageRegexp.MatchString(*r.Language) {
+			return fmt.Errorf("field 'language' must be a two-letter ISO 639-1 code, e.g. ru")
+		}
+	}
+
+	if r.Currency != nil {
+		*r.Currency = strings.ToUpper(strings.TrimSpace(*r.Currency))
+		if *r.Currency != "" && !currencyRegexp.MatchString(*r.Currency) {
+			return fmt.Errorf("field 'currency' must be a three-letter ISO 4217 code, e.g. RUB")
+		}
+	}
+
+	return nil
+}
+
+func validateEmail(email string) error {
+	if !httputils.IsEmailCorrect(email) {
+		return fmt.Errorf("invalid email address")
+	}
+	return nil
+}
+
+// parseUserRequest reads a JSON or form body. Query values are only read when AllowQueryCredentials is set,
+// since they end up in access logs and browser history
+func (v *UserHandler) parseUserRequest(c *fiber.Ctx, req userRequest) error {
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(req); err != nil {
+			return fmt.Errorf("failed to parse request body: %v", err)
+		}
+	} else if v.AllowQueryCredentials && len(c.Request().URI().QueryString()) > 0 {
+		logger.FromContext(c.UserContext()).Warn("deprecated query credentials used")
+		c.Set("Deprecation", "true")
+		if err := c.QueryParser(req); err != nil {
+			return fmt.Errorf("failed to parse query: %v", err)
+		}
+	}
+
+	return req.validate()
+}
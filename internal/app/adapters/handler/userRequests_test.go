@@ -0,0 +1,44 @@
+package handler
+
+import "testing"
+
+func TestUpdateProfileRequestValidate(t *testing.T) {
+	text := func(s string) *string { return &s }
+
+	for _, tt := range []struct {
+		name string
+		req  UpdateProfileRequest
+		ok   bool
+	}{
+		{"nothing to change", UpdateProfileRequest{}, false},
+		{"blank name", UpdateProfileRequest{Name: text("  ")}, false},
+		{"phone with spaces and dashes", UpdateProfileRequest{Phone: text("+7 999 123-45-67")}, true},
+		{"phone without plus", UpdateProfileRequest{Phone: text("89991234567")}, false},
+		{"phone with letters", UpdateProfileRequest{Phone: text("+7999CALLME")}, false},
+		{"phone cleared", UpdateProfileRequest{Phone: text("")}, true},
+		{"language", UpdateProfileRequest{Language: text(" RU ")}, true},
+		{"language of three letters", UpdateProfileRequest{Language: text("rus")}, false},
+		{"language in cyrillic", UpdateProfileRequest{Language: text("ру")}, false},
+		{"currency", UpdateProfileRequest{Currency: text("rub")}, true},
+		{"currency of two letters", UpdateProfileRequest{Currency: text("RU")}, false},
+		{"currency symbol", UpdateProfileRequest{Currency: text("₽")}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.req.validate(); (err == nil) != tt.ok {
+				t.Fatalf("validate = %v, want ok %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestUpdateProfileRequestValidateNormalizes(t *testing.T) {
+	phone, language, currency := "+7 (999) 123-45-67", " RU", "rub "
+	req := UpdateProfileRequest{Phone: &phone, Language: &language, Currency: &currency}
+
+	if err := req.validate(); err != nil {
+		t.Fatalf("validate: %v", err)
+	}
+	if phone != "+79991234567" || language != "ru" || currency != "RUB" {
+		t.Fatalf("validated phone %q, language %q, currency %q, want +79991234567, ru and RUB", phone, language, currency)
+	}
+}
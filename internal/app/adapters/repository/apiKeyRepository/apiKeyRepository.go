@@ -0,0 +1,167 @@
+package apikeyrepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/domain/apikey"
+	"github.com/rom6n/otello/internal/pkg/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type ApiKeyRepository interface {
+	CreateApiKey(ctx context.Context, apiKey *apikey.ApiKey) error
+	GetApiKeyByHash(ctx context.Context, keyHash string) (*apikey.ApiKey, error)
+	GetApiKeysByOwnerUuid(ctx context.Context, ownerUuid uuid.UUID) ([]apikey.ApiKey, error)
+	RevokeApiKey(ctx context.Context, keyUuid, ownerUuid uuid.UUID) error
+	UpdateApiKeyLastUsed(ctx context.Context, keyUuid uuid.UUID, lastUsedAt, notBefore int64) error
+}
+
+type apiKeyRepo struct {
+	client         *mongo.Client
+	dbName         string
+	collectionName string
+	timeout        time.Duration
+}
+
+func New(dbConnection *mongo.Client, dbName, collectionName string, timeout time.Duration) ApiKeyRepository {
+	return &apiKeyRepo{
+		client:         dbConnection,
+		dbName:         dbName,
+		collectionName: collectionName,
+		timeout:        timeout,
+	}
+}
+
+// MongoSchema is the validator and the indexes of the collection New works with
+func MongoSchema(collectionName string) database.MongoCollection {
+	return database.MongoCollection{
+		Name: collectionName,
+		Validator: database.MongoSchemaObject(
+			[]string{"_id", "owner_id", "name", "prefix", "key_hash", "permissions", "created_at", "last_used_at"},
+			bson.D{
+				{Key: "_id", Value: database.MongoUuid},
+				{Key: "owner_id", Value: database.MongoUuid},
+				{Key: "name", Value: database.MongoString},
+				{Key: "prefix", Value: database.MongoString},
+				{Key: "key_hash", Value: database.MongoString},
+				{Key: "permissions", Value: database.MongoArray(database.MongoString)},
+				{Key: "created_at", Value: database.MongoInteger},
+				{Key: "last_used_at", Value: database.MongoInteger},
+				{Key: "revoked_at", Value: database.MongoInteger},
+			},
+		),
+		Indexes: []database.MongoIndex{
+			{Name: "key_hash_unique", Keys: bson.D{{Key: "key_hash", Value: 1}}, Unique: true},
+			{Name: "owner_id_created_at", Keys: bson.D{{Key: "owner_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		},
+	}
+}
+
+func (v *apiKeyRepo) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return database.OperationContext(ctx, v.timeout, database.StorageMongo, "api_key", method)
+}
+
+func (v *apiKeyRepo) getCollection() *mongo.Collection {
+	return v.client.Database(v.dbName).Collection(v.collectionName)
+}
+
+func (v *apiKeyRepo) CreateApiKey(ctx context.Context, apiKey *apikey.ApiKey) (err error) {
+	dbCtx, end := v.getContext(ctx, "CreateApiKey")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	if _, err := collection.InsertOne(dbCtx, apiKey); err != nil {
+		return fmt.Errorf("failed to create api key: %v", err)
+	}
+
+	return nil
+}
+
+func (v *apiKeyRepo) GetApiKeyByHash(ctx context.Context, keyHash string) (_ *apikey.ApiKey, err error) {
+	dbCtx, end := v.getContext(ctx, "GetApiKeyByHash")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	var apiKey apikey.ApiKey
+	err = collection.FindOne(dbCtx, bson.D{{Key: "key_hash", Value: keyHash}}).Decode(&apiKey)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, apikey.ErrInvalidApiKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key from database: %v", err)
+	}
+
+	return &apiKey, nil
+}
+
+func (v *apiKeyRepo) GetApiKeysByOwnerUuid(ctx context.Context, ownerUuid uuid.UUID) (_ []apikey.ApiKey, err error) {
+	dbCtx, end := v.getContext(ctx, "GetApiKeysByOwnerUuid")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	opts := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+	cursor, err := collection.Find(dbCtx, bson.D{{Key: "owner_id", Value: ownerUuid}}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find api keys: %v", err)
+	}
+
+	var apiKeys []apikey.ApiKey
+	if err := cursor.All(dbCtx, &apiKeys); err != nil {
+		return nil, fmt.Errorf("failed to decode api keys: %v", err)
+	}
+
+	return apiKeys, nil
+}
+
+func (v *apiKeyRepo) RevokeApiKey(ctx context.Context, keyUuid, ownerUuid uuid.UUID) (err error) {
+	dbCtx, end := v.getContext(ctx, "RevokeApiKey")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	filter := bson.D{
+		{Key: "_id", Value: keyUuid},
+		{Key: "owner_id", Value: ownerUuid},
+		{Key: "revoked_at", Value: bson.D{{Key: "$exists", Value: false}}},
+	}
+	update := bson.M{"$set": bson.M{"revoked_at": time.Now().Unix()}}
+
+	result, err := collection.UpdateOne(dbCtx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("api key not found or already revoked")
+	}
+
+	return nil
+}
+
+// UpdateApiKeyLastUsed skips the write if the key was already marked used after notBefore, so busy keys don't write on every request
+func (v *apiKeyRepo) UpdateApiKeyLastUsed(ctx context.Context, keyUuid uuid.UUID, lastUsedAt, notBefore int64) (err error) {
+	dbCtx, end := v.getContext(ctx, "UpdateApiKeyLastUsed")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	filter := bson.D{
+		{Key: "_id", Value: keyUuid},
+		{Key: "last_used_at", Value: bson.D{{Key: "$lt", Value: notBefore}}},
+	}
+	update := bson.M{"$set": bson.M{"last_used_at": lastUsedAt}}
+
+	if _, err := collection.UpdateOne(dbCtx, filter, update); err != nil {
+		return fmt.Errorf("failed to update api key last used: %v", err)
+	}
+
+	return nil
+}
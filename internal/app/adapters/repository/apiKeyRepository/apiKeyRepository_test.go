@@ -0,0 +1,136 @@
+package apikeyrepository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/repositorytest"
+	"github.com/rom6n/otello/internal/app/domain/apikey"
+	"github.com/rom6n/otello/internal/app/domain/user"
+)
+
+func TestMemoryApiKeyRepository(t *testing.T) {
+	testApiKeyRepository(t, func(t *testing.T) (ApiKeyRepository, repositorytest.Fixtures) {
+		return NewMemory(), repositorytest.NoFixtures{}
+	})
+}
+
+func TestMongoApiKeyRepository(t *testing.T) {
+	testApiKeyRepository(t, func(t *testing.T) (ApiKeyRepository, repositorytest.Fixtures) {
+		client, dbName := repositorytest.MongoDatabase(t)
+		repositorytest.EnsureMongoSchema(t, client, dbName, MongoSchema("apiKeys"))
+		return New(client, dbName, "apiKeys", 30*time.Second), repositorytest.NoFixtures{}
+	})
+}
+
+func TestPostgresApiKeyRepository(t *testing.T) {
+	testApiKeyRepository(t, func(t *testing.T) (ApiKeyRepository, repositorytest.Fixtures) {
+		pool := repositorytest.PostgresDatabase(t)
+		return NewPostgres(pool, 30*time.Second), repositorytest.PostgresFixtures(pool)
+	})
+}
+
+func testApiKeyRepository(t *testing.T, newRepo func(t *testing.T) (ApiKeyRepository, repositorytest.Fixtures)) {
+	ctx := context.Background()
+
+	t.Run("get by hash", func(t *testing.T) {
+		repo, fixtures := newRepo(t)
+
+		created := apikey.NewApiKey(fixtures.User(t), "channel manager", "otk_abcd", "hash", []user.Permission{user.PermManageHotelRooms})
+		if err := repo.CreateApiKey(ctx, created); err != nil {
+			t.Fatalf("CreateApiKey: %v", err)
+		}
+
+		found, err := repo.GetApiKeyByHash(ctx, "hash")
+		if err != nil {
+			t.Fatalf("GetApiKeyByHash: %v", err)
+		}
+		if !reflect.DeepEqual(found, created) {
+			t.Fatalf("GetApiKeyByHash = %+v, want %+v", *found, *created)
+		}
+
+		if _, err := repo.GetApiKeyByHash(ctx, "unknown"); !errors.Is(err, apikey.ErrInvalidApiKey) {
+			t.Fatalf("GetApiKeyByHash of an unknown key error = %v, want ErrInvalidApiKey", err)
+		}
+	})
+
+	t.Run("get by owner, newest first", func(t *testing.T) {
+		repo, fixtures := newRepo(t)
+		ownerUuid := fixtures.User(t)
+
+		older := apikey.NewApiKey(ownerUuid, "older", "otk_1", "hash1", nil)
+		older.CreatedAt = 1000
+		newer := apikey.NewApiKey(ownerUuid, "newer", "otk_2", "hash2", nil)
+		newer.CreatedAt = 2000
+		other := apikey.NewApiKey(fixtures.User(t), "other", "otk_3", "hash3", nil)
+		for _, key := range []*apikey.ApiKey{older, newer, other} {
+			if err := repo.CreateApiKey(ctx, key); err != nil {
+				t.Fatalf("CreateApiKey: %v", err)
+			}
+		}
+
+		apiKeys, err := repo.GetApiKeysByOwnerUuid(ctx, ownerUuid)
+		if err != nil {
+			t.Fatalf("GetApiKeysByOwnerUuid: %v", err)
+		}
+		if len(apiKeys) != 2 || apiKeys[0].Uuid != newer.Uuid || apiKeys[1].Uuid != older.Uuid {
+			t.Fatalf("GetApiKeysByOwnerUuid = %+v, want newer then older", apiKeys)
+		}
+	})
+
+	t.Run("revoke", func(t *testing.T) {
+		repo, fixtures := newRepo(t)
+		ownerUuid := fixtures.User(t)
+
+		created := apikey.NewApiKey(ownerUuid, "key", "otk_abcd", "hash", nil)
+		if err := repo.CreateApiKey(ctx, created); err != nil {
+			t.Fatalf("CreateApiKey: %v", err)
+		}
+
+		if err := repo.RevokeApiKey(ctx, created.Uuid, uuid.New()); err == nil {
+			t.Fatalf("RevokeApiKey by another user: want error")
+		}
+		if err := repo.RevokeApiKey(ctx, created.Uuid, ownerUuid); err != nil {
+			t.Fatalf("RevokeApiKey: %v", err)
+		}
+		if err := repo.RevokeApiKey(ctx, created.Uuid, ownerUuid); err == nil {
+			t.Fatalf("second RevokeApiKey: want error")
+		}
+
+		found, err := repo.GetApiKeyByHash(ctx, "hash")
+		if err != nil {
+			t.Fatalf("GetApiKeyByHash: %v", err)
+		}
+		if !found.IsRevoked() {
+			t.Fatalf("key is not revoked: %+v", *found)
+		}
+	})
+
+	t.Run("last used is written only when older than not before", func(t *testing.T) {
+		repo, fixtures := newRepo(t)
+
+		created := apikey.NewApiKey(fixtures.User(t), "key", "otk_abcd", "hash", nil)
+		if err := repo.CreateApiKey(ctx, created); err != nil {
+			t.Fatalf("CreateApiKey: %v", err)
+		}
+
+		if err := repo.UpdateApiKeyLastUsed(ctx, created.Uuid, 1000, 500); err != nil {
+			t.Fatalf("UpdateApiKeyLastUsed: %v", err)
+		}
+		if err := repo.UpdateApiKeyLastUsed(ctx, created.Uuid, 1100, 1000); err != nil {
+			t.Fatalf("UpdateApiKeyLastUsed: %v", err)
+		}
+
+		found, err := repo.GetApiKeyByHash(ctx, "hash")
+		if err != nil {
+			t.Fatalf("GetApiKeyByHash: %v", err)
+		}
+		if found.LastUsedAt != 1000 {
+			t.Fatalf("last used = %v, want 1000", found.LastUsedAt)
+		}
+	})
+}
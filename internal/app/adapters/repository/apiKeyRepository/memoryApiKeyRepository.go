@@ -0,0 +1,91 @@
+package apikeyrepository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/memorystore"
+	"github.com/rom6n/otello/internal/app/domain/apikey"
+)
+
+type memoryApiKeyRepo struct {
+	apiKeys *memorystore.Store[uuid.UUID, apikey.ApiKey]
+}
+
+// NewMemory keeps api keys in process memory, for tests and running without MongoDB
+func NewMemory() ApiKeyRepository {
+	return &memoryApiKeyRepo{
+		apiKeys: memorystore.New(func(k *apikey.ApiKey) uuid.UUID { return k.Uuid }, cloneApiKey),
+	}
+}
+
+func cloneApiKey(apiKey apikey.ApiKey) apikey.ApiKey {
+	apiKey.Permissions = slices.Clone(apiKey.Permissions)
+	return apiKey
+}
+
+func (v *memoryApiKeyRepo) CreateApiKey(ctx context.Context, apiKey *apikey.ApiKey) error {
+	if err := v.apiKeys.Insert(*apiKey); err != nil {
+		return fmt.Errorf("failed to create api key: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryApiKeyRepo) GetApiKeyByHash(ctx context.Context, keyHash string) (*apikey.ApiKey, error) {
+	apiKey, ok := v.apiKeys.FindOne(func(k *apikey.ApiKey) bool {
+		return k.KeyHash == keyHash
+	})
+	if !ok {
+		return nil, apikey.ErrInvalidApiKey
+	}
+
+	return &apiKey, nil
+}
+
+func (v *memoryApiKeyRepo) GetApiKeysByOwnerUuid(ctx context.Context, ownerUuid uuid.UUID) ([]apikey.ApiKey, error) {
+	apiKeys := v.apiKeys.Find(func(k *apikey.ApiKey) bool {
+		return k.OwnerUuid == ownerUuid
+	})
+
+	sort.SliceStable(apiKeys, func(i, j int) bool { return apiKeys[i].CreatedAt > apiKeys[j].CreatedAt })
+
+	return apiKeys, nil
+}
+
+func (v *memoryApiKeyRepo) RevokeApiKey(ctx context.Context, keyUuid, ownerUuid uuid.UUID) error {
+	_, revoked, err := v.apiKeys.Update(keyUuid, func(k *apikey.ApiKey) bool {
+		if k.OwnerUuid != ownerUuid || k.IsRevoked() {
+			return false
+		}
+		k.RevokedAt = time.Now().Unix()
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %v", err)
+	}
+	if !revoked {
+		return fmt.Errorf("api key not found or already revoked")
+	}
+
+	return nil
+}
+
+func (v *memoryApiKeyRepo) UpdateApiKeyLastUsed(ctx context.Context, keyUuid uuid.UUID, lastUsedAt, notBefore int64) error {
+	_, _, err := v.apiKeys.Update(keyUuid, func(k *apikey.ApiKey) bool {
+		if k.LastUsedAt >= notBefore {
+			return false
+		}
+		k.LastUsedAt = lastUsedAt
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update api key last used: %v", err)
+	}
+
+	return nil
+}
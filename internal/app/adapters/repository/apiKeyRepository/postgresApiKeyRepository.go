@@ -0,0 +1,113 @@
+package apikeyrepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rom6n/otello/internal/app/domain/apikey"
+	"github.com/rom6n/otello/internal/pkg/database"
+)
+
+const apiKeyColumns = "id, owner_id, name, prefix, key_hash, permissions, created_at, last_used_at, revoked_at"
+
+type postgresApiKeyRepo struct {
+	pool    *pgxpool.Pool
+	timeout time.Duration
+}
+
+// NewPostgres keeps api keys in the api_keys table, the schema comes from database.MigratePostgres
+func NewPostgres(pool *pgxpool.Pool, timeout time.Duration) ApiKeyRepository {
+	return &postgresApiKeyRepo{
+		pool:    pool,
+		timeout: timeout,
+	}
+}
+
+func (v *postgresApiKeyRepo) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return database.OperationContext(ctx, v.timeout, database.StoragePostgres, "api_key", method)
+}
+
+func scanApiKey(row pgx.Row) (apikey.ApiKey, error) {
+	var apiKey apikey.ApiKey
+	err := row.Scan(&apiKey.Uuid, &apiKey.OwnerUuid, &apiKey.Name, &apiKey.Prefix, &apiKey.KeyHash, &apiKey.Permissions, &apiKey.CreatedAt, &apiKey.LastUsedAt, &apiKey.RevokedAt)
+	return apiKey, err
+}
+
+func (v *postgresApiKeyRepo) CreateApiKey(ctx context.Context, apiKey *apikey.ApiKey) (err error) {
+	dbCtx, end := v.getContext(ctx, "CreateApiKey")
+	defer end(&err)
+
+	_, err = v.pool.Exec(dbCtx, "INSERT INTO api_keys ("+apiKeyColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		apiKey.Uuid, apiKey.OwnerUuid, apiKey.Name, apiKey.Prefix, apiKey.KeyHash, apiKey.Permissions, apiKey.CreatedAt, apiKey.LastUsedAt, apiKey.RevokedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create api key: %v", err)
+	}
+
+	return nil
+}
+
+func (v *postgresApiKeyRepo) GetApiKeyByHash(ctx context.Context, keyHash string) (_ *apikey.ApiKey, err error) {
+	dbCtx, end := v.getContext(ctx, "GetApiKeyByHash")
+	defer end(&err)
+
+	apiKey, err := scanApiKey(v.pool.QueryRow(dbCtx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = $1", keyHash))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, apikey.ErrInvalidApiKey
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get api key from database: %v", err)
+	}
+
+	return &apiKey, nil
+}
+
+func (v *postgresApiKeyRepo) GetApiKeysByOwnerUuid(ctx context.Context, ownerUuid uuid.UUID) (_ []apikey.ApiKey, err error) {
+	dbCtx, end := v.getContext(ctx, "GetApiKeysByOwnerUuid")
+	defer end(&err)
+
+	rows, err := v.pool.Query(dbCtx, "SELECT "+apiKeyColumns+" FROM api_keys WHERE owner_id = $1 ORDER BY created_at DESC", ownerUuid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find api keys: %v", err)
+	}
+
+	apiKeys, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (apikey.ApiKey, error) { return scanApiKey(row) })
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode api keys: %v", err)
+	}
+
+	return apiKeys, nil
+}
+
+func (v *postgresApiKeyRepo) RevokeApiKey(ctx context.Context, keyUuid, ownerUuid uuid.UUID) (err error) {
+	dbCtx, end := v.getContext(ctx, "RevokeApiKey")
+	defer end(&err)
+
+	result, err := v.pool.Exec(dbCtx, "UPDATE api_keys SET revoked_at = $3 WHERE id = $1 AND owner_id = $2 AND revoked_at = 0",
+		keyUuid, ownerUuid, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %v", err)
+	}
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("api key not found or already revoked")
+	}
+
+	return nil
+}
+
+// UpdateApiKeyLastUsed skips the write if the key was already marked used after notBefore, so busy keys don't write on every request
+func (v *postgresApiKeyRepo) UpdateApiKeyLastUsed(ctx context.Context, keyUuid uuid.UUID, lastUsedAt, notBefore int64) (err error) {
+	dbCtx, end := v.getContext(ctx, "UpdateApiKeyLastUsed")
+	defer end(&err)
+
+	_, err = v.pool.Exec(dbCtx, "UPDATE api_keys SET last_used_at = $2 WHERE id = $1 AND last_used_at < $3", keyUuid, lastUsedAt, notBefore)
+	if err != nil {
+		return fmt.Errorf("failed to update api key last used: %v", err)
+	}
+
+	return nil
+}
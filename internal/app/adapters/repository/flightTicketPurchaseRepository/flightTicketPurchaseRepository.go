@@ -0,0 +1,98 @@
+package flightticketpurchaserepository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/domain/flightticket"
+	"github.com/rom6n/otello/internal/pkg/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type FlightTicketPurchaseRepository interface {
+	CreatePurchase(ctx context.Context, purchase *flightticket.Purchase) error
+	GetPurchasesByBuyerUuid(ctx context.Context, buyerUuid uuid.UUID) ([]flightticket.Purchase, error)
+}
+
+type flightTicketPurchaseRepo struct {
+	client         *mongo.Client
+	dbName         string
+	collectionName string
+	timeout        time.Duration
+}
+
+func New(dbConnection *mongo.Client, dbName, collectionName string, timeout time.Duration) FlightTicketPurchaseRepository {
+	return &flightTicketPurchaseRepo{
+		client:         dbConnection,
+		dbName:         dbName,
+		collectionName: collectionName,
+		timeout:        timeout,
+	}
+}
+
+// MongoSchema is the validator and the indexes of the collection New works with
+func MongoSchema(collectionName string) database.MongoCollection {
+	return database.MongoCollection{
+		Name: collectionName,
+		Validator: database.MongoSchemaObject(
+			[]string{"_id", "flight_ticket_id", "buyer_id", "quantity", "created_at"},
+			bson.D{
+				{Key: "_id", Value: database.MongoUuid},
+				{Key: "flight_ticket_id", Value: database.MongoUuid},
+				{Key: "buyer_id", Value: database.MongoUuid},
+				{Key: "quantity", Value: database.MongoInteger},
+				{Key: "value", Value: database.MongoInteger},
+				{Key: "created_at", Value: database.MongoInteger},
+			},
+		),
+		Indexes: []database.MongoIndex{
+			{Name: "buyer_id_created_at", Keys: bson.D{{Key: "buyer_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		},
+	}
+}
+
+func (v *flightTicketPurchaseRepo) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return database.OperationContext(ctx, v.timeout, database.StorageMongo, "flight_ticket_purchase", method)
+}
+
+func (v *flightTicketPurchaseRepo) getCollection() *mongo.Collection {
+	return v.client.Database(v.dbName).Collection(v.collectionName)
+}
+
+func (v *flightTicketPurchaseRepo) CreatePurchase(ctx context.Context, purchase *flightticket.Purchase) (err error) {
+	dbCtx, end := v.getContext(ctx, "CreatePurchase")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	if _, err := collection.InsertOne(dbCtx, purchase); err != nil {
+		return fmt.Errorf("failed to create flight ticket purchase: %v", err)
+	}
+
+	return nil
+}
+
+func (v *flightTicketPurchaseRepo) GetPurchasesByBuyerUuid(ctx context.Context, buyerUuid uuid.UUID) (_ []flightticket.Purchase, err error) {
+	dbCtx, end := v.getContext(ctx, "GetPurchasesByBuyerUuid")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := collection.Find(dbCtx, bson.D{{Key: "buyer_id", Value: buyerUuid}}, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flight ticket purchases by buyer uuid: %v", err)
+	}
+
+	var purchases []flightticket.Purchase
+	if err := cursor.All(dbCtx, &purchases); err != nil {
+		return nil, fmt.Errorf("failed to decode flight ticket purchases: %v", err)
+	}
+
+	return purchases, nil
+}
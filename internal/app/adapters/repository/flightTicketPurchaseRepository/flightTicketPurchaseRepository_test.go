@@ -0,0 +1,75 @@
+package flightticketpurchaserepository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/repositorytest"
+	"github.com/rom6n/otello/internal/app/domain/flightticket"
+)
+
+func TestMemoryFlightTicketPurchaseRepository(t *testing.T) {
+	testFlightTicketPurchaseRepository(t, func(t *testing.T) (FlightTicketPurchaseRepository, repositorytest.Fixtures) {
+		return NewMemory(), repositorytest.NoFixtures{}
+	})
+}
+
+func TestMongoFlightTicketPurchaseRepository(t *testing.T) {
+	testFlightTicketPurchaseRepository(t, func(t *testing.T) (FlightTicketPurchaseRepository, repositorytest.Fixtures) {
+		client, dbName := repositorytest.MongoDatabase(t)
+		repositorytest.EnsureMongoSchema(t, client, dbName, MongoSchema("flightTicketPurchases"))
+		return New(client, dbName, "flightTicketPurchases", 30*time.Second), repositorytest.NoFixtures{}
+	})
+}
+
+func TestPostgresFlightTicketPurchaseRepository(t *testing.T) {
+	testFlightTicketPurchaseRepository(t, func(t *testing.T) (FlightTicketPurchaseRepository, repositorytest.Fixtures) {
+		pool := repositorytest.PostgresDatabase(t)
+		return NewPostgres(pool, 30*time.Second), repositorytest.PostgresFixtures(pool)
+	})
+}
+
+func testFlightTicketPurchaseRepository(t *testing.T, newRepo func(t *testing.T) (FlightTicketPurchaseRepository, repositorytest.Fixtures)) {
+	ctx := context.Background()
+
+	t.Run("get by buyer, newest first", func(t *testing.T) {
+		repo, fixtures := newRepo(t)
+		buyerUuid := fixtures.User(t)
+
+		older := flightticket.NewPurchase(fixtures.FlightTicket(t), buyerUuid, 1, repositorytest.Ptr[uint32](5000))
+		older.CreatedAt = 1000
+		newer := flightticket.NewPurchase(fixtures.FlightTicket(t), buyerUuid, 2, nil)
+		newer.CreatedAt = 2000
+		other := flightticket.NewPurchase(fixtures.FlightTicket(t), fixtures.User(t), 1, nil)
+		for _, purchase := range []*flightticket.Purchase{older, newer, other} {
+			if err := repo.CreatePurchase(ctx, purchase); err != nil {
+				t.Fatalf("CreatePurchase: %v", err)
+			}
+		}
+
+		purchases, err := repo.GetPurchasesByBuyerUuid(ctx, buyerUuid)
+		if err != nil {
+			t.Fatalf("GetPurchasesByBuyerUuid: %v", err)
+		}
+		if len(purchases) != 2 || purchases[0].Uuid != newer.Uuid || purchases[1].Uuid != older.Uuid {
+			t.Fatalf("GetPurchasesByBuyerUuid = %+v, want newer then older", purchases)
+		}
+		if purchases[1].Value == nil || *purchases[1].Value != 5000 || purchases[0].Value != nil {
+			t.Fatalf("values are not kept: %+v", purchases)
+		}
+	})
+
+	t.Run("no purchases", func(t *testing.T) {
+		repo, _ := newRepo(t)
+
+		purchases, err := repo.GetPurchasesByBuyerUuid(ctx, uuid.New())
+		if err != nil {
+			t.Fatalf("GetPurchasesByBuyerUuid: %v", err)
+		}
+		if len(purchases) != 0 {
+			t.Fatalf("GetPurchasesByBuyerUuid = %+v, want none", purchases)
+		}
+	})
+}
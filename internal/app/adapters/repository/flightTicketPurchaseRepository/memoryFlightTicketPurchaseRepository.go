@@ -0,0 +1,48 @@
+package flightticketpurchaserepository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/memorystore"
+	"github.com/rom6n/otello/internal/app/domain/flightticket"
+)
+
+type memoryFlightTicketPurchaseRepo struct {
+	purchases *memorystore.Store[uuid.UUID, flightticket.Purchase]
+}
+
+// NewMemory keeps flight ticket purchases in process memory, for tests and running without MongoDB
+func NewMemory() FlightTicketPurchaseRepository {
+	return &memoryFlightTicketPurchaseRepo{
+		purchases: memorystore.New(func(p *flightticket.Purchase) uuid.UUID { return p.Uuid }, clonePurchase),
+	}
+}
+
+func clonePurchase(purchase flightticket.Purchase) flightticket.Purchase {
+	if purchase.Value != nil {
+		value := *purchase.Value
+		purchase.Value = &value
+	}
+	return purchase
+}
+
+func (v *memoryFlightTicketPurchaseRepo) CreatePurchase(ctx context.Context, purchase *flightticket.Purchase) error {
+	if err := v.purchases.Insert(*purchase); err != nil {
+		return fmt.Errorf("failed to create flight ticket purchase: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryFlightTicketPurchaseRepo) GetPurchasesByBuyerUuid(ctx context.Context, buyerUuid uuid.UUID) ([]flightticket.Purchase, error) {
+	purchases := v.purchases.Find(func(p *flightticket.Purchase) bool {
+		return p.BuyerUuid == buyerUuid
+	})
+
+	sort.SliceStable(purchases, func(i, j int) bool { return purchases[i].CreatedAt > purchases[j].CreatedAt })
+
+	return purchases, nil
+}
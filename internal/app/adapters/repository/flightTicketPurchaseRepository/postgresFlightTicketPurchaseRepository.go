@@ -0,0 +1,66 @@
+package flightticketpurchaserepository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rom6n/otello/internal/app/domain/flightticket"
+	"github.com/rom6n/otello/internal/pkg/database"
+)
+
+const purchaseColumns = "id, flight_ticket_id, buyer_id, quantity, value, created_at"
+
+type postgresFlightTicketPurchaseRepo struct {
+	pool    *pgxpool.Pool
+	timeout time.Duration
+}
+
+// NewPostgres keeps purchases in the flight_ticket_purchases table, the schema comes from database.MigratePostgres
+func NewPostgres(pool *pgxpool.Pool, timeout time.Duration) FlightTicketPurchaseRepository {
+	return &postgresFlightTicketPurchaseRepo{
+		pool:    pool,
+		timeout: timeout,
+	}
+}
+
+func (v *postgresFlightTicketPurchaseRepo) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return database.OperationContext(ctx, v.timeout, database.StoragePostgres, "flight_ticket_purchase", method)
+}
+
+func (v *postgresFlightTicketPurchaseRepo) CreatePurchase(ctx context.Context, purchase *flightticket.Purchase) (err error) {
+	dbCtx, end := v.getContext(ctx, "CreatePurchase")
+	defer end(&err)
+
+	_, err = v.pool.Exec(dbCtx, "INSERT INTO flight_ticket_purchases ("+purchaseColumns+") VALUES ($1, $2, $3, $4, $5, $6)",
+		purchase.Uuid, purchase.FlightTicketUuid, purchase.BuyerUuid, purchase.Quantity, purchase.Value, purchase.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create flight ticket purchase: %v", err)
+	}
+
+	return nil
+}
+
+func (v *postgresFlightTicketPurchaseRepo) GetPurchasesByBuyerUuid(ctx context.Context, buyerUuid uuid.UUID) (_ []flightticket.Purchase, err error) {
+	dbCtx, end := v.getContext(ctx, "GetPurchasesByBuyerUuid")
+	defer end(&err)
+
+	rows, err := v.pool.Query(dbCtx, "SELECT "+purchaseColumns+" FROM flight_ticket_purchases WHERE buyer_id = $1 ORDER BY created_at DESC", buyerUuid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get flight ticket purchases by buyer uuid: %v", err)
+	}
+
+	purchases, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (flightticket.Purchase, error) {
+		var purchase flightticket.Purchase
+		err := row.Scan(&purchase.Uuid, &purchase.FlightTicketUuid, &purchase.BuyerUuid, &purchase.Quantity, &purchase.Value, &purchase.CreatedAt)
+		return purchase, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode flight ticket purchases: %v", err)
+	}
+
+	return purchases, nil
+}
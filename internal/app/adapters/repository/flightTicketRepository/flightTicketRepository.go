@@ -7,6 +7,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/rom6n/otello/internal/app/domain/flightticket"
+	"github.com/rom6n/otello/internal/pkg/database"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
@@ -36,21 +37,46 @@ func New(dbConnection *mongo.Client, dbName, collectionName string, timeout time
 	}
 }
 
-func (v *flightTicketRepo) getContext(ctx context.Context) (context.Context, context.CancelFunc) {
-	return context.WithTimeout(ctx, v.timeout)
+// MongoSchema is the validator and the indexes of the collection New works with
+func MongoSchema(collectionName string) database.MongoCollection {
+	return database.MongoCollection{
+		Name: collectionName,
+		Validator: database.MongoSchemaObject(
+			[]string{"_id", "city_from", "city_to", "quantity", "value", "take_off", "arrival", "category", "operator_id"},
+			bson.D{
+				{Key: "_id", Value: database.MongoUuid},
+				{Key: "city_from", Value: database.MongoString},
+				{Key: "city_to", Value: database.MongoString},
+				{Key: "quantity", Value: database.MongoInteger},
+				{Key: "value", Value: database.MongoType("int", "long", "null")},
+				{Key: "take_off", Value: database.MongoType("int", "long", "null")},
+				{Key: "arrival", Value: database.MongoInteger},
+				{Key: "category", Value: database.MongoString},
+				{Key: "operator_id", Value: database.MongoUuid},
+			},
+		),
+		Indexes: []database.MongoIndex{
+			{Name: "city_from_city_to_take_off", Keys: bson.D{{Key: "city_from", Value: 1}, {Key: "city_to", Value: 1}, {Key: "take_off", Value: 1}}},
+			{Name: "take_off", Keys: bson.D{{Key: "take_off", Value: 1}}},
+		},
+	}
+}
+
+func (v *flightTicketRepo) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return database.OperationContext(ctx, v.timeout, database.StorageMongo, "flight_ticket", method)
 }
 
 func (v *flightTicketRepo) getCollection() *mongo.Collection {
 	return v.client.Database(v.dbName).Collection(v.collectionName)
 }
 
-func (v *flightTicketRepo) CreateFlightTicket(ctx context.Context, flightTicket *flightticket.FlightTicket) error {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *flightTicketRepo) CreateFlightTicket(ctx context.Context, flightTicket *flightticket.FlightTicket) (err error) {
+	dbCtx, end := v.getContext(ctx, "CreateFlightTicket")
+	defer end(&err)
 
 	collection := v.getCollection()
 
-	_, err := collection.InsertOne(dbCtx, flightTicket)
+	_, err = collection.InsertOne(dbCtx, flightTicket)
 	if err != nil {
 		return fmt.Errorf("failed to create flight ticket: %v", err)
 	}
@@ -58,24 +84,25 @@ func (v *flightTicketRepo) CreateFlightTicket(ctx context.Context, flightTicket
 	return nil
 }
 
-func (v *flightTicketRepo) UpdateFlightTicket(ctx context.Context, flightTicket *flightticket.FlightTicket) error {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *flightTicketRepo) UpdateFlightTicket(ctx context.Context, flightTicket *flightticket.FlightTicket) (err error) {
+	dbCtx, end := v.getContext(ctx, "UpdateFlightTicket")
+	defer end(&err)
 
 	collection := v.getCollection()
 
 	update := bson.M{
 		"$set": bson.M{
-			"city_from": flightTicket.CityFrom,
-			"city_to":   flightTicket.CityTo,
-			"quantity":  flightTicket.Quantity,
-			"value":     flightTicket.Value,
-			"take_off":  flightTicket.TakeOff,
-			"arrival":   flightTicket.Arrival,
+			"city_from":   flightTicket.CityFrom,
+			"city_to":     flightTicket.CityTo,
+			"quantity":    flightTicket.Quantity,
+			"value":       flightTicket.Value,
+			"take_off":    flightTicket.TakeOff,
+			"arrival":     flightTicket.Arrival,
+			"operator_id": flightTicket.OperatorUuid,
 		},
 	}
 
-	_, err := collection.UpdateByID(dbCtx, flightTicket.Uuid, update)
+	_, err = collection.UpdateByID(dbCtx, flightTicket.Uuid, update)
 	if err != nil {
 		return fmt.Errorf("failed to update flight ticket: %v", err)
 	}
@@ -83,9 +110,9 @@ func (v *flightTicketRepo) UpdateFlightTicket(ctx context.Context, flightTicket
 	return nil
 }
 
-func (v *flightTicketRepo) DeleteFlightTicket(ctx context.Context, flightTicketUuid uuid.UUID) error {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *flightTicketRepo) DeleteFlightTicket(ctx context.Context, flightTicketUuid uuid.UUID) (err error) {
+	dbCtx, end := v.getContext(ctx, "DeleteFlightTicket")
+	defer end(&err)
 
 	collection := v.getCollection()
 
@@ -100,15 +127,15 @@ func (v *flightTicketRepo) DeleteFlightTicket(ctx context.Context, flightTicketU
 	return nil
 }
 
-func (v *flightTicketRepo) GetFlightTicket(ctx context.Context, flightTicketUuid uuid.UUID) (*flightticket.FlightTicket, error) {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *flightTicketRepo) GetFlightTicket(ctx context.Context, flightTicketUuid uuid.UUID) (_ *flightticket.FlightTicket, err error) {
+	dbCtx, end := v.getContext(ctx, "GetFlightTicket")
+	defer end(&err)
 
 	collection := v.getCollection()
 
 	var foundedFlightTicket flightticket.FlightTicket
 
-	err := collection.FindOne(dbCtx, bson.D{{Key: "_id", Value: flightTicketUuid}}).Decode(&foundedFlightTicket)
+	err = collection.FindOne(dbCtx, bson.D{{Key: "_id", Value: flightTicketUuid}}).Decode(&foundedFlightTicket)
 	if err != nil {
 		return nil, fmt.Errorf("failed to find flight ticket: %v", err)
 	}
@@ -116,9 +143,9 @@ func (v *flightTicketRepo) GetFlightTicket(ctx context.Context, flightTicketUuid
 	return &foundedFlightTicket, nil
 }
 
-func (v *flightTicketRepo) GetFlightTicketWithParams(ctx context.Context, flightTicketFilter *flightticket.FlightTicket) ([]flightticket.FlightTicket, error) {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *flightTicketRepo) GetFlightTicketWithParams(ctx context.Context, flightTicketFilter *flightticket.FlightTicket) (_ []flightticket.FlightTicket, err error) {
+	dbCtx, end := v.getContext(ctx, "GetFlightTicketWithParams")
+	defer end(&err)
 
 	collection := v.getCollection()
 
@@ -141,9 +168,9 @@ func (v *flightTicketRepo) GetFlightTicketWithParams(ctx context.Context, flight
 	return foundedFlightTickets, nil
 }
 
-func (v *flightTicketRepo) BuyFlightTicket(ctx context.Context, flightTicketUuid uuid.UUID, amountPassengers uint32) error {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *flightTicketRepo) BuyFlightTicket(ctx context.Context, flightTicketUuid uuid.UUID, amountPassengers uint32) (err error) {
+	dbCtx, end := v.getContext(ctx, "BuyFlightTicket")
+	defer end(&err)
 
 	collection := v.getCollection()
 
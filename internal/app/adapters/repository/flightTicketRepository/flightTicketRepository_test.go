@@ -0,0 +1,165 @@
+package flightticketrepository
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/repositorytest"
+	"github.com/rom6n/otello/internal/app/domain/flightticket"
+)
+
+func TestMemoryFlightTicketRepository(t *testing.T) {
+	testFlightTicketRepository(t, func(t *testing.T) (FlightTicketRepository, repositorytest.Fixtures) {
+		return NewMemory(), repositorytest.NoFixtures{}
+	})
+}
+
+func TestMongoFlightTicketRepository(t *testing.T) {
+	testFlightTicketRepository(t, func(t *testing.T) (FlightTicketRepository, repositorytest.Fixtures) {
+		client, dbName := repositorytest.MongoDatabase(t)
+		repositorytest.EnsureMongoSchema(t, client, dbName, MongoSchema("flightTickets"))
+		return New(client, dbName, "flightTickets", 30*time.Second), repositorytest.NoFixtures{}
+	})
+}
+
+func TestPostgresFlightTicketRepository(t *testing.T) {
+	testFlightTicketRepository(t, func(t *testing.T) (FlightTicketRepository, repositorytest.Fixtures) {
+		pool := repositorytest.PostgresDatabase(t)
+		return NewPostgres(pool, 30*time.Second), repositorytest.PostgresFixtures(pool)
+	})
+}
+
+func testFlightTicketRepository(t *testing.T, newRepo func(t *testing.T) (FlightTicketRepository, repositorytest.Fixtures)) {
+	ctx := context.Background()
+
+	t.Run("create, get, update and delete", func(t *testing.T) {
+		repo, fixtures := newRepo(t)
+
+		created := flightticket.NewFlightTicket("Moscow", "Kazan", 10, repositorytest.Ptr[uint32](5000), repositorytest.Ptr[int64](1000), 2000, uuid.Nil)
+		if err := repo.CreateFlightTicket(ctx, created); err != nil {
+			t.Fatalf("CreateFlightTicket: %v", err)
+		}
+
+		found, err := repo.GetFlightTicket(ctx, created.Uuid)
+		if err != nil {
+			t.Fatalf("GetFlightTicket: %v", err)
+		}
+		if !reflect.DeepEqual(found, created) {
+			t.Fatalf("GetFlightTicket = %+v, want %+v", *found, *created)
+		}
+
+		updated := *created
+		updated.CityTo = "Sochi"
+		updated.Quantity = 20
+		updated.TakeOff = repositorytest.Ptr[int64](1500)
+		updated.OperatorUuid = fixtures.User(t)
+		if err := repo.UpdateFlightTicket(ctx, &updated); err != nil {
+			t.Fatalf("UpdateFlightTicket: %v", err)
+		}
+
+		found, err = repo.GetFlightTicket(ctx, created.Uuid)
+		if err != nil {
+			t.Fatalf("GetFlightTicket after update: %v", err)
+		}
+		if !reflect.DeepEqual(*found, updated) {
+			t.Fatalf("GetFlightTicket after update = %+v, want %+v", *found, updated)
+		}
+
+		if err := repo.DeleteFlightTicket(ctx, created.Uuid); err != nil {
+			t.Fatalf("DeleteFlightTicket: %v", err)
+		}
+		if _, err := repo.GetFlightTicket(ctx, created.Uuid); err == nil {
+			t.Fatalf("GetFlightTicket after delete: want error")
+		}
+		if err := repo.DeleteFlightTicket(ctx, created.Uuid); err == nil {
+			t.Fatalf("DeleteFlightTicket of a deleted ticket: want error")
+		}
+	})
+
+	t.Run("buy", func(t *testing.T) {
+		repo, _ := newRepo(t)
+
+		created := flightticket.NewFlightTicket("Moscow", "Kazan", 3, nil, nil, 0, uuid.Nil)
+		if err := repo.CreateFlightTicket(ctx, created); err != nil {
+			t.Fatalf("CreateFlightTicket: %v", err)
+		}
+
+		if err := repo.BuyFlightTicket(ctx, created.Uuid, 2); err != nil {
+			t.Fatalf("BuyFlightTicket: %v", err)
+		}
+
+		found, err := repo.GetFlightTicket(ctx, created.Uuid)
+		if err != nil {
+			t.Fatalf("GetFlightTicket: %v", err)
+		}
+		if found.Quantity != 1 {
+			t.Fatalf("quantity = %v, want 1", found.Quantity)
+		}
+
+		if err := repo.BuyFlightTicket(ctx, uuid.New(), 1); err == nil {
+			t.Fatalf("BuyFlightTicket of a missing ticket: want error")
+		}
+	})
+
+	t.Run("get with params", func(t *testing.T) {
+		repo, _ := newRepo(t)
+
+		morning := flightticket.NewFlightTicket("Moscow", "Kazan", 5, nil, repositorytest.Ptr[int64](1000), 2000, uuid.Nil)
+		evening := flightticket.NewFlightTicket("Moscow", "Kazan", 1, nil, repositorytest.Ptr[int64](5000), 6000, uuid.Nil)
+		soldOut := flightticket.NewFlightTicket("Moscow", "Kazan", 0, nil, repositorytest.Ptr[int64](1000), 2000, uuid.Nil)
+		sochi := flightticket.NewFlightTicket("Moscow", "Sochi", 5, nil, repositorytest.Ptr[int64](1000), 2000, uuid.Nil)
+		noTakeOff := flightticket.NewFlightTicket("Kazan", "Moscow", 5, nil, nil, 0, uuid.Nil)
+		for _, ticket := range []*flightticket.FlightTicket{morning, evening, soldOut, sochi, noTakeOff} {
+			if err := repo.CreateFlightTicket(ctx, ticket); err != nil {
+				t.Fatalf("CreateFlightTicket: %v", err)
+			}
+		}
+
+		tests := []struct {
+			name   string
+			filter flightticket.FlightTicket
+			want   []*flightticket.FlightTicket
+		}{
+			{name: "no filter skips sold out", want: []*flightticket.FlightTicket{morning, evening, sochi, noTakeOff}},
+			{name: "uuid", filter: flightticket.FlightTicket{Uuid: sochi.Uuid}, want: []*flightticket.FlightTicket{sochi}},
+			{name: "quantity", filter: flightticket.FlightTicket{Quantity: 2}, want: []*flightticket.FlightTicket{morning, sochi, noTakeOff}},
+			{name: "route", filter: flightticket.FlightTicket{CityFrom: "Moscow", CityTo: "Kazan"}, want: []*flightticket.FlightTicket{morning, evening}},
+			{name: "city to is ignored without city from", filter: flightticket.FlightTicket{CityTo: "Sochi"}, want: []*flightticket.FlightTicket{morning, evening, sochi, noTakeOff}},
+			{name: "take off range", filter: flightticket.FlightTicket{TakeOff: repositorytest.Ptr[int64](500), Arrival: 1500}, want: []*flightticket.FlightTicket{morning, sochi}},
+			{name: "take off range is inclusive", filter: flightticket.FlightTicket{TakeOff: repositorytest.Ptr[int64](1000), Arrival: 5000}, want: []*flightticket.FlightTicket{morning, evening, sochi}},
+			{name: "route and take off", filter: flightticket.FlightTicket{CityFrom: "Moscow", CityTo: "Kazan", TakeOff: repositorytest.Ptr[int64](4000), Arrival: 9000}, want: []*flightticket.FlightTicket{evening}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				tickets, err := repo.GetFlightTicketWithParams(ctx, &tt.filter)
+				if err != nil {
+					t.Fatalf("GetFlightTicketWithParams: %v", err)
+				}
+				assertFlightTickets(t, tickets, tt.want)
+			})
+		}
+	})
+}
+
+// assertFlightTickets compares ignoring order
+func assertFlightTickets(t *testing.T, got []flightticket.FlightTicket, want []*flightticket.FlightTicket) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %v tickets, want %v: %+v", len(got), len(want), got)
+	}
+
+	gotByUuid := map[uuid.UUID]flightticket.FlightTicket{}
+	for _, ticket := range got {
+		gotByUuid[ticket.Uuid] = ticket
+	}
+	for _, ticket := range want {
+		if found, ok := gotByUuid[ticket.Uuid]; !ok || !reflect.DeepEqual(found, *ticket) {
+			t.Fatalf("ticket %+v is missing or differs: %+v", *ticket, found)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package flightticketrepository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/memorystore"
+	"github.com/rom6n/otello/internal/app/domain/flightticket"
+)
+
+type memoryFlightTicketRepo struct {
+	flightTickets *memorystore.Store[uuid.UUID, flightticket.FlightTicket]
+}
+
+// NewMemory keeps flight tickets in process memory, for tests and running without MongoDB
+func NewMemory() FlightTicketRepository {
+	return &memoryFlightTicketRepo{
+		flightTickets: memorystore.New(func(t *flightticket.FlightTicket) uuid.UUID { return t.Uuid }, cloneFlightTicket),
+	}
+}
+
+func cloneFlightTicket(flightTicket flightticket.FlightTicket) flightticket.FlightTicket {
+	if flightTicket.Value != nil {
+		value := *flightTicket.Value
+		flightTicket.Value = &value
+	}
+	if flightTicket.TakeOff != nil {
+		takeOff := *flightTicket.TakeOff
+		flightTicket.TakeOff = &takeOff
+	}
+	return flightTicket
+}
+
+func (v *memoryFlightTicketRepo) CreateFlightTicket(ctx context.Context, flightTicket *flightticket.FlightTicket) error {
+	if err := v.flightTickets.Insert(*flightTicket); err != nil {
+		return fmt.Errorf("failed to create flight ticket: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryFlightTicketRepo) UpdateFlightTicket(ctx context.Context, flightTicket *flightticket.FlightTicket) error {
+	updated := cloneFlightTicket(*flightTicket)
+
+	_, _, err := v.flightTickets.Update(flightTicket.Uuid, func(stored *flightticket.FlightTicket) bool {
+		stored.CityFrom = updated.CityFrom
+		stored.CityTo = updated.CityTo
+		stored.Quantity = updated.Quantity
+		stored.Value = updated.Value
+		stored.TakeOff = updated.TakeOff
+		stored.Arrival = updated.Arrival
+		stored.OperatorUuid = updated.OperatorUuid
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update flight ticket: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryFlightTicketRepo) DeleteFlightTicket(ctx context.Context, flightTicketUuid uuid.UUID) error {
+	if !v.flightTickets.Delete(flightTicketUuid) {
+		return fmt.Errorf("failed to delete flight ticket: flight ticket not found")
+	}
+
+	return nil
+}
+
+func (v *memoryFlightTicketRepo) GetFlightTicket(ctx context.Context, flightTicketUuid uuid.UUID) (*flightticket.FlightTicket, error) {
+	foundedFlightTicket, ok := v.flightTickets.Get(flightTicketUuid)
+	if !ok {
+		return nil, fmt.Errorf("failed to find flight ticket: flight ticket not found")
+	}
+
+	return &foundedFlightTicket, nil
+}
+
+func (v *memoryFlightTicketRepo) GetFlightTicketWithParams(ctx context.Context, flightTicketFilter *flightticket.FlightTicket) ([]flightticket.FlightTicket, error) {
+	return v.flightTickets.Find(func(t *flightticket.FlightTicket) bool {
+		return matchSearchFilter(flightTicketFilter, t)
+	}), nil
+}
+
+func (v *memoryFlightTicketRepo) BuyFlightTicket(ctx context.Context, flightTicketUuid uuid.UUID, amountPassengers uint32) error {
+	_, bought, _ := v.flightTickets.Update(flightTicketUuid, func(stored *flightticket.FlightTicket) bool {
+		// Mongo would store a negative quantity, uint32 can't hold it
+		if amountPassengers == 0 || amountPassengers > stored.Quantity {
+			return false
+		}
+		stored.Quantity -= amountPassengers
+		return true
+	})
+	if !bought {
+		return fmt.Errorf("flight ticket not exists")
+	}
+
+	return nil
+}
+
+// matchSearchFilter reports whether the ticket matches the query built by ParseParamsToSearchFilter
+func matchSearchFilter(filter *flightticket.FlightTicket, flightTicket *flightticket.FlightTicket) bool {
+	if filter.Uuid != uuid.Nil && flightTicket.Uuid != filter.Uuid {
+		return false
+	}
+
+	var amountQuantity uint32 = 1
+	if filter.Quantity > 0 {
+		amountQuantity = filter.Quantity
+	}
+	if flightTicket.Quantity < amountQuantity {
+		return false
+	}
+
+	if filter.CityFrom != "" && (flightTicket.CityFrom != filter.CityFrom || flightTicket.CityTo != filter.CityTo) {
+		return false
+	}
+
+	// like in Mongo, a ticket without take off time doesn't match the range
+	if filter.TakeOff != nil {
+		if flightTicket.TakeOff == nil || *flightTicket.TakeOff < *filter.TakeOff || *flightTicket.TakeOff > filter.Arrival {
+			return false
+		}
+	}
+
+	return true
+}
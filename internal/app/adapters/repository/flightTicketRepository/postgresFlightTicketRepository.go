@@ -0,0 +1,166 @@
+package flightticketrepository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rom6n/otello/internal/app/domain/flightticket"
+	"github.com/rom6n/otello/internal/pkg/database"
+	"github.com/rom6n/otello/internal/utils/postgresutils"
+)
+
+const flightTicketColumns = "id, city_from, city_to, quantity, value, take_off, arrival, category, operator_id"
+
+type postgresFlightTicketRepo struct {
+	pool    *pgxpool.Pool
+	timeout time.Duration
+}
+
+// NewPostgres keeps flight tickets in the flight_tickets table, the schema comes from database.MigratePostgres
+func NewPostgres(pool *pgxpool.Pool, timeout time.Duration) FlightTicketRepository {
+	return &postgresFlightTicketRepo{
+		pool:    pool,
+		timeout: timeout,
+	}
+}
+
+func (v *postgresFlightTicketRepo) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return database.OperationContext(ctx, v.timeout, database.StoragePostgres, "flight_ticket", method)
+}
+
+func scanFlightTicket(row pgx.Row) (flightticket.FlightTicket, error) {
+	var flightTicket flightticket.FlightTicket
+	var operatorUuid *uuid.UUID
+
+	err := row.Scan(
+		&flightTicket.Uuid, &flightTicket.CityFrom, &flightTicket.CityTo, &flightTicket.Quantity, &flightTicket.Value,
+		&flightTicket.TakeOff, &flightTicket.Arrival, &flightTicket.Category, &operatorUuid,
+	)
+	flightTicket.OperatorUuid = postgresutils.UuidOrNil(operatorUuid)
+
+	return flightTicket, err
+}
+
+func (v *postgresFlightTicketRepo) CreateFlightTicket(ctx context.Context, flightTicket *flightticket.FlightTicket) (err error) {
+	dbCtx, end := v.getContext(ctx, "CreateFlightTicket")
+	defer end(&err)
+
+	_, err = v.pool.Exec(dbCtx, "INSERT INTO flight_tickets ("+flightTicketColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)",
+		flightTicket.Uuid, flightTicket.CityFrom, flightTicket.CityTo, flightTicket.Quantity, flightTicket.Value,
+		flightTicket.TakeOff, flightTicket.Arrival, flightTicket.Category, postgresutils.NullableUuid(flightTicket.OperatorUuid))
+	if err != nil {
+		return fmt.Errorf("failed to create flight ticket: %v", err)
+	}
+
+	return nil
+}
+
+func (v *postgresFlightTicketRepo) UpdateFlightTicket(ctx context.Context, flightTicket *flightticket.FlightTicket) (err error) {
+	dbCtx, end := v.getContext(ctx, "UpdateFlightTicket")
+	defer end(&err)
+
+	_, err = v.pool.Exec(dbCtx,
+		"UPDATE flight_tickets SET city_from = $2, city_to = $3, quantity = $4, value = $5, take_off = $6, arrival = $7, operator_id = $8 WHERE id = $1",
+		flightTicket.Uuid, flightTicket.CityFrom, flightTicket.CityTo, flightTicket.Quantity, flightTicket.Value,
+		flightTicket.TakeOff, flightTicket.Arrival, postgresutils.NullableUuid(flightTicket.OperatorUuid))
+	if err != nil {
+		return fmt.Errorf("failed to update flight ticket: %v", err)
+	}
+
+	return nil
+}
+
+func (v *postgresFlightTicketRepo) DeleteFlightTicket(ctx context.Context, flightTicketUuid uuid.UUID) (err error) {
+	dbCtx, end := v.getContext(ctx, "DeleteFlightTicket")
+	defer end(&err)
+
+	result, err := v.pool.Exec(dbCtx, "DELETE FROM flight_tickets WHERE id = $1", flightTicketUuid)
+	if err != nil {
+		return fmt.Errorf("failed to delete flight ticket: %v", err)
+	}
+	if result.RowsAffected() < 1 {
+		return fmt.Errorf("failed to delete flight ticket: flight ticket not found")
+	}
+
+	return nil
+}
+
+func (v *postgresFlightTicketRepo) GetFlightTicket(ctx context.Context, flightTicketUuid uuid.UUID) (_ *flightticket.FlightTicket, err error) {
+	dbCtx, end := v.getContext(ctx, "GetFlightTicket")
+	defer end(&err)
+
+	foundedFlightTicket, err := scanFlightTicket(v.pool.QueryRow(dbCtx, "SELECT "+flightTicketColumns+" FROM flight_tickets WHERE id = $1", flightTicketUuid))
+	if err != nil {
+		return nil, fmt.Errorf("failed to find flight ticket: %v", err)
+	}
+
+	return &foundedFlightTicket, nil
+}
+
+func (v *postgresFlightTicketRepo) GetFlightTicketWithParams(ctx context.Context, flightTicketFilter *flightticket.FlightTicket) (_ []flightticket.FlightTicket, err error) {
+	dbCtx, end := v.getContext(ctx, "GetFlightTicketWithParams")
+	defer end(&err)
+
+	where := postgresSearchFilter(flightTicketFilter)
+
+	rows, findErr := v.pool.Query(dbCtx, "SELECT "+flightTicketColumns+" FROM flight_tickets"+where.Where(), where.Args...)
+	if findErr != nil {
+		return nil, fmt.Errorf("failed to find flight ticket: %v", findErr)
+	}
+
+	foundedFlightTickets, decodeErr := pgx.CollectRows(rows, func(row pgx.CollectableRow) (flightticket.FlightTicket, error) { return scanFlightTicket(row) })
+	if decodeErr != nil {
+		return nil, fmt.Errorf("failed to decode flight tickets: %v", decodeErr)
+	}
+
+	return foundedFlightTickets, nil
+}
+
+// BuyFlightTicket fails without changes when fewer tickets are left than amountPassengers
+func (v *postgresFlightTicketRepo) BuyFlightTicket(ctx context.Context, flightTicketUuid uuid.UUID, amountPassengers uint32) (err error) {
+	dbCtx, end := v.getContext(ctx, "BuyFlightTicket")
+	defer end(&err)
+
+	result, err := v.pool.Exec(dbCtx, "UPDATE flight_tickets SET quantity = quantity - $2 WHERE id = $1 AND $2 > 0 AND quantity >= $2",
+		flightTicketUuid, int64(amountPassengers))
+	if err != nil {
+		return fmt.Errorf("failed to find flight ticket: %v", err)
+	}
+
+	if result.RowsAffected() == 0 {
+		return fmt.Errorf("flight ticket not exists")
+	}
+
+	return nil
+}
+
+// postgresSearchFilter is ParseParamsToSearchFilter for SQL, a NULL take off doesn't match the range like in Mongo
+func postgresSearchFilter(filter *flightticket.FlightTicket) *postgresutils.Filter {
+	var where postgresutils.Filter
+
+	if filter.Uuid != uuid.Nil {
+		where.Add("id = %v", filter.Uuid)
+	}
+
+	var amountQuantity uint32 = 1
+	if filter.Quantity > 0 {
+		amountQuantity = filter.Quantity
+	}
+	where.Add("quantity >= %v", amountQuantity)
+
+	if filter.CityFrom != "" {
+		where.Add("city_from = %v", filter.CityFrom)
+		where.Add("city_to = %v", filter.CityTo)
+	}
+
+	if filter.TakeOff != nil {
+		where.Add("take_off >= %v", *filter.TakeOff)
+		where.Add("take_off <= %v", filter.Arrival)
+	}
+
+	return &where
+}
@@ -2,21 +2,26 @@ package hotelrepository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/rom6n/otello/internal/app/domain/hotel"
+	"github.com/rom6n/otello/internal/pkg/database"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
+var ErrHotelNotFound = errors.New("hotel not found")
+
 type HotelRepository interface {
 	CreateHotel(ctx context.Context, hotel *hotel.Hotel) error
 	UpdateHotel(ctx context.Context, hotel *hotel.Hotel) error
 	DeleteHotel(ctx context.Context, hotelUuid uuid.UUID) error
 	GetHotel(ctx context.Context, hotelUuid uuid.UUID) (*hotel.Hotel, error)
 	GetHotelWithParams(ctx context.Context, city string, stars int32, hotelUuid uuid.UUID, starsFrom, starsTo uint32) ([]hotel.Hotel, error)
+	GetHotelsByOwnerUuid(ctx context.Context, ownerUuid uuid.UUID) ([]hotel.Hotel, error)
 }
 
 type hotelRepo struct {
@@ -35,21 +40,43 @@ func New(dbConnection *mongo.Client, dbName, collectionName string, timeout time
 	}
 }
 
-func (v *hotelRepo) getContext(ctx context.Context) (context.Context, context.CancelFunc) {
-	return context.WithTimeout(ctx, v.timeout)
+// MongoSchema is the validator and the indexes of the collection New works with
+func MongoSchema(collectionName string) database.MongoCollection {
+	return database.MongoCollection{
+		Name: collectionName,
+		Validator: database.MongoSchemaObject(
+			[]string{"_id", "name", "city", "stars", "owner_id"},
+			bson.D{
+				{Key: "_id", Value: database.MongoUuid},
+				{Key: "name", Value: database.MongoString},
+				{Key: "city", Value: database.MongoString},
+				{Key: "stars", Value: database.MongoInteger},
+				{Key: "owner_id", Value: database.MongoUuid},
+				{Key: "deleted_at", Value: database.MongoInteger},
+			},
+		),
+		Indexes: []database.MongoIndex{
+			{Name: "city_stars", Keys: bson.D{{Key: "city", Value: 1}, {Key: "stars", Value: 1}}},
+			{Name: "owner_id", Keys: bson.D{{Key: "owner_id", Value: 1}}},
+		},
+	}
+}
+
+func (v *hotelRepo) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return database.OperationContext(ctx, v.timeout, database.StorageMongo, "hotel", method)
 }
 
 func (v *hotelRepo) getCollection() *mongo.Collection {
 	return v.client.Database(v.dbName).Collection(v.collectionName)
 }
 
-func (v *hotelRepo) CreateHotel(ctx context.Context, hotel *hotel.Hotel) error {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *hotelRepo) CreateHotel(ctx context.Context, hotel *hotel.Hotel) (err error) {
+	dbCtx, end := v.getContext(ctx, "CreateHotel")
+	defer end(&err)
 
 	collection := v.getCollection()
 
-	_, err := collection.InsertOne(dbCtx, hotel)
+	_, err = collection.InsertOne(dbCtx, hotel)
 	if err != nil {
 		return fmt.Errorf("failed to create hotel: %v", err)
 	}
@@ -57,21 +84,22 @@ func (v *hotelRepo) CreateHotel(ctx context.Context, hotel *hotel.Hotel) error {
 	return nil
 }
 
-func (v *hotelRepo) UpdateHotel(ctx context.Context, hotel *hotel.Hotel) error {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *hotelRepo) UpdateHotel(ctx context.Context, hotel *hotel.Hotel) (err error) {
+	dbCtx, end := v.getContext(ctx, "UpdateHotel")
+	defer end(&err)
 
 	collection := v.getCollection()
 
 	update := bson.M{
 		"$set": bson.M{
-			"name":  hotel.Name,
-			"city":  hotel.City,
-			"stars": hotel.Stars,
+			"name":     hotel.Name,
+			"city":     hotel.City,
+			"stars":    hotel.Stars,
+			"owner_id": hotel.OwnerUuid,
 		},
 	}
 
-	_, err := collection.UpdateByID(dbCtx, hotel.Uuid, update)
+	_, err = collection.UpdateByID(dbCtx, hotel.Uuid, update)
 	if err != nil {
 		return fmt.Errorf("failed to update hotel: %v", err)
 	}
@@ -79,32 +107,42 @@ func (v *hotelRepo) UpdateHotel(ctx context.Context, hotel *hotel.Hotel) error {
 	return nil
 }
 
-func (v *hotelRepo) DeleteHotel(ctx context.Context, hotelUuid uuid.UUID) error {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *hotelRepo) DeleteHotel(ctx context.Context, hotelUuid uuid.UUID) (err error) {
+	dbCtx, end := v.getContext(ctx, "DeleteHotel")
+	defer end(&err)
 
 	collection := v.getCollection()
 
-	result, err := collection.DeleteOne(dbCtx, bson.D{{Key: "_id", Value: hotelUuid}})
+	// the hotel is kept for the history of rents, searches skip it
+	filter := bson.D{
+		{Key: "_id", Value: hotelUuid},
+		{Key: "deleted_at", Value: bson.D{{Key: "$exists", Value: false}}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "deleted_at", Value: time.Now().Unix()}}}}
+
+	result, err := collection.UpdateOne(dbCtx, filter, update)
 	if err != nil {
 		return fmt.Errorf("failed to delete hotel: %v", err)
 	}
-	if result.DeletedCount < 1 {
-		return fmt.Errorf("failed to delete hotel: hotel not found")
+	if result.MatchedCount < 1 {
+		return ErrHotelNotFound
 	}
 
 	return nil
 }
 
-func (v *hotelRepo) GetHotel(ctx context.Context, hotelUuid uuid.UUID) (*hotel.Hotel, error) {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *hotelRepo) GetHotel(ctx context.Context, hotelUuid uuid.UUID) (_ *hotel.Hotel, err error) {
+	dbCtx, end := v.getContext(ctx, "GetHotel")
+	defer end(&err)
 
 	collection := v.getCollection()
 
 	var foundedHotel hotel.Hotel
 
-	err := collection.FindOne(dbCtx, bson.D{{Key: "_id", Value: hotelUuid}}).Decode(&foundedHotel)
+	err = collection.FindOne(dbCtx, bson.D{{Key: "_id", Value: hotelUuid}}).Decode(&foundedHotel)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrHotelNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to find hotel: %v", err)
 	}
@@ -112,13 +150,13 @@ func (v *hotelRepo) GetHotel(ctx context.Context, hotelUuid uuid.UUID) (*hotel.H
 	return &foundedHotel, nil
 }
 
-func (v *hotelRepo) GetHotelWithParams(ctx context.Context, city string, stars int32, hotelUuid uuid.UUID, starsFrom, starsTo uint32) ([]hotel.Hotel, error) {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *hotelRepo) GetHotelWithParams(ctx context.Context, city string, stars int32, hotelUuid uuid.UUID, starsFrom, starsTo uint32) (_ []hotel.Hotel, err error) {
+	dbCtx, end := v.getContext(ctx, "GetHotelWithParams")
+	defer end(&err)
 
 	collection := v.getCollection()
 
-	findParams := bson.D{}
+	findParams := bson.D{{Key: "deleted_at", Value: bson.D{{Key: "$exists", Value: false}}}}
 
 	if city != "" {
 		findParams = append(findParams, bson.E{Key: "city", Value: city})
@@ -157,3 +195,28 @@ func (v *hotelRepo) GetHotelWithParams(ctx context.Context, city string, stars i
 
 	return hotels, nil
 }
+
+func (v *hotelRepo) GetHotelsByOwnerUuid(ctx context.Context, ownerUuid uuid.UUID) (_ []hotel.Hotel, err error) {
+	dbCtx, end := v.getContext(ctx, "GetHotelsByOwnerUuid")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	filter := bson.D{
+		{Key: "owner_id", Value: ownerUuid},
+		{Key: "deleted_at", Value: bson.D{{Key: "$exists", Value: false}}},
+	}
+
+	cursor, err := collection.Find(dbCtx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find hotels by owner: %v", err)
+	}
+
+	var hotels []hotel.Hotel
+
+	if err := cursor.All(dbCtx, &hotels); err != nil {
+		return nil, fmt.Errorf("failed to decode hotels: %v", err)
+	}
+
+	return hotels, nil
+}
@@ -0,0 +1,180 @@
+package hotelrepository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/repositorytest"
+	"github.com/rom6n/otello/internal/app/domain/hotel"
+)
+
+func TestMemoryHotelRepository(t *testing.T) {
+	testHotelRepository(t, func(t *testing.T) (HotelRepository, repositorytest.Fixtures) {
+		return NewMemory(), repositorytest.NoFixtures{}
+	})
+}
+
+func TestMongoHotelRepository(t *testing.T) {
+	testHotelRepository(t, func(t *testing.T) (HotelRepository, repositorytest.Fixtures) {
+		client, dbName := repositorytest.MongoDatabase(t)
+		repositorytest.EnsureMongoSchema(t, client, dbName, MongoSchema("hotels"))
+		return New(client, dbName, "hotels", 30*time.Second), repositorytest.NoFixtures{}
+	})
+}
+
+func TestPostgresHotelRepository(t *testing.T) {
+	testHotelRepository(t, func(t *testing.T) (HotelRepository, repositorytest.Fixtures) {
+		pool := repositorytest.PostgresDatabase(t)
+		return NewPostgres(pool, 30*time.Second), repositorytest.PostgresFixtures(pool)
+	})
+}
+
+func testHotelRepository(t *testing.T, newRepo func(t *testing.T) (HotelRepository, repositorytest.Fixtures)) {
+	ctx := context.Background()
+
+	t.Run("create, get, update and delete", func(t *testing.T) {
+		repo, fixtures := newRepo(t)
+		ownerUuid := fixtures.User(t)
+
+		created := hotel.NewHotel("Grand", "Moscow", 4, uuid.Nil)
+		if err := repo.CreateHotel(ctx, created); err != nil {
+			t.Fatalf("CreateHotel: %v", err)
+		}
+
+		found, err := repo.GetHotel(ctx, created.Uuid)
+		if err != nil {
+			t.Fatalf("GetHotel: %v", err)
+		}
+		if *found != *created {
+			t.Fatalf("GetHotel = %+v, want %+v", *found, *created)
+		}
+
+		updated := *created
+		updated.Name = "Grand Plaza"
+		updated.Stars = 5
+		updated.OwnerUuid = ownerUuid
+		if err := repo.UpdateHotel(ctx, &updated); err != nil {
+			t.Fatalf("UpdateHotel: %v", err)
+		}
+
+		found, err = repo.GetHotel(ctx, created.Uuid)
+		if err != nil {
+			t.Fatalf("GetHotel after update: %v", err)
+		}
+		if *found != updated {
+			t.Fatalf("GetHotel after update = %+v, want %+v", *found, updated)
+		}
+
+		if err := repo.DeleteHotel(ctx, created.Uuid); err != nil {
+			t.Fatalf("DeleteHotel: %v", err)
+		}
+		found, err = repo.GetHotel(ctx, created.Uuid)
+		if err != nil {
+			t.Fatalf("GetHotel after delete: %v", err)
+		}
+		if !found.IsDeleted() {
+			t.Fatalf("GetHotel after delete = %+v, want deleted", *found)
+		}
+		if hotels, err := repo.GetHotelWithParams(ctx, "", 0, created.Uuid, 0, 0); err != nil || len(hotels) != 0 {
+			t.Fatalf("GetHotelWithParams after delete = %v, %v, want none", hotels, err)
+		}
+		if hotels, err := repo.GetHotelsByOwnerUuid(ctx, ownerUuid); err != nil || len(hotels) != 0 {
+			t.Fatalf("GetHotelsByOwnerUuid after delete = %v, %v, want none", hotels, err)
+		}
+		if err := repo.DeleteHotel(ctx, created.Uuid); !errors.Is(err, ErrHotelNotFound) {
+			t.Fatalf("DeleteHotel of a deleted hotel error = %v, want ErrHotelNotFound", err)
+		}
+	})
+
+	t.Run("get missing hotel", func(t *testing.T) {
+		repo, _ := newRepo(t)
+
+		if _, err := repo.GetHotel(ctx, uuid.New()); !errors.Is(err, ErrHotelNotFound) {
+			t.Fatalf("GetHotel error = %v, want ErrHotelNotFound", err)
+		}
+	})
+
+	t.Run("get with params", func(t *testing.T) {
+		repo, _ := newRepo(t)
+
+		moscow3 := hotel.NewHotel("A", "Moscow", 3, uuid.Nil)
+		moscow5 := hotel.NewHotel("B", "Moscow", 5, uuid.Nil)
+		kazan4 := hotel.NewHotel("C", "Kazan", 4, uuid.Nil)
+		for _, h := range []*hotel.Hotel{moscow3, moscow5, kazan4} {
+			if err := repo.CreateHotel(ctx, h); err != nil {
+				t.Fatalf("CreateHotel: %v", err)
+			}
+		}
+
+		tests := []struct {
+			name      string
+			city      string
+			stars     int32
+			hotelUuid uuid.UUID
+			starsFrom uint32
+			starsTo   uint32
+			want      []*hotel.Hotel
+		}{
+			{name: "no filter", want: []*hotel.Hotel{moscow3, moscow5, kazan4}},
+			{name: "city", city: "Moscow", want: []*hotel.Hotel{moscow3, moscow5}},
+			{name: "stars", stars: 4, want: []*hotel.Hotel{kazan4}},
+			{name: "stars from", starsFrom: 4, want: []*hotel.Hotel{moscow5, kazan4}},
+			{name: "stars to", starsTo: 4, want: []*hotel.Hotel{moscow3, kazan4}},
+			{name: "stars range", starsFrom: 4, starsTo: 4, want: []*hotel.Hotel{kazan4}},
+			{name: "city and stars range", city: "Moscow", starsFrom: 4, want: []*hotel.Hotel{moscow5}},
+			{name: "uuid", hotelUuid: kazan4.Uuid, want: []*hotel.Hotel{kazan4}},
+			{name: "nothing", city: "Sochi", want: nil},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				hotels, err := repo.GetHotelWithParams(ctx, tt.city, tt.stars, tt.hotelUuid, tt.starsFrom, tt.starsTo)
+				if err != nil {
+					t.Fatalf("GetHotelWithParams: %v", err)
+				}
+				assertHotels(t, hotels, tt.want)
+			})
+		}
+	})
+
+	t.Run("get by owner", func(t *testing.T) {
+		repo, fixtures := newRepo(t)
+		ownerUuid := fixtures.User(t)
+
+		owned := hotel.NewHotel("A", "Moscow", 3, ownerUuid)
+		other := hotel.NewHotel("B", "Moscow", 3, fixtures.User(t))
+		for _, h := range []*hotel.Hotel{owned, other} {
+			if err := repo.CreateHotel(ctx, h); err != nil {
+				t.Fatalf("CreateHotel: %v", err)
+			}
+		}
+
+		hotels, err := repo.GetHotelsByOwnerUuid(ctx, ownerUuid)
+		if err != nil {
+			t.Fatalf("GetHotelsByOwnerUuid: %v", err)
+		}
+		assertHotels(t, hotels, []*hotel.Hotel{owned})
+	})
+}
+
+// assertHotels compares ignoring order
+func assertHotels(t *testing.T, got []hotel.Hotel, want []*hotel.Hotel) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %v hotels, want %v: %+v", len(got), len(want), got)
+	}
+
+	gotByUuid := map[uuid.UUID]hotel.Hotel{}
+	for _, h := range got {
+		gotByUuid[h.Uuid] = h
+	}
+	for _, h := range want {
+		if found, ok := gotByUuid[h.Uuid]; !ok || found != *h {
+			t.Fatalf("hotel %+v is missing or differs: %+v", *h, found)
+		}
+	}
+}
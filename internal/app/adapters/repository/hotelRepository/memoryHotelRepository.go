@@ -0,0 +1,95 @@
+package hotelrepository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/memorystore"
+	"github.com/rom6n/otello/internal/app/domain/hotel"
+)
+
+type memoryHotelRepo struct {
+	hotels *memorystore.Store[uuid.UUID, hotel.Hotel]
+}
+
+// NewMemory keeps hotels in process memory, for tests and running without MongoDB
+func NewMemory() HotelRepository {
+	return &memoryHotelRepo{
+		hotels: memorystore.New(func(h *hotel.Hotel) uuid.UUID { return h.Uuid }, nil),
+	}
+}
+
+func (v *memoryHotelRepo) CreateHotel(ctx context.Context, hotel *hotel.Hotel) error {
+	if err := v.hotels.Insert(*hotel); err != nil {
+		return fmt.Errorf("failed to create hotel: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryHotelRepo) UpdateHotel(ctx context.Context, updated *hotel.Hotel) error {
+	_, _, err := v.hotels.Update(updated.Uuid, func(stored *hotel.Hotel) bool {
+		stored.Name = updated.Name
+		stored.City = updated.City
+		stored.Stars = updated.Stars
+		stored.OwnerUuid = updated.OwnerUuid
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update hotel: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryHotelRepo) DeleteHotel(ctx context.Context, hotelUuid uuid.UUID) error {
+	_, updated, err := v.hotels.Update(hotelUuid, func(stored *hotel.Hotel) bool {
+		if stored.IsDeleted() {
+			return false
+		}
+		stored.DeletedAt = time.Now().Unix()
+		return true
+	})
+	if err != nil || !updated {
+		return ErrHotelNotFound
+	}
+
+	return nil
+}
+
+func (v *memoryHotelRepo) GetHotel(ctx context.Context, hotelUuid uuid.UUID) (*hotel.Hotel, error) {
+	foundedHotel, ok := v.hotels.Get(hotelUuid)
+	if !ok {
+		return nil, ErrHotelNotFound
+	}
+
+	return &foundedHotel, nil
+}
+
+func (v *memoryHotelRepo) GetHotelWithParams(ctx context.Context, city string, stars int32, hotelUuid uuid.UUID, starsFrom, starsTo uint32) ([]hotel.Hotel, error) {
+	return v.hotels.Find(func(h *hotel.Hotel) bool {
+		switch {
+		case h.IsDeleted():
+			return false
+		case city != "" && h.City != city:
+			return false
+		case stars > 0 && h.Stars != stars:
+			return false
+		case starsFrom > 0 && int64(h.Stars) < int64(starsFrom):
+			return false
+		case starsTo > 0 && int64(h.Stars) > int64(starsTo):
+			return false
+		case hotelUuid != uuid.Nil && h.Uuid != hotelUuid:
+			return false
+		}
+		return true
+	}), nil
+}
+
+func (v *memoryHotelRepo) GetHotelsByOwnerUuid(ctx context.Context, ownerUuid uuid.UUID) ([]hotel.Hotel, error) {
+	return v.hotels.Find(func(h *hotel.Hotel) bool {
+		return h.OwnerUuid == ownerUuid && !h.IsDeleted()
+	}), nil
+}
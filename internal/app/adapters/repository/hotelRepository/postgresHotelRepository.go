@@ -0,0 +1,161 @@
+package hotelrepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rom6n/otello/internal/app/domain/hotel"
+	"github.com/rom6n/otello/internal/pkg/database"
+	"github.com/rom6n/otello/internal/utils/postgresutils"
+)
+
+const hotelColumns = "id, name, city, stars, owner_id, deleted_at"
+
+type postgresHotelRepo struct {
+	pool    *pgxpool.Pool
+	timeout time.Duration
+}
+
+// NewPostgres keeps hotels in the hotels table, the schema comes from database.MigratePostgres
+func NewPostgres(pool *pgxpool.Pool, timeout time.Duration) HotelRepository {
+	return &postgresHotelRepo{
+		pool:    pool,
+		timeout: timeout,
+	}
+}
+
+func (v *postgresHotelRepo) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return database.OperationContext(ctx, v.timeout, database.StoragePostgres, "hotel", method)
+}
+
+func scanHotel(row pgx.Row) (hotel.Hotel, error) {
+	var foundHotel hotel.Hotel
+	var ownerUuid *uuid.UUID
+
+	err := row.Scan(&foundHotel.Uuid, &foundHotel.Name, &foundHotel.City, &foundHotel.Stars, &ownerUuid, &foundHotel.DeletedAt)
+	foundHotel.OwnerUuid = postgresutils.UuidOrNil(ownerUuid)
+
+	return foundHotel, err
+}
+
+func (v *postgresHotelRepo) findHotels(ctx context.Context, filter *postgresutils.Filter) ([]hotel.Hotel, error) {
+	rows, err := v.pool.Query(ctx, "SELECT "+hotelColumns+" FROM hotels"+filter.Where(), filter.Args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find hotels: %v", err)
+	}
+
+	hotels, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (hotel.Hotel, error) { return scanHotel(row) })
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode hotels: %v", err)
+	}
+
+	return hotels, nil
+}
+
+func (v *postgresHotelRepo) CreateHotel(ctx context.Context, hotel *hotel.Hotel) (err error) {
+	dbCtx, end := v.getContext(ctx, "CreateHotel")
+	defer end(&err)
+
+	_, err = v.pool.Exec(dbCtx, "INSERT INTO hotels ("+hotelColumns+") VALUES ($1, $2, $3, $4, $5, $6)",
+		hotel.Uuid, hotel.Name, hotel.City, hotel.Stars, postgresutils.NullableUuid(hotel.OwnerUuid), hotel.DeletedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create hotel: %v", err)
+	}
+
+	return nil
+}
+
+func (v *postgresHotelRepo) UpdateHotel(ctx context.Context, hotel *hotel.Hotel) (err error) {
+	dbCtx, end := v.getContext(ctx, "UpdateHotel")
+	defer end(&err)
+
+	_, err = v.pool.Exec(dbCtx, "UPDATE hotels SET name = $2, city = $3, stars = $4, owner_id = $5 WHERE id = $1",
+		hotel.Uuid, hotel.Name, hotel.City, hotel.Stars, postgresutils.NullableUuid(hotel.OwnerUuid))
+	if err != nil {
+		return fmt.Errorf("failed to update hotel: %v", err)
+	}
+
+	return nil
+}
+
+func (v *postgresHotelRepo) DeleteHotel(ctx context.Context, hotelUuid uuid.UUID) (err error) {
+	dbCtx, end := v.getContext(ctx, "DeleteHotel")
+	defer end(&err)
+
+	// the hotel is kept for the history of rents, searches skip it
+	result, err := v.pool.Exec(dbCtx, "UPDATE hotels SET deleted_at = $2 WHERE id = $1 AND deleted_at = 0", hotelUuid, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to delete hotel: %v", err)
+	}
+	if result.RowsAffected() < 1 {
+		return ErrHotelNotFound
+	}
+
+	return nil
+}
+
+func (v *postgresHotelRepo) GetHotel(ctx context.Context, hotelUuid uuid.UUID) (_ *hotel.Hotel, err error) {
+	dbCtx, end := v.getContext(ctx, "GetHotel")
+	defer end(&err)
+
+	foundHotel, err := scanHotel(v.pool.QueryRow(dbCtx, "SELECT "+hotelColumns+" FROM hotels WHERE id = $1", hotelUuid))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrHotelNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find hotel: %v", err)
+	}
+
+	return &foundHotel, nil
+}
+
+func (v *postgresHotelRepo) GetHotelWithParams(ctx context.Context, city string, stars int32, hotelUuid uuid.UUID, starsFrom, starsTo uint32) (_ []hotel.Hotel, err error) {
+	dbCtx, end := v.getContext(ctx, "GetHotelWithParams")
+	defer end(&err)
+
+	var filter postgresutils.Filter
+	filter.Add("deleted_at = %v", 0)
+
+	if city != "" {
+		filter.Add("city = %v", city)
+	}
+
+	if stars > 0 {
+		filter.Add("stars = %v", stars)
+	}
+
+	if starsFrom > 0 {
+		filter.Add("stars >= %v", int64(starsFrom))
+	}
+
+	if starsTo > 0 {
+		filter.Add("stars <= %v", int64(starsTo))
+	}
+
+	if hotelUuid != uuid.Nil {
+		filter.Add("id = %v", hotelUuid)
+	}
+
+	return v.findHotels(dbCtx, &filter)
+}
+
+func (v *postgresHotelRepo) GetHotelsByOwnerUuid(ctx context.Context, ownerUuid uuid.UUID) (_ []hotel.Hotel, err error) {
+	dbCtx, end := v.getContext(ctx, "GetHotelsByOwnerUuid")
+	defer end(&err)
+
+	var filter postgresutils.Filter
+	filter.Add("owner_id = %v", ownerUuid)
+	filter.Add("deleted_at = %v", 0)
+
+	hotels, err := v.findHotels(dbCtx, &filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find hotels by owner: %v", err)
+	}
+
+	return hotels, nil
+}
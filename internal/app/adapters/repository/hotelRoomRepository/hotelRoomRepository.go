@@ -2,15 +2,20 @@ package hotelRoomroomrepository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/rom6n/otello/internal/app/domain/hotelroom"
+	"github.com/rom6n/otello/internal/pkg/database"
+	"github.com/rom6n/otello/internal/pkg/logger"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
 )
 
+var ErrHotelRoomNotFound = errors.New("hotel room not found")
+
 type HotelRoomRepository interface {
 	CreateHotelRoom(ctx context.Context, hotelRoom *hotelroom.HotelRoom) error
 	UpdateHotelRoom(ctx context.Context, hotelRoom *hotelroom.HotelRoom) error
@@ -35,21 +40,43 @@ func New(dbConnection *mongo.Client, dbName, collectionName string, timeout time
 	}
 }
 
-func (v *hotelRoomRepo) getContext(ctx context.Context) (context.Context, context.CancelFunc) {
-	return context.WithTimeout(ctx, v.timeout)
+// MongoSchema is the validator and the indexes of the collection New works with
+func MongoSchema(collectionName string) database.MongoCollection {
+	return database.MongoCollection{
+		Name: collectionName,
+		Validator: database.MongoSchemaObject(
+			[]string{"_id", "hotel_uuid", "rooms", "type", "amount_people", "value"},
+			bson.D{
+				{Key: "_id", Value: database.MongoUuid},
+				{Key: "hotel_uuid", Value: database.MongoUuid},
+				{Key: "rooms", Value: database.MongoInteger},
+				{Key: "type", Value: database.MongoString},
+				{Key: "amount_people", Value: database.MongoInteger},
+				{Key: "value", Value: database.MongoType("int", "long", "null")},
+				{Key: "deleted_at", Value: database.MongoInteger},
+			},
+		),
+		Indexes: []database.MongoIndex{
+			{Name: "hotel_uuid", Keys: bson.D{{Key: "hotel_uuid", Value: 1}}},
+		},
+	}
+}
+
+func (v *hotelRoomRepo) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return database.OperationContext(ctx, v.timeout, database.StorageMongo, "hotel_room", method)
 }
 
 func (v *hotelRoomRepo) getCollection() *mongo.Collection {
 	return v.client.Database(v.dbName).Collection(v.collectionName)
 }
 
-func (v *hotelRoomRepo) CreateHotelRoom(ctx context.Context, hotelRoom *hotelroom.HotelRoom) error {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *hotelRoomRepo) CreateHotelRoom(ctx context.Context, hotelRoom *hotelroom.HotelRoom) (err error) {
+	dbCtx, end := v.getContext(ctx, "CreateHotelRoom")
+	defer end(&err)
 
 	collection := v.getCollection()
 
-	_, err := collection.InsertOne(dbCtx, hotelRoom)
+	_, err = collection.InsertOne(dbCtx, hotelRoom)
 	if err != nil {
 		return fmt.Errorf("failed to create hotel room: %v", err)
 	}
@@ -57,9 +84,9 @@ func (v *hotelRoomRepo) CreateHotelRoom(ctx context.Context, hotelRoom *hotelroo
 	return nil
 }
 
-func (v *hotelRoomRepo) UpdateHotelRoom(ctx context.Context, hotelRoom *hotelroom.HotelRoom) error {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *hotelRoomRepo) UpdateHotelRoom(ctx context.Context, hotelRoom *hotelroom.HotelRoom) (err error) {
+	dbCtx, end := v.getContext(ctx, "UpdateHotelRoom")
+	defer end(&err)
 
 	collection := v.getCollection()
 
@@ -73,7 +100,7 @@ func (v *hotelRoomRepo) UpdateHotelRoom(ctx context.Context, hotelRoom *hotelroo
 		},
 	}
 
-	_, err := collection.UpdateByID(dbCtx, hotelRoom.Uuid, update)
+	_, err = collection.UpdateByID(dbCtx, hotelRoom.Uuid, update)
 	if err != nil {
 		return fmt.Errorf("failed to update hotel room: %v", err)
 	}
@@ -81,32 +108,42 @@ func (v *hotelRoomRepo) UpdateHotelRoom(ctx context.Context, hotelRoom *hotelroo
 	return nil
 }
 
-func (v *hotelRoomRepo) DeleteHotelRoom(ctx context.Context, hotelRoomUuid uuid.UUID) error {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *hotelRoomRepo) DeleteHotelRoom(ctx context.Context, hotelRoomUuid uuid.UUID) (err error) {
+	dbCtx, end := v.getContext(ctx, "DeleteHotelRoom")
+	defer end(&err)
 
 	collection := v.getCollection()
 
-	result, err := collection.DeleteOne(dbCtx, bson.D{{Key: "_id", Value: hotelRoomUuid}})
+	// the room is kept for the history of rents, searches skip it
+	filter := bson.D{
+		{Key: "_id", Value: hotelRoomUuid},
+		{Key: "deleted_at", Value: bson.D{{Key: "$exists", Value: false}}},
+	}
+	update := bson.D{{Key: "$set", Value: bson.D{{Key: "deleted_at", Value: time.Now().Unix()}}}}
+
+	result, err := collection.UpdateOne(dbCtx, filter, update)
 	if err != nil {
 		return fmt.Errorf("failed to delete hotel room: %v", err)
 	}
-	if result.DeletedCount < 1 {
-		return fmt.Errorf("failed to delete hotel room: hotel room not found")
+	if result.MatchedCount < 1 {
+		return ErrHotelRoomNotFound
 	}
 
 	return nil
 }
 
-func (v *hotelRoomRepo) GetHotelRoom(ctx context.Context, hotelRoomUuid uuid.UUID) (*hotelroom.HotelRoom, error) {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *hotelRoomRepo) GetHotelRoom(ctx context.Context, hotelRoomUuid uuid.UUID) (_ *hotelroom.HotelRoom, err error) {
+	dbCtx, end := v.getContext(ctx, "GetHotelRoom")
+	defer end(&err)
 
 	collection := v.getCollection()
 
 	var hotelRoom hotelroom.HotelRoom
 
-	err := collection.FindOne(dbCtx, bson.D{{Key: "_id", Value: hotelRoomUuid}}).Decode(&hotelRoom)
+	err = collection.FindOne(dbCtx, bson.D{{Key: "_id", Value: hotelRoomUuid}}).Decode(&hotelRoom)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrHotelRoomNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to find hotel room: %v", err)
 	}
@@ -114,20 +151,18 @@ func (v *hotelRoomRepo) GetHotelRoom(ctx context.Context, hotelRoomUuid uuid.UUI
 	return &hotelRoom, nil
 }
 
-func (v *hotelRoomRepo) GetHotelRoomsWithParams(ctx context.Context, filter *hotelroom.FindHotelRoomFilterDTO) ([]hotelroom.HotelRoom, error) {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *hotelRoomRepo) GetHotelRoomsWithParams(ctx context.Context, filter *hotelroom.FindHotelRoomFilterDTO) (_ []hotelroom.HotelRoom, err error) {
+	dbCtx, end := v.getContext(ctx, "GetHotelRoomsWithParams")
+	defer end(&err)
 
 	collection := v.getCollection()
 
-	fmt.Printf("filter: %+v\n", filter)
-
 	findParams := ParseParamsToSearchFilter(filter)
 	if len(findParams) == 0 {
 		findParams = bson.D{}
 	}
 
-	fmt.Printf("findParams: %+v\n", findParams)
+	logger.FromContext(ctx).Debug("finding hotel rooms", "filter", findParams)
 
 	cursor, err := collection.Find(dbCtx, findParams)
 	if err != nil {
@@ -207,5 +242,9 @@ func ParseParamsToSearchFilter(filter *hotelroom.FindHotelRoomFilterDTO) bson.D
 		findParams = append(findParams, bson.E{Key: "hotel_uuid", Value: filter.HotelUuid})
 	}
 
+	if !filter.IncludeDeleted {
+		findParams = append(findParams, bson.E{Key: "deleted_at", Value: bson.D{{Key: "$exists", Value: false}}})
+	}
+
 	return findParams
 }
@@ -0,0 +1,183 @@
+package hotelRoomroomrepository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/repositorytest"
+	"github.com/rom6n/otello/internal/app/domain/hotelroom"
+)
+
+func TestMemoryHotelRoomRepository(t *testing.T) {
+	testHotelRoomRepository(t, func(t *testing.T) (HotelRoomRepository, repositorytest.Fixtures) {
+		return NewMemory(), repositorytest.NoFixtures{}
+	})
+}
+
+func TestMongoHotelRoomRepository(t *testing.T) {
+	testHotelRoomRepository(t, func(t *testing.T) (HotelRoomRepository, repositorytest.Fixtures) {
+		client, dbName := repositorytest.MongoDatabase(t)
+		repositorytest.EnsureMongoSchema(t, client, dbName, MongoSchema("hotelRooms"))
+		return New(client, dbName, "hotelRooms", 30*time.Second), repositorytest.NoFixtures{}
+	})
+}
+
+func TestPostgresHotelRoomRepository(t *testing.T) {
+	testHotelRoomRepository(t, func(t *testing.T) (HotelRoomRepository, repositorytest.Fixtures) {
+		pool := repositorytest.PostgresDatabase(t)
+		return NewPostgres(pool, 30*time.Second), repositorytest.PostgresFixtures(pool)
+	})
+}
+
+func testHotelRoomRepository(t *testing.T, newRepo func(t *testing.T) (HotelRoomRepository, repositorytest.Fixtures)) {
+	ctx := context.Background()
+
+	t.Run("create, get, update and delete", func(t *testing.T) {
+		repo, fixtures := newRepo(t)
+
+		created := hotelroom.NewHotelRoom(fixtures.Hotel(t), 2, hotelroom.Standard, 3, repositorytest.Ptr[int64](5000))
+		if err := repo.CreateHotelRoom(ctx, created); err != nil {
+			t.Fatalf("CreateHotelRoom: %v", err)
+		}
+
+		found, err := repo.GetHotelRoom(ctx, created.Uuid)
+		if err != nil {
+			t.Fatalf("GetHotelRoom: %v", err)
+		}
+		if !reflect.DeepEqual(found, created) {
+			t.Fatalf("GetHotelRoom = %+v, want %+v", *found, *created)
+		}
+
+		updated := *created
+		updated.Rooms = 3
+		updated.Type = hotelroom.Premium
+		updated.Value = repositorytest.Ptr[int64](9000)
+		if err := repo.UpdateHotelRoom(ctx, &updated); err != nil {
+			t.Fatalf("UpdateHotelRoom: %v", err)
+		}
+
+		found, err = repo.GetHotelRoom(ctx, created.Uuid)
+		if err != nil {
+			t.Fatalf("GetHotelRoom after update: %v", err)
+		}
+		if !reflect.DeepEqual(*found, updated) {
+			t.Fatalf("GetHotelRoom after update = %+v, want %+v", *found, updated)
+		}
+
+		if err := repo.DeleteHotelRoom(ctx, created.Uuid); err != nil {
+			t.Fatalf("DeleteHotelRoom: %v", err)
+		}
+		deleted, err := repo.GetHotelRoom(ctx, created.Uuid)
+		if err != nil {
+			t.Fatalf("GetHotelRoom after delete: %v", err)
+		}
+		if !deleted.IsDeleted() {
+			t.Fatalf("GetHotelRoom after delete = %+v, want deleted", *deleted)
+		}
+		if rooms, err := repo.GetHotelRoomsWithParams(ctx, &hotelroom.FindHotelRoomFilterDTO{Uuid: created.Uuid}); err != nil || len(rooms) != 0 {
+			t.Fatalf("GetHotelRoomsWithParams after delete = %v, %v, want none", rooms, err)
+		}
+		rooms, err := repo.GetHotelRoomsWithParams(ctx, &hotelroom.FindHotelRoomFilterDTO{Uuid: created.Uuid, IncludeDeleted: true})
+		if err != nil || len(rooms) != 1 {
+			t.Fatalf("GetHotelRoomsWithParams with deleted = %v, %v, want the room", rooms, err)
+		}
+		if err := repo.DeleteHotelRoom(ctx, created.Uuid); !errors.Is(err, ErrHotelRoomNotFound) {
+			t.Fatalf("DeleteHotelRoom of a deleted room error = %v, want ErrHotelRoomNotFound", err)
+		}
+	})
+
+	t.Run("get missing hotel room", func(t *testing.T) {
+		repo, _ := newRepo(t)
+
+		if _, err := repo.GetHotelRoom(ctx, uuid.New()); !errors.Is(err, ErrHotelRoomNotFound) {
+			t.Fatalf("GetHotelRoom error = %v, want ErrHotelRoomNotFound", err)
+		}
+	})
+
+	t.Run("stored room is not changed through the returned pointer", func(t *testing.T) {
+		repo, fixtures := newRepo(t)
+
+		created := hotelroom.NewHotelRoom(fixtures.Hotel(t), 2, hotelroom.Standard, 3, repositorytest.Ptr[int64](5000))
+		if err := repo.CreateHotelRoom(ctx, created); err != nil {
+			t.Fatalf("CreateHotelRoom: %v", err)
+		}
+		*created.Value = 1
+
+		found, err := repo.GetHotelRoom(ctx, created.Uuid)
+		if err != nil {
+			t.Fatalf("GetHotelRoom: %v", err)
+		}
+		if *found.Value != 5000 {
+			t.Fatalf("value = %v, want 5000", *found.Value)
+		}
+	})
+
+	t.Run("get with params", func(t *testing.T) {
+		repo, fixtures := newRepo(t)
+		hotelUuid := fixtures.Hotel(t)
+
+		small := hotelroom.NewHotelRoom(hotelUuid, 1, hotelroom.Standard, 2, repositorytest.Ptr[int64](3000))
+		medium := hotelroom.NewHotelRoom(hotelUuid, 2, hotelroom.Lagre, 4, repositorytest.Ptr[int64](6000))
+		large := hotelroom.NewHotelRoom(fixtures.Hotel(t), 4, hotelroom.Premium, 6, repositorytest.Ptr[int64](12000))
+		noValue := hotelroom.NewHotelRoom(fixtures.Hotel(t), 2, hotelroom.Standard, 2, nil)
+		for _, r := range []*hotelroom.HotelRoom{small, medium, large, noValue} {
+			if err := repo.CreateHotelRoom(ctx, r); err != nil {
+				t.Fatalf("CreateHotelRoom: %v", err)
+			}
+		}
+
+		tests := []struct {
+			name   string
+			filter hotelroom.FindHotelRoomFilterDTO
+			want   []*hotelroom.HotelRoom
+		}{
+			{name: "no filter", want: []*hotelroom.HotelRoom{small, medium, large, noValue}},
+			{name: "uuid", filter: hotelroom.FindHotelRoomFilterDTO{Uuid: medium.Uuid}, want: []*hotelroom.HotelRoom{medium}},
+			{name: "hotel", filter: hotelroom.FindHotelRoomFilterDTO{HotelUuid: hotelUuid}, want: []*hotelroom.HotelRoom{small, medium}},
+			{name: "rooms from", filter: hotelroom.FindHotelRoomFilterDTO{RoomsFrom: 2}, want: []*hotelroom.HotelRoom{medium, large, noValue}},
+			{name: "rooms to", filter: hotelroom.FindHotelRoomFilterDTO{RoomsTo: 2}, want: []*hotelroom.HotelRoom{small, medium, noValue}},
+			{name: "rooms range", filter: hotelroom.FindHotelRoomFilterDTO{RoomsFrom: 2, RoomsTo: 3}, want: []*hotelroom.HotelRoom{medium, noValue}},
+			{name: "value from skips rooms without value", filter: hotelroom.FindHotelRoomFilterDTO{ValueFrom: repositorytest.Ptr[int64](6000)}, want: []*hotelroom.HotelRoom{medium, large}},
+			{name: "value to skips rooms without value", filter: hotelroom.FindHotelRoomFilterDTO{ValueTo: repositorytest.Ptr[int64](6000)}, want: []*hotelroom.HotelRoom{small, medium}},
+			{name: "zero value bound is a bound", filter: hotelroom.FindHotelRoomFilterDTO{ValueFrom: repositorytest.Ptr[int64](0)}, want: []*hotelroom.HotelRoom{small, medium, large}},
+			{name: "amount people range", filter: hotelroom.FindHotelRoomFilterDTO{AmountPeopleFrom: 3, AmountPeopleTo: 5}, want: []*hotelroom.HotelRoom{medium}},
+			{name: "first type", filter: hotelroom.FindHotelRoomFilterDTO{TypeFirst: "standard"}, want: []*hotelroom.HotelRoom{small, noValue}},
+			{name: "second type", filter: hotelroom.FindHotelRoomFilterDTO{TypeSecond: "premium"}, want: []*hotelroom.HotelRoom{large}},
+			{name: "both types", filter: hotelroom.FindHotelRoomFilterDTO{TypeFirst: "large", TypeSecond: "premium"}, want: []*hotelroom.HotelRoom{medium, large}},
+			{name: "combined", filter: hotelroom.FindHotelRoomFilterDTO{HotelUuid: hotelUuid, TypeFirst: "standard", RoomsFrom: 2}, want: nil},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				rooms, err := repo.GetHotelRoomsWithParams(ctx, &tt.filter)
+				if err != nil {
+					t.Fatalf("GetHotelRoomsWithParams: %v", err)
+				}
+				assertHotelRooms(t, rooms, tt.want)
+			})
+		}
+	})
+}
+
+// assertHotelRooms compares ignoring order
+func assertHotelRooms(t *testing.T, got []hotelroom.HotelRoom, want []*hotelroom.HotelRoom) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %v rooms, want %v: %+v", len(got), len(want), got)
+	}
+
+	gotByUuid := map[uuid.UUID]hotelroom.HotelRoom{}
+	for _, r := range got {
+		gotByUuid[r.Uuid] = r
+	}
+	for _, r := range want {
+		if found, ok := gotByUuid[r.Uuid]; !ok || !reflect.DeepEqual(found, *r) {
+			t.Fatalf("room %+v is missing or differs: %+v", *r, found)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package hotelRoomroomrepository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/memorystore"
+	"github.com/rom6n/otello/internal/app/domain/hotelroom"
+)
+
+type memoryHotelRoomRepo struct {
+	hotelRooms *memorystore.Store[uuid.UUID, hotelroom.HotelRoom]
+}
+
+// NewMemory keeps hotel rooms in process memory, for tests and running without MongoDB
+func NewMemory() HotelRoomRepository {
+	return &memoryHotelRoomRepo{
+		hotelRooms: memorystore.New(func(r *hotelroom.HotelRoom) uuid.UUID { return r.Uuid }, cloneHotelRoom),
+	}
+}
+
+func cloneHotelRoom(hotelRoom hotelroom.HotelRoom) hotelroom.HotelRoom {
+	if hotelRoom.Value != nil {
+		value := *hotelRoom.Value
+		hotelRoom.Value = &value
+	}
+	return hotelRoom
+}
+
+func (v *memoryHotelRoomRepo) CreateHotelRoom(ctx context.Context, hotelRoom *hotelroom.HotelRoom) error {
+	if err := v.hotelRooms.Insert(*hotelRoom); err != nil {
+		return fmt.Errorf("failed to create hotel room: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryHotelRoomRepo) UpdateHotelRoom(ctx context.Context, hotelRoom *hotelroom.HotelRoom) error {
+	updated := cloneHotelRoom(*hotelRoom)
+
+	_, _, err := v.hotelRooms.Update(hotelRoom.Uuid, func(stored *hotelroom.HotelRoom) bool {
+		stored.HotelUuid = updated.HotelUuid
+		stored.Value = updated.Value
+		stored.Rooms = updated.Rooms
+		stored.Type = updated.Type
+		stored.AmountPeople = updated.AmountPeople
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update hotel room: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryHotelRoomRepo) DeleteHotelRoom(ctx context.Context, hotelRoomUuid uuid.UUID) error {
+	_, updated, err := v.hotelRooms.Update(hotelRoomUuid, func(stored *hotelroom.HotelRoom) bool {
+		if stored.IsDeleted() {
+			return false
+		}
+		stored.DeletedAt = time.Now().Unix()
+		return true
+	})
+	if err != nil || !updated {
+		return ErrHotelRoomNotFound
+	}
+
+	return nil
+}
+
+func (v *memoryHotelRoomRepo) GetHotelRoom(ctx context.Context, hotelRoomUuid uuid.UUID) (*hotelroom.HotelRoom, error) {
+	hotelRoom, ok := v.hotelRooms.Get(hotelRoomUuid)
+	if !ok {
+		return nil, ErrHotelRoomNotFound
+	}
+
+	return &hotelRoom, nil
+}
+
+func (v *memoryHotelRoomRepo) GetHotelRoomsWithParams(ctx context.Context, filter *hotelroom.FindHotelRoomFilterDTO) ([]hotelroom.HotelRoom, error) {
+	return v.hotelRooms.Find(func(r *hotelroom.HotelRoom) bool {
+		return matchSearchFilter(filter, r)
+	}), nil
+}
+
+// matchSearchFilter reports whether the room matches the query built by ParseParamsToSearchFilter
+func matchSearchFilter(filter *hotelroom.FindHotelRoomFilterDTO, hotelRoom *hotelroom.HotelRoom) bool {
+	if filter.Uuid != uuid.Nil && hotelRoom.Uuid != filter.Uuid {
+		return false
+	}
+
+	if filter.RoomsFrom > 0 && hotelRoom.Rooms < filter.RoomsFrom {
+		return false
+	}
+	if filter.RoomsTo > 0 && hotelRoom.Rooms > filter.RoomsTo {
+		return false
+	}
+
+	// like in Mongo, a room without a value doesn't match any value range
+	if filter.ValueFrom != nil && (hotelRoom.Value == nil || *hotelRoom.Value < *filter.ValueFrom) {
+		return false
+	}
+	if filter.ValueTo != nil && (hotelRoom.Value == nil || *hotelRoom.Value > *filter.ValueTo) {
+		return false
+	}
+
+	if filter.AmountPeopleFrom > 0 && hotelRoom.AmountPeople < filter.AmountPeopleFrom {
+		return false
+	}
+	if filter.AmountPeopleTo > 0 && hotelRoom.AmountPeople > filter.AmountPeopleTo {
+		return false
+	}
+
+	roomType := string(hotelRoom.Type)
+	if filter.TypeFirst != "" && filter.TypeSecond != "" {
+		if roomType != filter.TypeFirst && roomType != filter.TypeSecond {
+			return false
+		}
+	} else if filter.TypeFirst != "" && roomType != filter.TypeFirst {
+		return false
+	} else if filter.TypeSecond != "" && roomType != filter.TypeSecond {
+		return false
+	}
+
+	if filter.HotelUuid != uuid.Nil && hotelRoom.HotelUuid != filter.HotelUuid {
+		return false
+	}
+
+	if !filter.IncludeDeleted && hotelRoom.IsDeleted() {
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,164 @@
+package hotelRoomroomrepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rom6n/otello/internal/app/domain/hotelroom"
+	"github.com/rom6n/otello/internal/pkg/database"
+	"github.com/rom6n/otello/internal/utils/postgresutils"
+)
+
+const hotelRoomColumns = "id, hotel_uuid, rooms, type, amount_people, value, deleted_at"
+
+type postgresHotelRoomRepo struct {
+	pool    *pgxpool.Pool
+	timeout time.Duration
+}
+
+// NewPostgres keeps hotel rooms in the hotel_rooms table, the schema comes from database.MigratePostgres
+func NewPostgres(pool *pgxpool.Pool, timeout time.Duration) HotelRoomRepository {
+	return &postgresHotelRoomRepo{
+		pool:    pool,
+		timeout: timeout,
+	}
+}
+
+func (v *postgresHotelRoomRepo) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return database.OperationContext(ctx, v.timeout, database.StoragePostgres, "hotel_room", method)
+}
+
+func scanHotelRoom(row pgx.Row) (hotelroom.HotelRoom, error) {
+	var hotelRoom hotelroom.HotelRoom
+	err := row.Scan(&hotelRoom.Uuid, &hotelRoom.HotelUuid, &hotelRoom.Rooms, &hotelRoom.Type, &hotelRoom.AmountPeople, &hotelRoom.Value, &hotelRoom.DeletedAt)
+	return hotelRoom, err
+}
+
+func (v *postgresHotelRoomRepo) CreateHotelRoom(ctx context.Context, hotelRoom *hotelroom.HotelRoom) (err error) {
+	dbCtx, end := v.getContext(ctx, "CreateHotelRoom")
+	defer end(&err)
+
+	_, err = v.pool.Exec(dbCtx, "INSERT INTO hotel_rooms ("+hotelRoomColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		hotelRoom.Uuid, hotelRoom.HotelUuid, hotelRoom.Rooms, hotelRoom.Type, hotelRoom.AmountPeople, hotelRoom.Value, hotelRoom.DeletedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create hotel room: %v", err)
+	}
+
+	return nil
+}
+
+func (v *postgresHotelRoomRepo) UpdateHotelRoom(ctx context.Context, hotelRoom *hotelroom.HotelRoom) (err error) {
+	dbCtx, end := v.getContext(ctx, "UpdateHotelRoom")
+	defer end(&err)
+
+	_, err = v.pool.Exec(dbCtx, "UPDATE hotel_rooms SET hotel_uuid = $2, rooms = $3, type = $4, amount_people = $5, value = $6 WHERE id = $1",
+		hotelRoom.Uuid, hotelRoom.HotelUuid, hotelRoom.Rooms, hotelRoom.Type, hotelRoom.AmountPeople, hotelRoom.Value)
+	if err != nil {
+		return fmt.Errorf("failed to update hotel room: %v", err)
+	}
+
+	return nil
+}
+
+func (v *postgresHotelRoomRepo) DeleteHotelRoom(ctx context.Context, hotelRoomUuid uuid.UUID) (err error) {
+	dbCtx, end := v.getContext(ctx, "DeleteHotelRoom")
+	defer end(&err)
+
+	// the room is kept for the history of rents, searches skip it
+	result, err := v.pool.Exec(dbCtx, "UPDATE hotel_rooms SET deleted_at = $2 WHERE id = $1 AND deleted_at = 0", hotelRoomUuid, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to delete hotel room: %v", err)
+	}
+	if result.RowsAffected() < 1 {
+		return ErrHotelRoomNotFound
+	}
+
+	return nil
+}
+
+func (v *postgresHotelRoomRepo) GetHotelRoom(ctx context.Context, hotelRoomUuid uuid.UUID) (_ *hotelroom.HotelRoom, err error) {
+	dbCtx, end := v.getContext(ctx, "GetHotelRoom")
+	defer end(&err)
+
+	hotelRoom, err := scanHotelRoom(v.pool.QueryRow(dbCtx, "SELECT "+hotelRoomColumns+" FROM hotel_rooms WHERE id = $1", hotelRoomUuid))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrHotelRoomNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to find hotel room: %v", err)
+	}
+
+	return &hotelRoom, nil
+}
+
+func (v *postgresHotelRoomRepo) GetHotelRoomsWithParams(ctx context.Context, filter *hotelroom.FindHotelRoomFilterDTO) (_ []hotelroom.HotelRoom, err error) {
+	dbCtx, end := v.getContext(ctx, "GetHotelRoomsWithParams")
+	defer end(&err)
+
+	where := postgresSearchFilter(filter)
+
+	rows, err := v.pool.Query(dbCtx, "SELECT "+hotelRoomColumns+" FROM hotel_rooms"+where.Where(), where.Args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find hotel rooms: %v", err)
+	}
+
+	hotelRooms, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (hotelroom.HotelRoom, error) { return scanHotelRoom(row) })
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode hotel rooms: %v", err)
+	}
+
+	return hotelRooms, nil
+}
+
+// postgresSearchFilter is ParseParamsToSearchFilter for SQL, a NULL value doesn't match a value range like in Mongo
+func postgresSearchFilter(filter *hotelroom.FindHotelRoomFilterDTO) *postgresutils.Filter {
+	var where postgresutils.Filter
+
+	if filter.Uuid != uuid.Nil {
+		where.Add("id = %v", filter.Uuid)
+	}
+
+	if filter.RoomsFrom > 0 {
+		where.Add("rooms >= %v", filter.RoomsFrom)
+	}
+	if filter.RoomsTo > 0 {
+		where.Add("rooms <= %v", filter.RoomsTo)
+	}
+
+	if filter.ValueFrom != nil {
+		where.Add("value >= %v", *filter.ValueFrom)
+	}
+	if filter.ValueTo != nil {
+		where.Add("value <= %v", *filter.ValueTo)
+	}
+
+	if filter.AmountPeopleFrom > 0 {
+		where.Add("amount_people >= %v", filter.AmountPeopleFrom)
+	}
+	if filter.AmountPeopleTo > 0 {
+		where.Add("amount_people <= %v", filter.AmountPeopleTo)
+	}
+
+	if filter.TypeFirst != "" && filter.TypeSecond != "" {
+		where.Add("type = ANY(%v)", []string{filter.TypeFirst, filter.TypeSecond})
+	} else if filter.TypeFirst != "" {
+		where.Add("type = %v", filter.TypeFirst)
+	} else if filter.TypeSecond != "" {
+		where.Add("type = %v", filter.TypeSecond)
+	}
+
+	if filter.HotelUuid != uuid.Nil {
+		where.Add("hotel_uuid = %v", filter.HotelUuid)
+	}
+
+	if !filter.IncludeDeleted {
+		where.Add("deleted_at = %v", 0)
+	}
+
+	return &where
+}
@@ -0,0 +1,144 @@
+package loginattemptrepository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rom6n/otello/internal/app/domain/loginattempt"
+	"github.com/rom6n/otello/internal/pkg/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type LoginAttemptRepository interface {
+	GetLoginAttempts(ctx context.Context, keys []string) ([]loginattempt.LoginAttempt, error)
+	RegisterLoginFailure(ctx context.Context, key string, failureWindow time.Duration) (*loginattempt.LoginAttempt, error)
+	ReleaseLoginFailure(ctx context.Context, key string) error
+	ResetLoginAttempts(ctx context.Context, key string) error
+}
+
+type loginAttemptRepo struct {
+	client         *mongo.Client
+	dbName         string
+	collectionName string
+	timeout        time.Duration
+}
+
+func New(dbConnection *mongo.Client, dbName, collectionName string, timeout time.Duration) LoginAttemptRepository {
+	return &loginAttemptRepo{
+		client:         dbConnection,
+		dbName:         dbName,
+		collectionName: collectionName,
+		timeout:        timeout,
+	}
+}
+
+// MongoSchema is the validator and the indexes of the collection New works with
+func MongoSchema(collectionName string) database.MongoCollection {
+	return database.MongoCollection{
+		Name: collectionName,
+		Validator: database.MongoSchemaObject(
+			[]string{"_id", "failures", "last_failure_at"},
+			bson.D{
+				{Key: "_id", Value: database.MongoString},
+				{Key: "failures", Value: database.MongoInteger},
+				{Key: "last_failure_at", Value: database.MongoInteger},
+				{Key: "prev_failure_at", Value: database.MongoInteger},
+			},
+		),
+	}
+}
+
+func (v *loginAttemptRepo) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return database.OperationContext(ctx, v.timeout, database.StorageMongo, "login_attempt", method)
+}
+
+func (v *loginAttemptRepo) getCollection() *mongo.Collection {
+	return v.client.Database(v.dbName).Collection(v.collectionName)
+}
+
+func (v *loginAttemptRepo) GetLoginAttempts(ctx context.Context, keys []string) (_ []loginattempt.LoginAttempt, err error) {
+	dbCtx, end := v.getContext(ctx, "GetLoginAttempts")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	cursor, err := collection.Find(dbCtx, bson.D{{Key: "_id", Value: bson.D{{Key: "$in", Value: keys}}}})
+	if err != nil {
+		return nil, fmt.Errorf("failed to find login attempts: %v", err)
+	}
+
+	var loginAttempts []loginattempt.LoginAttempt
+	if err := cursor.All(dbCtx, &loginAttempts); err != nil {
+		return nil, fmt.Errorf("failed to decode login attempts: %v", err)
+	}
+
+	return loginAttempts, nil
+}
+
+// RegisterLoginFailure atomically increments the counter, starting over when the last failure is older than failureWindow.
+// The counter is returned as it is after the increment
+func (v *loginAttemptRepo) RegisterLoginFailure(ctx context.Context, key string, failureWindow time.Duration) (_ *loginattempt.LoginAttempt, err error) {
+	dbCtx, end := v.getContext(ctx, "RegisterLoginFailure")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	now := time.Now().Unix()
+	windowStart := now - int64(failureWindow.Seconds())
+
+	update := bson.A{
+		bson.D{{Key: "$set", Value: bson.D{
+			{Key: "failures", Value: bson.D{{Key: "$cond", Value: bson.A{
+				bson.D{{Key: "$lt", Value: bson.A{bson.D{{Key: "$ifNull", Value: bson.A{"$last_failure_at", 0}}}, windowStart}}},
+				1,
+				bson.D{{Key: "$add", Value: bson.A{"$failures", 1}}},
+			}}}},
+			{Key: "prev_failure_at", Value: bson.D{{Key: "$ifNull", Value: bson.A{"$last_failure_at", 0}}}},
+			{Key: "last_failure_at", Value: now},
+		}}},
+	}
+
+	var loginAttempt loginattempt.LoginAttempt
+	err = collection.FindOneAndUpdate(
+		dbCtx,
+		bson.D{{Key: "_id", Value: key}},
+		update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&loginAttempt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to register login failure: %v", err)
+	}
+
+	return &loginAttempt, nil
+}
+
+// ReleaseLoginFailure takes back one failure registered for an attempt that turned out right
+func (v *loginAttemptRepo) ReleaseLoginFailure(ctx context.Context, key string) (err error) {
+	dbCtx, end := v.getContext(ctx, "ReleaseLoginFailure")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	filter := bson.D{{Key: "_id", Value: key}, {Key: "failures", Value: bson.D{{Key: "$gt", Value: 0}}}}
+	if _, err := collection.UpdateOne(dbCtx, filter, bson.D{{Key: "$inc", Value: bson.D{{Key: "failures", Value: -1}}}}); err != nil {
+		return fmt.Errorf("failed to release login failure: %v", err)
+	}
+
+	return nil
+}
+
+func (v *loginAttemptRepo) ResetLoginAttempts(ctx context.Context, key string) (err error) {
+	dbCtx, end := v.getContext(ctx, "ResetLoginAttempts")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	if _, err := collection.DeleteOne(dbCtx, bson.D{{Key: "_id", Value: key}}); err != nil {
+		return fmt.Errorf("failed to reset login attempts: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,154 @@
+package loginattemptrepository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rom6n/otello/internal/app/adapters/repository/repositorytest"
+	"github.com/rom6n/otello/internal/app/domain/loginattempt"
+)
+
+func TestMemoryLoginAttemptRepository(t *testing.T) {
+	testLoginAttemptRepository(t, func(t *testing.T) (LoginAttemptRepository, repositorytest.Fixtures) {
+		return NewMemory(), repositorytest.NoFixtures{}
+	})
+}
+
+func TestMongoLoginAttemptRepository(t *testing.T) {
+	testLoginAttemptRepository(t, func(t *testing.T) (LoginAttemptRepository, repositorytest.Fixtures) {
+		client, dbName := repositorytest.MongoDatabase(t)
+		repositorytest.EnsureMongoSchema(t, client, dbName, MongoSchema("loginAttempts"))
+		return New(client, dbName, "loginAttempts", 30*time.Second), repositorytest.NoFixtures{}
+	})
+}
+
+func TestPostgresLoginAttemptRepository(t *testing.T) {
+	testLoginAttemptRepository(t, func(t *testing.T) (LoginAttemptRepository, repositorytest.Fixtures) {
+		pool := repositorytest.PostgresDatabase(t)
+		return NewPostgres(pool, 30*time.Second), repositorytest.PostgresFixtures(pool)
+	})
+}
+
+func testLoginAttemptRepository(t *testing.T, newRepo func(t *testing.T) (LoginAttemptRepository, repositorytest.Fixtures)) {
+	ctx := context.Background()
+	emailKey := loginattempt.EmailKey("ivan@example.com")
+	ipKey := loginattempt.IpKey("10.0.0.1")
+
+	t.Run("failures are counted per key", func(t *testing.T) {
+		repo, _ := newRepo(t)
+
+		var lastFailureAt int64
+		for want := 1; want <= 3; want++ {
+			loginAttempt, err := repo.RegisterLoginFailure(ctx, emailKey, time.Hour)
+			if err != nil {
+				t.Fatalf("RegisterLoginFailure: %v", err)
+			}
+			if loginAttempt.Key != emailKey || loginAttempt.Failures != want || loginAttempt.LastFailureAt == 0 {
+				t.Fatalf("RegisterLoginFailure = %+v, want %v failures", *loginAttempt, want)
+			}
+			if loginAttempt.PrevFailureAt != lastFailureAt {
+				t.Fatalf("PrevFailureAt = %v, want the last failure before it %v", loginAttempt.PrevFailureAt, lastFailureAt)
+			}
+			lastFailureAt = loginAttempt.LastFailureAt
+		}
+
+		if _, err := repo.RegisterLoginFailure(ctx, ipKey, time.Hour); err != nil {
+			t.Fatalf("RegisterLoginFailure: %v", err)
+		}
+
+		loginAttempts, err := repo.GetLoginAttempts(ctx, []string{emailKey, ipKey, loginattempt.IpKey("10.0.0.2")})
+		if err != nil {
+			t.Fatalf("GetLoginAttempts: %v", err)
+		}
+		failures := map[string]int{}
+		for _, loginAttempt := range loginAttempts {
+			failures[loginAttempt.Key] = loginAttempt.Failures
+		}
+		if len(failures) != 2 || failures[emailKey] != 3 || failures[ipKey] != 1 {
+			t.Fatalf("GetLoginAttempts failures = %v, want 3 for email and 1 for ip", failures)
+		}
+	})
+
+	t.Run("failures start over after the window", func(t *testing.T) {
+		repo, _ := newRepo(t)
+
+		for i := 0; i < 2; i++ {
+			if _, err := repo.RegisterLoginFailure(ctx, emailKey, time.Hour); err != nil {
+				t.Fatalf("RegisterLoginFailure: %v", err)
+			}
+		}
+
+		// a negative window puts the previous failure before the window start
+		loginAttempt, err := repo.RegisterLoginFailure(ctx, emailKey, -time.Hour)
+		if err != nil {
+			t.Fatalf("RegisterLoginFailure: %v", err)
+		}
+		if loginAttempt.Failures != 1 {
+			t.Fatalf("failures = %v, want 1", loginAttempt.Failures)
+		}
+	})
+
+	t.Run("release and reset", func(t *testing.T) {
+		repo, _ := newRepo(t)
+
+		for i := 0; i < 2; i++ {
+			if _, err := repo.RegisterLoginFailure(ctx, emailKey, time.Hour); err != nil {
+				t.Fatalf("RegisterLoginFailure: %v", err)
+			}
+		}
+		if err := repo.ReleaseLoginFailure(ctx, emailKey); err != nil {
+			t.Fatalf("ReleaseLoginFailure: %v", err)
+		}
+
+		loginAttempts, err := repo.GetLoginAttempts(ctx, []string{emailKey})
+		if err != nil {
+			t.Fatalf("GetLoginAttempts: %v", err)
+		}
+		if len(loginAttempts) != 1 || loginAttempts[0].Failures != 1 {
+			t.Fatalf("GetLoginAttempts = %+v, want 1 failure left", loginAttempts)
+		}
+
+		if err := repo.ResetLoginAttempts(ctx, emailKey); err != nil {
+			t.Fatalf("ResetLoginAttempts: %v", err)
+		}
+		loginAttempts, err = repo.GetLoginAttempts(ctx, []string{emailKey})
+		if err != nil {
+			t.Fatalf("GetLoginAttempts after reset: %v", err)
+		}
+		if len(loginAttempts) != 0 {
+			t.Fatalf("GetLoginAttempts after reset = %+v, want none", loginAttempts)
+		}
+	})
+
+	t.Run("release does not go below zero or create the key", func(t *testing.T) {
+		repo, _ := newRepo(t)
+
+		if err := repo.ReleaseLoginFailure(ctx, emailKey); err != nil {
+			t.Fatalf("ReleaseLoginFailure of a missing key: %v", err)
+		}
+		loginAttempts, err := repo.GetLoginAttempts(ctx, []string{emailKey})
+		if err != nil {
+			t.Fatalf("GetLoginAttempts: %v", err)
+		}
+		if len(loginAttempts) != 0 {
+			t.Fatalf("GetLoginAttempts = %+v, want none", loginAttempts)
+		}
+
+		if _, err := repo.RegisterLoginFailure(ctx, emailKey, time.Hour); err != nil {
+			t.Fatalf("RegisterLoginFailure: %v", err)
+		}
+		for i := 0; i < 2; i++ {
+			if err := repo.ReleaseLoginFailure(ctx, emailKey); err != nil {
+				t.Fatalf("ReleaseLoginFailure: %v", err)
+			}
+		}
+		loginAttempts, err = repo.GetLoginAttempts(ctx, []string{emailKey})
+		if err != nil {
+			t.Fatalf("GetLoginAttempts: %v", err)
+		}
+		if len(loginAttempts) != 1 || loginAttempts[0].Failures != 0 {
+			t.Fatalf("GetLoginAttempts = %+v, want 0 failures", loginAttempts)
+		}
+	})
+}
@@ -0,0 +1,71 @@
+package loginattemptrepository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/rom6n/otello/internal/app/adapters/repository/memorystore"
+	"github.com/rom6n/otello/internal/app/domain/loginattempt"
+)
+
+type memoryLoginAttemptRepo struct {
+	loginAttempts *memorystore.Store[string, loginattempt.LoginAttempt]
+}
+
+// NewMemory keeps login attempts in process memory, for tests and running without MongoDB.
+// Unlike the Mongo repository the counters are not shared between app instances
+func NewMemory() LoginAttemptRepository {
+	return &memoryLoginAttemptRepo{
+		loginAttempts: memorystore.New(func(a *loginattempt.LoginAttempt) string { return a.Key }, nil),
+	}
+}
+
+func (v *memoryLoginAttemptRepo) GetLoginAttempts(ctx context.Context, keys []string) ([]loginattempt.LoginAttempt, error) {
+	return v.loginAttempts.Find(func(a *loginattempt.LoginAttempt) bool {
+		return slices.Contains(keys, a.Key)
+	}), nil
+}
+
+func (v *memoryLoginAttemptRepo) RegisterLoginFailure(ctx context.Context, key string, failureWindow time.Duration) (*loginattempt.LoginAttempt, error) {
+	now := time.Now().Unix()
+	windowStart := now - int64(failureWindow.Seconds())
+
+	loginAttempt, err := v.loginAttempts.Upsert(key, func(a *loginattempt.LoginAttempt, exists bool) {
+		a.Key = key
+		if a.LastFailureAt < windowStart {
+			a.Failures = 1
+		} else {
+			a.Failures++
+		}
+		a.PrevFailureAt = a.LastFailureAt
+		a.LastFailureAt = now
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to register login failure: %v", err)
+	}
+
+	return &loginAttempt, nil
+}
+
+func (v *memoryLoginAttemptRepo) ReleaseLoginFailure(ctx context.Context, key string) error {
+	_, _, err := v.loginAttempts.Update(key, func(a *loginattempt.LoginAttempt) bool {
+		if a.Failures == 0 {
+			return false
+		}
+		a.Failures--
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to release login failure: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryLoginAttemptRepo) ResetLoginAttempts(ctx context.Context, key string) error {
+	v.loginAttempts.Delete(key)
+
+	return nil
+}
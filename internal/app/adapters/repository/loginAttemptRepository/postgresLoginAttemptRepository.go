@@ -0,0 +1,101 @@
+package loginattemptrepository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rom6n/otello/internal/app/domain/loginattempt"
+	"github.com/rom6n/otello/internal/pkg/database"
+)
+
+const loginAttemptColumns = "key, failures, last_failure_at, prev_failure_at"
+
+type postgresLoginAttemptRepo struct {
+	pool    *pgxpool.Pool
+	timeout time.Duration
+}
+
+// NewPostgres keeps login attempts in the login_attempts table, the schema comes from database.MigratePostgres
+func NewPostgres(pool *pgxpool.Pool, timeout time.Duration) LoginAttemptRepository {
+	return &postgresLoginAttemptRepo{
+		pool:    pool,
+		timeout: timeout,
+	}
+}
+
+func (v *postgresLoginAttemptRepo) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return database.OperationContext(ctx, v.timeout, database.StoragePostgres, "login_attempt", method)
+}
+
+func scanLoginAttempt(row pgx.Row) (loginattempt.LoginAttempt, error) {
+	var loginAttempt loginattempt.LoginAttempt
+	err := row.Scan(&loginAttempt.Key, &loginAttempt.Failures, &loginAttempt.LastFailureAt, &loginAttempt.PrevFailureAt)
+	return loginAttempt, err
+}
+
+func (v *postgresLoginAttemptRepo) GetLoginAttempts(ctx context.Context, keys []string) (_ []loginattempt.LoginAttempt, err error) {
+	dbCtx, end := v.getContext(ctx, "GetLoginAttempts")
+	defer end(&err)
+
+	rows, err := v.pool.Query(dbCtx, "SELECT "+loginAttemptColumns+" FROM login_attempts WHERE key = ANY($1)", keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find login attempts: %v", err)
+	}
+
+	loginAttempts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (loginattempt.LoginAttempt, error) { return scanLoginAttempt(row) })
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode login attempts: %v", err)
+	}
+
+	return loginAttempts, nil
+}
+
+// RegisterLoginFailure atomically increments the counter, starting over when the last failure is older than failureWindow.
+// The counter is returned as it is after the increment
+func (v *postgresLoginAttemptRepo) RegisterLoginFailure(ctx context.Context, key string, failureWindow time.Duration) (_ *loginattempt.LoginAttempt, err error) {
+	dbCtx, end := v.getContext(ctx, "RegisterLoginFailure")
+	defer end(&err)
+
+	now := time.Now().Unix()
+	windowStart := now - int64(failureWindow.Seconds())
+
+	query := `INSERT INTO login_attempts (key, failures, last_failure_at) VALUES ($1, 1, $2)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < $3 THEN 1 ELSE login_attempts.failures + 1 END,
+			prev_failure_at = login_attempts.last_failure_at,
+			last_failure_at = $2
+		RETURNING ` + loginAttemptColumns
+
+	loginAttempt, err := scanLoginAttempt(v.pool.QueryRow(dbCtx, query, key, now, windowStart))
+	if err != nil {
+		return nil, fmt.Errorf("failed to register login failure: %v", err)
+	}
+
+	return &loginAttempt, nil
+}
+
+// ReleaseLoginFailure takes back one failure registered for an attempt that turned out right
+func (v *postgresLoginAttemptRepo) ReleaseLoginFailure(ctx context.Context, key string) (err error) {
+	dbCtx, end := v.getContext(ctx, "ReleaseLoginFailure")
+	defer end(&err)
+
+	if _, err := v.pool.Exec(dbCtx, "UPDATE login_attempts SET failures = failures - 1 WHERE key = $1 AND failures > 0", key); err != nil {
+		return fmt.Errorf("failed to release login failure: %v", err)
+	}
+
+	return nil
+}
+
+func (v *postgresLoginAttemptRepo) ResetLoginAttempts(ctx context.Context, key string) (err error) {
+	dbCtx, end := v.getContext(ctx, "ResetLoginAttempts")
+	defer end(&err)
+
+	if _, err := v.pool.Exec(dbCtx, "DELETE FROM login_attempts WHERE key = $1", key); err != nil {
+		return fmt.Errorf("failed to reset login attempts: %v", err)
+	}
+
+	return nil
+}
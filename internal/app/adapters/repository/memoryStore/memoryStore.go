@@ -0,0 +1,281 @@
+package memorystore
+
+import (
+	"errors"
+	"sort"
+	"sync"
+)
+
+var ErrDuplicateKey = errors.New("duplicate key")
+
+// Store is a thread-safe collection kept in process memory. Documents are cloned on the way in and out,
+// so callers can't change stored data without an update. Like a Mongo collection without sort,
+// documents are returned in insertion order
+type Store[K comparable, V any] struct {
+	mu      sync.RWMutex
+	docs    map[K]*document[V]
+	nextSeq uint64
+	key     func(*V) K
+	clone   func(V) V
+	unique  []func(*V) string
+}
+
+type document[V any] struct {
+	seq   uint64
+	value V
+}
+
+// New creates a store, clone must deep copy pointers and slices of V (nil if V has none)
+func New[K comparable, V any](key func(*V) K, clone func(V) V) *Store[K, V] {
+	if clone == nil {
+		clone = func(value V) V { return value }
+	}
+
+	return &Store[K, V]{
+		docs:  make(map[K]*document[V]),
+		key:   key,
+		clone: clone,
+	}
+}
+
+// Unique makes inserts and updates fail with ErrDuplicateKey when another document has the same index value.
+// Empty values are not indexed
+func (s *Store[K, V]) Unique(index func(*V) string) *Store[K, V] {
+	s.unique = append(s.unique, index)
+	return s
+}
+
+func (s *Store[K, V]) Insert(value V) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := s.key(&value)
+	if _, exists := s.docs[key]; exists {
+		return ErrDuplicateKey
+	}
+
+	stored := s.clone(value)
+	if err := s.checkUnique(map[K]V{key: stored}); err != nil {
+		return err
+	}
+
+	s.nextSeq++
+	s.docs[key] = &document[V]{seq: s.nextSeq, value: stored}
+
+	return nil
+}
+
+func (s *Store[K, V]) Get(key K) (V, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	doc, ok := s.docs[key]
+	if !ok {
+		var zero V
+		return zero, false
+	}
+
+	return s.clone(doc.value), true
+}
+
+// Find returns the matching documents, a nil match returns all of them
+func (s *Store[K, V]) Find(match func(*V) bool) []V {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	found := []V{}
+	for _, doc := range s.sorted(match) {
+		found = append(found, s.clone(doc.value))
+	}
+
+	return found
+}
+
+func (s *Store[K, V]) FindOne(match func(*V) bool) (V, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	docs := s.sorted(match)
+	if len(docs) == 0 {
+		var zero V
+		return zero, false
+	}
+
+	return s.clone(docs[0].value), true
+}
+
+func (s *Store[K, V]) Count(match func(*V) bool) int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return int64(len(s.sorted(match)))
+}
+
+// Update applies update to a copy of the document and stores it if update returns true.
+// It returns the updated document and false if there is no such document or update returned false
+func (s *Store[K, V]) Update(key K, update func(*V) bool) (V, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var zero V
+
+	doc, ok := s.docs[key]
+	if !ok {
+		return zero, false, nil
+	}
+
+	updated := s.clone(doc.value)
+	if !update(&updated) {
+		return zero, false, nil
+	}
+
+	if err := s.checkUnique(map[K]V{key: updated}); err != nil {
+		return zero, false, err
+	}
+	doc.value = updated
+
+	return s.clone(updated), true, nil
+}
+
+// UpdateOne is Update for the first matching document
+func (s *Store[K, V]) UpdateOne(match func(*V) bool, update func(*V) bool) (V, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var zero V
+
+	for _, doc := range s.sorted(match) {
+		updated := s.clone(doc.value)
+		if !update(&updated) {
+			continue
+		}
+
+		if err := s.checkUnique(map[K]V{s.key(&updated): updated}); err != nil {
+			return zero, false, err
+		}
+		doc.value = updated
+
+		return s.clone(updated), true, nil
+	}
+
+	return zero, false, nil
+}
+
+// UpdateMany updates every matching document, all of them or none if a unique index is violated
+func (s *Store[K, V]) UpdateMany(match func(*V) bool, update func(*V) bool) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	changes := map[K]V{}
+	for _, doc := range s.sorted(match) {
+		updated := s.clone(doc.value)
+		if update(&updated) {
+			changes[s.key(&updated)] = updated
+		}
+	}
+
+	if err := s.checkUnique(changes); err != nil {
+		return 0, err
+	}
+	for key, updated := range changes {
+		s.docs[key].value = updated
+	}
+
+	return int64(len(changes)), nil
+}
+
+// Upsert is Update that inserts the document when there is none, update gets a zero value with exists = false
+// and must set the key
+func (s *Store[K, V]) Upsert(key K, update func(value *V, exists bool)) (V, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var zero V
+
+	doc, exists := s.docs[key]
+
+	var updated V
+	if exists {
+		updated = s.clone(doc.value)
+	}
+	update(&updated, exists)
+
+	if err := s.checkUnique(map[K]V{key: updated}); err != nil {
+		return zero, err
+	}
+
+	if exists {
+		doc.value = updated
+	} else {
+		s.nextSeq++
+		s.docs[key] = &document[V]{seq: s.nextSeq, value: updated}
+	}
+
+	return s.clone(updated), nil
+}
+
+func (s *Store[K, V]) Delete(key K) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.docs[key]; !ok {
+		return false
+	}
+	delete(s.docs, key)
+
+	return true
+}
+
+// sorted returns the matching documents in insertion order, the caller must hold the lock
+func (s *Store[K, V]) sorted(match func(*V) bool) []*document[V] {
+	docs := make([]*document[V], 0, len(s.docs))
+	for _, doc := range s.docs {
+		if match == nil || match(&doc.value) {
+			docs = append(docs, doc)
+		}
+	}
+
+	sort.Slice(docs, func(i, j int) bool { return docs[i].seq < docs[j].seq })
+
+	return docs
+}
+
+// checkUnique checks the unique indexes as if changes were already stored, the caller must hold the lock
+func (s *Store[K, V]) checkUnique(changes map[K]V) error {
+	for _, index := range s.unique {
+		seen := map[string]K{}
+
+		for key, doc := range s.docs {
+			value := doc.value
+			if changed, ok := changes[key]; ok {
+				value = changed
+			}
+			if err := addIndexValue(seen, index(&value), key); err != nil {
+				return err
+			}
+		}
+
+		for key, changed := range changes {
+			if _, stored := s.docs[key]; stored {
+				continue
+			}
+			if err := addIndexValue(seen, index(&changed), key); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func addIndexValue[K comparable](seen map[string]K, indexValue string, key K) error {
+	if indexValue == "" {
+		return nil
+	}
+	if _, taken := seen[indexValue]; taken {
+		return ErrDuplicateKey
+	}
+	seen[indexValue] = key
+
+	return nil
+}
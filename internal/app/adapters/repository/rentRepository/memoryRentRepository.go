@@ -0,0 +1,94 @@
+package rentrepository
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/memorystore"
+	"github.com/rom6n/otello/internal/app/domain/rent"
+)
+
+type memoryRentRepo struct {
+	rents *memorystore.Store[uuid.UUID, rent.Rent]
+	// createMu makes the dates check and the insert of CreateRent one step
+	createMu sync.Mutex
+}
+
+// NewMemory keeps rents in process memory, for tests and running without MongoDB
+func NewMemory() RentRepository {
+	return &memoryRentRepo{
+		rents: memorystore.New(func(r *rent.Rent) uuid.UUID { return r.Uuid }, nil),
+	}
+}
+
+func (v *memoryRentRepo) CreateRent(ctx context.Context, newRent *rent.Rent) error {
+	v.createMu.Lock()
+	defer v.createMu.Unlock()
+
+	_, overlaps := v.rents.FindOne(func(r *rent.Rent) bool {
+		return r.RoomUuid == newRent.RoomUuid && !r.IsCancelled() && r.DateFrom < newRent.DateTo && r.DateTo > newRent.DateFrom
+	})
+	if overlaps {
+		return rent.ErrAlreadyRented
+	}
+
+	if err := v.rents.Insert(*newRent); err != nil {
+		return fmt.Errorf("failed to create rent: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryRentRepo) GetRentsByHotelRoomUuid(ctx context.Context, hotelRoomUuid uuid.UUID) ([]rent.Rent, error) {
+	return v.rents.Find(func(r *rent.Rent) bool {
+		return r.RoomUuid == hotelRoomUuid && !r.IsCancelled()
+	}), nil
+}
+
+func (v *memoryRentRepo) GetRentsWithParams(ctx context.Context, filter *rent.FindRentFilterDTO) ([]rent.Rent, error) {
+	return v.rents.Find(func(r *rent.Rent) bool {
+		// an empty but not nil RoomUuids matches nothing, like $in with an empty array
+		if filter.RoomUuids != nil && !slices.Contains(filter.RoomUuids, r.RoomUuid) {
+			return false
+		}
+		if filter.RenterUuid != uuid.Nil && r.RenterUuid != filter.RenterUuid {
+			return false
+		}
+		return true
+	}), nil
+}
+
+func (v *memoryRentRepo) GetRent(ctx context.Context, rentUuid uuid.UUID) (*rent.Rent, error) {
+	foundedRent, ok := v.rents.Get(rentUuid)
+	if !ok {
+		return nil, ErrRentNotFound
+	}
+
+	return &foundedRent, nil
+}
+
+func (v *memoryRentRepo) CancelRent(ctx context.Context, rentUuid uuid.UUID, reason string) error {
+	_, updated, err := v.rents.Update(rentUuid, func(stored *rent.Rent) bool {
+		if stored.IsCancelled() {
+			return false
+		}
+		stored.CancelledAt = time.Now().Unix()
+		stored.CancelReason = reason
+		return true
+	})
+	if err != nil || !updated {
+		return ErrRentNotFound
+	}
+
+	return nil
+}
+
+func (v *memoryRentRepo) CountRentsByRenterUuid(ctx context.Context, renterUuid uuid.UUID, endsAfter int64) (int64, error) {
+	return v.rents.Count(func(r *rent.Rent) bool {
+		return r.RenterUuid == renterUuid && !r.IsCancelled() && (endsAfter <= 0 || r.DateTo > endsAfter)
+	}), nil
+}
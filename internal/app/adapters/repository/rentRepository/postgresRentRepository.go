@@ -0,0 +1,157 @@
+package rentrepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rom6n/otello/internal/app/domain/rent"
+	"github.com/rom6n/otello/internal/pkg/database"
+	"github.com/rom6n/otello/internal/utils/postgresutils"
+)
+
+const rentColumns = "id, hotel_room_id, renter_id, date_from, date_to, cancelled_at, cancel_reason"
+
+type postgresRentRepo struct {
+	pool    *pgxpool.Pool
+	timeout time.Duration
+}
+
+// NewPostgres keeps rents in the rents table, the schema comes from database.MigratePostgres.
+// Overlapping not cancelled rents of a room are rejected by the table itself, so concurrent bookings can't both succeed
+func NewPostgres(pool *pgxpool.Pool, timeout time.Duration) RentRepository {
+	return &postgresRentRepo{
+		pool:    pool,
+		timeout: timeout,
+	}
+}
+
+func (v *postgresRentRepo) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return database.OperationContext(ctx, v.timeout, database.StoragePostgres, "rent", method)
+}
+
+func scanRent(row pgx.Row) (rent.Rent, error) {
+	var foundRent rent.Rent
+	err := row.Scan(&foundRent.Uuid, &foundRent.RoomUuid, &foundRent.RenterUuid, &foundRent.DateFrom, &foundRent.DateTo, &foundRent.CancelledAt, &foundRent.CancelReason)
+	return foundRent, err
+}
+
+func (v *postgresRentRepo) findRents(ctx context.Context, filter *postgresutils.Filter) ([]rent.Rent, error) {
+	rows, err := v.pool.Query(ctx, "SELECT "+rentColumns+" FROM rents"+filter.Where(), filter.Args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find rents: %v", err)
+	}
+
+	rents, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (rent.Rent, error) { return scanRent(row) })
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode rents: %v", err)
+	}
+
+	return rents, nil
+}
+
+// CreateRent returns rent.ErrAlreadyRented if the room is already rented for a part of the dates
+func (v *postgresRentRepo) CreateRent(ctx context.Context, newRent *rent.Rent) (err error) {
+	dbCtx, end := v.getContext(ctx, "CreateRent")
+	defer end(&err)
+
+	_, err = v.pool.Exec(dbCtx, "INSERT INTO rents ("+rentColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		newRent.Uuid, newRent.RoomUuid, newRent.RenterUuid, newRent.DateFrom, newRent.DateTo, newRent.CancelledAt, newRent.CancelReason)
+	if postgresutils.IsExclusionViolation(err) {
+		return rent.ErrAlreadyRented
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create rent: %v", err)
+	}
+
+	return nil
+}
+
+func (v *postgresRentRepo) GetRentsByHotelRoomUuid(ctx context.Context, hotelRoomUuid uuid.UUID) (_ []rent.Rent, err error) {
+	dbCtx, end := v.getContext(ctx, "GetRentsByHotelRoomUuid")
+	defer end(&err)
+
+	var filter postgresutils.Filter
+	filter.Add("hotel_room_id = %v", hotelRoomUuid)
+	filter.Add("cancelled_at = %v", 0)
+
+	rents, err := v.findRents(dbCtx, &filter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rents by hotel room uuid: %v", err)
+	}
+
+	return rents, nil
+}
+
+func (v *postgresRentRepo) GetRentsWithParams(ctx context.Context, filter *rent.FindRentFilterDTO) (_ []rent.Rent, err error) {
+	dbCtx, end := v.getContext(ctx, "GetRentsWithParams")
+	defer end(&err)
+
+	var where postgresutils.Filter
+
+	// an empty but not nil RoomUuids matches nothing, like $in with an empty array
+	if filter.RoomUuids != nil {
+		where.Add("hotel_room_id = ANY(%v)", filter.RoomUuids)
+	}
+
+	if filter.RenterUuid != uuid.Nil {
+		where.Add("renter_id = %v", filter.RenterUuid)
+	}
+
+	return v.findRents(dbCtx, &where)
+}
+
+func (v *postgresRentRepo) GetRent(ctx context.Context, rentUuid uuid.UUID) (_ *rent.Rent, err error) {
+	dbCtx, end := v.getContext(ctx, "GetRent")
+	defer end(&err)
+
+	foundedRent, err := scanRent(v.pool.QueryRow(dbCtx, "SELECT "+rentColumns+" FROM rents WHERE id = $1", rentUuid))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrRentNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode rent: %v", err)
+	}
+
+	return &foundedRent, nil
+}
+
+func (v *postgresRentRepo) CancelRent(ctx context.Context, rentUuid uuid.UUID, reason string) (err error) {
+	dbCtx, end := v.getContext(ctx, "CancelRent")
+	defer end(&err)
+
+	result, err := v.pool.Exec(dbCtx, "UPDATE rents SET cancelled_at = $2, cancel_reason = $3 WHERE id = $1 AND cancelled_at = 0",
+		rentUuid, time.Now().Unix(), reason)
+	if err != nil {
+		return fmt.Errorf("failed to cancel rent: %v", err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrRentNotFound
+	}
+
+	return nil
+}
+
+// CountRentsByRenterUuid counts not cancelled rents ending after endsAfter (unix), 0 counts all of them
+func (v *postgresRentRepo) CountRentsByRenterUuid(ctx context.Context, renterUuid uuid.UUID, endsAfter int64) (_ int64, err error) {
+	dbCtx, end := v.getContext(ctx, "CountRentsByRenterUuid")
+	defer end(&err)
+
+	var filter postgresutils.Filter
+	filter.Add("renter_id = %v", renterUuid)
+	filter.Add("cancelled_at = %v", 0)
+	if endsAfter > 0 {
+		filter.Add("date_to > %v", endsAfter)
+	}
+
+	var count int64
+	if err := v.pool.QueryRow(dbCtx, "SELECT count(*) FROM rents"+filter.Where(), filter.Args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count rents: %v", err)
+	}
+
+	return count, nil
+}
@@ -2,20 +2,33 @@ package rentrepository
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/rom6n/otello/internal/app/domain/rent"
+	"github.com/rom6n/otello/internal/pkg/database"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
+// roomLockRetry is how often CreateRent checks if the lock of the room is free
+const roomLockRetry = 20 * time.Millisecond
+
+var ErrRentNotFound = errors.New("rent not found")
+
 type RentRepository interface {
+	// CreateRent returns rent.ErrAlreadyRented if the room is already rented for a part of the dates
 	CreateRent(ctx context.Context, rent *rent.Rent) error
+	// GetRentsByHotelRoomUuid skips cancelled rents, the rest take the dates of the room
 	GetRentsByHotelRoomUuid(ctx context.Context, hotelRoomUuid uuid.UUID) ([]rent.Rent, error)
-	DeleteRent(ctx context.Context, rentUuid uuid.UUID) error
+	GetRentsWithParams(ctx context.Context, filter *rent.FindRentFilterDTO) ([]rent.Rent, error)
+	// CancelRent keeps the rent for the history, ErrRentNotFound if it is missing or already cancelled
+	CancelRent(ctx context.Context, rentUuid uuid.UUID, reason string) error
 	GetRent(ctx context.Context, rentUuid uuid.UUID) (*rent.Rent, error)
+	CountRentsByRenterUuid(ctx context.Context, renterUuid uuid.UUID, endsAfter int64) (int64, error)
 }
 
 type rentRepo struct {
@@ -34,21 +47,86 @@ func New(dbConnection *mongo.Client, dbName, collectionName string, timeout time
 	}
 }
 
-func (v *rentRepo) getContext(ctx context.Context) (context.Context, context.CancelFunc) {
-	return context.WithTimeout(ctx, v.timeout)
+// MongoSchema is the validator and the indexes of the collection New works with
+func MongoSchema(collectionName string) database.MongoCollection {
+	return database.MongoCollection{
+		Name: collectionName,
+		Validator: database.MongoSchemaObject(
+			[]string{"_id", "hotel_room_id", "renter_id", "date_from", "date_to"},
+			bson.D{
+				{Key: "_id", Value: database.MongoUuid},
+				{Key: "hotel_room_id", Value: database.MongoUuid},
+				{Key: "renter_id", Value: database.MongoUuid},
+				{Key: "date_from", Value: database.MongoInteger},
+				{Key: "date_to", Value: database.MongoInteger},
+				{Key: "cancelled_at", Value: database.MongoInteger},
+				{Key: "cancel_reason", Value: database.MongoString},
+			},
+		),
+		Indexes: []database.MongoIndex{
+			{Name: "hotel_room_id_date_from", Keys: bson.D{{Key: "hotel_room_id", Value: 1}, {Key: "date_from", Value: 1}}},
+			{Name: "renter_id_date_to", Keys: bson.D{{Key: "renter_id", Value: 1}, {Key: "date_to", Value: 1}}},
+		},
+	}
+}
+
+// MongoRoomLockSchema is the collection of the room locks CreateRent takes, New names it after the rents collection
+func MongoRoomLockSchema(collectionName string) database.MongoCollection {
+	return database.MongoCollection{
+		Name: roomLockCollectionName(collectionName),
+		Validator: database.MongoSchemaObject(
+			[]string{"_id", "owner", "locked_until"},
+			bson.D{
+				{Key: "_id", Value: database.MongoUuid},
+				{Key: "owner", Value: database.MongoUuid},
+				{Key: "locked_until", Value: database.MongoInteger},
+			},
+		),
+	}
+}
+
+func roomLockCollectionName(collectionName string) string {
+	return collectionName + "_room_locks"
+}
+
+func (v *rentRepo) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return database.OperationContext(ctx, v.timeout, database.StorageMongo, "rent", method)
 }
 
 func (v *rentRepo) getCollection() *mongo.Collection {
 	return v.client.Database(v.dbName).Collection(v.collectionName)
 }
 
-func (v *rentRepo) CreateRent(ctx context.Context, rent *rent.Rent) error {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+// CreateRent checks the dates and inserts the rent under the lock of the room, MongoDB has no constraint
+// for overlapping ranges and two rents checked at the same time would both pass
+func (v *rentRepo) CreateRent(ctx context.Context, newRent *rent.Rent) (err error) {
+	dbCtx, end := v.getContext(ctx, "CreateRent")
+	defer end(&err)
+
+	unlock, err := v.lockRoom(dbCtx, newRent.RoomUuid)
+	if err != nil {
+		return err
+	}
+	defer unlock()
 
 	collection := v.getCollection()
 
-	_, err := collection.InsertOne(dbCtx, rent)
+	filter := bson.D{
+		{Key: "hotel_room_id", Value: newRent.RoomUuid},
+		{Key: "cancelled_at", Value: bson.D{{Key: "$exists", Value: false}}},
+		{Key: "date_from", Value: bson.D{{Key: "$lt", Value: newRent.DateTo}}},
+		{Key: "date_to", Value: bson.D{{Key: "$gt", Value: newRent.DateFrom}}},
+	}
+
+	overlapping, err := collection.CountDocuments(dbCtx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		return fmt.Errorf("failed to check rent dates: %v", err)
+	}
+	if overlapping > 0 {
+		return rent.ErrAlreadyRented
+	}
+
+	_, err = collection.InsertOne(dbCtx, newRent)
 	if err != nil {
 		return fmt.Errorf("failed to create rent: %v", err)
 	}
@@ -56,13 +134,54 @@ func (v *rentRepo) CreateRent(ctx context.Context, rent *rent.Rent) error {
 	return nil
 }
 
-func (v *rentRepo) GetRentsByHotelRoomUuid(ctx context.Context, hotelRoomUuid uuid.UUID) ([]rent.Rent, error) {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+// lockRoom waits until no one else holds the room and takes it for v.timeout at most, the context of
+// the holder ends by then too, so the lock of a crashed instance doesn't block the room for long
+func (v *rentRepo) lockRoom(ctx context.Context, roomUuid uuid.UUID) (func(), error) {
+	locks := v.client.Database(v.dbName).Collection(roomLockCollectionName(v.collectionName))
+	owner := uuid.New()
+
+	for {
+		now := time.Now()
+		// a held lock doesn't match the filter, so the upsert inserts the same _id and fails
+		_, err := locks.UpdateOne(ctx,
+			bson.D{{Key: "_id", Value: roomUuid}, {Key: "locked_until", Value: bson.D{{Key: "$lte", Value: now.UnixMilli()}}}},
+			bson.D{{Key: "$set", Value: bson.D{{Key: "owner", Value: owner}, {Key: "locked_until", Value: now.Add(v.timeout).UnixMilli()}}}},
+			options.UpdateOne().SetUpsert(true),
+		)
+		if err == nil {
+			break
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("failed to lock room: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("failed to lock room: %v", ctx.Err())
+		case <-time.After(roomLockRetry):
+		}
+	}
+
+	return func() {
+		// the lock expires anyway, a failed release only makes the next rent of the room wait
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), v.timeout)
+		defer cancel()
+		_, _ = locks.DeleteOne(releaseCtx, bson.D{{Key: "_id", Value: roomUuid}, {Key: "owner", Value: owner}})
+	}, nil
+}
+
+func (v *rentRepo) GetRentsByHotelRoomUuid(ctx context.Context, hotelRoomUuid uuid.UUID) (_ []rent.Rent, err error) {
+	dbCtx, end := v.getContext(ctx, "GetRentsByHotelRoomUuid")
+	defer end(&err)
 
 	collection := v.getCollection()
 
-	cursor, err := collection.Find(dbCtx, bson.D{{Key: "hotel_room_id", Value: hotelRoomUuid}})
+	filter := bson.D{
+		{Key: "hotel_room_id", Value: hotelRoomUuid},
+		{Key: "cancelled_at", Value: bson.D{{Key: "$exists", Value: false}}},
+	}
+
+	cursor, err := collection.Find(dbCtx, filter)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get rents by hotel room uuid: %v", err)
 	}
@@ -75,15 +194,47 @@ func (v *rentRepo) GetRentsByHotelRoomUuid(ctx context.Context, hotelRoomUuid uu
 	return rents, nil
 }
 
-func (v *rentRepo) GetRent(ctx context.Context, rentUuid uuid.UUID) (*rent.Rent, error) {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *rentRepo) GetRentsWithParams(ctx context.Context, filter *rent.FindRentFilterDTO) (_ []rent.Rent, err error) {
+	dbCtx, end := v.getContext(ctx, "GetRentsWithParams")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	findParams := bson.D{}
+
+	if filter.RoomUuids != nil {
+		findParams = append(findParams, bson.E{Key: "hotel_room_id", Value: bson.D{{Key: "$in", Value: filter.RoomUuids}}})
+	}
+
+	if filter.RenterUuid != uuid.Nil {
+		findParams = append(findParams, bson.E{Key: "renter_id", Value: filter.RenterUuid})
+	}
+
+	cursor, err := collection.Find(dbCtx, findParams)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find rents: %v", err)
+	}
+
+	var rents []rent.Rent
+	if err := cursor.All(dbCtx, &rents); err != nil {
+		return nil, fmt.Errorf("failed to decode rents: %v", err)
+	}
+
+	return rents, nil
+}
+
+func (v *rentRepo) GetRent(ctx context.Context, rentUuid uuid.UUID) (_ *rent.Rent, err error) {
+	dbCtx, end := v.getContext(ctx, "GetRent")
+	defer end(&err)
 
 	collection := v.getCollection()
 
 	var foundedRent rent.Rent
 
-	err := collection.FindOne(dbCtx, bson.D{{Key: "_id", Value: rentUuid}}).Decode(&foundedRent)
+	err = collection.FindOne(dbCtx, bson.D{{Key: "_id", Value: rentUuid}}).Decode(&foundedRent)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrRentNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to decode rent: %v", err)
 	}
@@ -91,20 +242,51 @@ func (v *rentRepo) GetRent(ctx context.Context, rentUuid uuid.UUID) (*rent.Rent,
 	return &foundedRent, nil
 }
 
-func (v *rentRepo) DeleteRent(ctx context.Context, rentUuid uuid.UUID) error {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *rentRepo) CancelRent(ctx context.Context, rentUuid uuid.UUID, reason string) (err error) {
+	dbCtx, end := v.getContext(ctx, "CancelRent")
+	defer end(&err)
 
 	collection := v.getCollection()
 
-	isSomethingDeleted, err := collection.DeleteOne(dbCtx, bson.D{{Key: "_id", Value: rentUuid}})
-	if err != nil {
-		return fmt.Errorf("failed to delete rent: %v", err)
+	filter := bson.D{
+		{Key: "_id", Value: rentUuid},
+		{Key: "cancelled_at", Value: bson.D{{Key: "$exists", Value: false}}},
 	}
+	update := bson.D{{Key: "$set", Value: bson.D{
+		{Key: "cancelled_at", Value: time.Now().Unix()},
+		{Key: "cancel_reason", Value: reason},
+	}}}
 
-	if isSomethingDeleted.DeletedCount == 0 {
-		return fmt.Errorf("failed to delete rent: rent object not found")
+	result, err := collection.UpdateOne(dbCtx, filter, update)
+	if err != nil {
+		return fmt.Errorf("failed to cancel rent: %v", err)
+	}
+	if result.MatchedCount < 1 {
+		return ErrRentNotFound
 	}
 
 	return nil
 }
+
+// CountRentsByRenterUuid counts not cancelled rents ending after endsAfter (unix), 0 counts all of them
+func (v *rentRepo) CountRentsByRenterUuid(ctx context.Context, renterUuid uuid.UUID, endsAfter int64) (_ int64, err error) {
+	dbCtx, end := v.getContext(ctx, "CountRentsByRenterUuid")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	filter := bson.D{
+		{Key: "renter_id", Value: renterUuid},
+		{Key: "cancelled_at", Value: bson.D{{Key: "$exists", Value: false}}},
+	}
+	if endsAfter > 0 {
+		filter = append(filter, bson.E{Key: "date_to", Value: bson.D{{Key: "$gt", Value: endsAfter}}})
+	}
+
+	count, err := collection.CountDocuments(dbCtx, filter)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count rents: %v", err)
+	}
+
+	return count, nil
+}
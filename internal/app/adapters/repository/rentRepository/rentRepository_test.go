@@ -0,0 +1,256 @@
+package rentrepository
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/repositorytest"
+	"github.com/rom6n/otello/internal/app/domain/rent"
+)
+
+func TestMemoryRentRepository(t *testing.T) {
+	testRentRepository(t, func(t *testing.T) (RentRepository, repositorytest.Fixtures) {
+		return NewMemory(), repositorytest.NoFixtures{}
+	})
+}
+
+func TestMongoRentRepository(t *testing.T) {
+	testRentRepository(t, func(t *testing.T) (RentRepository, repositorytest.Fixtures) {
+		client, dbName := repositorytest.MongoDatabase(t)
+		repositorytest.EnsureMongoSchema(t, client, dbName, MongoSchema("rents"))
+		repositorytest.EnsureMongoSchema(t, client, dbName, MongoRoomLockSchema("rents"))
+		return New(client, dbName, "rents", 30*time.Second), repositorytest.NoFixtures{}
+	})
+}
+
+func TestPostgresRentRepository(t *testing.T) {
+	testRentRepository(t, func(t *testing.T) (RentRepository, repositorytest.Fixtures) {
+		pool := repositorytest.PostgresDatabase(t)
+		return NewPostgres(pool, 30*time.Second), repositorytest.PostgresFixtures(pool)
+	})
+}
+
+func testRentRepository(t *testing.T, newRepo func(t *testing.T) (RentRepository, repositorytest.Fixtures)) {
+	ctx := context.Background()
+
+	t.Run("create, get and cancel", func(t *testing.T) {
+		repo, fixtures := newRepo(t)
+
+		created := rent.NewRent(fixtures.HotelRoom(t), fixtures.User(t), 1000, 2000)
+		if err := repo.CreateRent(ctx, created); err != nil {
+			t.Fatalf("CreateRent: %v", err)
+		}
+
+		found, err := repo.GetRent(ctx, created.Uuid)
+		if err != nil {
+			t.Fatalf("GetRent: %v", err)
+		}
+		if *found != *created {
+			t.Fatalf("GetRent = %+v, want %+v", *found, *created)
+		}
+
+		if err := repo.CancelRent(ctx, created.Uuid, rent.CancelReasonForced); err != nil {
+			t.Fatalf("CancelRent: %v", err)
+		}
+		found, err = repo.GetRent(ctx, created.Uuid)
+		if err != nil {
+			t.Fatalf("GetRent after cancel: %v", err)
+		}
+		if !found.IsCancelled() || found.CancelReason != rent.CancelReasonForced {
+			t.Fatalf("GetRent after cancel = %+v, want cancelled by force", *found)
+		}
+		if err := repo.CancelRent(ctx, created.Uuid, rent.CancelReasonRequest); !errors.Is(err, ErrRentNotFound) {
+			t.Fatalf("CancelRent of a cancelled rent error = %v, want ErrRentNotFound", err)
+		}
+	})
+
+	t.Run("overlapping rents are rejected", func(t *testing.T) {
+		repo, fixtures := newRepo(t)
+
+		roomUuid, renterUuid := fixtures.HotelRoom(t), fixtures.User(t)
+		if err := repo.CreateRent(ctx, rent.NewRent(roomUuid, renterUuid, 1000, 2000)); err != nil {
+			t.Fatalf("CreateRent: %v", err)
+		}
+
+		if err := repo.CreateRent(ctx, rent.NewRent(roomUuid, renterUuid, 1500, 2500)); !errors.Is(err, rent.ErrAlreadyRented) {
+			t.Fatalf("CreateRent overlapping = %v, want ErrAlreadyRented", err)
+		}
+		if err := repo.CreateRent(ctx, rent.NewRent(roomUuid, renterUuid, 2000, 3000)); err != nil {
+			t.Fatalf("CreateRent starting on the day the previous one ends: %v", err)
+		}
+		if err := repo.CreateRent(ctx, rent.NewRent(fixtures.HotelRoom(t), renterUuid, 1000, 2000)); err != nil {
+			t.Fatalf("CreateRent of another room: %v", err)
+		}
+
+		cancelled := rent.NewRent(roomUuid, renterUuid, 5000, 6000)
+		if err := repo.CreateRent(ctx, cancelled); err != nil {
+			t.Fatalf("CreateRent: %v", err)
+		}
+		if err := repo.CancelRent(ctx, cancelled.Uuid, rent.CancelReasonForced); err != nil {
+			t.Fatalf("CancelRent: %v", err)
+		}
+		if err := repo.CreateRent(ctx, rent.NewRent(roomUuid, renterUuid, 5000, 6000)); err != nil {
+			t.Fatalf("CreateRent over a cancelled rent: %v", err)
+		}
+	})
+
+	t.Run("concurrent overlapping rents", func(t *testing.T) {
+		repo, fixtures := newRepo(t)
+		roomUuid := fixtures.HotelRoom(t)
+
+		const renters = 10
+		renterUuids := make([]uuid.UUID, renters)
+		for i := range renterUuids {
+			renterUuids[i] = fixtures.User(t)
+		}
+
+		var wg sync.WaitGroup
+		errs := make([]error, renters)
+		for i, renterUuid := range renterUuids {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				// each rent overlaps all the others by at least the day 1000-1001
+				errs[i] = repo.CreateRent(ctx, rent.NewRent(roomUuid, renterUuid, 1000-int64(i), 1001+int64(i)))
+			}()
+		}
+		wg.Wait()
+
+		created := 0
+		for _, err := range errs {
+			switch {
+			case err == nil:
+				created++
+			case !errors.Is(err, rent.ErrAlreadyRented):
+				t.Fatalf("CreateRent = %v, want ok or ErrAlreadyRented", err)
+			}
+		}
+		if created != 1 {
+			t.Fatalf("%v of %v overlapping rents were created, want 1", created, renters)
+		}
+	})
+
+	t.Run("get and cancel missing rent", func(t *testing.T) {
+		repo, _ := newRepo(t)
+
+		if _, err := repo.GetRent(ctx, uuid.New()); !errors.Is(err, ErrRentNotFound) {
+			t.Fatalf("GetRent error = %v, want ErrRentNotFound", err)
+		}
+		if err := repo.CancelRent(ctx, uuid.New(), rent.CancelReasonRequest); !errors.Is(err, ErrRentNotFound) {
+			t.Fatalf("CancelRent error = %v, want ErrRentNotFound", err)
+		}
+	})
+
+	type testRents struct {
+		roomUuid, otherRoomUuid, renterUuid             uuid.UUID
+		past, future, otherRoom, otherRenter, cancelled *rent.Rent
+	}
+
+	newRents := func(t *testing.T) (RentRepository, testRents) {
+		repo, fixtures := newRepo(t)
+
+		rents := testRents{roomUuid: fixtures.HotelRoom(t), otherRoomUuid: fixtures.HotelRoom(t), renterUuid: fixtures.User(t)}
+		rents.past = rent.NewRent(rents.roomUuid, rents.renterUuid, 1000, 2000)
+		rents.future = rent.NewRent(rents.roomUuid, rents.renterUuid, 5000, 6000)
+		rents.otherRoom = rent.NewRent(rents.otherRoomUuid, rents.renterUuid, 1000, 2000)
+		rents.otherRenter = rent.NewRent(rents.roomUuid, fixtures.User(t), 3000, 4000)
+		rents.cancelled = rent.NewRent(rents.roomUuid, rents.renterUuid, 7000, 8000)
+		for _, r := range []*rent.Rent{rents.past, rents.future, rents.otherRoom, rents.otherRenter, rents.cancelled} {
+			if err := repo.CreateRent(ctx, r); err != nil {
+				t.Fatalf("CreateRent: %v", err)
+			}
+		}
+
+		if err := repo.CancelRent(ctx, rents.cancelled.Uuid, rent.CancelReasonRequest); err != nil {
+			t.Fatalf("CancelRent: %v", err)
+		}
+		cancelled, err := repo.GetRent(ctx, rents.cancelled.Uuid)
+		if err != nil {
+			t.Fatalf("GetRent: %v", err)
+		}
+		rents.cancelled = cancelled
+		return repo, rents
+	}
+
+	t.Run("get by room", func(t *testing.T) {
+		repo, rents := newRents(t)
+
+		found, err := repo.GetRentsByHotelRoomUuid(ctx, rents.roomUuid)
+		if err != nil {
+			t.Fatalf("GetRentsByHotelRoomUuid: %v", err)
+		}
+		// cancelled rents no longer take the dates
+		assertRents(t, found, []*rent.Rent{rents.past, rents.future, rents.otherRenter})
+	})
+
+	t.Run("get with params", func(t *testing.T) {
+		repo, rents := newRents(t)
+		roomUuid, otherRoomUuid, renterUuid := rents.roomUuid, rents.otherRoomUuid, rents.renterUuid
+		past, future, otherRoom, otherRenter, cancelled := rents.past, rents.future, rents.otherRoom, rents.otherRenter, rents.cancelled
+
+		tests := []struct {
+			name   string
+			filter rent.FindRentFilterDTO
+			want   []*rent.Rent
+		}{
+			{name: "no filter with cancelled", want: []*rent.Rent{past, future, otherRoom, otherRenter, cancelled}},
+			{name: "rooms", filter: rent.FindRentFilterDTO{RoomUuids: []uuid.UUID{otherRoomUuid}}, want: []*rent.Rent{otherRoom}},
+			{name: "empty rooms match nothing", filter: rent.FindRentFilterDTO{RoomUuids: []uuid.UUID{}}, want: nil},
+			{name: "renter", filter: rent.FindRentFilterDTO{RenterUuid: renterUuid}, want: []*rent.Rent{past, future, otherRoom, cancelled}},
+			{name: "rooms and renter", filter: rent.FindRentFilterDTO{RoomUuids: []uuid.UUID{roomUuid}, RenterUuid: renterUuid}, want: []*rent.Rent{past, future, cancelled}},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				found, err := repo.GetRentsWithParams(ctx, &tt.filter)
+				if err != nil {
+					t.Fatalf("GetRentsWithParams: %v", err)
+				}
+				assertRents(t, found, tt.want)
+			})
+		}
+	})
+
+	t.Run("count by renter", func(t *testing.T) {
+		repo, rents := newRents(t)
+
+		all, err := repo.CountRentsByRenterUuid(ctx, rents.renterUuid, 0)
+		if err != nil {
+			t.Fatalf("CountRentsByRenterUuid: %v", err)
+		}
+		if all != 3 {
+			t.Fatalf("CountRentsByRenterUuid = %v, want 3 without the cancelled one", all)
+		}
+
+		upcoming, err := repo.CountRentsByRenterUuid(ctx, rents.renterUuid, 2000)
+		if err != nil {
+			t.Fatalf("CountRentsByRenterUuid: %v", err)
+		}
+		if upcoming != 1 {
+			t.Fatalf("CountRentsByRenterUuid ending after 2000 = %v, want 1", upcoming)
+		}
+	})
+}
+
+// assertRents compares ignoring order
+func assertRents(t *testing.T, got []rent.Rent, want []*rent.Rent) {
+	t.Helper()
+
+	if len(got) != len(want) {
+		t.Fatalf("got %v rents, want %v: %+v", len(got), len(want), got)
+	}
+
+	gotByUuid := map[uuid.UUID]rent.Rent{}
+	for _, r := range got {
+		gotByUuid[r.Uuid] = r
+	}
+	for _, r := range want {
+		if found, ok := gotByUuid[r.Uuid]; !ok || found != *r {
+			t.Fatalf("rent %+v is missing or differs: %+v", *r, found)
+		}
+	}
+}
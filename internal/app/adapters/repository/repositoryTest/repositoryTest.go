@@ -0,0 +1,196 @@
+package repositorytest
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rom6n/otello/internal/pkg/database"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// MongoDatabase connects to MONGO_TEST_URI and returns the name of a new database that is dropped after the test.
+// The test is skipped when MONGO_TEST_URI is not set
+func MongoDatabase(t *testing.T) (*mongo.Client, string) {
+	t.Helper()
+
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI is not set")
+	}
+
+	client, err := mongo.Connect(options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatalf("failed to connect to mongodb: %v", err)
+	}
+
+	dbName := testDatabaseName()
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := client.Database(dbName).Drop(ctx); err != nil {
+			t.Errorf("failed to drop test database: %v", err)
+		}
+		if err := client.Disconnect(ctx); err != nil {
+			t.Errorf("failed to disconnect from mongodb: %v", err)
+		}
+	})
+
+	return client, dbName
+}
+
+// EnsureMongoSchema creates the collection with its validator and indexes, so the test fails on documents the validator rejects.
+// A second plan must be empty, otherwise the startup would change the schema on every run
+func EnsureMongoSchema(t *testing.T, client *mongo.Client, dbName string, collection database.MongoCollection) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	db := client.Database(dbName)
+	collections := []database.MongoCollection{collection}
+
+	if err := database.EnsureMongoSchema(ctx, db, collections); err != nil {
+		t.Fatalf("EnsureMongoSchema: %v", err)
+	}
+
+	changes, err := database.PlanMongoSchema(ctx, db, collections)
+	if err != nil {
+		t.Fatalf("PlanMongoSchema: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Fatalf("schema is not idempotent, planned again: %v", changes)
+	}
+}
+
+// PostgresDatabase creates a migrated database on the server of POSTGRES_TEST_URL that is dropped after the test.
+// The test is skipped when POSTGRES_TEST_URL is not set
+func PostgresDatabase(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+
+	url := os.Getenv("POSTGRES_TEST_URL")
+	if url == "" {
+		t.Skip("POSTGRES_TEST_URL is not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+
+	admin, err := pgx.Connect(ctx, url)
+	if err != nil {
+		t.Fatalf("failed to connect to postgres: %v", err)
+	}
+	defer admin.Close(ctx)
+
+	dbName := testDatabaseName()
+	if _, err := admin.Exec(ctx, "CREATE DATABASE "+dbName); err != nil {
+		t.Fatalf("failed to create test database: %v", err)
+	}
+
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		admin, err := pgx.Connect(ctx, url)
+		if err != nil {
+			t.Errorf("failed to connect to postgres: %v", err)
+			return
+		}
+		defer admin.Close(ctx)
+
+		if _, err := admin.Exec(ctx, "DROP DATABASE "+dbName+" WITH (FORCE)"); err != nil {
+			t.Errorf("failed to drop test database: %v", err)
+		}
+	})
+
+	poolConfig, err := pgxpool.ParseConfig(url)
+	if err != nil {
+		t.Fatalf("failed to parse POSTGRES_TEST_URL: %v", err)
+	}
+	poolConfig.ConnConfig.Database = dbName
+
+	pool, err := pgxpool.NewWithConfig(ctx, poolConfig)
+	if err != nil {
+		t.Fatalf("failed to connect to test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+
+	if err := database.MigratePostgres(ctx, pool); err != nil {
+		t.Fatalf("failed to migrate test database: %v", err)
+	}
+
+	return pool
+}
+
+func testDatabaseName() string {
+	return "otello_test_" + strings.ReplaceAll(uuid.NewString(), "-", "")
+}
+
+// Fixtures creates the rows that a tested row references, so it passes the foreign keys of the storage
+type Fixtures interface {
+	User(t *testing.T) uuid.UUID
+	Hotel(t *testing.T) uuid.UUID
+	HotelRoom(t *testing.T) uuid.UUID
+	FlightTicket(t *testing.T) uuid.UUID
+}
+
+// NoFixtures is for storages without foreign keys, it only makes up uuids
+type NoFixtures struct{}
+
+func (NoFixtures) User(t *testing.T) uuid.UUID         { return uuid.New() }
+func (NoFixtures) Hotel(t *testing.T) uuid.UUID        { return uuid.New() }
+func (NoFixtures) HotelRoom(t *testing.T) uuid.UUID    { return uuid.New() }
+func (NoFixtures) FlightTicket(t *testing.T) uuid.UUID { return uuid.New() }
+
+type postgresFixtures struct {
+	pool *pgxpool.Pool
+}
+
+// PostgresFixtures inserts minimal rows with SQL, the repositories can't be used here as their tests import this package
+func PostgresFixtures(pool *pgxpool.Pool) Fixtures {
+	return &postgresFixtures{pool: pool}
+}
+
+func (v *postgresFixtures) insert(t *testing.T, query string, args ...any) uuid.UUID {
+	t.Helper()
+
+	id := uuid.New()
+	if _, err := v.pool.Exec(context.Background(), query, append([]any{id}, args...)...); err != nil {
+		t.Fatalf("failed to insert fixture: %v", err)
+	}
+
+	return id
+}
+
+func (v *postgresFixtures) User(t *testing.T) uuid.UUID {
+	t.Helper()
+	return v.insert(t, "INSERT INTO users (id, email, role) VALUES ($1, $2, 'user')", fmt.Sprintf("fixture-%v@example.com", uuid.NewString()))
+}
+
+func (v *postgresFixtures) Hotel(t *testing.T) uuid.UUID {
+	t.Helper()
+	return v.insert(t, "INSERT INTO hotels (id, name, city, stars) VALUES ($1, 'Fixture', 'Fixture', 1)")
+}
+
+func (v *postgresFixtures) HotelRoom(t *testing.T) uuid.UUID {
+	t.Helper()
+	return v.insert(t, "INSERT INTO hotel_rooms (id, hotel_uuid, rooms, type, amount_people) VALUES ($1, $2, 1, 'standard', 1)", v.Hotel(t))
+}
+
+func (v *postgresFixtures) FlightTicket(t *testing.T) uuid.UUID {
+	t.Helper()
+	return v.insert(t, "INSERT INTO flight_tickets (id, city_from, city_to, quantity, arrival) VALUES ($1, 'Fixture', 'Fixture', 0, 0)")
+}
+
+func Ptr[T any](value T) *T {
+	return &value
+}
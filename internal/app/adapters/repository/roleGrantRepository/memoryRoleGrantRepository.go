@@ -0,0 +1,40 @@
+package rolegrantrepository
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/memorystore"
+	"github.com/rom6n/otello/internal/app/domain/rolegrant"
+)
+
+type memoryRoleGrantRepo struct {
+	roleGrants *memorystore.Store[uuid.UUID, rolegrant.RoleGrant]
+}
+
+// NewMemory keeps role grants in process memory, for tests and running without MongoDB
+func NewMemory() RoleGrantRepository {
+	return &memoryRoleGrantRepo{
+		roleGrants: memorystore.New(func(g *rolegrant.RoleGrant) uuid.UUID { return g.Uuid }, nil),
+	}
+}
+
+func (v *memoryRoleGrantRepo) CreateRoleGrant(ctx context.Context, roleGrant *rolegrant.RoleGrant) error {
+	if err := v.roleGrants.Insert(*roleGrant); err != nil {
+		return fmt.Errorf("failed to create role grant: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryRoleGrantRepo) GetRoleGrantsByUserUuid(ctx context.Context, userUuid uuid.UUID) ([]rolegrant.RoleGrant, error) {
+	roleGrants := v.roleGrants.Find(func(g *rolegrant.RoleGrant) bool {
+		return g.UserUuid == userUuid
+	})
+
+	sort.SliceStable(roleGrants, func(i, j int) bool { return roleGrants[i].CreatedAt > roleGrants[j].CreatedAt })
+
+	return roleGrants, nil
+}
@@ -0,0 +1,72 @@
+package rolegrantrepository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rom6n/otello/internal/app/domain/rolegrant"
+	"github.com/rom6n/otello/internal/pkg/database"
+	"github.com/rom6n/otello/internal/utils/postgresutils"
+)
+
+const roleGrantColumns = "id, user_id, action, role, previous_role, granted_by, created_at"
+
+type postgresRoleGrantRepo struct {
+	pool    *pgxpool.Pool
+	timeout time.Duration
+}
+
+// NewPostgres keeps role grants in the role_grants table, the schema comes from database.MigratePostgres
+func NewPostgres(pool *pgxpool.Pool, timeout time.Duration) RoleGrantRepository {
+	return &postgresRoleGrantRepo{
+		pool:    pool,
+		timeout: timeout,
+	}
+}
+
+func (v *postgresRoleGrantRepo) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return database.OperationContext(ctx, v.timeout, database.StoragePostgres, "role_grant", method)
+}
+
+func (v *postgresRoleGrantRepo) CreateRoleGrant(ctx context.Context, roleGrant *rolegrant.RoleGrant) (err error) {
+	dbCtx, end := v.getContext(ctx, "CreateRoleGrant")
+	defer end(&err)
+
+	_, err = v.pool.Exec(dbCtx, "INSERT INTO role_grants ("+roleGrantColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		roleGrant.Uuid, roleGrant.UserUuid, roleGrant.Action, roleGrant.Role, roleGrant.PreviousRole,
+		postgresutils.NullableUuid(roleGrant.GrantedBy), roleGrant.CreatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create role grant: %v", err)
+	}
+
+	return nil
+}
+
+func (v *postgresRoleGrantRepo) GetRoleGrantsByUserUuid(ctx context.Context, userUuid uuid.UUID) (_ []rolegrant.RoleGrant, err error) {
+	dbCtx, end := v.getContext(ctx, "GetRoleGrantsByUserUuid")
+	defer end(&err)
+
+	rows, err := v.pool.Query(dbCtx, "SELECT "+roleGrantColumns+" FROM role_grants WHERE user_id = $1 ORDER BY created_at DESC", userUuid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role grants by user uuid: %v", err)
+	}
+
+	roleGrants, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (rolegrant.RoleGrant, error) {
+		var roleGrant rolegrant.RoleGrant
+		var grantedBy *uuid.UUID
+
+		err := row.Scan(&roleGrant.Uuid, &roleGrant.UserUuid, &roleGrant.Action, &roleGrant.Role, &roleGrant.PreviousRole, &grantedBy, &roleGrant.CreatedAt)
+		roleGrant.GrantedBy = postgresutils.UuidOrNil(grantedBy)
+
+		return roleGrant, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode role grants: %v", err)
+	}
+
+	return roleGrants, nil
+}
@@ -0,0 +1,100 @@
+package rolegrantrepository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/domain/rolegrant"
+	"github.com/rom6n/otello/internal/pkg/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+type RoleGrantRepository interface {
+	CreateRoleGrant(ctx context.Context, roleGrant *rolegrant.RoleGrant) error
+	GetRoleGrantsByUserUuid(ctx context.Context, userUuid uuid.UUID) ([]rolegrant.RoleGrant, error)
+}
+
+type roleGrantRepo struct {
+	client         *mongo.Client
+	dbName         string
+	collectionName string
+	timeout        time.Duration
+}
+
+func New(dbConnection *mongo.Client, dbName, collectionName string, timeout time.Duration) RoleGrantRepository {
+	return &roleGrantRepo{
+		client:         dbConnection,
+		dbName:         dbName,
+		collectionName: collectionName,
+		timeout:        timeout,
+	}
+}
+
+// MongoSchema is the validator and the indexes of the collection New works with
+func MongoSchema(collectionName string) database.MongoCollection {
+	return database.MongoCollection{
+		Name: collectionName,
+		Validator: database.MongoSchemaObject(
+			[]string{"_id", "user_id", "action", "role", "previous_role", "granted_by", "created_at"},
+			bson.D{
+				{Key: "_id", Value: database.MongoUuid},
+				{Key: "user_id", Value: database.MongoUuid},
+				{Key: "action", Value: database.MongoString},
+				{Key: "role", Value: database.MongoString},
+				{Key: "previous_role", Value: database.MongoString},
+				{Key: "granted_by", Value: database.MongoUuid},
+				{Key: "created_at", Value: database.MongoInteger},
+			},
+		),
+		Indexes: []database.MongoIndex{
+			{Name: "user_id_created_at", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}}},
+		},
+	}
+}
+
+func (v *roleGrantRepo) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return database.OperationContext(ctx, v.timeout, database.StorageMongo, "role_grant", method)
+}
+
+func (v *roleGrantRepo) getCollection() *mongo.Collection {
+	return v.client.Database(v.dbName).Collection(v.collectionName)
+}
+
+func (v *roleGrantRepo) CreateRoleGrant(ctx context.Context, roleGrant *rolegrant.RoleGrant) (err error) {
+	dbCtx, end := v.getContext(ctx, "CreateRoleGrant")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	_, err = collection.InsertOne(dbCtx, roleGrant)
+	if err != nil {
+		return fmt.Errorf("failed to create role grant: %v", err)
+	}
+
+	return nil
+}
+
+func (v *roleGrantRepo) GetRoleGrantsByUserUuid(ctx context.Context, userUuid uuid.UUID) (_ []rolegrant.RoleGrant, err error) {
+	dbCtx, end := v.getContext(ctx, "GetRoleGrantsByUserUuid")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	findOptions := options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}})
+
+	cursor, err := collection.Find(dbCtx, bson.D{{Key: "user_id", Value: userUuid}}, findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get role grants by user uuid: %v", err)
+	}
+
+	var roleGrants []rolegrant.RoleGrant
+	if err := cursor.All(dbCtx, &roleGrants); err != nil {
+		return nil, fmt.Errorf("failed to decode role grants: %v", err)
+	}
+
+	return roleGrants, nil
+}
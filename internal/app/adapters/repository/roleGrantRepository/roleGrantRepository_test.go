@@ -0,0 +1,69 @@
+package rolegrantrepository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/repositorytest"
+	"github.com/rom6n/otello/internal/app/domain/rolegrant"
+	"github.com/rom6n/otello/internal/app/domain/user"
+)
+
+func TestMemoryRoleGrantRepository(t *testing.T) {
+	testRoleGrantRepository(t, func(t *testing.T) (RoleGrantRepository, repositorytest.Fixtures) {
+		return NewMemory(), repositorytest.NoFixtures{}
+	})
+}
+
+func TestMongoRoleGrantRepository(t *testing.T) {
+	testRoleGrantRepository(t, func(t *testing.T) (RoleGrantRepository, repositorytest.Fixtures) {
+		client, dbName := repositorytest.MongoDatabase(t)
+		repositorytest.EnsureMongoSchema(t, client, dbName, MongoSchema("roleGrants"))
+		return New(client, dbName, "roleGrants", 30*time.Second), repositorytest.NoFixtures{}
+	})
+}
+
+func TestPostgresRoleGrantRepository(t *testing.T) {
+	testRoleGrantRepository(t, func(t *testing.T) (RoleGrantRepository, repositorytest.Fixtures) {
+		pool := repositorytest.PostgresDatabase(t)
+		return NewPostgres(pool, 30*time.Second), repositorytest.PostgresFixtures(pool)
+	})
+}
+
+func testRoleGrantRepository(t *testing.T, newRepo func(t *testing.T) (RoleGrantRepository, repositorytest.Fixtures)) {
+	ctx := context.Background()
+
+	t.Run("get by user, newest first", func(t *testing.T) {
+		repo, fixtures := newRepo(t)
+		userUuid, adminUuid := fixtures.User(t), fixtures.User(t)
+
+		grant := rolegrant.NewRoleGrant(userUuid, rolegrant.ActionGrant, user.RoleHotelManager, user.RoleUser, adminUuid)
+		grant.CreatedAt = 1000
+		revoke := rolegrant.NewRoleGrant(userUuid, rolegrant.ActionRevoke, user.RoleUser, user.RoleHotelManager, adminUuid)
+		revoke.CreatedAt = 2000
+		other := rolegrant.NewRoleGrant(fixtures.User(t), rolegrant.ActionBootstrap, user.RoleAdmin, user.RoleUser, uuid.Nil)
+		for _, g := range []*rolegrant.RoleGrant{grant, revoke, other} {
+			if err := repo.CreateRoleGrant(ctx, g); err != nil {
+				t.Fatalf("CreateRoleGrant: %v", err)
+			}
+		}
+
+		roleGrants, err := repo.GetRoleGrantsByUserUuid(ctx, userUuid)
+		if err != nil {
+			t.Fatalf("GetRoleGrantsByUserUuid: %v", err)
+		}
+		if len(roleGrants) != 2 || roleGrants[0] != *revoke || roleGrants[1] != *grant {
+			t.Fatalf("GetRoleGrantsByUserUuid = %+v, want revoke then grant", roleGrants)
+		}
+
+		bootstrapped, err := repo.GetRoleGrantsByUserUuid(ctx, other.UserUuid)
+		if err != nil {
+			t.Fatalf("GetRoleGrantsByUserUuid: %v", err)
+		}
+		if len(bootstrapped) != 1 || bootstrapped[0] != *other {
+			t.Fatalf("GetRoleGrantsByUserUuid = %+v, want the bootstrap grant without granted_by", bootstrapped)
+		}
+	})
+}
@@ -0,0 +1,377 @@
+package userrepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/memorystore"
+	"github.com/rom6n/otello/internal/app/domain/user"
+)
+
+type memoryUserRepo struct {
+	users *memorystore.Store[uuid.UUID, user.User]
+}
+
+// NewMemory keeps users in process memory, for tests and running without MongoDB.
+// Emails are unique from the start, as if EnsureEmailIndex was already called
+func NewMemory() UserRepository {
+	users := memorystore.New(func(u *user.User) uuid.UUID { return u.Uuid }, cloneUser).
+		Unique(func(u *user.User) string { return u.Email })
+
+	return &memoryUserRepo{
+		users: users,
+	}
+}
+
+func cloneUser(u user.User) user.User {
+	u.RecoveryCodeHashes = slices.Clone(u.RecoveryCodeHashes)
+	return u
+}
+
+// update applies a Mongo UpdateByID-like change, a missing user is not an error
+func (v *memoryUserRepo) update(userId uuid.UUID, update func(*user.User)) (bool, error) {
+	_, found, err := v.users.Update(userId, func(u *user.User) bool {
+		update(u)
+		return true
+	})
+	if errors.Is(err, memorystore.ErrDuplicateKey) {
+		return false, ErrEmailTaken
+	}
+
+	return found, err
+}
+
+func (v *memoryUserRepo) CreateUser(ctx context.Context, user *user.User) error {
+	err := v.users.Insert(*user)
+	if errors.Is(err, memorystore.ErrDuplicateKey) {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create user: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryUserRepo) GetUser(ctx context.Context, email string) (*user.User, error) {
+	foundUser, ok := v.users.FindOne(func(u *user.User) bool {
+		return u.Email == email
+	})
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+
+	return &foundUser, nil
+}
+
+func (v *memoryUserRepo) GetUserByUuid(ctx context.Context, userId uuid.UUID) (*user.User, error) {
+	foundUser, ok := v.users.Get(userId)
+	if !ok {
+		return nil, ErrUserNotFound
+	}
+
+	return &foundUser, nil
+}
+
+func (v *memoryUserRepo) UpdateUserName(ctx context.Context, userId uuid.UUID, newName string) error {
+	if _, err := v.update(userId, func(u *user.User) { u.Name = newName }); err != nil {
+		return fmt.Errorf("failed to update name: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryUserRepo) UpdateUserProfile(ctx context.Context, userId uuid.UUID, profile user.ProfileUpdate) error {
+	if profile.Name == nil && profile.Phone == nil && profile.Language == nil && profile.Currency == nil {
+		return nil
+	}
+
+	found, err := v.update(userId, func(u *user.User) {
+		for field, value := range map[*string]*string{
+			&u.Name:     profile.Name,
+			&u.Phone:    profile.Phone,
+			&u.Language: profile.Language,
+			&u.Currency: profile.Currency,
+		} {
+			if value != nil {
+				*field = *value
+			}
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update profile: %v", err)
+	}
+	if !found {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (v *memoryUserRepo) UpdateUserRole(ctx context.Context, userId uuid.UUID, newRole string) error {
+	_, err := v.update(userId, func(u *user.User) {
+		u.Role = user.UserRole(newRole)
+		u.TokenVersion++
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update role: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryUserRepo) UpdateUserPassword(ctx context.Context, userId uuid.UUID, newHashedPassword string) error {
+	if _, err := v.update(userId, func(u *user.User) { u.Password = newHashedPassword }); err != nil {
+		return fmt.Errorf("failed to update password: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryUserRepo) UpdateUserEmailVerified(ctx context.Context, userId uuid.UUID, verified bool) error {
+	if _, err := v.update(userId, func(u *user.User) { u.EmailVerified = verified }); err != nil {
+		return fmt.Errorf("failed to update email verification: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryUserRepo) UpdateUserPendingEmail(ctx context.Context, userId uuid.UUID, pendingEmail string) error {
+	if _, err := v.update(userId, func(u *user.User) { u.PendingEmail = pendingEmail }); err != nil {
+		return fmt.Errorf("failed to update pending email: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryUserRepo) UpdateUserEmail(ctx context.Context, userId uuid.UUID, newEmail string) error {
+	_, err := v.update(userId, func(u *user.User) {
+		u.Email = newEmail
+		u.EmailVerified = true
+		u.PendingEmail = ""
+	})
+	if errors.Is(err, ErrEmailTaken) {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update email: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryUserRepo) IncrementUserTokenVersion(ctx context.Context, userId uuid.UUID) (int64, error) {
+	updatedUser, found, err := v.users.Update(userId, func(u *user.User) bool {
+		u.TokenVersion++
+		return true
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment token version: %v", err)
+	}
+	if !found {
+		return 0, ErrUserNotFound
+	}
+
+	return updatedUser.TokenVersion, nil
+}
+
+func (v *memoryUserRepo) UpdateUserDisabled(ctx context.Context, userId uuid.UUID, disabled bool) error {
+	found, err := v.update(userId, func(u *user.User) {
+		if !disabled {
+			u.DisabledAt = 0
+			return
+		}
+		u.DisabledAt = time.Now().Unix()
+		u.TokenVersion++
+	})
+	if err != nil {
+		return fmt.Errorf("failed to update disabled: %v", err)
+	}
+	if !found {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+func (v *memoryUserRepo) FindUsers(ctx context.Context, filter *user.FindUserFilterDTO) ([]user.User, int64, error) {
+	query := strings.ToLower(filter.Query)
+
+	users := v.users.Find(func(u *user.User) bool {
+		switch {
+		case u.IsDeleted():
+			return false
+		case query != "" && !strings.Contains(strings.ToLower(u.Email), query) && !strings.Contains(strings.ToLower(u.Name), query):
+			return false
+		case filter.Role != "" && u.Role != filter.Role:
+			return false
+		case filter.Disabled != nil && u.IsDisabled() != *filter.Disabled:
+			return false
+		}
+		return true
+	})
+
+	total := int64(len(users))
+
+	sort.SliceStable(users, func(i, j int) bool { return users[i].Email < users[j].Email })
+
+	// like Mongo, a zero limit means no limit
+	start := min(max(filter.Offset, 0), total)
+	end := total
+	if filter.Limit > 0 {
+		end = min(start+filter.Limit, total)
+	}
+
+	return users[start:end], total, nil
+}
+
+func (v *memoryUserRepo) AnonymizeUser(ctx context.Context, userId uuid.UUID) error {
+	_, err := v.update(userId, func(u *user.User) {
+		*u = user.User{
+			Uuid:         u.Uuid,
+			Name:         "Deleted user",
+			Email:        fmt.Sprintf("deleted-%v@deleted.invalid", userId),
+			Role:         user.RoleUser,
+			TokenVersion: u.TokenVersion + 1,
+			DeletedAt:    time.Now().Unix(),
+			DisabledAt:   u.DisabledAt,
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("failed to anonymize user: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryUserRepo) UpdateUserTotpPendingSecret(ctx context.Context, userId uuid.UUID, secret string) error {
+	if _, err := v.update(userId, func(u *user.User) { u.TotpPendingSecret = secret }); err != nil {
+		return fmt.Errorf("failed to update totp pending secret: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryUserRepo) UpdateUserTotpSecret(ctx context.Context, userId uuid.UUID, secret string) error {
+	if _, err := v.update(userId, func(u *user.User) { u.TotpSecret = secret }); err != nil {
+		return fmt.Errorf("failed to update totp secret: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryUserRepo) EnableUserTotp(ctx context.Context, userId uuid.UUID, secret string, counter int64, recoveryCodeHashes []string) error {
+	_, err := v.update(userId, func(u *user.User) {
+		u.TotpEnabled = true
+		u.TotpSecret = secret
+		u.TotpLastCounter = counter
+		u.RecoveryCodeHashes = slices.Clone(recoveryCodeHashes)
+		u.TotpPendingSecret = ""
+	})
+	if err != nil {
+		return fmt.Errorf("failed to enable totp: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryUserRepo) DisableUserTotp(ctx context.Context, userId uuid.UUID) error {
+	_, err := v.update(userId, func(u *user.User) {
+		u.TotpEnabled = false
+		u.TotpSecret = ""
+		u.TotpPendingSecret = ""
+		u.TotpLastCounter = 0
+		u.RecoveryCodeHashes = nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to disable totp: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryUserRepo) UseUserTotpCounter(ctx context.Context, userId uuid.UUID, counter int64) (bool, error) {
+	_, used, err := v.users.Update(userId, func(u *user.User) bool {
+		// a zero counter is not stored in Mongo (omitempty), so it matches like a missing field
+		if u.TotpLastCounter != 0 && u.TotpLastCounter >= counter {
+			return false
+		}
+		u.TotpLastCounter = counter
+		return true
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to use totp code: %v", err)
+	}
+
+	return used, nil
+}
+
+func (v *memoryUserRepo) UseUserRecoveryCode(ctx context.Context, userId uuid.UUID, codeHash string) (bool, error) {
+	_, used, err := v.users.Update(userId, func(u *user.User) bool {
+		if !slices.Contains(u.RecoveryCodeHashes, codeHash) {
+			return false
+		}
+		u.RecoveryCodeHashes = slices.DeleteFunc(u.RecoveryCodeHashes, func(hash string) bool { return hash == codeHash })
+		return true
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %v", err)
+	}
+
+	return used, nil
+}
+
+func (v *memoryUserRepo) UpdateUserRecoveryCodes(ctx context.Context, userId uuid.UUID, recoveryCodeHashes []string) error {
+	if _, err := v.update(userId, func(u *user.User) { u.RecoveryCodeHashes = slices.Clone(recoveryCodeHashes) }); err != nil {
+		return fmt.Errorf("failed to update recovery codes: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryUserRepo) EnsureEmailIndex(ctx context.Context) error {
+	return nil
+}
+
+func (v *memoryUserRepo) FindDuplicateEmails(ctx context.Context) ([]DuplicateEmail, error) {
+	groups := map[string]*DuplicateEmail{}
+	for _, u := range v.users.Find(nil) {
+		email := user.NormalizeEmail(u.Email)
+		if groups[email] == nil {
+			groups[email] = &DuplicateEmail{Email: email}
+		}
+		groups[email].UserUuids = append(groups[email].UserUuids, u.Uuid)
+		groups[email].Emails = append(groups[email].Emails, u.Email)
+	}
+
+	var duplicates []DuplicateEmail
+	for _, group := range groups {
+		if len(group.UserUuids) > 1 {
+			duplicates = append(duplicates, *group)
+		}
+	}
+
+	sort.Slice(duplicates, func(i, j int) bool { return duplicates[i].Email < duplicates[j].Email })
+
+	return duplicates, nil
+}
+
+func (v *memoryUserRepo) NormalizeEmails(ctx context.Context) (int64, error) {
+	normalized, err := v.users.UpdateMany(func(u *user.User) bool {
+		return u.Email != user.NormalizeEmail(u.Email)
+	}, func(u *user.User) bool {
+		u.Email = user.NormalizeEmail(u.Email)
+		return true
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to normalize emails: %v", err)
+	}
+
+	return normalized, nil
+}
@@ -0,0 +1,406 @@
+package userrepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rom6n/otello/internal/app/domain/user"
+	"github.com/rom6n/otello/internal/pkg/database"
+	"github.com/rom6n/otello/internal/utils/postgresutils"
+)
+
+const (
+	userColumns = "id, name, email, email_verified, password, role, pending_email, token_version, deleted_at, disabled_at, " +
+		"phone, language, currency, totp_enabled, totp_secret, totp_pending_secret, totp_last_counter, recovery_code_hashes"
+
+	// normalizedEmailSql is user.NormalizeEmail in SQL
+	normalizedEmailSql = `lower(btrim(email, E' \t\n\v\f\r'))`
+)
+
+type postgresUserRepo struct {
+	pool    *pgxpool.Pool
+	timeout time.Duration
+}
+
+// NewPostgres keeps users in the users table, the schema comes from database.MigratePostgres.
+// Emails are unique from the start, as if EnsureEmailIndex was already called
+func NewPostgres(pool *pgxpool.Pool, timeout time.Duration) UserRepository {
+	return &postgresUserRepo{
+		pool:    pool,
+		timeout: timeout,
+	}
+}
+
+func (v *postgresUserRepo) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return database.OperationContext(ctx, v.timeout, database.StoragePostgres, "user", method)
+}
+
+// userFields are the userColumns of u, for both writing and scanning
+func userFields(u *user.User) []any {
+	return []any{
+		&u.Uuid, &u.Name, &u.Email, &u.EmailVerified, &u.Password, &u.Role, &u.PendingEmail, &u.TokenVersion, &u.DeletedAt, &u.DisabledAt,
+		&u.Phone, &u.Language, &u.Currency, &u.TotpEnabled, &u.TotpSecret, &u.TotpPendingSecret, &u.TotpLastCounter, &u.RecoveryCodeHashes,
+	}
+}
+
+func (v *postgresUserRepo) getUserBy(ctx context.Context, method, column string, value any) (_ *user.User, err error) {
+	dbCtx, end := v.getContext(ctx, method)
+	defer end(&err)
+
+	var foundUser user.User
+	err = v.pool.QueryRow(dbCtx, "SELECT "+userColumns+" FROM users WHERE "+column+" = $1", value).Scan(userFields(&foundUser)...)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user from database: %v", err)
+	}
+
+	return &foundUser, nil
+}
+
+// update runs an UPDATE of one user and reports whether the user exists, a missing user is not an error like in Mongo UpdateByID
+func (v *postgresUserRepo) update(ctx context.Context, method, query string, args ...any) (_ bool, err error) {
+	dbCtx, end := v.getContext(ctx, method)
+	defer end(&err)
+
+	result, err := v.pool.Exec(dbCtx, query, args...)
+	if postgresutils.IsUniqueViolation(err) {
+		return false, ErrEmailTaken
+	}
+	if err != nil {
+		return false, err
+	}
+
+	return result.RowsAffected() > 0, nil
+}
+
+func (v *postgresUserRepo) CreateUser(ctx context.Context, user *user.User) (err error) {
+	dbCtx, end := v.getContext(ctx, "CreateUser")
+	defer end(&err)
+
+	_, err = v.pool.Exec(dbCtx, "INSERT INTO users ("+userColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)",
+		userFields(user)...)
+	if postgresutils.IsUniqueViolation(err) {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to create user: %v", err)
+	}
+
+	return nil
+}
+
+func (v *postgresUserRepo) GetUser(ctx context.Context, email string) (*user.User, error) {
+	return v.getUserBy(ctx, "GetUser", "email", email)
+}
+
+func (v *postgresUserRepo) GetUserByUuid(ctx context.Context, userId uuid.UUID) (*user.User, error) {
+	return v.getUserBy(ctx, "GetUserByUuid", "id", userId)
+}
+
+func (v *postgresUserRepo) UpdateUserName(ctx context.Context, userId uuid.UUID, newName string) error {
+	if _, err := v.update(ctx, "UpdateUserName", "UPDATE users SET name = $2 WHERE id = $1", userId, newName); err != nil {
+		return fmt.Errorf("failed to update name: %v", err)
+	}
+
+	return nil
+}
+
+func (v *postgresUserRepo) UpdateUserProfile(ctx context.Context, userId uuid.UUID, profile user.ProfileUpdate) error {
+	args := []any{userId}
+	var set []string
+	for _, field := range []struct {
+		column string
+		value  *string
+	}{
+		{"name", profile.Name},
+		{"phone", profile.Phone},
+		{"language", profile.Language},
+		{"currency", profile.Currency},
+	} {
+		if field.value != nil {
+			args = append(args, *field.value)
+			set = append(set, fmt.Sprintf("%v = $%v", field.column, len(args)))
+		}
+	}
+	if len(set) == 0 {
+		return nil
+	}
+
+	found, err := v.update(ctx, "UpdateUserProfile", "UPDATE users SET "+strings.Join(set, ", ")+" WHERE id = $1", args...)
+	if err != nil {
+		return fmt.Errorf("failed to update profile: %v", err)
+	}
+	if !found {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdateUserRole also ends every session, jwts carry the role and would keep the old one
+func (v *postgresUserRepo) UpdateUserRole(ctx context.Context, userId uuid.UUID, newRole string) error {
+	if _, err := v.update(ctx, "UpdateUserRole", "UPDATE users SET role = $2, token_version = token_version + 1 WHERE id = $1", userId, newRole); err != nil {
+		return fmt.Errorf("failed to update role: %v", err)
+	}
+
+	return nil
+}
+
+func (v *postgresUserRepo) UpdateUserPassword(ctx context.Context, userId uuid.UUID, newHashedPassword string) error {
+	if _, err := v.update(ctx, "UpdateUserPassword", "UPDATE users SET password = $2 WHERE id = $1", userId, newHashedPassword); err != nil {
+		return fmt.Errorf("failed to update password: %v", err)
+	}
+
+	return nil
+}
+
+func (v *postgresUserRepo) UpdateUserEmailVerified(ctx context.Context, userId uuid.UUID, verified bool) error {
+	if _, err := v.update(ctx, "UpdateUserEmailVerified", "UPDATE users SET email_verified = $2 WHERE id = $1", userId, verified); err != nil {
+		return fmt.Errorf("failed to update email verification: %v", err)
+	}
+
+	return nil
+}
+
+func (v *postgresUserRepo) UpdateUserPendingEmail(ctx context.Context, userId uuid.UUID, pendingEmail string) error {
+	if _, err := v.update(ctx, "UpdateUserPendingEmail", "UPDATE users SET pending_email = $2 WHERE id = $1", userId, pendingEmail); err != nil {
+		return fmt.Errorf("failed to update pending email: %v", err)
+	}
+
+	return nil
+}
+
+// UpdateUserEmail replaces the email with an already verified one and clears the pending email
+func (v *postgresUserRepo) UpdateUserEmail(ctx context.Context, userId uuid.UUID, newEmail string) error {
+	_, err := v.update(ctx, "UpdateUserEmail", "UPDATE users SET email = $2, email_verified = true, pending_email = '' WHERE id = $1", userId, newEmail)
+	if errors.Is(err, ErrEmailTaken) {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update email: %v", err)
+	}
+
+	return nil
+}
+
+// IncrementUserTokenVersion invalidates every jwt issued to the user before the call
+func (v *postgresUserRepo) IncrementUserTokenVersion(ctx context.Context, userId uuid.UUID) (_ int64, err error) {
+	dbCtx, end := v.getContext(ctx, "IncrementUserTokenVersion")
+	defer end(&err)
+
+	var tokenVersion int64
+	err = v.pool.QueryRow(dbCtx, "UPDATE users SET token_version = token_version + 1 WHERE id = $1 RETURNING token_version", userId).Scan(&tokenVersion)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, ErrUserNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment token version: %v", err)
+	}
+
+	return tokenVersion, nil
+}
+
+// UpdateUserDisabled also ends every session when the user is disabled, so they don't come back on enable
+func (v *postgresUserRepo) UpdateUserDisabled(ctx context.Context, userId uuid.UUID, disabled bool) error {
+	query := "UPDATE users SET disabled_at = 0 WHERE id = $1"
+	args := []any{userId}
+	if disabled {
+		query = "UPDATE users SET disabled_at = $2, token_version = token_version + 1 WHERE id = $1"
+		args = append(args, time.Now().Unix())
+	}
+
+	found, err := v.update(ctx, "UpdateUserDisabled", query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update disabled: %v", err)
+	}
+	if !found {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// FindUsers returns a page of not deleted users and the total count for the filter
+func (v *postgresUserRepo) FindUsers(ctx context.Context, filter *user.FindUserFilterDTO) (_ []user.User, _ int64, err error) {
+	dbCtx, end := v.getContext(ctx, "FindUsers")
+	defer end(&err)
+
+	var where postgresutils.Filter
+	where.Add("deleted_at = %v", 0)
+
+	if filter.Query != "" {
+		where.Add("(email ILIKE %[1]v OR name ILIKE %[1]v)", postgresutils.ContainsPattern(filter.Query))
+	}
+
+	if filter.Role != "" {
+		where.Add("role = %v", filter.Role)
+	}
+
+	if filter.Disabled != nil {
+		where.Add("(disabled_at <> 0) = %v", *filter.Disabled)
+	}
+
+	var total int64
+	if err := v.pool.QueryRow(dbCtx, "SELECT count(*) FROM users"+where.Where(), where.Args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %v", err)
+	}
+
+	// like Mongo, emails are compared byte by byte and a zero limit means no limit
+	args := append(where.Args, filter.Offset, filter.Limit)
+	query := fmt.Sprintf("SELECT %v FROM users%v ORDER BY email COLLATE \"C\" OFFSET $%v LIMIT NULLIF($%v, 0)", userColumns, where.Where(), len(args)-1, len(args))
+
+	rows, err := v.pool.Query(dbCtx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find users: %v", err)
+	}
+
+	users, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (user.User, error) {
+		var foundUser user.User
+		err := row.Scan(userFields(&foundUser)...)
+		return foundUser, err
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to decode users: %v", err)
+	}
+
+	return users, total, nil
+}
+
+// AnonymizeUser wipes personal data but keeps the row, so rents and tickets still reference an existing user
+func (v *postgresUserRepo) AnonymizeUser(ctx context.Context, userId uuid.UUID) error {
+	query := `UPDATE users SET
+		name = 'Deleted user', email = $2, email_verified = false, password = '', role = $3, deleted_at = $4,
+		pending_email = '', phone = '', language = '', currency = '',
+		totp_enabled = false, totp_secret = '', totp_pending_secret = '', totp_last_counter = 0, recovery_code_hashes = NULL,
+		token_version = token_version + 1
+		WHERE id = $1`
+
+	_, err := v.update(ctx, "AnonymizeUser", query, userId, fmt.Sprintf("deleted-%v@deleted.invalid", userId), user.RoleUser, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to anonymize user: %v", err)
+	}
+
+	return nil
+}
+
+func (v *postgresUserRepo) UpdateUserTotpPendingSecret(ctx context.Context, userId uuid.UUID, secret string) error {
+	if _, err := v.update(ctx, "UpdateUserTotpPendingSecret", "UPDATE users SET totp_pending_secret = $2 WHERE id = $1", userId, secret); err != nil {
+		return fmt.Errorf("failed to update totp pending secret: %v", err)
+	}
+
+	return nil
+}
+
+func (v *postgresUserRepo) UpdateUserTotpSecret(ctx context.Context, userId uuid.UUID, secret string) error {
+	if _, err := v.update(ctx, "UpdateUserTotpSecret", "UPDATE users SET totp_secret = $2 WHERE id = $1", userId, secret); err != nil {
+		return fmt.Errorf("failed to update totp secret: %v", err)
+	}
+
+	return nil
+}
+
+func (v *postgresUserRepo) EnableUserTotp(ctx context.Context, userId uuid.UUID, secret string, counter int64, recoveryCodeHashes []string) error {
+	query := `UPDATE users SET
+		totp_enabled = true, totp_secret = $2, totp_last_counter = $3, recovery_code_hashes = $4, totp_pending_secret = ''
+		WHERE id = $1`
+
+	if _, err := v.update(ctx, "EnableUserTotp", query, userId, secret, counter, recoveryCodeHashes); err != nil {
+		return fmt.Errorf("failed to enable totp: %v", err)
+	}
+
+	return nil
+}
+
+func (v *postgresUserRepo) DisableUserTotp(ctx context.Context, userId uuid.UUID) error {
+	query := `UPDATE users SET
+		totp_enabled = false, totp_secret = '', totp_pending_secret = '', totp_last_counter = 0, recovery_code_hashes = NULL
+		WHERE id = $1`
+
+	if _, err := v.update(ctx, "DisableUserTotp", query, userId); err != nil {
+		return fmt.Errorf("failed to disable totp: %v", err)
+	}
+
+	return nil
+}
+
+// UseUserTotpCounter stores the time step of an accepted code. It returns false if that step or a later one was already used
+func (v *postgresUserRepo) UseUserTotpCounter(ctx context.Context, userId uuid.UUID, counter int64) (bool, error) {
+	// a zero counter means none was used yet, like the missing field in Mongo
+	used, err := v.update(ctx, "UseUserTotpCounter", "UPDATE users SET totp_last_counter = $2 WHERE id = $1 AND (totp_last_counter < $2 OR totp_last_counter = 0)", userId, counter)
+	if err != nil {
+		return false, fmt.Errorf("failed to use totp code: %v", err)
+	}
+
+	return used, nil
+}
+
+// UseUserRecoveryCode removes the code so it works only once. It returns false if the user has no such code
+func (v *postgresUserRepo) UseUserRecoveryCode(ctx context.Context, userId uuid.UUID, codeHash string) (bool, error) {
+	used, err := v.update(ctx, "UseUserRecoveryCode", "UPDATE users SET recovery_code_hashes = array_remove(recovery_code_hashes, $2) WHERE id = $1 AND $2 = ANY(recovery_code_hashes)", userId, codeHash)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %v", err)
+	}
+
+	return used, nil
+}
+
+func (v *postgresUserRepo) UpdateUserRecoveryCodes(ctx context.Context, userId uuid.UUID, recoveryCodeHashes []string) error {
+	if _, err := v.update(ctx, "UpdateUserRecoveryCodes", "UPDATE users SET recovery_code_hashes = $2 WHERE id = $1", userId, recoveryCodeHashes); err != nil {
+		return fmt.Errorf("failed to update recovery codes: %v", err)
+	}
+
+	return nil
+}
+
+// EnsureEmailIndex does nothing, the unique email constraint is a part of the schema
+func (v *postgresUserRepo) EnsureEmailIndex(ctx context.Context) error {
+	return nil
+}
+
+func (v *postgresUserRepo) FindDuplicateEmails(ctx context.Context) (_ []DuplicateEmail, err error) {
+	dbCtx, end := v.getContext(ctx, "FindDuplicateEmails")
+	defer end(&err)
+
+	query := `SELECT ` + normalizedEmailSql + `, array_agg(id ORDER BY email), array_agg(email ORDER BY email)
+		FROM users
+		GROUP BY ` + normalizedEmailSql + `
+		HAVING count(*) > 1
+		ORDER BY ` + normalizedEmailSql + ` COLLATE "C"`
+
+	rows, err := v.pool.Query(dbCtx, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find duplicate emails: %v", err)
+	}
+
+	duplicates, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (DuplicateEmail, error) {
+		var duplicate DuplicateEmail
+		err := row.Scan(&duplicate.Email, &duplicate.UserUuids, &duplicate.Emails)
+		return duplicate, err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode duplicate emails: %v", err)
+	}
+
+	return duplicates, nil
+}
+
+// NormalizeEmails lowercases and trims stored emails, call it only when FindDuplicateEmails is empty
+func (v *postgresUserRepo) NormalizeEmails(ctx context.Context) (_ int64, err error) {
+	dbCtx, end := v.getContext(ctx, "NormalizeEmails")
+	defer end(&err)
+
+	result, err := v.pool.Exec(dbCtx, "UPDATE users SET email = "+normalizedEmailSql+" WHERE email <> "+normalizedEmailSql)
+	if err != nil {
+		return 0, fmt.Errorf("failed to normalize emails: %v", err)
+	}
+
+	return result.RowsAffected(), nil
+}
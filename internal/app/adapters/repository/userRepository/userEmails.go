@@ -0,0 +1,93 @@
+package userrepository
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const emailIndexName = "email_unique"
+
+// DuplicateEmail is a group of accounts whose emails are the same after normalization
+type DuplicateEmail struct {
+	Email     string      `bson:"_id"`
+	UserUuids []uuid.UUID `bson:"user_ids"`
+	Emails    []string    `bson:"emails"`
+}
+
+// normalizedEmailExpr is user.NormalizeEmail as an aggregation expression
+var normalizedEmailExpr = bson.D{{Key: "$toLower", Value: bson.D{{Key: "$trim", Value: bson.D{{Key: "input", Value: "$email"}}}}}}
+
+// EnsureEmailIndex fails while there are duplicate emails, see FindDuplicateEmails
+func (v *userRepo) EnsureEmailIndex(ctx context.Context) (err error) {
+	dbCtx, end := v.getContext(ctx, "EnsureEmailIndex")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	index := mongo.IndexModel{
+		Keys:    bson.D{{Key: "email", Value: 1}},
+		Options: options.Index().SetName(emailIndexName).SetUnique(true),
+	}
+
+	if _, err := collection.Indexes().CreateOne(dbCtx, index); err != nil {
+		return fmt.Errorf("failed to create unique email index: %v", err)
+	}
+
+	return nil
+}
+
+func (v *userRepo) FindDuplicateEmails(ctx context.Context) (_ []DuplicateEmail, err error) {
+	dbCtx, end := v.getContext(ctx, "FindDuplicateEmails")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	pipeline := mongo.Pipeline{
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: normalizedEmailExpr},
+			{Key: "user_ids", Value: bson.D{{Key: "$push", Value: "$_id"}}},
+			{Key: "emails", Value: bson.D{{Key: "$push", Value: "$email"}}},
+			{Key: "count", Value: bson.D{{Key: "$sum", Value: 1}}},
+		}}},
+		{{Key: "$match", Value: bson.D{{Key: "count", Value: bson.D{{Key: "$gt", Value: 1}}}}}},
+		{{Key: "$sort", Value: bson.D{{Key: "_id", Value: 1}}}},
+	}
+
+	cursor, aggregateErr := collection.Aggregate(dbCtx, pipeline)
+	if aggregateErr != nil {
+		return nil, fmt.Errorf("failed to find duplicate emails: %v", aggregateErr)
+	}
+	defer cursor.Close(dbCtx)
+
+	var duplicates []DuplicateEmail
+	if err := cursor.All(dbCtx, &duplicates); err != nil {
+		return nil, fmt.Errorf("failed to decode duplicate emails: %v", err)
+	}
+
+	return duplicates, nil
+}
+
+// NormalizeEmails lowercases and trims stored emails, call it only when FindDuplicateEmails is empty
+func (v *userRepo) NormalizeEmails(ctx context.Context) (_ int64, err error) {
+	dbCtx, end := v.getContext(ctx, "NormalizeEmails")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	filter := bson.D{{Key: "$expr", Value: bson.D{{Key: "$ne", Value: bson.A{"$email", normalizedEmailExpr}}}}}
+	update := mongo.Pipeline{
+		{{Key: "$set", Value: bson.D{{Key: "email", Value: normalizedEmailExpr}}}},
+	}
+
+	result, err := collection.UpdateMany(dbCtx, filter, update)
+	if err != nil {
+		return 0, fmt.Errorf("failed to normalize emails: %v", err)
+	}
+
+	return result.ModifiedCount, nil
+}
@@ -2,20 +2,49 @@ package userrepository
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"regexp"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/rom6n/otello/internal/app/domain/user"
+	"github.com/rom6n/otello/internal/pkg/database"
 	"go.mongodb.org/mongo-driver/v2/bson"
 	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var (
+	ErrUserNotFound = errors.New("user not found")
+	ErrEmailTaken   = errors.New("email is already in use")
 )
 
 type UserRepository interface {
 	CreateUser(ctx context.Context, user *user.User) error
 	GetUser(ctx context.Context, email string) (*user.User, error)
+	GetUserByUuid(ctx context.Context, userId uuid.UUID) (*user.User, error)
 	UpdateUserName(ctx context.Context, userId uuid.UUID, newName string) error
+	UpdateUserProfile(ctx context.Context, userId uuid.UUID, profile user.ProfileUpdate) error
 	UpdateUserRole(ctx context.Context, userId uuid.UUID, newRole string) error
+	UpdateUserPassword(ctx context.Context, userId uuid.UUID, newHashedPassword string) error
+	UpdateUserEmailVerified(ctx context.Context, userId uuid.UUID, verified bool) error
+	UpdateUserPendingEmail(ctx context.Context, userId uuid.UUID, pendingEmail string) error
+	UpdateUserEmail(ctx context.Context, userId uuid.UUID, newEmail string) error
+	IncrementUserTokenVersion(ctx context.Context, userId uuid.UUID) (int64, error)
+	UpdateUserDisabled(ctx context.Context, userId uuid.UUID, disabled bool) error
+	FindUsers(ctx context.Context, filter *user.FindUserFilterDTO) ([]user.User, int64, error)
+	AnonymizeUser(ctx context.Context, userId uuid.UUID) error
+	UpdateUserTotpPendingSecret(ctx context.Context, userId uuid.UUID, secret string) error
+	EnableUserTotp(ctx context.Context, userId uuid.UUID, secret string, counter int64, recoveryCodeHashes []string) error
+	UpdateUserTotpSecret(ctx context.Context, userId uuid.UUID, secret string) error
+	DisableUserTotp(ctx context.Context, userId uuid.UUID) error
+	UseUserTotpCounter(ctx context.Context, userId uuid.UUID, counter int64) (bool, error)
+	UseUserRecoveryCode(ctx context.Context, userId uuid.UUID, codeHash string) (bool, error)
+	UpdateUserRecoveryCodes(ctx context.Context, userId uuid.UUID, recoveryCodeHashes []string) error
+	EnsureEmailIndex(ctx context.Context) error
+	FindDuplicateEmails(ctx context.Context) ([]DuplicateEmail, error)
+	NormalizeEmails(ctx context.Context) (int64, error)
 }
 
 type userRepo struct {
@@ -34,21 +63,58 @@ func New(dbConnection *mongo.Client, dbName, collectionName string, timeout time
 	}
 }
 
-func (v *userRepo) getContext(ctx context.Context) (context.Context, context.CancelFunc) {
-	return context.WithTimeout(ctx, v.timeout)
+// MongoSchema is the validator and the indexes of the collection New works with,
+// the unique email index is left to EnsureEmailIndex because it needs normalized emails
+func MongoSchema(collectionName string) database.MongoCollection {
+	return database.MongoCollection{
+		Name: collectionName,
+		Validator: database.MongoSchemaObject(
+			[]string{"_id", "email", "email_verified", "password", "role", "token_version"},
+			bson.D{
+				{Key: "_id", Value: database.MongoUuid},
+				{Key: "name", Value: database.MongoString},
+				{Key: "email", Value: database.MongoString},
+				{Key: "email_verified", Value: database.MongoBool},
+				{Key: "password", Value: database.MongoString},
+				{Key: "role", Value: database.MongoString},
+				{Key: "pending_email", Value: database.MongoString},
+				{Key: "token_version", Value: database.MongoInteger},
+				{Key: "deleted_at", Value: database.MongoInteger},
+				{Key: "disabled_at", Value: database.MongoInteger},
+				{Key: "phone", Value: database.MongoString},
+				{Key: "language", Value: database.MongoString},
+				{Key: "currency", Value: database.MongoString},
+				{Key: "totp_enabled", Value: database.MongoBool},
+				{Key: "totp_secret", Value: database.MongoString},
+				{Key: "totp_pending_secret", Value: database.MongoString},
+				{Key: "totp_last_counter", Value: database.MongoInteger},
+				{Key: "recovery_code_hashes", Value: database.MongoArray(database.MongoString)},
+			},
+		),
+		Indexes: []database.MongoIndex{
+			{Name: "role", Keys: bson.D{{Key: "role", Value: 1}}},
+		},
+	}
+}
+
+func (v *userRepo) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return database.OperationContext(ctx, v.timeout, database.StorageMongo, "user", method)
 }
 
 func (v *userRepo) getCollection() *mongo.Collection {
 	return v.client.Database(v.dbName).Collection(v.collectionName)
 }
 
-func (v *userRepo) CreateUser(ctx context.Context, user *user.User) error {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *userRepo) CreateUser(ctx context.Context, user *user.User) (err error) {
+	dbCtx, end := v.getContext(ctx, "CreateUser")
+	defer end(&err)
 
 	collection := v.getCollection()
 
-	_, err := collection.InsertOne(dbCtx, user)
+	_, err = collection.InsertOne(dbCtx, user)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrEmailTaken
+	}
 	if err != nil {
 		return fmt.Errorf("failed to create user: %v", err)
 	}
@@ -56,23 +122,45 @@ func (v *userRepo) CreateUser(ctx context.Context, user *user.User) error {
 	return nil
 }
 
-func (v *userRepo) GetUser(ctx context.Context, email string) (*user.User, error) {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *userRepo) GetUser(ctx context.Context, email string) (_ *user.User, err error) {
+	dbCtx, end := v.getContext(ctx, "GetUser")
+	defer end(&err)
 
 	collection := v.getCollection()
 
 	var user user.User
-	if err := collection.FindOne(dbCtx, bson.D{{Key: "email", Value: email}}).Decode(&user); err != nil {
+	err = collection.FindOne(dbCtx, bson.D{{Key: "email", Value: email}}).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
 		return nil, fmt.Errorf("failed to get user from database: %v", err)
 	}
 
 	return &user, nil
 }
 
-func (v *userRepo) UpdateUserName(ctx context.Context, userId uuid.UUID, newName string) error {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *userRepo) GetUserByUuid(ctx context.Context, userId uuid.UUID) (_ *user.User, err error) {
+	dbCtx, end := v.getContext(ctx, "GetUserByUuid")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	var user user.User
+	err = collection.FindOne(dbCtx, bson.D{{Key: "_id", Value: userId}}).Decode(&user)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrUserNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user from database: %v", err)
+	}
+
+	return &user, nil
+}
+
+func (v *userRepo) UpdateUserName(ctx context.Context, userId uuid.UUID, newName string) (err error) {
+	dbCtx, end := v.getContext(ctx, "UpdateUserName")
+	defer end(&err)
 
 	collection := v.getCollection()
 
@@ -89,9 +177,55 @@ func (v *userRepo) UpdateUserName(ctx context.Context, userId uuid.UUID, newName
 	return nil
 }
 
-func (v *userRepo) UpdateUserRole(ctx context.Context, userId uuid.UUID, newRole string) error {
-	dbCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *userRepo) UpdateUserProfile(ctx context.Context, userId uuid.UUID, profile user.ProfileUpdate) (err error) {
+	dbCtx, end := v.getContext(ctx, "UpdateUserProfile")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	set := bson.M{}
+	unset := bson.M{}
+	for field, value := range map[string]*string{
+		"name":     profile.Name,
+		"phone":    profile.Phone,
+		"language": profile.Language,
+		"currency": profile.Currency,
+	} {
+		switch {
+		case value == nil:
+		case *value == "":
+			unset[field] = ""
+		default:
+			set[field] = *value
+		}
+	}
+
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if len(update) == 0 {
+		return nil
+	}
+
+	result, err := collection.UpdateByID(dbCtx, userId, update)
+	if err != nil {
+		return fmt.Errorf("failed to update profile: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// UpdateUserRole also ends every session, jwts carry the role and would keep the old one
+func (v *userRepo) UpdateUserRole(ctx context.Context, userId uuid.UUID, newRole string) (err error) {
+	dbCtx, end := v.getContext(ctx, "UpdateUserRole")
+	defer end(&err)
 
 	collection := v.getCollection()
 
@@ -99,6 +233,9 @@ func (v *userRepo) UpdateUserRole(ctx context.Context, userId uuid.UUID, newRole
 		"$set": bson.M{
 			"role": newRole,
 		},
+		"$inc": bson.M{
+			"token_version": 1,
+		},
 	}
 
 	if _, err := collection.UpdateByID(dbCtx, userId, update); err != nil {
@@ -107,3 +244,394 @@ func (v *userRepo) UpdateUserRole(ctx context.Context, userId uuid.UUID, newRole
 
 	return nil
 }
+
+func (v *userRepo) UpdateUserPassword(ctx context.Context, userId uuid.UUID, newHashedPassword string) (err error) {
+	dbCtx, end := v.getContext(ctx, "UpdateUserPassword")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	update := bson.M{
+		"$set": bson.M{
+			"password": newHashedPassword,
+		},
+	}
+
+	if _, err := collection.UpdateByID(dbCtx, userId, update); err != nil {
+		return fmt.Errorf("failed to update password: %v", err)
+	}
+
+	return nil
+}
+
+func (v *userRepo) UpdateUserEmailVerified(ctx context.Context, userId uuid.UUID, verified bool) (err error) {
+	dbCtx, end := v.getContext(ctx, "UpdateUserEmailVerified")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	update := bson.M{
+		"$set": bson.M{
+			"email_verified": verified,
+		},
+	}
+
+	if _, err := collection.UpdateByID(dbCtx, userId, update); err != nil {
+		return fmt.Errorf("failed to update email verification: %v", err)
+	}
+
+	return nil
+}
+
+func (v *userRepo) UpdateUserPendingEmail(ctx context.Context, userId uuid.UUID, pendingEmail string) (err error) {
+	dbCtx, end := v.getContext(ctx, "UpdateUserPendingEmail")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	update := bson.M{
+		"$set": bson.M{
+			"pending_email": pendingEmail,
+		},
+	}
+
+	if _, err := collection.UpdateByID(dbCtx, userId, update); err != nil {
+		return fmt.Errorf("failed to update pending email: %v", err)
+	}
+
+	return nil
+}
+
+// UpdateUserEmail replaces the email with an already verified one and clears the pending email
+func (v *userRepo) UpdateUserEmail(ctx context.Context, userId uuid.UUID, newEmail string) (err error) {
+	dbCtx, end := v.getContext(ctx, "UpdateUserEmail")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	update := bson.M{
+		"$set": bson.M{
+			"email":          newEmail,
+			"email_verified": true,
+		},
+		"$unset": bson.M{
+			"pending_email": "",
+		},
+	}
+
+	_, err = collection.UpdateByID(dbCtx, userId, update)
+	if mongo.IsDuplicateKeyError(err) {
+		return ErrEmailTaken
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update email: %v", err)
+	}
+
+	return nil
+}
+
+// UpdateUserDisabled also ends every session when the user is disabled, so they don't come back on enable
+func (v *userRepo) UpdateUserDisabled(ctx context.Context, userId uuid.UUID, disabled bool) (err error) {
+	dbCtx, end := v.getContext(ctx, "UpdateUserDisabled")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	update := bson.M{
+		"$unset": bson.M{
+			"disabled_at": "",
+		},
+	}
+	if disabled {
+		update = bson.M{
+			"$set": bson.M{
+				"disabled_at": time.Now().Unix(),
+			},
+			"$inc": bson.M{
+				"token_version": 1,
+			},
+		}
+	}
+
+	result, err := collection.UpdateByID(dbCtx, userId, update)
+	if err != nil {
+		return fmt.Errorf("failed to update disabled: %v", err)
+	}
+	if result.MatchedCount == 0 {
+		return ErrUserNotFound
+	}
+
+	return nil
+}
+
+// FindUsers returns a page of not deleted users and the total count for the filter
+func (v *userRepo) FindUsers(ctx context.Context, filter *user.FindUserFilterDTO) (_ []user.User, _ int64, err error) {
+	dbCtx, end := v.getContext(ctx, "FindUsers")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	findParams := bson.D{{Key: "deleted_at", Value: bson.D{{Key: "$exists", Value: false}}}}
+
+	if filter.Query != "" {
+		pattern := bson.Regex{Pattern: regexp.QuoteMeta(filter.Query), Options: "i"}
+		findParams = append(findParams, bson.E{Key: "$or", Value: bson.A{
+			bson.D{{Key: "email", Value: pattern}},
+			bson.D{{Key: "name", Value: pattern}},
+		}})
+	}
+
+	if filter.Role != "" {
+		findParams = append(findParams, bson.E{Key: "role", Value: filter.Role})
+	}
+
+	if filter.Disabled != nil {
+		findParams = append(findParams, bson.E{Key: "disabled_at", Value: bson.D{{Key: "$exists", Value: *filter.Disabled}}})
+	}
+
+	total, countErr := collection.CountDocuments(dbCtx, findParams)
+	if countErr != nil {
+		return nil, 0, fmt.Errorf("failed to count users: %v", countErr)
+	}
+
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "email", Value: 1}}).
+		SetSkip(filter.Offset).
+		SetLimit(filter.Limit)
+
+	cursor, err := collection.Find(dbCtx, findParams, findOptions)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to find users: %v", err)
+	}
+
+	users := []user.User{}
+	if err := cursor.All(dbCtx, &users); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode users: %v", err)
+	}
+
+	return users, total, nil
+}
+
+// IncrementUserTokenVersion invalidates every jwt issued to the user before the call
+func (v *userRepo) IncrementUserTokenVersion(ctx context.Context, userId uuid.UUID) (_ int64, err error) {
+	dbCtx, end := v.getContext(ctx, "IncrementUserTokenVersion")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	update := bson.M{
+		"$inc": bson.M{
+			"token_version": 1,
+		},
+	}
+
+	var updatedUser user.User
+	err = collection.FindOneAndUpdate(dbCtx, bson.D{{Key: "_id", Value: userId}}, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&updatedUser)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return 0, ErrUserNotFound
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to increment token version: %v", err)
+	}
+
+	return updatedUser.TokenVersion, nil
+}
+
+// AnonymizeUser wipes personal data but keeps the document, so rents and tickets still reference an existing user
+func (v *userRepo) AnonymizeUser(ctx context.Context, userId uuid.UUID) (err error) {
+	dbCtx, end := v.getContext(ctx, "AnonymizeUser")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	update := bson.M{
+		"$set": bson.M{
+			"name":           "Deleted user",
+			"email":          fmt.Sprintf("deleted-%v@deleted.invalid", userId),
+			"email_verified": false,
+			"password":       "",
+			"role":           user.RoleUser,
+			"deleted_at":     time.Now().Unix(),
+		},
+		"$unset": bson.M{
+			"pending_email":        "",
+			"phone":                "",
+			"language":             "",
+			"currency":             "",
+			"totp_enabled":         "",
+			"totp_secret":          "",
+			"totp_pending_secret":  "",
+			"totp_last_counter":    "",
+			"recovery_code_hashes": "",
+		},
+		"$inc": bson.M{
+			"token_version": 1,
+		},
+	}
+
+	if _, err := collection.UpdateByID(dbCtx, userId, update); err != nil {
+		return fmt.Errorf("failed to anonymize user: %v", err)
+	}
+
+	return nil
+}
+
+func (v *userRepo) UpdateUserTotpPendingSecret(ctx context.Context, userId uuid.UUID, secret string) (err error) {
+	dbCtx, end := v.getContext(ctx, "UpdateUserTotpPendingSecret")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	update := bson.M{
+		"$set": bson.M{
+			"totp_pending_secret": secret,
+		},
+	}
+
+	if _, err := collection.UpdateByID(dbCtx, userId, update); err != nil {
+		return fmt.Errorf("failed to update totp pending secret: %v", err)
+	}
+
+	return nil
+}
+
+func (v *userRepo) UpdateUserTotpSecret(ctx context.Context, userId uuid.UUID, secret string) (err error) {
+	dbCtx, end := v.getContext(ctx, "UpdateUserTotpSecret")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	update := bson.M{
+		"$set": bson.M{
+			"totp_secret": secret,
+		},
+	}
+
+	if _, err := collection.UpdateByID(dbCtx, userId, update); err != nil {
+		return fmt.Errorf("failed to update totp secret: %v", err)
+	}
+
+	return nil
+}
+
+func (v *userRepo) EnableUserTotp(ctx context.Context, userId uuid.UUID, secret string, counter int64, recoveryCodeHashes []string) (err error) {
+	dbCtx, end := v.getContext(ctx, "EnableUserTotp")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	update := bson.M{
+		"$set": bson.M{
+			"totp_enabled":         true,
+			"totp_secret":          secret,
+			"totp_last_counter":    counter,
+			"recovery_code_hashes": recoveryCodeHashes,
+		},
+		"$unset": bson.M{
+			"totp_pending_secret": "",
+		},
+	}
+
+	if _, err := collection.UpdateByID(dbCtx, userId, update); err != nil {
+		return fmt.Errorf("failed to enable totp: %v", err)
+	}
+
+	return nil
+}
+
+func (v *userRepo) DisableUserTotp(ctx context.Context, userId uuid.UUID) (err error) {
+	dbCtx, end := v.getContext(ctx, "DisableUserTotp")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	update := bson.M{
+		"$set": bson.M{
+			"totp_enabled": false,
+		},
+		"$unset": bson.M{
+			"totp_secret":          "",
+			"totp_pending_secret":  "",
+			"totp_last_counter":    "",
+			"recovery_code_hashes": "",
+		},
+	}
+
+	if _, err := collection.UpdateByID(dbCtx, userId, update); err != nil {
+		return fmt.Errorf("failed to disable totp: %v", err)
+	}
+
+	return nil
+}
+
+// UseUserTotpCounter stores the time step of an accepted code. It returns false if that step or a later one was already used
+func (v *userRepo) UseUserTotpCounter(ctx context.Context, userId uuid.UUID, counter int64) (_ bool, err error) {
+	dbCtx, end := v.getContext(ctx, "UseUserTotpCounter")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	filter := bson.D{
+		{Key: "_id", Value: userId},
+		{Key: "$or", Value: bson.A{
+			bson.D{{Key: "totp_last_counter", Value: bson.D{{Key: "$lt", Value: counter}}}},
+			bson.D{{Key: "totp_last_counter", Value: bson.D{{Key: "$exists", Value: false}}}},
+		}},
+	}
+	update := bson.M{
+		"$set": bson.M{
+			"totp_last_counter": counter,
+		},
+	}
+
+	result, err := collection.UpdateOne(dbCtx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to use totp code: %v", err)
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+// UseUserRecoveryCode removes the code so it works only once. It returns false if the user has no such code
+func (v *userRepo) UseUserRecoveryCode(ctx context.Context, userId uuid.UUID, codeHash string) (_ bool, err error) {
+	dbCtx, end := v.getContext(ctx, "UseUserRecoveryCode")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	filter := bson.D{
+		{Key: "_id", Value: userId},
+		{Key: "recovery_code_hashes", Value: codeHash},
+	}
+	update := bson.M{
+		"$pull": bson.M{
+			"recovery_code_hashes": codeHash,
+		},
+	}
+
+	result, err := collection.UpdateOne(dbCtx, filter, update)
+	if err != nil {
+		return false, fmt.Errorf("failed to use recovery code: %v", err)
+	}
+
+	return result.ModifiedCount == 1, nil
+}
+
+func (v *userRepo) UpdateUserRecoveryCodes(ctx context.Context, userId uuid.UUID, recoveryCodeHashes []string) (err error) {
+	dbCtx, end := v.getContext(ctx, "UpdateUserRecoveryCodes")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	update := bson.M{
+		"$set": bson.M{
+			"recovery_code_hashes": recoveryCodeHashes,
+		},
+	}
+
+	if _, err := collection.UpdateByID(dbCtx, userId, update); err != nil {
+		return fmt.Errorf("failed to update recovery codes: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,436 @@
+package userrepository
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/repositorytest"
+	"github.com/rom6n/otello/internal/app/domain/user"
+)
+
+func TestMemoryUserRepository(t *testing.T) {
+	testUserRepository(t, func(t *testing.T) UserRepository {
+		return NewMemory()
+	})
+}
+
+func TestMongoUserRepository(t *testing.T) {
+	testUserRepository(t, func(t *testing.T) UserRepository {
+		client, dbName := repositorytest.MongoDatabase(t)
+		repositorytest.EnsureMongoSchema(t, client, dbName, MongoSchema("users"))
+
+		repo := New(client, dbName, "users", 30*time.Second)
+		if err := repo.EnsureEmailIndex(context.Background()); err != nil {
+			t.Fatalf("EnsureEmailIndex: %v", err)
+		}
+
+		return repo
+	})
+}
+
+func TestPostgresUserRepository(t *testing.T) {
+	testUserRepository(t, func(t *testing.T) UserRepository {
+		return NewPostgres(repositorytest.PostgresDatabase(t), 30*time.Second)
+	})
+}
+
+func testUserRepository(t *testing.T, newRepo func(t *testing.T) UserRepository) {
+	ctx := context.Background()
+
+	createUser := func(t *testing.T, repo UserRepository, name, email string) *user.User {
+		t.Helper()
+
+		created := user.NewUser(name, email, "hash")
+		if err := repo.CreateUser(ctx, created); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+		return created
+	}
+
+	getUser := func(t *testing.T, repo UserRepository, userUuid uuid.UUID) *user.User {
+		t.Helper()
+
+		found, err := repo.GetUserByUuid(ctx, userUuid)
+		if err != nil {
+			t.Fatalf("GetUserByUuid: %v", err)
+		}
+		return found
+	}
+
+	t.Run("create and get", func(t *testing.T) {
+		repo := newRepo(t)
+		created := createUser(t, repo, "Ivan", "ivan@example.com")
+
+		byUuid := getUser(t, repo, created.Uuid)
+		if !reflect.DeepEqual(byUuid, created) {
+			t.Fatalf("GetUserByUuid = %+v, want %+v", *byUuid, *created)
+		}
+
+		byEmail, err := repo.GetUser(ctx, "ivan@example.com")
+		if err != nil {
+			t.Fatalf("GetUser: %v", err)
+		}
+		if byEmail.Uuid != created.Uuid {
+			t.Fatalf("GetUser = %v, want %v", byEmail.Uuid, created.Uuid)
+		}
+
+		if _, err := repo.GetUser(ctx, "petr@example.com"); !errors.Is(err, ErrUserNotFound) {
+			t.Fatalf("GetUser of a missing user error = %v, want ErrUserNotFound", err)
+		}
+		if _, err := repo.GetUserByUuid(ctx, uuid.New()); !errors.Is(err, ErrUserNotFound) {
+			t.Fatalf("GetUserByUuid of a missing user error = %v, want ErrUserNotFound", err)
+		}
+	})
+
+	t.Run("emails are unique", func(t *testing.T) {
+		repo := newRepo(t)
+		createUser(t, repo, "Ivan", "ivan@example.com")
+		petr := createUser(t, repo, "Petr", "petr@example.com")
+
+		if err := repo.CreateUser(ctx, user.NewUser("Ivan 2", "Ivan@Example.com", "hash")); !errors.Is(err, ErrEmailTaken) {
+			t.Fatalf("CreateUser with a taken email error = %v, want ErrEmailTaken", err)
+		}
+		if err := repo.UpdateUserEmail(ctx, petr.Uuid, "ivan@example.com"); !errors.Is(err, ErrEmailTaken) {
+			t.Fatalf("UpdateUserEmail to a taken email error = %v, want ErrEmailTaken", err)
+		}
+
+		if found := getUser(t, repo, petr.Uuid); found.Email != "petr@example.com" {
+			t.Fatalf("email = %v, want it unchanged", found.Email)
+		}
+	})
+
+	t.Run("update fields", func(t *testing.T) {
+		repo := newRepo(t)
+		created := createUser(t, repo, "Ivan", "ivan@example.com")
+
+		if err := repo.UpdateUserName(ctx, created.Uuid, "Ivan Ivanov"); err != nil {
+			t.Fatalf("UpdateUserName: %v", err)
+		}
+		if err := repo.UpdateUserRole(ctx, created.Uuid, string(user.RoleHotelManager)); err != nil {
+			t.Fatalf("UpdateUserRole: %v", err)
+		}
+		if err := repo.UpdateUserPassword(ctx, created.Uuid, "new hash"); err != nil {
+			t.Fatalf("UpdateUserPassword: %v", err)
+		}
+		if err := repo.UpdateUserPendingEmail(ctx, created.Uuid, "new@example.com"); err != nil {
+			t.Fatalf("UpdateUserPendingEmail: %v", err)
+		}
+
+		found := getUser(t, repo, created.Uuid)
+		if found.Name != "Ivan Ivanov" || found.Role != user.RoleHotelManager || found.Password != "new hash" || found.PendingEmail != "new@example.com" || found.EmailVerified {
+			t.Fatalf("user after updates = %+v", *found)
+		}
+		// jwts carry the role, so a role change ends the sessions
+		if found.TokenVersion != 1 {
+			t.Fatalf("token version after a role change = %v, want 1", found.TokenVersion)
+		}
+
+		if err := repo.UpdateUserEmail(ctx, created.Uuid, "new@example.com"); err != nil {
+			t.Fatalf("UpdateUserEmail: %v", err)
+		}
+
+		found = getUser(t, repo, created.Uuid)
+		if found.Email != "new@example.com" || found.PendingEmail != "" || !found.EmailVerified {
+			t.Fatalf("user after email change = %+v", *found)
+		}
+
+		if err := repo.UpdateUserEmailVerified(ctx, created.Uuid, false); err != nil {
+			t.Fatalf("UpdateUserEmailVerified: %v", err)
+		}
+		if found := getUser(t, repo, created.Uuid); found.EmailVerified {
+			t.Fatalf("email is still verified")
+		}
+	})
+
+	t.Run("update profile", func(t *testing.T) {
+		repo := newRepo(t)
+		created := createUser(t, repo, "Ivan", "ivan@example.com")
+
+		err := repo.UpdateUserProfile(ctx, created.Uuid, user.ProfileUpdate{
+			Phone:    repositorytest.Ptr("+79991234567"),
+			Language: repositorytest.Ptr("ru"),
+			Currency: repositorytest.Ptr("RUB"),
+		})
+		if err != nil {
+			t.Fatalf("UpdateUserProfile: %v", err)
+		}
+
+		if err := repo.UpdateUserProfile(ctx, created.Uuid, user.ProfileUpdate{Language: repositorytest.Ptr("")}); err != nil {
+			t.Fatalf("UpdateUserProfile: %v", err)
+		}
+
+		found := getUser(t, repo, created.Uuid)
+		if found.Name != "Ivan" || found.Phone != "+79991234567" || found.Language != "" || found.Currency != "RUB" {
+			t.Fatalf("profile = %+v", *found)
+		}
+
+		if err := repo.UpdateUserProfile(ctx, uuid.New(), user.ProfileUpdate{Name: repositorytest.Ptr("Petr")}); !errors.Is(err, ErrUserNotFound) {
+			t.Fatalf("UpdateUserProfile of a missing user error = %v, want ErrUserNotFound", err)
+		}
+	})
+
+	t.Run("token version, disable and enable", func(t *testing.T) {
+		repo := newRepo(t)
+		created := createUser(t, repo, "Ivan", "ivan@example.com")
+
+		version, err := repo.IncrementUserTokenVersion(ctx, created.Uuid)
+		if err != nil {
+			t.Fatalf("IncrementUserTokenVersion: %v", err)
+		}
+		if version != 1 {
+			t.Fatalf("token version = %v, want 1", version)
+		}
+		if _, err := repo.IncrementUserTokenVersion(ctx, uuid.New()); !errors.Is(err, ErrUserNotFound) {
+			t.Fatalf("IncrementUserTokenVersion of a missing user error = %v, want ErrUserNotFound", err)
+		}
+
+		if err := repo.UpdateUserDisabled(ctx, created.Uuid, true); err != nil {
+			t.Fatalf("UpdateUserDisabled: %v", err)
+		}
+		found := getUser(t, repo, created.Uuid)
+		if !found.IsDisabled() || found.TokenVersion != 2 {
+			t.Fatalf("disabled user = %+v, want disabled with token version 2", *found)
+		}
+
+		if err := repo.UpdateUserDisabled(ctx, created.Uuid, false); err != nil {
+			t.Fatalf("UpdateUserDisabled: %v", err)
+		}
+		found = getUser(t, repo, created.Uuid)
+		if found.IsDisabled() || found.TokenVersion != 2 {
+			t.Fatalf("enabled user = %+v, want enabled with token version 2", *found)
+		}
+
+		if err := repo.UpdateUserDisabled(ctx, uuid.New(), true); !errors.Is(err, ErrUserNotFound) {
+			t.Fatalf("UpdateUserDisabled of a missing user error = %v, want ErrUserNotFound", err)
+		}
+	})
+
+	t.Run("find users", func(t *testing.T) {
+		repo := newRepo(t)
+		anna := createUser(t, repo, "Anna", "anna@example.com")
+		boris := createUser(t, repo, "Boris", "boris@mail.com")
+		clara := createUser(t, repo, "Clara Mail", "clara@example.com")
+		deleted := createUser(t, repo, "Dmitry", "dmitry@mail.com")
+
+		if err := repo.UpdateUserRole(ctx, boris.Uuid, string(user.RoleSupportAgent)); err != nil {
+			t.Fatalf("UpdateUserRole: %v", err)
+		}
+		if err := repo.UpdateUserDisabled(ctx, clara.Uuid, true); err != nil {
+			t.Fatalf("UpdateUserDisabled: %v", err)
+		}
+		if err := repo.AnonymizeUser(ctx, deleted.Uuid); err != nil {
+			t.Fatalf("AnonymizeUser: %v", err)
+		}
+
+		tests := []struct {
+			name      string
+			filter    user.FindUserFilterDTO
+			want      []*user.User
+			wantTotal int64
+		}{
+			{name: "all sorted by email without deleted", want: []*user.User{anna, boris, clara}, wantTotal: 3},
+			{name: "query matches email or name ignoring case", filter: user.FindUserFilterDTO{Query: "MAIL"}, want: []*user.User{boris, clara}, wantTotal: 2},
+			{name: "query is not a regexp", filter: user.FindUserFilterDTO{Query: "a.*"}, want: nil, wantTotal: 0},
+			{name: "role", filter: user.FindUserFilterDTO{Role: user.RoleSupportAgent}, want: []*user.User{boris}, wantTotal: 1},
+			{name: "disabled", filter: user.FindUserFilterDTO{Disabled: repositorytest.Ptr(true)}, want: []*user.User{clara}, wantTotal: 1},
+			{name: "not disabled", filter: user.FindUserFilterDTO{Disabled: repositorytest.Ptr(false)}, want: []*user.User{anna, boris}, wantTotal: 2},
+			{name: "page", filter: user.FindUserFilterDTO{Offset: 1, Limit: 1}, want: []*user.User{boris}, wantTotal: 3},
+			{name: "page after the end", filter: user.FindUserFilterDTO{Offset: 5, Limit: 1}, want: nil, wantTotal: 3},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				users, total, err := repo.FindUsers(ctx, &tt.filter)
+				if err != nil {
+					t.Fatalf("FindUsers: %v", err)
+				}
+				if total != tt.wantTotal {
+					t.Fatalf("total = %v, want %v", total, tt.wantTotal)
+				}
+				if len(users) != len(tt.want) {
+					t.Fatalf("got %v users, want %v: %+v", len(users), len(tt.want), users)
+				}
+				for i := range users {
+					if users[i].Uuid != tt.want[i].Uuid {
+						t.Fatalf("user %v = %v, want %v", i, users[i].Email, tt.want[i].Email)
+					}
+				}
+			})
+		}
+	})
+
+	t.Run("anonymize", func(t *testing.T) {
+		repo := newRepo(t)
+		created := createUser(t, repo, "Ivan", "ivan@example.com")
+
+		if err := repo.UpdateUserProfile(ctx, created.Uuid, user.ProfileUpdate{Phone: repositorytest.Ptr("+79991234567")}); err != nil {
+			t.Fatalf("UpdateUserProfile: %v", err)
+		}
+		if err := repo.EnableUserTotp(ctx, created.Uuid, "secret", 10, []string{"code"}); err != nil {
+			t.Fatalf("EnableUserTotp: %v", err)
+		}
+		if err := repo.AnonymizeUser(ctx, created.Uuid); err != nil {
+			t.Fatalf("AnonymizeUser: %v", err)
+		}
+
+		found := getUser(t, repo, created.Uuid)
+		if !found.IsDeleted() || found.Email == "ivan@example.com" || found.Name != "Deleted user" || found.Password != "" || found.Phone != "" ||
+			found.TotpEnabled || found.TotpSecret != "" || len(found.RecoveryCodeHashes) != 0 || found.TokenVersion != 1 {
+			t.Fatalf("anonymized user = %+v", *found)
+		}
+
+		// the email is free again
+		createUser(t, repo, "Ivan", "ivan@example.com")
+	})
+
+	t.Run("totp", func(t *testing.T) {
+		repo := newRepo(t)
+		created := createUser(t, repo, "Ivan", "ivan@example.com")
+
+		if err := repo.UpdateUserTotpPendingSecret(ctx, created.Uuid, "pending"); err != nil {
+			t.Fatalf("UpdateUserTotpPendingSecret: %v", err)
+		}
+		if found := getUser(t, repo, created.Uuid); found.TotpPendingSecret != "pending" {
+			t.Fatalf("pending secret = %q, want pending", found.TotpPendingSecret)
+		}
+
+		if err := repo.EnableUserTotp(ctx, created.Uuid, "secret", 10, []string{"code1", "code2"}); err != nil {
+			t.Fatalf("EnableUserTotp: %v", err)
+		}
+		found := getUser(t, repo, created.Uuid)
+		if !found.TotpEnabled || found.TotpSecret != "secret" || found.TotpPendingSecret != "" || found.TotpLastCounter != 10 || len(found.RecoveryCodeHashes) != 2 {
+			t.Fatalf("user with totp = %+v", *found)
+		}
+
+		if err := repo.UpdateUserTotpSecret(ctx, created.Uuid, "new-secret"); err != nil {
+			t.Fatalf("UpdateUserTotpSecret: %v", err)
+		}
+		if found := getUser(t, repo, created.Uuid); found.TotpSecret != "new-secret" || found.TotpLastCounter != 10 || len(found.RecoveryCodeHashes) != 2 {
+			t.Fatalf("user with updated totp secret = %+v", *found)
+		}
+
+		for _, tt := range []struct {
+			counter int64
+			want    bool
+		}{{10, false}, {9, false}, {11, true}, {11, false}} {
+			used, err := repo.UseUserTotpCounter(ctx, created.Uuid, tt.counter)
+			if err != nil {
+				t.Fatalf("UseUserTotpCounter: %v", err)
+			}
+			if used != tt.want {
+				t.Fatalf("UseUserTotpCounter(%v) = %v, want %v", tt.counter, used, tt.want)
+			}
+		}
+
+		for _, tt := range []struct {
+			code string
+			want bool
+		}{{"code1", true}, {"code1", false}, {"unknown", false}} {
+			used, err := repo.UseUserRecoveryCode(ctx, created.Uuid, tt.code)
+			if err != nil {
+				t.Fatalf("UseUserRecoveryCode: %v", err)
+			}
+			if used != tt.want {
+				t.Fatalf("UseUserRecoveryCode(%v) = %v, want %v", tt.code, used, tt.want)
+			}
+		}
+
+		if err := repo.UpdateUserRecoveryCodes(ctx, created.Uuid, []string{"code3"}); err != nil {
+			t.Fatalf("UpdateUserRecoveryCodes: %v", err)
+		}
+		if found := getUser(t, repo, created.Uuid); !reflect.DeepEqual(found.RecoveryCodeHashes, []string{"code3"}) {
+			t.Fatalf("recovery codes = %v, want [code3]", found.RecoveryCodeHashes)
+		}
+
+		if err := repo.DisableUserTotp(ctx, created.Uuid); err != nil {
+			t.Fatalf("DisableUserTotp: %v", err)
+		}
+		found = getUser(t, repo, created.Uuid)
+		if found.TotpEnabled || found.TotpSecret != "" || found.TotpLastCounter != 0 || len(found.RecoveryCodeHashes) != 0 {
+			t.Fatalf("user without totp = %+v", *found)
+		}
+
+		used, err := repo.UseUserTotpCounter(ctx, created.Uuid, 1)
+		if err != nil {
+			t.Fatalf("UseUserTotpCounter: %v", err)
+		}
+		if !used {
+			t.Fatalf("UseUserTotpCounter after disable = false, want true")
+		}
+	})
+
+	t.Run("normalize emails", func(t *testing.T) {
+		repo := newRepo(t)
+
+		duplicates, err := repo.FindDuplicateEmails(ctx)
+		if err != nil {
+			t.Fatalf("FindDuplicateEmails: %v", err)
+		}
+		if len(duplicates) != 0 {
+			t.Fatalf("FindDuplicateEmails = %+v, want none", duplicates)
+		}
+
+		// users created before emails were normalized
+		legacy := user.NewUser("Ivan", "", "hash")
+		legacy.Email = " Ivan@Example.com"
+		if err := repo.CreateUser(ctx, legacy); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+		createUser(t, repo, "Petr", "petr@example.com")
+
+		normalized, err := repo.NormalizeEmails(ctx)
+		if err != nil {
+			t.Fatalf("NormalizeEmails: %v", err)
+		}
+		if normalized != 1 {
+			t.Fatalf("NormalizeEmails = %v, want 1", normalized)
+		}
+		if found := getUser(t, repo, legacy.Uuid); found.Email != "ivan@example.com" {
+			t.Fatalf("email = %q, want ivan@example.com", found.Email)
+		}
+
+		// a rerun finds nothing left to change
+		normalized, err = repo.NormalizeEmails(ctx)
+		if err != nil {
+			t.Fatalf("NormalizeEmails again: %v", err)
+		}
+		if normalized != 0 {
+			t.Fatalf("NormalizeEmails again = %v, want 0", normalized)
+		}
+		if found := getUser(t, repo, legacy.Uuid); found.Email != "ivan@example.com" {
+			t.Fatalf("email after the rerun = %q, want ivan@example.com", found.Email)
+		}
+	})
+
+	t.Run("find duplicate emails", func(t *testing.T) {
+		repo := newRepo(t)
+
+		// the same emails in other case or with spaces around, stored before emails were normalized
+		for _, email := range []string{"Ivan@Example.com", "ivan@example.com", " ivan@example.com ", "petr@example.com\t", "PETR@example.com"} {
+			legacy := user.NewUser("Ivan", "", "hash")
+			legacy.Email = email
+			if err := repo.CreateUser(ctx, legacy); err != nil {
+				t.Fatalf("CreateUser %q: %v", email, err)
+			}
+		}
+		createUser(t, repo, "Anna", "anna@example.com")
+
+		duplicates, err := repo.FindDuplicateEmails(ctx)
+		if err != nil {
+			t.Fatalf("FindDuplicateEmails: %v", err)
+		}
+		if len(duplicates) != 2 ||
+			duplicates[0].Email != "ivan@example.com" || len(duplicates[0].UserUuids) != 3 || len(duplicates[0].Emails) != 3 ||
+			duplicates[1].Email != "petr@example.com" || len(duplicates[1].UserUuids) != 2 || len(duplicates[1].Emails) != 2 {
+			t.Fatalf("FindDuplicateEmails = %+v, want the groups of ivan@example.com and petr@example.com", duplicates)
+		}
+
+		if _, err := repo.NormalizeEmails(ctx); err == nil {
+			t.Fatalf("NormalizeEmails with duplicates: want error")
+		}
+	})
+}
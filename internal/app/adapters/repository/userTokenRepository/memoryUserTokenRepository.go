@@ -0,0 +1,86 @@
+package usertokenrepository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/memorystore"
+	"github.com/rom6n/otello/internal/app/domain/usertoken"
+)
+
+type memoryUserTokenRepo struct {
+	userTokens *memorystore.Store[uuid.UUID, usertoken.UserToken]
+}
+
+// NewMemory keeps user tokens in process memory, for tests and running without MongoDB
+func NewMemory() UserTokenRepository {
+	return &memoryUserTokenRepo{
+		userTokens: memorystore.New(func(t *usertoken.UserToken) uuid.UUID { return t.Uuid }, cloneUserToken),
+	}
+}
+
+func cloneUserToken(userToken usertoken.UserToken) usertoken.UserToken {
+	if userToken.UsedAt != nil {
+		usedAt := *userToken.UsedAt
+		userToken.UsedAt = &usedAt
+	}
+	return userToken
+}
+
+func (v *memoryUserTokenRepo) CreateUserToken(ctx context.Context, userToken *usertoken.UserToken) error {
+	if err := v.userTokens.Insert(*userToken); err != nil {
+		return fmt.Errorf("failed to create user token: %v", err)
+	}
+
+	return nil
+}
+
+func (v *memoryUserTokenRepo) GetUserToken(ctx context.Context, tokenHash string, purpose usertoken.TokenPurpose) (*usertoken.UserToken, error) {
+	userToken, ok := v.userTokens.FindOne(validTokenMatcher(tokenHash, purpose, time.Now().Unix()))
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+
+	return &userToken, nil
+}
+
+func (v *memoryUserTokenRepo) UseUserToken(ctx context.Context, tokenHash string, purpose usertoken.TokenPurpose) (*usertoken.UserToken, error) {
+	now := time.Now().Unix()
+
+	userToken, used, err := v.userTokens.UpdateOne(validTokenMatcher(tokenHash, purpose, now), func(t *usertoken.UserToken) bool {
+		t.UsedAt = &now
+		return true
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to use user token: %v", err)
+	}
+	if !used {
+		return nil, ErrInvalidToken
+	}
+
+	return &userToken, nil
+}
+
+func (v *memoryUserTokenRepo) InvalidateUserTokens(ctx context.Context, userUuid uuid.UUID, purpose usertoken.TokenPurpose) error {
+	now := time.Now().Unix()
+
+	_, err := v.userTokens.UpdateMany(func(t *usertoken.UserToken) bool {
+		return t.UserUuid == userUuid && t.Purpose == purpose && t.UsedAt == nil
+	}, func(t *usertoken.UserToken) bool {
+		t.UsedAt = &now
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("failed to invalidate user tokens: %v", err)
+	}
+
+	return nil
+}
+
+func validTokenMatcher(tokenHash string, purpose usertoken.TokenPurpose, now int64) func(*usertoken.UserToken) bool {
+	return func(t *usertoken.UserToken) bool {
+		return t.TokenHash == tokenHash && t.Purpose == purpose && t.UsedAt == nil && t.ExpiresAt > now
+	}
+}
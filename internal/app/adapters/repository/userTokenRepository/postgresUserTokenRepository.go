@@ -0,0 +1,111 @@
+package usertokenrepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rom6n/otello/internal/app/domain/usertoken"
+	"github.com/rom6n/otello/internal/pkg/database"
+)
+
+const userTokenColumns = "id, user_id, purpose, token_hash, created_at, expires_at, used_at"
+
+type postgresUserTokenRepo struct {
+	pool    *pgxpool.Pool
+	timeout time.Duration
+}
+
+// NewPostgres keeps tokens in the user_tokens table, the schema comes from database.MigratePostgres
+func NewPostgres(pool *pgxpool.Pool, timeout time.Duration) UserTokenRepository {
+	return &postgresUserTokenRepo{
+		pool:    pool,
+		timeout: timeout,
+	}
+}
+
+func (v *postgresUserTokenRepo) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return database.OperationContext(ctx, v.timeout, database.StoragePostgres, "user_token", method)
+}
+
+func scanUserToken(row pgx.Row) (*usertoken.UserToken, error) {
+	var userToken usertoken.UserToken
+	err := row.Scan(&userToken.Uuid, &userToken.UserUuid, &userToken.Purpose, &userToken.TokenHash, &userToken.CreatedAt, &userToken.ExpiresAt, &userToken.UsedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &userToken, nil
+}
+
+func (v *postgresUserTokenRepo) CreateUserToken(ctx context.Context, userToken *usertoken.UserToken) (err error) {
+	dbCtx, end := v.getContext(ctx, "CreateUserToken")
+	defer end(&err)
+
+	_, err = v.pool.Exec(dbCtx, "INSERT INTO user_tokens ("+userTokenColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7)",
+		userToken.Uuid, userToken.UserUuid, userToken.Purpose, userToken.TokenHash, userToken.CreatedAt, userToken.ExpiresAt, userToken.UsedAt)
+	if err != nil {
+		return fmt.Errorf("failed to create user token: %v", err)
+	}
+
+	return nil
+}
+
+// GetUserToken returns an unused, unexpired token without using it
+func (v *postgresUserTokenRepo) GetUserToken(ctx context.Context, tokenHash string, purpose usertoken.TokenPurpose) (_ *usertoken.UserToken, err error) {
+	dbCtx, end := v.getContext(ctx, "GetUserToken")
+	defer end(&err)
+
+	userToken, err := scanUserToken(v.pool.QueryRow(dbCtx,
+		"SELECT "+userTokenColumns+" FROM user_tokens WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3 LIMIT 1",
+		tokenHash, purpose, time.Now().Unix()))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user token: %v", err)
+	}
+
+	return userToken, nil
+}
+
+// UseUserToken atomically marks an unused, unexpired token as used and returns it
+func (v *postgresUserTokenRepo) UseUserToken(ctx context.Context, tokenHash string, purpose usertoken.TokenPurpose) (_ *usertoken.UserToken, err error) {
+	dbCtx, end := v.getContext(ctx, "UseUserToken")
+	defer end(&err)
+
+	query := `UPDATE user_tokens SET used_at = $3
+		WHERE id = (
+			SELECT id FROM user_tokens
+			WHERE token_hash = $1 AND purpose = $2 AND used_at IS NULL AND expires_at > $3
+			LIMIT 1 FOR UPDATE
+		) AND used_at IS NULL
+		RETURNING ` + userTokenColumns
+
+	userToken, err := scanUserToken(v.pool.QueryRow(dbCtx, query, tokenHash, purpose, time.Now().Unix()))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to use user token: %v", err)
+	}
+
+	return userToken, nil
+}
+
+func (v *postgresUserTokenRepo) InvalidateUserTokens(ctx context.Context, userUuid uuid.UUID, purpose usertoken.TokenPurpose) (err error) {
+	dbCtx, end := v.getContext(ctx, "InvalidateUserTokens")
+	defer end(&err)
+
+	_, err = v.pool.Exec(dbCtx, "UPDATE user_tokens SET used_at = $3 WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL",
+		userUuid, purpose, time.Now().Unix())
+	if err != nil {
+		return fmt.Errorf("failed to invalidate user tokens: %v", err)
+	}
+
+	return nil
+}
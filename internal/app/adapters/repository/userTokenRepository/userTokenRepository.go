@@ -0,0 +1,160 @@
+package usertokenrepository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/domain/usertoken"
+	"github.com/rom6n/otello/internal/pkg/database"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+var ErrInvalidToken = errors.New("token is invalid, expired or already used")
+
+type UserTokenRepository interface {
+	CreateUserToken(ctx context.Context, userToken *usertoken.UserToken) error
+	GetUserToken(ctx context.Context, tokenHash string, purpose usertoken.TokenPurpose) (*usertoken.UserToken, error)
+	UseUserToken(ctx context.Context, tokenHash string, purpose usertoken.TokenPurpose) (*usertoken.UserToken, error)
+	InvalidateUserTokens(ctx context.Context, userUuid uuid.UUID, purpose usertoken.TokenPurpose) error
+}
+
+type userTokenRepo struct {
+	client         *mongo.Client
+	dbName         string
+	collectionName string
+	timeout        time.Duration
+}
+
+func New(dbConnection *mongo.Client, dbName, collectionName string, timeout time.Duration) UserTokenRepository {
+	return &userTokenRepo{
+		client:         dbConnection,
+		dbName:         dbName,
+		collectionName: collectionName,
+		timeout:        timeout,
+	}
+}
+
+// MongoSchema is the validator and the indexes of the collection New works with
+func MongoSchema(collectionName string) database.MongoCollection {
+	return database.MongoCollection{
+		Name: collectionName,
+		Validator: database.MongoSchemaObject(
+			[]string{"_id", "user_id", "purpose", "token_hash", "created_at", "expires_at", "used_at"},
+			bson.D{
+				{Key: "_id", Value: database.MongoUuid},
+				{Key: "user_id", Value: database.MongoUuid},
+				{Key: "purpose", Value: database.MongoString},
+				{Key: "token_hash", Value: database.MongoString},
+				{Key: "created_at", Value: database.MongoInteger},
+				{Key: "expires_at", Value: database.MongoInteger},
+				{Key: "used_at", Value: database.MongoType("int", "long", "null")},
+			},
+		),
+		Indexes: []database.MongoIndex{
+			{Name: "token_hash_purpose", Keys: bson.D{{Key: "token_hash", Value: 1}, {Key: "purpose", Value: 1}}},
+			{Name: "user_id_purpose", Keys: bson.D{{Key: "user_id", Value: 1}, {Key: "purpose", Value: 1}}},
+		},
+	}
+}
+
+func (v *userTokenRepo) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return database.OperationContext(ctx, v.timeout, database.StorageMongo, "user_token", method)
+}
+
+func (v *userTokenRepo) getCollection() *mongo.Collection {
+	return v.client.Database(v.dbName).Collection(v.collectionName)
+}
+
+func (v *userTokenRepo) CreateUserToken(ctx context.Context, userToken *usertoken.UserToken) (err error) {
+	dbCtx, end := v.getContext(ctx, "CreateUserToken")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	_, err = collection.InsertOne(dbCtx, userToken)
+	if err != nil {
+		return fmt.Errorf("failed to create user token: %v", err)
+	}
+
+	return nil
+}
+
+// GetUserToken returns an unused, unexpired token without using it
+func (v *userTokenRepo) GetUserToken(ctx context.Context, tokenHash string, purpose usertoken.TokenPurpose) (_ *usertoken.UserToken, err error) {
+	dbCtx, end := v.getContext(ctx, "GetUserToken")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	filter := bson.D{
+		{Key: "token_hash", Value: tokenHash},
+		{Key: "purpose", Value: purpose},
+		{Key: "used_at", Value: nil},
+		{Key: "expires_at", Value: bson.D{{Key: "$gt", Value: time.Now().Unix()}}},
+	}
+
+	var userToken usertoken.UserToken
+	err = collection.FindOne(dbCtx, filter).Decode(&userToken)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user token: %v", err)
+	}
+
+	return &userToken, nil
+}
+
+// UseUserToken atomically marks an unused, unexpired token as used and returns it
+func (v *userTokenRepo) UseUserToken(ctx context.Context, tokenHash string, purpose usertoken.TokenPurpose) (_ *usertoken.UserToken, err error) {
+	dbCtx, end := v.getContext(ctx, "UseUserToken")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	now := time.Now().Unix()
+
+	filter := bson.D{
+		{Key: "token_hash", Value: tokenHash},
+		{Key: "purpose", Value: purpose},
+		{Key: "used_at", Value: nil},
+		{Key: "expires_at", Value: bson.D{{Key: "$gt", Value: now}}},
+	}
+	update := bson.M{"$set": bson.M{"used_at": now}}
+
+	var userToken usertoken.UserToken
+	err = collection.FindOneAndUpdate(dbCtx, filter, update, options.FindOneAndUpdate().SetReturnDocument(options.After)).Decode(&userToken)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, ErrInvalidToken
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to use user token: %v", err)
+	}
+
+	return &userToken, nil
+}
+
+func (v *userTokenRepo) InvalidateUserTokens(ctx context.Context, userUuid uuid.UUID, purpose usertoken.TokenPurpose) (err error) {
+	dbCtx, end := v.getContext(ctx, "InvalidateUserTokens")
+	defer end(&err)
+
+	collection := v.getCollection()
+
+	filter := bson.D{
+		{Key: "user_id", Value: userUuid},
+		{Key: "purpose", Value: purpose},
+		{Key: "used_at", Value: nil},
+	}
+	update := bson.M{"$set": bson.M{"used_at": time.Now().Unix()}}
+
+	if _, err := collection.UpdateMany(dbCtx, filter, update); err != nil {
+		return fmt.Errorf("failed to invalidate user tokens: %v", err)
+	}
+
+	return nil
+}
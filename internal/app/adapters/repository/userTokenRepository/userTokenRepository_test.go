@@ -0,0 +1,126 @@
+package usertokenrepository
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rom6n/otello/internal/app/adapters/repository/repositorytest"
+	"github.com/rom6n/otello/internal/app/domain/usertoken"
+)
+
+func TestMemoryUserTokenRepository(t *testing.T) {
+	testUserTokenRepository(t, func(t *testing.T) (UserTokenRepository, repositorytest.Fixtures) {
+		return NewMemory(), repositorytest.NoFixtures{}
+	})
+}
+
+func TestMongoUserTokenRepository(t *testing.T) {
+	testUserTokenRepository(t, func(t *testing.T) (UserTokenRepository, repositorytest.Fixtures) {
+		client, dbName := repositorytest.MongoDatabase(t)
+		repositorytest.EnsureMongoSchema(t, client, dbName, MongoSchema("userTokens"))
+		return New(client, dbName, "userTokens", 30*time.Second), repositorytest.NoFixtures{}
+	})
+}
+
+func TestPostgresUserTokenRepository(t *testing.T) {
+	testUserTokenRepository(t, func(t *testing.T) (UserTokenRepository, repositorytest.Fixtures) {
+		pool := repositorytest.PostgresDatabase(t)
+		return NewPostgres(pool, 30*time.Second), repositorytest.PostgresFixtures(pool)
+	})
+}
+
+func testUserTokenRepository(t *testing.T, newRepo func(t *testing.T) (UserTokenRepository, repositorytest.Fixtures)) {
+	ctx := context.Background()
+
+	t.Run("get does not use the token, use works once", func(t *testing.T) {
+		repo, fixtures := newRepo(t)
+
+		created := usertoken.NewUserToken(fixtures.User(t), usertoken.PurposePasswordReset, "hash", time.Hour)
+		if err := repo.CreateUserToken(ctx, created); err != nil {
+			t.Fatalf("CreateUserToken: %v", err)
+		}
+
+		for i := 0; i < 2; i++ {
+			found, err := repo.GetUserToken(ctx, "hash", usertoken.PurposePasswordReset)
+			if err != nil {
+				t.Fatalf("GetUserToken: %v", err)
+			}
+			if found.Uuid != created.Uuid || found.UsedAt != nil {
+				t.Fatalf("GetUserToken = %+v, want unused %v", *found, created.Uuid)
+			}
+		}
+
+		used, err := repo.UseUserToken(ctx, "hash", usertoken.PurposePasswordReset)
+		if err != nil {
+			t.Fatalf("UseUserToken: %v", err)
+		}
+		if used.Uuid != created.Uuid || used.UsedAt == nil {
+			t.Fatalf("UseUserToken = %+v, want used %v", *used, created.Uuid)
+		}
+
+		if _, err := repo.UseUserToken(ctx, "hash", usertoken.PurposePasswordReset); !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("second UseUserToken error = %v, want ErrInvalidToken", err)
+		}
+		if _, err := repo.GetUserToken(ctx, "hash", usertoken.PurposePasswordReset); !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("GetUserToken of a used token error = %v, want ErrInvalidToken", err)
+		}
+	})
+
+	t.Run("wrong purpose, expired and unknown tokens are invalid", func(t *testing.T) {
+		repo, fixtures := newRepo(t)
+
+		valid := usertoken.NewUserToken(fixtures.User(t), usertoken.PurposeEmailVerification, "valid", time.Hour)
+		expired := usertoken.NewUserToken(fixtures.User(t), usertoken.PurposeEmailVerification, "expired", -time.Minute)
+		for _, token := range []*usertoken.UserToken{valid, expired} {
+			if err := repo.CreateUserToken(ctx, token); err != nil {
+				t.Fatalf("CreateUserToken: %v", err)
+			}
+		}
+
+		for _, tt := range []struct {
+			hash    string
+			purpose usertoken.TokenPurpose
+		}{
+			{"valid", usertoken.PurposePasswordReset},
+			{"expired", usertoken.PurposeEmailVerification},
+			{"unknown", usertoken.PurposeEmailVerification},
+		} {
+			if _, err := repo.GetUserToken(ctx, tt.hash, tt.purpose); !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("GetUserToken(%v, %v) error = %v, want ErrInvalidToken", tt.hash, tt.purpose, err)
+			}
+			if _, err := repo.UseUserToken(ctx, tt.hash, tt.purpose); !errors.Is(err, ErrInvalidToken) {
+				t.Fatalf("UseUserToken(%v, %v) error = %v, want ErrInvalidToken", tt.hash, tt.purpose, err)
+			}
+		}
+	})
+
+	t.Run("invalidate only the user's tokens of the purpose", func(t *testing.T) {
+		repo, fixtures := newRepo(t)
+		userUuid := fixtures.User(t)
+
+		reset := usertoken.NewUserToken(userUuid, usertoken.PurposePasswordReset, "reset", time.Hour)
+		verification := usertoken.NewUserToken(userUuid, usertoken.PurposeEmailVerification, "verification", time.Hour)
+		otherUser := usertoken.NewUserToken(fixtures.User(t), usertoken.PurposePasswordReset, "other", time.Hour)
+		for _, token := range []*usertoken.UserToken{reset, verification, otherUser} {
+			if err := repo.CreateUserToken(ctx, token); err != nil {
+				t.Fatalf("CreateUserToken: %v", err)
+			}
+		}
+
+		if err := repo.InvalidateUserTokens(ctx, userUuid, usertoken.PurposePasswordReset); err != nil {
+			t.Fatalf("InvalidateUserTokens: %v", err)
+		}
+
+		if _, err := repo.GetUserToken(ctx, "reset", usertoken.PurposePasswordReset); !errors.Is(err, ErrInvalidToken) {
+			t.Fatalf("GetUserToken of an invalidated token error = %v, want ErrInvalidToken", err)
+		}
+		if _, err := repo.GetUserToken(ctx, "verification", usertoken.PurposeEmailVerification); err != nil {
+			t.Fatalf("GetUserToken of another purpose: %v", err)
+		}
+		if _, err := repo.GetUserToken(ctx, "other", usertoken.PurposePasswordReset); err != nil {
+			t.Fatalf("GetUserToken of another user: %v", err)
+		}
+	})
+}
@@ -0,0 +1,145 @@
+package apikeyusecases
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/apikeyrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/userrepository"
+	"github.com/rom6n/otello/internal/app/domain/apikey"
+	"github.com/rom6n/otello/internal/app/domain/user"
+	"github.com/rom6n/otello/internal/pkg/logger"
+	"github.com/rom6n/otello/internal/pkg/tracing"
+	"github.com/rom6n/otello/internal/utils/hashutils"
+)
+
+const (
+	maxApiKeysPerUser = 20
+	displayPrefixLen  = len(apikey.KeyPrefix) + 6
+	// last_used_at is written at most once per interval for each key
+	lastUsedUpdateInterval = 1 * time.Minute
+)
+
+type ApiKeyUsecases interface {
+	Create(ctx context.Context, actor user.Actor, name string, permissions []user.Permission) (*apikey.ApiKey, string, error)
+	GetOwned(ctx context.Context, actor user.Actor) ([]apikey.ApiKey, error)
+	Revoke(ctx context.Context, actor user.Actor, keyUuid uuid.UUID) error
+	Authenticate(ctx context.Context, rawKey string) (*apikey.ApiKey, user.Actor, error)
+}
+
+type apiKeyUsecase struct {
+	apiKeyRepo apikeyrepository.ApiKeyRepository
+	userRepo   userrepository.UserRepository
+	timeout    time.Duration
+}
+
+func New(apiKeyRepo apikeyrepository.ApiKeyRepository, userRepo userrepository.UserRepository, timeout time.Duration) ApiKeyUsecases {
+	return &apiKeyUsecase{
+		apiKeyRepo: apiKeyRepo,
+		userRepo:   userRepo,
+		timeout:    timeout,
+	}
+}
+
+func (v *apiKeyUsecase) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return tracing.OperationContext(ctx, v.timeout, "usecase api_key."+method)
+}
+
+// Create returns the raw key, it is shown only once
+func (v *apiKeyUsecase) Create(ctx context.Context, actor user.Actor, name string, permissions []user.Permission) (_ *apikey.ApiKey, _ string, err error) {
+	usecaseCtx, end := v.getContext(ctx, "Create")
+	defer end(&err)
+
+	if len(permissions) == 0 {
+		return nil, "", fmt.Errorf("api key needs at least one permission")
+	}
+
+	for _, permission := range permissions {
+		if !actor.Can(permission) {
+			return nil, "", fmt.Errorf("%w '%v' to give it to an api key", user.ErrNoPermission, permission)
+		}
+	}
+
+	apiKeys, getErr := v.apiKeyRepo.GetApiKeysByOwnerUuid(usecaseCtx, actor.Uuid)
+	if getErr != nil {
+		return nil, "", getErr
+	}
+
+	activeKeys := 0
+	for _, apiKey := range apiKeys {
+		if !apiKey.IsRevoked() {
+			activeKeys++
+		}
+	}
+	if activeKeys >= maxApiKeysPerUser {
+		return nil, "", fmt.Errorf("you can have at most %v active api keys", maxApiKeysPerUser)
+	}
+
+	token, genErr := hashutils.GenerateToken()
+	if genErr != nil {
+		return nil, "", genErr
+	}
+	rawKey := apikey.KeyPrefix + token
+
+	newApiKey := apikey.NewApiKey(actor.Uuid, name, rawKey[:displayPrefixLen], hashutils.HashToken(rawKey), permissions)
+	if err := v.apiKeyRepo.CreateApiKey(usecaseCtx, newApiKey); err != nil {
+		return nil, "", err
+	}
+
+	return newApiKey, rawKey, nil
+}
+
+func (v *apiKeyUsecase) GetOwned(ctx context.Context, actor user.Actor) (_ []apikey.ApiKey, err error) {
+	usecaseCtx, end := v.getContext(ctx, "GetOwned")
+	defer end(&err)
+
+	return v.apiKeyRepo.GetApiKeysByOwnerUuid(usecaseCtx, actor.Uuid)
+}
+
+func (v *apiKeyUsecase) Revoke(ctx context.Context, actor user.Actor, keyUuid uuid.UUID) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "Revoke")
+	defer end(&err)
+
+	return v.apiKeyRepo.RevokeApiKey(usecaseCtx, keyUuid, actor.Uuid)
+}
+
+// Authenticate resolves a raw key to its owner, limited to the key's permissions. The owner's current role is used,
+// so revoking the role also limits the key
+func (v *apiKeyUsecase) Authenticate(ctx context.Context, rawKey string) (_ *apikey.ApiKey, _ user.Actor, err error) {
+	usecaseCtx, end := v.getContext(ctx, "Authenticate")
+	defer end(&err)
+
+	if !strings.HasPrefix(rawKey, apikey.KeyPrefix) {
+		return nil, user.Actor{}, apikey.ErrInvalidApiKey
+	}
+
+	apiKey, getErr := v.apiKeyRepo.GetApiKeyByHash(usecaseCtx, hashutils.HashToken(rawKey))
+	if getErr != nil {
+		return nil, user.Actor{}, getErr
+	}
+
+	if apiKey.IsRevoked() {
+		return nil, user.Actor{}, apikey.ErrInvalidApiKey
+	}
+
+	owner, ownerErr := v.userRepo.GetUserByUuid(usecaseCtx, apiKey.OwnerUuid)
+	if ownerErr != nil {
+		return nil, user.Actor{}, ownerErr
+	}
+
+	if owner.IsDeleted() || owner.IsDisabled() {
+		return nil, user.Actor{}, apikey.ErrInvalidApiKey
+	}
+
+	now := time.Now()
+	if err := v.apiKeyRepo.UpdateApiKeyLastUsed(usecaseCtx, apiKey.Uuid, now.Unix(), now.Add(-lastUsedUpdateInterval).Unix()); err != nil {
+		logger.FromContext(ctx).Warn("failed to update api key last used", "key_prefix", apiKey.Prefix, "error", err)
+	}
+
+	// never nil, an actor without key permissions is a session with the full role
+	keyPermissions := append(make([]user.Permission, 0, len(apiKey.Permissions)), apiKey.Permissions...)
+	return apiKey, user.Actor{Uuid: owner.Uuid, Role: owner.Role, KeyPermissions: keyPermissions}, nil
+}
@@ -8,42 +8,56 @@ import (
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/flightticketpurchaserepository"
 	"github.com/rom6n/otello/internal/app/adapters/repository/flightticketrepository"
 	"github.com/rom6n/otello/internal/app/domain/flightticket"
+	"github.com/rom6n/otello/internal/app/domain/user"
+	"github.com/rom6n/otello/internal/pkg/logger"
+	"github.com/rom6n/otello/internal/pkg/metrics"
+	"github.com/rom6n/otello/internal/pkg/tracing"
 )
 
 const maxLayover = int64(24 * 60 * 60)
 
 type FlightTicketUsecases interface {
-	Create(ctx context.Context, flightTicket *flightticket.FlightTicket) error
-	Update(ctx context.Context, newFlightTicketData *flightticket.FlightTicket) error
-	Delete(ctx context.Context, flightTicketUuid uuid.UUID) error
+	Create(ctx context.Context, actor user.Actor, flightTicket *flightticket.FlightTicket) error
+	Update(ctx context.Context, actor user.Actor, newFlightTicketData *flightticket.FlightTicket) error
+	Delete(ctx context.Context, actor user.Actor, flightTicketUuid uuid.UUID) error
 	Get(ctx context.Context, flightTicketUuid uuid.UUID) (*flightticket.FlightTicket, error)
-	Buy(ctx context.Context, flightTicketUuid uuid.UUID, amountPassengers uint32) (*flightticket.FlightTicket, error)
+	Buy(ctx context.Context, buyerUuid, flightTicketUuid uuid.UUID, amountPassengers uint32) (*flightticket.FlightTicket, error)
 	GetWithParams(ctx context.Context, flightTicketFilter *flightticket.FlightTicket, cityVia *string, needSort, isAsc bool) ([]Path, []flightticket.FlightTicket, error)
 }
 
 type flightTicketUsecase struct {
 	flightTicketRepo flightticketrepository.FlightTicketRepository
+	purchaseRepo     flightticketpurchaserepository.FlightTicketPurchaseRepository
 	timeout          time.Duration
 }
 
-func New(flightTicketRepo flightticketrepository.FlightTicketRepository, timeout time.Duration) FlightTicketUsecases {
+func New(flightTicketRepo flightticketrepository.FlightTicketRepository, purchaseRepo flightticketpurchaserepository.FlightTicketPurchaseRepository, timeout time.Duration) FlightTicketUsecases {
 	return &flightTicketUsecase{
 		flightTicketRepo: flightTicketRepo,
+		purchaseRepo:     purchaseRepo,
 		timeout:          timeout,
 	}
 }
 
-func (v *flightTicketUsecase) getContext(ctx context.Context) (context.Context, context.CancelFunc) {
-	return context.WithTimeout(ctx, v.timeout)
+func (v *flightTicketUsecase) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return tracing.OperationContext(ctx, v.timeout, "usecase flight_ticket."+method)
 }
 
-func (v *flightTicketUsecase) Create(ctx context.Context, flightTicket *flightticket.FlightTicket) error {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *flightTicketUsecase) Create(ctx context.Context, actor user.Actor, flightTicket *flightticket.FlightTicket) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "Create")
+	defer end(&err)
 
-	err := v.flightTicketRepo.CreateFlightTicket(usecaseCtx, flightTicket)
+	switch actor.PermissionScope(user.PermManageFlights) {
+	case user.ScopeNone:
+		return fmt.Errorf("%w to create flight tickets", user.ErrNoPermission)
+	case user.ScopeOwn:
+		flightTicket.OperatorUuid = actor.Uuid
+	}
+
+	err = v.flightTicketRepo.CreateFlightTicket(usecaseCtx, flightTicket)
 	if err != nil {
 		return err
 	}
@@ -51,11 +65,24 @@ func (v *flightTicketUsecase) Create(ctx context.Context, flightTicket *flightti
 	return nil
 }
 
-func (v *flightTicketUsecase) Update(ctx context.Context, newFlightTicketData *flightticket.FlightTicket) error {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *flightTicketUsecase) Update(ctx context.Context, actor user.Actor, newFlightTicketData *flightticket.FlightTicket) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "Update")
+	defer end(&err)
+
+	foundFlightTicket, getErr := v.flightTicketRepo.GetFlightTicket(usecaseCtx, newFlightTicketData.Uuid)
+	if getErr != nil {
+		return getErr
+	}
+
+	if !actor.CanAccess(user.PermManageFlights, foundFlightTicket.OperatorUuid) {
+		return fmt.Errorf("%w to update this flight ticket", user.ErrNoPermission)
+	}
+
+	if newFlightTicketData.OperatorUuid != foundFlightTicket.OperatorUuid && actor.PermissionScope(user.PermManageFlights) != user.ScopeAll {
+		return fmt.Errorf("%w to change the flight ticket operator", user.ErrNoPermission)
+	}
 
-	err := v.flightTicketRepo.UpdateFlightTicket(usecaseCtx, newFlightTicketData)
+	err = v.flightTicketRepo.UpdateFlightTicket(usecaseCtx, newFlightTicketData)
 	if err != nil {
 		return err
 	}
@@ -63,11 +90,20 @@ func (v *flightTicketUsecase) Update(ctx context.Context, newFlightTicketData *f
 	return nil
 }
 
-func (v *flightTicketUsecase) Delete(ctx context.Context, flightTicketUuid uuid.UUID) error {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *flightTicketUsecase) Delete(ctx context.Context, actor user.Actor, flightTicketUuid uuid.UUID) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "Delete")
+	defer end(&err)
 
-	err := v.flightTicketRepo.DeleteFlightTicket(usecaseCtx, flightTicketUuid)
+	foundFlightTicket, getErr := v.flightTicketRepo.GetFlightTicket(usecaseCtx, flightTicketUuid)
+	if getErr != nil {
+		return getErr
+	}
+
+	if !actor.CanAccess(user.PermManageFlights, foundFlightTicket.OperatorUuid) {
+		return fmt.Errorf("%w to delete this flight ticket", user.ErrNoPermission)
+	}
+
+	err = v.flightTicketRepo.DeleteFlightTicket(usecaseCtx, flightTicketUuid)
 	if err != nil {
 		return err
 	}
@@ -75,9 +111,9 @@ func (v *flightTicketUsecase) Delete(ctx context.Context, flightTicketUuid uuid.
 	return nil
 }
 
-func (v *flightTicketUsecase) Get(ctx context.Context, flightTicketUuid uuid.UUID) (*flightticket.FlightTicket, error) {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *flightTicketUsecase) Get(ctx context.Context, flightTicketUuid uuid.UUID) (_ *flightticket.FlightTicket, err error) {
+	usecaseCtx, end := v.getContext(ctx, "Get")
+	defer end(&err)
 
 	foundFlightTicket, err := v.flightTicketRepo.GetFlightTicket(usecaseCtx, flightTicketUuid)
 	if err != nil {
@@ -87,9 +123,9 @@ func (v *flightTicketUsecase) Get(ctx context.Context, flightTicketUuid uuid.UUI
 	return foundFlightTicket, nil
 }
 
-func (v *flightTicketUsecase) GetWithParams(ctx context.Context, flightTicketFilter *flightticket.FlightTicket, cityVia *string, needSort, isAsc bool) ([]Path, []flightticket.FlightTicket, error) {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *flightTicketUsecase) GetWithParams(ctx context.Context, flightTicketFilter *flightticket.FlightTicket, cityVia *string, needSort, isAsc bool) (_ []Path, _ []flightticket.FlightTicket, err error) {
+	usecaseCtx, end := v.getContext(ctx, "GetWithParams")
+	defer end(&err)
 
 	flightTicketFilterCopy := *flightTicketFilter
 	flightTicketFilterCopy.CityFrom = ""
@@ -119,9 +155,9 @@ func (v *flightTicketUsecase) GetWithParams(ctx context.Context, flightTicketFil
 	return layoverPathsOr3CityPaths, straightPaths, nil
 }
 
-func (v *flightTicketUsecase) Buy(ctx context.Context, flightTicketUuid uuid.UUID, amountPassengers uint32) (*flightticket.FlightTicket, error) {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *flightTicketUsecase) Buy(ctx context.Context, buyerUuid, flightTicketUuid uuid.UUID, amountPassengers uint32) (_ *flightticket.FlightTicket, err error) {
+	usecaseCtx, end := v.getContext(ctx, "Buy")
+	defer end(&err)
 
 	foundFlightTicket, getErr := v.Get(ctx, flightTicketUuid)
 	if getErr != nil {
@@ -136,12 +172,19 @@ func (v *flightTicketUsecase) Buy(ctx context.Context, flightTicketUuid uuid.UUI
 	if buyErr != nil {
 		return nil, buyErr
 	}
+	metrics.FlightSeatsSold.Add(float64(amountPassengers))
 
 	foundFlightTicket.Quantity = amountPassengers
 	if foundFlightTicket.Value != nil {
 		*foundFlightTicket.Value *= amountPassengers
 	}
 
+	// the seats are already taken, a lost purchase record must not fail the purchase itself
+	purchase := flightticket.NewPurchase(flightTicketUuid, buyerUuid, amountPassengers, foundFlightTicket.Value)
+	if err := v.purchaseRepo.CreatePurchase(usecaseCtx, purchase); err != nil {
+		logger.FromContext(ctx).Error("failed to record flight ticket purchase", "purchase", purchase.Uuid, "flight_ticket", flightTicketUuid, "buyer", buyerUuid, "error", err)
+	}
+
 	return foundFlightTicket, nil
 }
 
@@ -274,7 +317,15 @@ func checkResultForBestPaths(results []Path, cityVia *string) ([]flightticket.Fl
 func findPathsLogic(pq *PriorityQueue, index map[string][]*flightticket.FlightTicket, allFoundFlightTicketsLen int, cityTo string, cityVia *string) []Path {
 	results := make([]Path, 0, allFoundFlightTicketsLen)
 
+	maxHeapSize, expansions := pq.Len(), 0
+	defer func() {
+		metrics.FlightSearchHeapSize.Observe(float64(maxHeapSize))
+		metrics.FlightSearchExpansions.Observe(float64(expansions))
+	}()
+
 	for pq.Len() > 0 {
+		maxHeapSize = max(maxHeapSize, pq.Len())
+		expansions++
 		item := heap.Pop(pq).(*candidate)
 		cur := item.path
 		last := cur.Flights[len(cur.Flights)-1]
@@ -2,46 +2,78 @@ package hotelroomusecases
 
 import (
 	"context"
+	"fmt"
 	"sort"
 	"time"
 
 	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/hotelrepository"
 	hotelroomrepository "github.com/rom6n/otello/internal/app/adapters/repository/hotelroomrepository"
 	"github.com/rom6n/otello/internal/app/adapters/repository/rentrepository"
+	"github.com/rom6n/otello/internal/app/application/usecases/rentusecases"
+	"github.com/rom6n/otello/internal/app/domain/hotel"
 	"github.com/rom6n/otello/internal/app/domain/hotelroom"
+	"github.com/rom6n/otello/internal/app/domain/user"
+	"github.com/rom6n/otello/internal/pkg/tracing"
 )
 
 type HotelRoomUsecases interface {
-	Create(ctx context.Context, hotelRoom *hotelroom.HotelRoom) error
-	Update(ctx context.Context, newHotelRoomData *hotelroom.HotelRoom) error
-	Delete(ctx context.Context, hotelRoomUuid uuid.UUID) error
+	Create(ctx context.Context, actor user.Actor, hotelRoom *hotelroom.HotelRoom) error
+	Update(ctx context.Context, actor user.Actor, newHotelRoomData *hotelroom.HotelRoom) error
+	Delete(ctx context.Context, actor user.Actor, hotelRoomUuid uuid.UUID, force bool) error
+	UpdatePrice(ctx context.Context, actor user.Actor, hotelRoomUuid uuid.UUID, value int64) (*hotelroom.HotelRoom, error)
 	Get(ctx context.Context, hotelRoomUuid uuid.UUID) (*hotelroom.HotelRoom, error)
 	GetWithParams(ctx context.Context, filter *hotelroom.FindHotelRoomFilterDTO) ([]hotelroom.HotelRoom, error)
 }
 
 type hotelRoomUsecase struct {
 	hotelRoomRepo hotelroomrepository.HotelRoomRepository
+	hotelRepo     hotelrepository.HotelRepository
 	rentRepo      rentrepository.RentRepository
+	rentUsecases  rentusecases.RentUsecases
 	timeout       time.Duration
 }
 
-func New(hotelRoomRepo hotelroomrepository.HotelRoomRepository, rentRepo rentrepository.RentRepository, timeout time.Duration) HotelRoomUsecases {
+func New(hotelRoomRepo hotelroomrepository.HotelRoomRepository, hotelRepo hotelrepository.HotelRepository, rentRepo rentrepository.RentRepository, rentUsecases rentusecases.RentUsecases, timeout time.Duration) HotelRoomUsecases {
 	return &hotelRoomUsecase{
 		hotelRoomRepo: hotelRoomRepo,
+		hotelRepo:     hotelRepo,
 		rentRepo:      rentRepo,
+		rentUsecases:  rentUsecases,
 		timeout:       timeout,
 	}
 }
 
-func (v *hotelRoomUsecase) getContext(ctx context.Context) (context.Context, context.CancelFunc) {
-	return context.WithTimeout(ctx, v.timeout)
+func (v *hotelRoomUsecase) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return tracing.OperationContext(ctx, v.timeout, "usecase hotel_room."+method)
 }
 
-func (v *hotelRoomUsecase) Create(ctx context.Context, hotelRoom *hotelroom.HotelRoom) error {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+// checkHotelAccess also rejects unknown and deleted hotels, rooms can't be added to them
+func (v *hotelRoomUsecase) checkHotelAccess(ctx context.Context, actor user.Actor, hotelUuid uuid.UUID) error {
+	foundHotel, getErr := v.hotelRepo.GetHotel(ctx, hotelUuid)
+	if getErr != nil {
+		return getErr
+	}
+	if foundHotel.IsDeleted() {
+		return hotel.ErrDeleted
+	}
+
+	if !actor.CanAccess(user.PermManageHotelRooms, foundHotel.OwnerUuid) {
+		return fmt.Errorf("%w to manage rooms of this hotel", user.ErrNoPermission)
+	}
+
+	return nil
+}
+
+func (v *hotelRoomUsecase) Create(ctx context.Context, actor user.Actor, hotelRoom *hotelroom.HotelRoom) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "Create")
+	defer end(&err)
+
+	if err := v.checkHotelAccess(usecaseCtx, actor, hotelRoom.HotelUuid); err != nil {
+		return err
+	}
 
-	err := v.hotelRoomRepo.CreateHotelRoom(usecaseCtx, hotelRoom)
+	err = v.hotelRoomRepo.CreateHotelRoom(usecaseCtx, hotelRoom)
 	if err != nil {
 		return err
 	}
@@ -49,11 +81,29 @@ func (v *hotelRoomUsecase) Create(ctx context.Context, hotelRoom *hotelroom.Hote
 	return nil
 }
 
-func (v *hotelRoomUsecase) Update(ctx context.Context, newHotelRoomData *hotelroom.HotelRoom) error {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *hotelRoomUsecase) Update(ctx context.Context, actor user.Actor, newHotelRoomData *hotelroom.HotelRoom) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "Update")
+	defer end(&err)
 
-	err := v.hotelRoomRepo.UpdateHotelRoom(usecaseCtx, newHotelRoomData)
+	foundHotelRoom, getErr := v.hotelRoomRepo.GetHotelRoom(usecaseCtx, newHotelRoomData.Uuid)
+	if getErr != nil {
+		return getErr
+	}
+	if foundHotelRoom.IsDeleted() {
+		return hotelroom.ErrDeleted
+	}
+
+	if err := v.checkHotelAccess(usecaseCtx, actor, foundHotelRoom.HotelUuid); err != nil {
+		return err
+	}
+
+	if newHotelRoomData.HotelUuid != foundHotelRoom.HotelUuid {
+		if err := v.checkHotelAccess(usecaseCtx, actor, newHotelRoomData.HotelUuid); err != nil {
+			return err
+		}
+	}
+
+	err = v.hotelRoomRepo.UpdateHotelRoom(usecaseCtx, newHotelRoomData)
 	if err != nil {
 		return err
 	}
@@ -61,11 +111,30 @@ func (v *hotelRoomUsecase) Update(ctx context.Context, newHotelRoomData *hotelro
 	return nil
 }
 
-func (v *hotelRoomUsecase) Delete(ctx context.Context, hotelRoomUuid uuid.UUID) error {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *hotelRoomUsecase) Delete(ctx context.Context, actor user.Actor, hotelRoomUuid uuid.UUID, force bool) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "Delete")
+	defer end(&err)
+
+	foundHotelRoom, getErr := v.hotelRoomRepo.GetHotelRoom(usecaseCtx, hotelRoomUuid)
+	if getErr != nil {
+		return getErr
+	}
+	if foundHotelRoom.IsDeleted() {
+		return hotelroom.ErrDeleted
+	}
+
+	if err := v.checkHotelAccess(usecaseCtx, actor, foundHotelRoom.HotelUuid); err != nil {
+		return err
+	}
+	if force && !actor.Can(user.PermCancelRents) {
+		return fmt.Errorf("%w to cancel rents", user.ErrNoPermission)
+	}
 
-	err := v.hotelRoomRepo.DeleteHotelRoom(usecaseCtx, hotelRoomUuid)
+	if err := v.rentUsecases.CancelActiveRents(usecaseCtx, actor, []uuid.UUID{hotelRoomUuid}, force); err != nil {
+		return err
+	}
+
+	err = v.hotelRoomRepo.DeleteHotelRoom(usecaseCtx, hotelRoomUuid)
 	if err != nil {
 		return err
 	}
@@ -73,9 +142,33 @@ func (v *hotelRoomUsecase) Delete(ctx context.Context, hotelRoomUuid uuid.UUID)
 	return nil
 }
 
-func (v *hotelRoomUsecase) Get(ctx context.Context, hotelRoomUuid uuid.UUID) (*hotelroom.HotelRoom, error) {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *hotelRoomUsecase) UpdatePrice(ctx context.Context, actor user.Actor, hotelRoomUuid uuid.UUID, value int64) (_ *hotelroom.HotelRoom, err error) {
+	usecaseCtx, end := v.getContext(ctx, "UpdatePrice")
+	defer end(&err)
+
+	foundHotelRoom, getErr := v.hotelRoomRepo.GetHotelRoom(usecaseCtx, hotelRoomUuid)
+	if getErr != nil {
+		return nil, getErr
+	}
+	if foundHotelRoom.IsDeleted() {
+		return nil, hotelroom.ErrDeleted
+	}
+
+	if err := v.checkHotelAccess(usecaseCtx, actor, foundHotelRoom.HotelUuid); err != nil {
+		return nil, err
+	}
+
+	foundHotelRoom.Value = &value
+	if err := v.hotelRoomRepo.UpdateHotelRoom(usecaseCtx, foundHotelRoom); err != nil {
+		return nil, err
+	}
+
+	return foundHotelRoom, nil
+}
+
+func (v *hotelRoomUsecase) Get(ctx context.Context, hotelRoomUuid uuid.UUID) (_ *hotelroom.HotelRoom, err error) {
+	usecaseCtx, end := v.getContext(ctx, "Get")
+	defer end(&err)
 
 	hotel, err := v.hotelRoomRepo.GetHotelRoom(usecaseCtx, hotelRoomUuid)
 	if err != nil {
@@ -85,9 +178,9 @@ func (v *hotelRoomUsecase) Get(ctx context.Context, hotelRoomUuid uuid.UUID) (*h
 	return hotel, nil
 }
 
-func (v *hotelRoomUsecase) GetWithParams(ctx context.Context, filter *hotelroom.FindHotelRoomFilterDTO) ([]hotelroom.HotelRoom, error) {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *hotelRoomUsecase) GetWithParams(ctx context.Context, filter *hotelroom.FindHotelRoomFilterDTO) (_ []hotelroom.HotelRoom, err error) {
+	usecaseCtx, end := v.getContext(ctx, "GetWithParams")
+	defer end(&err)
 
 	hotelRooms, err := v.hotelRoomRepo.GetHotelRoomsWithParams(usecaseCtx, filter)
 	if err != nil {
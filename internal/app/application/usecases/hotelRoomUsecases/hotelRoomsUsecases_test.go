@@ -0,0 +1,142 @@
+package hotelroomusecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/hotelrepository"
+	hotelroomrepository "github.com/rom6n/otello/internal/app/adapters/repository/hotelroomrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/rentrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/userrepository"
+	"github.com/rom6n/otello/internal/app/application/usecases/rentusecases"
+	"github.com/rom6n/otello/internal/app/domain/hotel"
+	"github.com/rom6n/otello/internal/app/domain/hotelroom"
+	"github.com/rom6n/otello/internal/app/domain/rent"
+	"github.com/rom6n/otello/internal/app/domain/user"
+	"github.com/rom6n/otello/internal/pkg/mailer"
+)
+
+type discardMailer struct{}
+
+func (discardMailer) Send(ctx context.Context, message mailer.Message) error {
+	return nil
+}
+
+type testHotelRoomUsecase struct {
+	HotelRoomUsecases
+	hotelRoomRepo hotelroomrepository.HotelRoomRepository
+	rentRepo      rentrepository.RentRepository
+	hotelRepo     hotelrepository.HotelRepository
+}
+
+func newTestHotelRoomUsecase(t *testing.T) testHotelRoomUsecase {
+	t.Helper()
+
+	hotelRepo := hotelrepository.NewMemory()
+	hotelRoomRepo := hotelroomrepository.NewMemory()
+	rentRepo := rentrepository.NewMemory()
+	rentUsecase := rentusecases.New(rentRepo, hotelRoomRepo, hotelRepo, userrepository.NewMemory(), discardMailer{}, 5*time.Second)
+
+	return testHotelRoomUsecase{
+		HotelRoomUsecases: New(hotelRoomRepo, hotelRepo, rentRepo, rentUsecase, 5*time.Second),
+		hotelRoomRepo:     hotelRoomRepo,
+		rentRepo:          rentRepo,
+		hotelRepo:         hotelRepo,
+	}
+}
+
+// partnerRoom creates a hotel owned by a new hotel manager with one room and returns both
+func (v testHotelRoomUsecase) partnerRoom(t *testing.T) (user.Actor, *hotelroom.HotelRoom) {
+	t.Helper()
+	ctx := context.Background()
+
+	partner := user.Actor{Uuid: uuid.New(), Role: user.RoleHotelManager}
+	newHotel := hotel.NewHotel("Volga", "Kazan", 4, partner.Uuid)
+	if err := v.hotelRepo.CreateHotel(ctx, newHotel); err != nil {
+		t.Fatalf("CreateHotel: %v", err)
+	}
+
+	price := int64(5000)
+	newHotelRoom := hotelroom.NewHotelRoom(newHotel.Uuid, 2, hotelroom.Standard, 2, &price)
+	if err := v.hotelRoomRepo.CreateHotelRoom(ctx, newHotelRoom); err != nil {
+		t.Fatalf("CreateHotelRoom: %v", err)
+	}
+
+	return partner, newHotelRoom
+}
+
+func TestPartnerManagesOnlyRoomsOfOwnHotels(t *testing.T) {
+	usecase := newTestHotelRoomUsecase(t)
+	ctx := context.Background()
+	partner, ownRoom := usecase.partnerRoom(t)
+	_, otherRoom := usecase.partnerRoom(t)
+
+	movedRoom := *otherRoom
+	movedRoom.Rooms = 3
+	if err := usecase.Update(ctx, partner, &movedRoom); !errors.Is(err, user.ErrNoPermission) {
+		t.Fatalf("Update of another partner's room = %v, want %v", err, user.ErrNoPermission)
+	}
+	if _, err := usecase.UpdatePrice(ctx, partner, otherRoom.Uuid, 1); !errors.Is(err, user.ErrNoPermission) {
+		t.Fatalf("UpdatePrice of another partner's room = %v, want %v", err, user.ErrNoPermission)
+	}
+	if err := usecase.Delete(ctx, partner, otherRoom.Uuid, false); !errors.Is(err, user.ErrNoPermission) {
+		t.Fatalf("Delete of another partner's room = %v, want %v", err, user.ErrNoPermission)
+	}
+	if err := usecase.Create(ctx, partner, hotelroom.NewHotelRoom(otherRoom.HotelUuid, 1, hotelroom.Standard, 1, nil)); !errors.Is(err, user.ErrNoPermission) {
+		t.Fatalf("Create in another partner's hotel = %v, want %v", err, user.ErrNoPermission)
+	}
+
+	// an own room can't be moved into a hotel of someone else either
+	ownRoomMoved := *ownRoom
+	ownRoomMoved.HotelUuid = otherRoom.HotelUuid
+	if err := usecase.Update(ctx, partner, &ownRoomMoved); !errors.Is(err, user.ErrNoPermission) {
+		t.Fatalf("Update moving the room to another partner's hotel = %v, want %v", err, user.ErrNoPermission)
+	}
+
+	if foundRoom, _ := usecase.hotelRoomRepo.GetHotelRoom(ctx, otherRoom.Uuid); foundRoom.IsDeleted() || *foundRoom.Value != *otherRoom.Value || foundRoom.Rooms != otherRoom.Rooms {
+		t.Fatalf("room of another partner = %+v, want it unchanged", foundRoom)
+	}
+
+	if _, err := usecase.UpdatePrice(ctx, partner, ownRoom.Uuid, 7000); err != nil {
+		t.Fatalf("UpdatePrice of own room: %v", err)
+	}
+	if err := usecase.Delete(ctx, partner, ownRoom.Uuid, false); err != nil {
+		t.Fatalf("Delete of own room: %v", err)
+	}
+}
+
+func TestForceDeleteRoomNeedsCancelPermission(t *testing.T) {
+	usecase := newTestHotelRoomUsecase(t)
+	ctx := context.Background()
+	partner, hotelRoom := usecase.partnerRoom(t)
+
+	now := time.Now()
+	activeRent := rent.NewRent(hotelRoom.Uuid, uuid.New(), now.Add(24*time.Hour).Unix(), now.Add(72*time.Hour).Unix())
+	if err := usecase.rentRepo.CreateRent(ctx, activeRent); err != nil {
+		t.Fatalf("CreateRent: %v", err)
+	}
+
+	// the partner's api key may manage rooms but not cancel rents
+	apiKeyActor := partner
+	apiKeyActor.KeyPermissions = []user.Permission{user.PermManageHotelRooms}
+	if err := usecase.Delete(ctx, apiKeyActor, hotelRoom.Uuid, true); !errors.Is(err, user.ErrNoPermission) {
+		t.Fatalf("forced Delete without 'rents:cancel' = %v, want %v", err, user.ErrNoPermission)
+	}
+
+	if foundRent, _ := usecase.rentRepo.GetRent(ctx, activeRent.Uuid); foundRent.IsCancelled() {
+		t.Fatal("rent was cancelled without 'rents:cancel'")
+	}
+	if foundRoom, _ := usecase.hotelRoomRepo.GetHotelRoom(ctx, hotelRoom.Uuid); foundRoom.IsDeleted() {
+		t.Fatal("room was deleted without 'rents:cancel'")
+	}
+
+	if err := usecase.Delete(ctx, partner, hotelRoom.Uuid, true); err != nil {
+		t.Fatalf("forced Delete by the partner: %v", err)
+	}
+	if foundRent, _ := usecase.rentRepo.GetRent(ctx, activeRent.Uuid); foundRent.CancelReason != rent.CancelReasonForced {
+		t.Fatalf("rent = %+v, want it cancelled by force", foundRent)
+	}
+}
@@ -2,43 +2,65 @@ package hotelusecases
 
 import (
 	"context"
+	"fmt"
 	"sort"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/rom6n/otello/internal/app/adapters/repository/hotelrepository"
+	hotelroomrepository "github.com/rom6n/otello/internal/app/adapters/repository/hotelroomrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/userrepository"
+	"github.com/rom6n/otello/internal/app/application/usecases/rentusecases"
 	"github.com/rom6n/otello/internal/app/domain/hotel"
+	"github.com/rom6n/otello/internal/app/domain/hotelroom"
+	"github.com/rom6n/otello/internal/app/domain/user"
+	"github.com/rom6n/otello/internal/pkg/tracing"
 )
 
 type HotelUsecases interface {
-	Create(ctx context.Context, hotel *hotel.Hotel) error
-	Update(ctx context.Context, newHotelData *hotel.Hotel) error
-	Delete(ctx context.Context, hotelUuid uuid.UUID) error
+	Create(ctx context.Context, actor user.Actor, hotel *hotel.Hotel) error
+	Update(ctx context.Context, actor user.Actor, newHotelData *hotel.Hotel) error
+	Delete(ctx context.Context, actor user.Actor, hotelUuid uuid.UUID, force bool) error
 	Get(ctx context.Context, hotelUuid uuid.UUID) (*hotel.Hotel, error)
 	GetWithParams(ctx context.Context, city string, stars int32, hotelUuid uuid.UUID, starsFrom, starsTo uint32, needSort, isAsc bool) ([]hotel.Hotel, error)
+	GetOwned(ctx context.Context, actor user.Actor) ([]hotel.Hotel, error)
 }
 
 type hotelUsecase struct {
-	hotelRepo hotelrepository.HotelRepository
-	timeout   time.Duration
+	hotelRepo     hotelrepository.HotelRepository
+	hotelRoomRepo hotelroomrepository.HotelRoomRepository
+	userRepo      userrepository.UserRepository
+	rentUsecases  rentusecases.RentUsecases
+	timeout       time.Duration
 }
 
-func New(hotelRepo hotelrepository.HotelRepository, timeout time.Duration) HotelUsecases {
+func New(hotelRepo hotelrepository.HotelRepository, hotelRoomRepo hotelroomrepository.HotelRoomRepository, userRepo userrepository.UserRepository, rentUsecases rentusecases.RentUsecases, timeout time.Duration) HotelUsecases {
 	return &hotelUsecase{
-		hotelRepo: hotelRepo,
-		timeout:   timeout,
+		hotelRepo:     hotelRepo,
+		hotelRoomRepo: hotelRoomRepo,
+		userRepo:      userRepo,
+		rentUsecases:  rentUsecases,
+		timeout:       timeout,
 	}
 }
 
-func (v *hotelUsecase) getContext(ctx context.Context) (context.Context, context.CancelFunc) {
-	return context.WithTimeout(ctx, v.timeout)
+func (v *hotelUsecase) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return tracing.OperationContext(ctx, v.timeout, "usecase hotel."+method)
 }
 
-func (v *hotelUsecase) Create(ctx context.Context, hotel *hotel.Hotel) error {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *hotelUsecase) Create(ctx context.Context, actor user.Actor, hotel *hotel.Hotel) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "Create")
+	defer end(&err)
 
-	err := v.hotelRepo.CreateHotel(usecaseCtx, hotel)
+	if actor.PermissionScope(user.PermManageHotels) != user.ScopeAll {
+		return fmt.Errorf("%w to create hotels", user.ErrNoPermission)
+	}
+
+	if err := v.checkOwnerIsPartner(usecaseCtx, hotel.OwnerUuid); err != nil {
+		return err
+	}
+
+	err = v.hotelRepo.CreateHotel(usecaseCtx, hotel)
 	if err != nil {
 		return err
 	}
@@ -46,11 +68,32 @@ func (v *hotelUsecase) Create(ctx context.Context, hotel *hotel.Hotel) error {
 	return nil
 }
 
-func (v *hotelUsecase) Update(ctx context.Context, newHotelData *hotel.Hotel) error {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *hotelUsecase) Update(ctx context.Context, actor user.Actor, newHotelData *hotel.Hotel) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "Update")
+	defer end(&err)
 
-	err := v.hotelRepo.UpdateHotel(usecaseCtx, newHotelData)
+	foundHotel, getErr := v.hotelRepo.GetHotel(usecaseCtx, newHotelData.Uuid)
+	if getErr != nil {
+		return getErr
+	}
+	if foundHotel.IsDeleted() {
+		return hotel.ErrDeleted
+	}
+
+	if !actor.CanAccess(user.PermManageHotels, foundHotel.OwnerUuid) {
+		return fmt.Errorf("%w to update this hotel", user.ErrNoPermission)
+	}
+
+	if newHotelData.OwnerUuid != foundHotel.OwnerUuid {
+		if actor.PermissionScope(user.PermManageHotels) != user.ScopeAll {
+			return fmt.Errorf("%w to change the hotel owner", user.ErrNoPermission)
+		}
+		if err := v.checkOwnerIsPartner(usecaseCtx, newHotelData.OwnerUuid); err != nil {
+			return err
+		}
+	}
+
+	err = v.hotelRepo.UpdateHotel(usecaseCtx, newHotelData)
 	if err != nil {
 		return err
 	}
@@ -58,11 +101,48 @@ func (v *hotelUsecase) Update(ctx context.Context, newHotelData *hotel.Hotel) er
 	return nil
 }
 
-func (v *hotelUsecase) Delete(ctx context.Context, hotelUuid uuid.UUID) error {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+// Delete keeps the hotel and its rooms for the history of rents, they are only hidden from searches
+func (v *hotelUsecase) Delete(ctx context.Context, actor user.Actor, hotelUuid uuid.UUID, force bool) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "Delete")
+	defer end(&err)
+
+	if actor.PermissionScope(user.PermManageHotels) != user.ScopeAll {
+		return fmt.Errorf("%w to delete hotels", user.ErrNoPermission)
+	}
+	// forcing cancels rents of other users, an API key with only 'hotels:manage' must not do that
+	if force && !actor.Can(user.PermCancelRents) {
+		return fmt.Errorf("%w to cancel rents", user.ErrNoPermission)
+	}
+
+	foundHotel, getErr := v.hotelRepo.GetHotel(usecaseCtx, hotelUuid)
+	if getErr != nil {
+		return getErr
+	}
+	if foundHotel.IsDeleted() {
+		return hotel.ErrDeleted
+	}
+
+	hotelRooms, getRoomsErr := v.hotelRoomRepo.GetHotelRoomsWithParams(usecaseCtx, &hotelroom.FindHotelRoomFilterDTO{HotelUuid: hotelUuid})
+	if getRoomsErr != nil {
+		return getRoomsErr
+	}
+
+	hotelRoomUuids := make([]uuid.UUID, 0, len(hotelRooms))
+	for _, hotelRoom := range hotelRooms {
+		hotelRoomUuids = append(hotelRoomUuids, hotelRoom.Uuid)
+	}
 
-	err := v.hotelRepo.DeleteHotel(usecaseCtx, hotelUuid)
+	if err := v.rentUsecases.CancelActiveRents(usecaseCtx, actor, hotelRoomUuids, force); err != nil {
+		return err
+	}
+
+	for _, hotelRoomUuid := range hotelRoomUuids {
+		if err := v.hotelRoomRepo.DeleteHotelRoom(usecaseCtx, hotelRoomUuid); err != nil {
+			return err
+		}
+	}
+
+	err = v.hotelRepo.DeleteHotel(usecaseCtx, hotelUuid)
 	if err != nil {
 		return err
 	}
@@ -70,9 +150,9 @@ func (v *hotelUsecase) Delete(ctx context.Context, hotelUuid uuid.UUID) error {
 	return nil
 }
 
-func (v *hotelUsecase) Get(ctx context.Context, hotelUuid uuid.UUID) (*hotel.Hotel, error) {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *hotelUsecase) Get(ctx context.Context, hotelUuid uuid.UUID) (_ *hotel.Hotel, err error) {
+	usecaseCtx, end := v.getContext(ctx, "Get")
+	defer end(&err)
 
 	foundHotel, err := v.hotelRepo.GetHotel(usecaseCtx, hotelUuid)
 	if err != nil {
@@ -82,9 +162,9 @@ func (v *hotelUsecase) Get(ctx context.Context, hotelUuid uuid.UUID) (*hotel.Hot
 	return foundHotel, nil
 }
 
-func (v *hotelUsecase) GetWithParams(ctx context.Context, city string, stars int32, hotelUuid uuid.UUID, starsFrom, starsTo uint32, needSort, isAsc bool) ([]hotel.Hotel, error) {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *hotelUsecase) GetWithParams(ctx context.Context, city string, stars int32, hotelUuid uuid.UUID, starsFrom, starsTo uint32, needSort, isAsc bool) (_ []hotel.Hotel, err error) {
+	usecaseCtx, end := v.getContext(ctx, "GetWithParams")
+	defer end(&err)
 
 	hotels, err := v.hotelRepo.GetHotelWithParams(usecaseCtx, city, stars, hotelUuid, starsFrom, starsTo)
 	if err != nil {
@@ -105,3 +185,36 @@ func (v *hotelUsecase) GetWithParams(ctx context.Context, city string, stars int
 
 	return hotels, nil
 }
+
+func (v *hotelUsecase) GetOwned(ctx context.Context, actor user.Actor) (_ []hotel.Hotel, err error) {
+	usecaseCtx, end := v.getContext(ctx, "GetOwned")
+	defer end(&err)
+
+	if actor.PermissionScope(user.PermManageHotelRooms) == user.ScopeNone {
+		return nil, fmt.Errorf("%w to manage hotels", user.ErrNoPermission)
+	}
+
+	hotels, err := v.hotelRepo.GetHotelsByOwnerUuid(usecaseCtx, actor.Uuid)
+	if err != nil {
+		return nil, err
+	}
+
+	return hotels, nil
+}
+
+func (v *hotelUsecase) checkOwnerIsPartner(ctx context.Context, ownerUuid uuid.UUID) error {
+	if ownerUuid == uuid.Nil {
+		return nil
+	}
+
+	owner, getErr := v.userRepo.GetUserByUuid(ctx, ownerUuid)
+	if getErr != nil {
+		return fmt.Errorf("failed to find hotel owner: %v", getErr)
+	}
+
+	if owner.Role != user.RoleHotelManager {
+		return fmt.Errorf("hotel owner must have role '%v'", user.RoleHotelManager)
+	}
+
+	return nil
+}
@@ -0,0 +1,135 @@
+package hotelusecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/hotelrepository"
+	hotelroomrepository "github.com/rom6n/otello/internal/app/adapters/repository/hotelroomrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/rentrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/userrepository"
+	"github.com/rom6n/otello/internal/app/application/usecases/rentusecases"
+	"github.com/rom6n/otello/internal/app/domain/hotel"
+	"github.com/rom6n/otello/internal/app/domain/hotelroom"
+	"github.com/rom6n/otello/internal/app/domain/rent"
+	"github.com/rom6n/otello/internal/app/domain/user"
+	"github.com/rom6n/otello/internal/pkg/mailer"
+)
+
+type discardMailer struct{}
+
+func (discardMailer) Send(ctx context.Context, message mailer.Message) error {
+	return nil
+}
+
+// newHotelWithActiveRent returns the usecase, the rent repository and a hotel whose only room has a rent that hasn't ended
+func newHotelWithActiveRent(t *testing.T) (HotelUsecases, rentrepository.RentRepository, *hotel.Hotel, *rent.Rent) {
+	t.Helper()
+	ctx := context.Background()
+
+	hotelRepo := hotelrepository.NewMemory()
+	hotelRoomRepo := hotelroomrepository.NewMemory()
+	rentRepo := rentrepository.NewMemory()
+	userRepo := userrepository.NewMemory()
+	rentUsecase := rentusecases.New(rentRepo, hotelRoomRepo, hotelRepo, userRepo, discardMailer{}, 5*time.Second)
+
+	newHotel := hotel.NewHotel("Volga", "Kazan", 4, uuid.Nil)
+	if err := hotelRepo.CreateHotel(ctx, newHotel); err != nil {
+		t.Fatalf("CreateHotel: %v", err)
+	}
+
+	price := int64(5000)
+	newHotelRoom := hotelroom.NewHotelRoom(newHotel.Uuid, 2, hotelroom.Standard, 2, &price)
+	if err := hotelRoomRepo.CreateHotelRoom(ctx, newHotelRoom); err != nil {
+		t.Fatalf("CreateHotelRoom: %v", err)
+	}
+
+	now := time.Now()
+	activeRent := rent.NewRent(newHotelRoom.Uuid, uuid.New(), now.Add(24*time.Hour).Unix(), now.Add(72*time.Hour).Unix())
+	if err := rentRepo.CreateRent(ctx, activeRent); err != nil {
+		t.Fatalf("CreateRent: %v", err)
+	}
+
+	return New(hotelRepo, hotelRoomRepo, userRepo, rentUsecase, 5*time.Second), rentRepo, newHotel, activeRent
+}
+
+func TestForceDeleteWithApiKeyNeedsCancelPermission(t *testing.T) {
+	usecase, rentRepo, newHotel, activeRent := newHotelWithActiveRent(t)
+	ctx := context.Background()
+	apiKeyActor := user.Actor{Uuid: uuid.New(), Role: user.RoleAdmin, KeyPermissions: []user.Permission{user.PermManageHotels}}
+
+	if err := usecase.Delete(ctx, apiKeyActor, newHotel.Uuid, true); !errors.Is(err, user.ErrNoPermission) {
+		t.Fatalf("Delete with a 'hotels:manage' api key = %v, want %v", err, user.ErrNoPermission)
+	}
+
+	foundRent, _ := rentRepo.GetRent(ctx, activeRent.Uuid)
+	if foundRent.IsCancelled() {
+		t.Fatal("rent was cancelled by an api key without 'rents:cancel'")
+	}
+
+	apiKeyActor.KeyPermissions = append(apiKeyActor.KeyPermissions, user.PermCancelRents)
+	if err := usecase.Delete(ctx, apiKeyActor, newHotel.Uuid, true); err != nil {
+		t.Fatalf("Delete with 'hotels:manage' and 'rents:cancel': %v", err)
+	}
+
+	foundRent, _ = rentRepo.GetRent(ctx, activeRent.Uuid)
+	if !foundRent.IsCancelled() {
+		t.Fatal("forced delete left the rent active")
+	}
+}
+
+func TestDeleteWithoutForceKeepsActiveRents(t *testing.T) {
+	usecase, _, newHotel, _ := newHotelWithActiveRent(t)
+	admin := user.Actor{Uuid: uuid.New(), Role: user.RoleAdmin}
+
+	if err := usecase.Delete(context.Background(), admin, newHotel.Uuid, false); !errors.Is(err, rent.ErrHasActiveRents) {
+		t.Fatalf("Delete without force = %v, want %v", err, rent.ErrHasActiveRents)
+	}
+}
+
+func TestPartnerUpdatesOnlyOwnHotels(t *testing.T) {
+	usecase, _, otherHotel, _ := newHotelWithActiveRent(t)
+	ctx := context.Background()
+	partner := user.Actor{Uuid: uuid.New(), Role: user.RoleHotelManager}
+	hotelRepo := usecase.(*hotelUsecase).hotelRepo
+
+	otherHotel.OwnerUuid = uuid.New()
+	if err := hotelRepo.UpdateHotel(ctx, otherHotel); err != nil {
+		t.Fatalf("UpdateHotel: %v", err)
+	}
+
+	ownHotel := hotel.NewHotel("Kama", "Perm", 3, partner.Uuid)
+	if err := hotelRepo.CreateHotel(ctx, ownHotel); err != nil {
+		t.Fatalf("CreateHotel: %v", err)
+	}
+
+	renamed := *otherHotel
+	renamed.Name = "Not Volga"
+	if err := usecase.Update(ctx, partner, &renamed); !errors.Is(err, user.ErrNoPermission) {
+		t.Fatalf("Update of another partner's hotel = %v, want %v", err, user.ErrNoPermission)
+	}
+	if foundHotel, _ := usecase.Get(ctx, otherHotel.Uuid); foundHotel.Name != otherHotel.Name {
+		t.Fatalf("hotel name = %q, want it unchanged", foundHotel.Name)
+	}
+
+	// a partner can't hand a hotel over or delete one, only admins do that
+	handedOver := *ownHotel
+	handedOver.OwnerUuid = otherHotel.OwnerUuid
+	if err := usecase.Update(ctx, partner, &handedOver); !errors.Is(err, user.ErrNoPermission) {
+		t.Fatalf("Update changing the owner = %v, want %v", err, user.ErrNoPermission)
+	}
+	for _, hotelUuid := range []uuid.UUID{ownHotel.Uuid, otherHotel.Uuid} {
+		if err := usecase.Delete(ctx, partner, hotelUuid, false); !errors.Is(err, user.ErrNoPermission) {
+			t.Fatalf("Delete by a partner = %v, want %v", err, user.ErrNoPermission)
+		}
+	}
+
+	ownRenamed := *ownHotel
+	ownRenamed.Name = "Kama Riverside"
+	if err := usecase.Update(ctx, partner, &ownRenamed); err != nil {
+		t.Fatalf("Update of own hotel: %v", err)
+	}
+}
@@ -3,74 +3,234 @@ package rentusecases
 import (
 	"context"
 	"fmt"
+	"slices"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/hotelrepository"
+	hotelroomrepository "github.com/rom6n/otello/internal/app/adapters/repository/hotelroomrepository"
 	"github.com/rom6n/otello/internal/app/adapters/repository/rentrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/userrepository"
+	"github.com/rom6n/otello/internal/app/domain/hotelroom"
 	"github.com/rom6n/otello/internal/app/domain/rent"
 	"github.com/rom6n/otello/internal/app/domain/user"
+	"github.com/rom6n/otello/internal/pkg/logger"
+	"github.com/rom6n/otello/internal/pkg/mailer"
+	"github.com/rom6n/otello/internal/pkg/metrics"
+	"github.com/rom6n/otello/internal/pkg/tracing"
 )
 
 type RentUsecases interface {
 	Create(ctx context.Context, rent *rent.Rent) error
 	Delete(ctx context.Context, dto rent.DeleteDTO) error
+	GetWithParams(ctx context.Context, actor user.Actor, filter *rent.FindRentFilterDTO) ([]rent.Rent, error)
+	// CancelActiveRents is called before deleting hotel rooms, without force it only checks there are no rents that haven't ended
+	CancelActiveRents(ctx context.Context, actor user.Actor, hotelRoomUuids []uuid.UUID, force bool) error
 }
 
 type rentUsecase struct {
-	rentRepo rentrepository.RentRepository
-	timeout  time.Duration
+	rentRepo      rentrepository.RentRepository
+	hotelRoomRepo hotelroomrepository.HotelRoomRepository
+	hotelRepo     hotelrepository.HotelRepository
+	userRepo      userrepository.UserRepository
+	mailer        mailer.Mailer
+	timeout       time.Duration
 }
 
-func New(rentRepo rentrepository.RentRepository, timeout time.Duration) RentUsecases {
+func New(rentRepo rentrepository.RentRepository, hotelRoomRepo hotelroomrepository.HotelRoomRepository, hotelRepo hotelrepository.HotelRepository, userRepo userrepository.UserRepository, mailer mailer.Mailer, timeout time.Duration) RentUsecases {
 	return &rentUsecase{
-		rentRepo: rentRepo,
-		timeout:  timeout,
+		rentRepo:      rentRepo,
+		hotelRoomRepo: hotelRoomRepo,
+		hotelRepo:     hotelRepo,
+		userRepo:      userRepo,
+		mailer:        mailer,
+		timeout:       timeout,
 	}
 }
 
-func (v *rentUsecase) getContext(ctx context.Context) (context.Context, context.CancelFunc) {
-	return context.WithTimeout(ctx, v.timeout)
+func (v *rentUsecase) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return tracing.OperationContext(ctx, v.timeout, "usecase rent."+method)
 }
 
-func (v *rentUsecase) Create(ctx context.Context, rent *rent.Rent) error {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *rentUsecase) Create(ctx context.Context, newRent *rent.Rent) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "Create")
+	defer end(&err)
 
-	foundedRents, getRentsErr := v.rentRepo.GetRentsByHotelRoomUuid(usecaseCtx, rent.RoomUuid)
-	if getRentsErr != nil {
-		return getRentsErr
+	hotelRoom, getRoomErr := v.hotelRoomRepo.GetHotelRoom(usecaseCtx, newRent.RoomUuid)
+	if getRoomErr != nil {
+		return getRoomErr
 	}
-
-	for _, foundedRent := range foundedRents {
-		if foundedRent.DateFrom < rent.DateTo && foundedRent.DateTo > rent.DateFrom {
-			return fmt.Errorf("this hotel room is already rented for this time")
-		}
+	if hotelRoom.IsDeleted() {
+		return hotelroom.ErrDeleted
 	}
 
-	err := v.rentRepo.CreateRent(usecaseCtx, rent)
+	// the repository checks the dates, only it can do that together with the insert
+	err = v.rentRepo.CreateRent(usecaseCtx, newRent)
 	if err != nil {
 		return err
 	}
+	metrics.RentsCreated.Inc()
 
 	return nil
 }
 
-func (v *rentUsecase) Delete(ctx context.Context, dto rent.DeleteDTO) error {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *rentUsecase) Delete(ctx context.Context, dto rent.DeleteDTO) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "Delete")
+	defer end(&err)
 
 	foundedRent, findErr := v.rentRepo.GetRent(usecaseCtx, dto.Uuid)
 	if findErr != nil {
 		return findErr
 	}
 
-	if dto.UserRole != string(user.RoleAdmin) && foundedRent.RenterUuid != dto.UserUuid {
-		return fmt.Errorf("you dont have permission to delete this rent")
+	if foundedRent.RenterUuid != dto.UserUuid {
+		actor := user.Actor{Uuid: dto.UserUuid, Role: user.UserRole(dto.UserRole)}
+		if err := v.checkRoomAccess(usecaseCtx, actor, user.PermCancelRents, foundedRent.RoomUuid); err != nil {
+			return err
+		}
 	}
 
-	err := v.rentRepo.DeleteRent(usecaseCtx, dto.Uuid)
+	err = v.rentRepo.CancelRent(usecaseCtx, dto.Uuid, rent.CancelReasonRequest)
 	if err != nil {
 		return err
 	}
+	metrics.RentsCancelled.WithLabelValues(metrics.CancelReasonRequest).Inc()
+
+	return nil
+}
+
+func (v *rentUsecase) GetWithParams(ctx context.Context, actor user.Actor, filter *rent.FindRentFilterDTO) (_ []rent.Rent, err error) {
+	usecaseCtx, end := v.getContext(ctx, "GetWithParams")
+	defer end(&err)
+
+	switch actor.PermissionScope(user.PermReadRents) {
+	case user.ScopeNone:
+		return nil, fmt.Errorf("%w to read rents", user.ErrNoPermission)
+	case user.ScopeOwn:
+		if filter.HotelUuid == uuid.Nil {
+			return nil, fmt.Errorf("%w to read rents of all hotels, choose your hotel", user.ErrNoPermission)
+		}
+		if err := v.checkHotelAccess(usecaseCtx, actor, user.PermReadRents, filter.HotelUuid); err != nil {
+			return nil, err
+		}
+	}
+
+	if filter.HotelUuid != uuid.Nil {
+		hotelRooms, getRoomsErr := v.hotelRoomRepo.GetHotelRoomsWithParams(usecaseCtx, &hotelroom.FindHotelRoomFilterDTO{HotelUuid: filter.HotelUuid, IncludeDeleted: true})
+		if getRoomsErr != nil {
+			return nil, getRoomsErr
+		}
+
+		hotelRoomUuids := make([]uuid.UUID, 0, len(hotelRooms))
+		for _, hotelRoom := range hotelRooms {
+			if filter.RoomUuids == nil || slices.Contains(filter.RoomUuids, hotelRoom.Uuid) {
+				hotelRoomUuids = append(hotelRoomUuids, hotelRoom.Uuid)
+			}
+		}
+		filter.RoomUuids = hotelRoomUuids
+	}
+
+	rents, err := v.rentRepo.GetRentsWithParams(usecaseCtx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return rents, nil
+}
+
+func (v *rentUsecase) CancelActiveRents(ctx context.Context, actor user.Actor, hotelRoomUuids []uuid.UUID, force bool) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "CancelActiveRents")
+	defer end(&err)
+
+	if len(hotelRoomUuids) == 0 {
+		return nil
+	}
+
+	rents, getRentsErr := v.rentRepo.GetRentsWithParams(usecaseCtx, &rent.FindRentFilterDTO{RoomUuids: hotelRoomUuids})
+	if getRentsErr != nil {
+		return getRentsErr
+	}
+
+	now := time.Now().Unix()
+	var activeRents []rent.Rent
+	for _, foundRent := range rents {
+		if foundRent.DateTo > now && !foundRent.IsCancelled() {
+			activeRents = append(activeRents, foundRent)
+		}
+	}
+
+	if len(activeRents) == 0 {
+		return nil
+	}
+	if !force {
+		return rent.ErrHasActiveRents
+	}
+
+	for _, activeRent := range activeRents {
+		if err := v.checkRoomAccess(usecaseCtx, actor, user.PermCancelRents, activeRent.RoomUuid); err != nil {
+			return err
+		}
+	}
+
+	for _, activeRent := range activeRents {
+		if err := v.rentRepo.CancelRent(usecaseCtx, activeRent.Uuid, rent.CancelReasonForced); err != nil {
+			return err
+		}
+		metrics.RentsCancelled.WithLabelValues(metrics.CancelReasonForced).Inc()
+		v.notifyRentCancelled(usecaseCtx, &activeRent)
+	}
+
+	return nil
+}
+
+// notifyRentCancelled only logs failures, the rent is already cancelled and the deletion goes on
+func (v *rentUsecase) notifyRentCancelled(ctx context.Context, cancelledRent *rent.Rent) {
+	renter, getErr := v.userRepo.GetUserByUuid(ctx, cancelledRent.RenterUuid)
+	if getErr != nil {
+		logger.FromContext(ctx).Warn("failed to find renter of cancelled rent", "rent", cancelledRent.Uuid, "error", getErr)
+		return
+	}
+	if renter.IsDeleted() {
+		return
+	}
+
+	if err := v.mailer.Send(ctx, mailer.Message{
+		To:      renter.Email,
+		Subject: "Otello: бронь отменена",
+		Body: fmt.Sprintf(
+			"Ваша бронь номера с %v по %v отменена: номер больше не сдаётся.",
+			time.Unix(cancelledRent.DateFrom, 0).UTC().Format("02.01.2006"), time.Unix(cancelledRent.DateTo, 0).UTC().Format("02.01.2006"),
+		),
+	}); err != nil {
+		logger.FromContext(ctx).Warn("failed to notify renter about cancelled rent", "rent", cancelledRent.Uuid, "error", err)
+	}
+}
+
+func (v *rentUsecase) checkRoomAccess(ctx context.Context, actor user.Actor, permission user.Permission, hotelRoomUuid uuid.UUID) error {
+	switch actor.PermissionScope(permission) {
+	case user.ScopeAll:
+		return nil
+	case user.ScopeNone:
+		return fmt.Errorf("%w to manage this rent", user.ErrNoPermission)
+	}
+
+	foundHotelRoom, getErr := v.hotelRoomRepo.GetHotelRoom(ctx, hotelRoomUuid)
+	if getErr != nil {
+		return getErr
+	}
+
+	return v.checkHotelAccess(ctx, actor, permission, foundHotelRoom.HotelUuid)
+}
+
+func (v *rentUsecase) checkHotelAccess(ctx context.Context, actor user.Actor, permission user.Permission, hotelUuid uuid.UUID) error {
+	foundHotel, getErr := v.hotelRepo.GetHotel(ctx, hotelUuid)
+	if getErr != nil {
+		return getErr
+	}
+
+	if !actor.CanAccess(permission, foundHotel.OwnerUuid) {
+		return fmt.Errorf("%w to manage rents of this hotel", user.ErrNoPermission)
+	}
 
 	return nil
 }
@@ -0,0 +1,225 @@
+package rentusecases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/hotelrepository"
+	hotelroomrepository "github.com/rom6n/otello/internal/app/adapters/repository/hotelroomrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/rentrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/userrepository"
+	"github.com/rom6n/otello/internal/app/domain/hotel"
+	"github.com/rom6n/otello/internal/app/domain/hotelroom"
+	"github.com/rom6n/otello/internal/app/domain/rent"
+	"github.com/rom6n/otello/internal/app/domain/user"
+	"github.com/rom6n/otello/internal/pkg/mailer"
+)
+
+type discardMailer struct{}
+
+func (discardMailer) Send(ctx context.Context, message mailer.Message) error {
+	return nil
+}
+
+type testRentUsecase struct {
+	RentUsecases
+	rentRepo      rentrepository.RentRepository
+	hotelRoomRepo hotelroomrepository.HotelRoomRepository
+	managerUuid   uuid.UUID
+	roomUuid      uuid.UUID
+}
+
+// newTestRentUsecase has one hotel owned by a hotel manager with one room
+func newTestRentUsecase(t *testing.T) testRentUsecase {
+	t.Helper()
+	ctx := context.Background()
+
+	hotelRepo := hotelrepository.NewMemory()
+	hotelRoomRepo := hotelroomrepository.NewMemory()
+	rentRepo := rentrepository.NewMemory()
+
+	managerUuid := uuid.New()
+	newHotel := hotel.NewHotel("Volga", "Kazan", 4, managerUuid)
+	if err := hotelRepo.CreateHotel(ctx, newHotel); err != nil {
+		t.Fatalf("CreateHotel: %v", err)
+	}
+
+	price := int64(5000)
+	newHotelRoom := hotelroom.NewHotelRoom(newHotel.Uuid, 2, hotelroom.Standard, 2, &price)
+	if err := hotelRoomRepo.CreateHotelRoom(ctx, newHotelRoom); err != nil {
+		t.Fatalf("CreateHotelRoom: %v", err)
+	}
+
+	return testRentUsecase{
+		RentUsecases:  New(rentRepo, hotelRoomRepo, hotelRepo, userrepository.NewMemory(), discardMailer{}, 5*time.Second),
+		rentRepo:      rentRepo,
+		hotelRoomRepo: hotelRoomRepo,
+		managerUuid:   managerUuid,
+		roomUuid:      newHotelRoom.Uuid,
+	}
+}
+
+// day is the start of the n-th day from now, rents are counted in whole days here
+func day(n int) int64 {
+	return time.Now().Truncate(24*time.Hour).AddDate(0, 0, n).Unix()
+}
+
+func (v testRentUsecase) rent(t *testing.T, renterUuid uuid.UUID, fromDay, toDay int) *rent.Rent {
+	t.Helper()
+
+	newRent := rent.NewRent(v.roomUuid, renterUuid, day(fromDay), day(toDay))
+	if err := v.Create(context.Background(), newRent); err != nil {
+		t.Fatalf("Create days %v-%v: %v", fromDay, toDay, err)
+	}
+	return newRent
+}
+
+func TestCreateRejectsOverlappingRents(t *testing.T) {
+	for _, tt := range []struct {
+		name           string
+		fromDay, toDay int
+		ok             bool
+	}{
+		{"ends when the rent starts", 5, 10, true},
+		{"starts when the rent ends", 20, 25, true},
+		{"same dates", 10, 20, false},
+		{"overlaps the start", 8, 12, false},
+		{"overlaps the end", 18, 22, false},
+		{"inside", 12, 15, false},
+		{"around", 5, 25, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase := newTestRentUsecase(t)
+			usecase.rent(t, uuid.New(), 10, 20)
+
+			err := usecase.Create(context.Background(), rent.NewRent(usecase.roomUuid, uuid.New(), day(tt.fromDay), day(tt.toDay)))
+			if tt.ok && err != nil {
+				t.Fatalf("Create = %v, want ok", err)
+			}
+			if !tt.ok && !errors.Is(err, rent.ErrAlreadyRented) {
+				t.Fatalf("Create = %v, want %v", err, rent.ErrAlreadyRented)
+			}
+		})
+	}
+}
+
+func TestCreateRejectsDeletedRoom(t *testing.T) {
+	usecase := newTestRentUsecase(t)
+	if err := usecase.hotelRoomRepo.DeleteHotelRoom(context.Background(), usecase.roomUuid); err != nil {
+		t.Fatalf("DeleteHotelRoom: %v", err)
+	}
+
+	err := usecase.Create(context.Background(), rent.NewRent(usecase.roomUuid, uuid.New(), day(1), day(2)))
+	if !errors.Is(err, hotelroom.ErrDeleted) {
+		t.Fatalf("Create = %v, want %v", err, hotelroom.ErrDeleted)
+	}
+}
+
+func TestCancelledRentFreesItsDates(t *testing.T) {
+	usecase := newTestRentUsecase(t)
+	ctx := context.Background()
+	renterUuid := uuid.New()
+	cancelledRent := usecase.rent(t, renterUuid, 10, 20)
+
+	if err := usecase.Delete(ctx, rent.DeleteDTO{Uuid: cancelledRent.Uuid, UserUuid: renterUuid, UserRole: string(user.RoleUser)}); err != nil {
+		t.Fatalf("Delete by the renter: %v", err)
+	}
+
+	foundRent, _ := usecase.rentRepo.GetRent(ctx, cancelledRent.Uuid)
+	if !foundRent.IsCancelled() || foundRent.CancelReason != rent.CancelReasonRequest {
+		t.Fatalf("rent after Delete = %+v, want it kept and cancelled on request", foundRent)
+	}
+
+	usecase.rent(t, uuid.New(), 12, 15)
+}
+
+func TestDeleteChecksWhoCancels(t *testing.T) {
+	for _, tt := range []struct {
+		name  string
+		actor func(v testRentUsecase, renterUuid uuid.UUID) rent.DeleteDTO
+		ok    bool
+	}{
+		{"renter", func(v testRentUsecase, renterUuid uuid.UUID) rent.DeleteDTO {
+			return rent.DeleteDTO{UserUuid: renterUuid, UserRole: string(user.RoleUser)}
+		}, true},
+		{"other user", func(v testRentUsecase, renterUuid uuid.UUID) rent.DeleteDTO {
+			return rent.DeleteDTO{UserUuid: uuid.New(), UserRole: string(user.RoleUser)}
+		}, false},
+		{"manager of the hotel", func(v testRentUsecase, renterUuid uuid.UUID) rent.DeleteDTO {
+			return rent.DeleteDTO{UserUuid: v.managerUuid, UserRole: string(user.RoleHotelManager)}
+		}, true},
+		{"manager of another hotel", func(v testRentUsecase, renterUuid uuid.UUID) rent.DeleteDTO {
+			return rent.DeleteDTO{UserUuid: uuid.New(), UserRole: string(user.RoleHotelManager)}
+		}, false},
+		{"support agent", func(v testRentUsecase, renterUuid uuid.UUID) rent.DeleteDTO {
+			return rent.DeleteDTO{UserUuid: uuid.New(), UserRole: string(user.RoleSupportAgent)}
+		}, true},
+		{"airline operator", func(v testRentUsecase, renterUuid uuid.UUID) rent.DeleteDTO {
+			return rent.DeleteDTO{UserUuid: uuid.New(), UserRole: string(user.RoleAirlineOperator)}
+		}, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			usecase := newTestRentUsecase(t)
+			ctx := context.Background()
+			renterUuid := uuid.New()
+			activeRent := usecase.rent(t, renterUuid, 10, 20)
+
+			dto := tt.actor(usecase, renterUuid)
+			dto.Uuid = activeRent.Uuid
+			err := usecase.Delete(ctx, dto)
+			if tt.ok && err != nil {
+				t.Fatalf("Delete = %v, want ok", err)
+			}
+			if !tt.ok && !errors.Is(err, user.ErrNoPermission) {
+				t.Fatalf("Delete = %v, want %v", err, user.ErrNoPermission)
+			}
+
+			foundRent, _ := usecase.rentRepo.GetRent(ctx, activeRent.Uuid)
+			if foundRent.IsCancelled() != tt.ok {
+				t.Fatalf("rent cancelled = %v, want %v", foundRent.IsCancelled(), tt.ok)
+			}
+		})
+	}
+}
+
+func TestCancelActiveRents(t *testing.T) {
+	usecase := newTestRentUsecase(t)
+	ctx := context.Background()
+	admin := user.Actor{Uuid: uuid.New(), Role: user.RoleAdmin}
+	pastRent := usecase.rent(t, uuid.New(), -10, -5)
+	activeRent := usecase.rent(t, uuid.New(), 10, 20)
+	roomUuids := []uuid.UUID{usecase.roomUuid}
+
+	if err := usecase.CancelActiveRents(ctx, admin, roomUuids, false); !errors.Is(err, rent.ErrHasActiveRents) {
+		t.Fatalf("CancelActiveRents without force = %v, want %v", err, rent.ErrHasActiveRents)
+	}
+
+	for name, actor := range map[string]user.Actor{
+		"airline operator":                   {Uuid: uuid.New(), Role: user.RoleAirlineOperator},
+		"api key without 'rents:cancel'":     {Uuid: uuid.New(), Role: user.RoleAdmin, KeyPermissions: []user.Permission{user.PermManageHotels, user.PermManageHotelRooms}},
+		"manager key without 'rents:cancel'": {Uuid: usecase.managerUuid, Role: user.RoleHotelManager, KeyPermissions: []user.Permission{user.PermManageHotelRooms}},
+	} {
+		if err := usecase.CancelActiveRents(ctx, actor, roomUuids, true); !errors.Is(err, user.ErrNoPermission) {
+			t.Fatalf("CancelActiveRents by %v = %v, want %v", name, err, user.ErrNoPermission)
+		}
+	}
+
+	otherManager := user.Actor{Uuid: uuid.New(), Role: user.RoleHotelManager}
+	if err := usecase.CancelActiveRents(ctx, otherManager, roomUuids, true); !errors.Is(err, user.ErrNoPermission) {
+		t.Fatalf("CancelActiveRents by a manager of another hotel = %v, want %v", err, user.ErrNoPermission)
+	}
+
+	if err := usecase.CancelActiveRents(ctx, admin, roomUuids, true); err != nil {
+		t.Fatalf("CancelActiveRents with force: %v", err)
+	}
+
+	if foundRent, _ := usecase.rentRepo.GetRent(ctx, activeRent.Uuid); foundRent.CancelReason != rent.CancelReasonForced {
+		t.Fatalf("active rent = %+v, want it cancelled by force", foundRent)
+	}
+	if foundRent, _ := usecase.rentRepo.GetRent(ctx, pastRent.Uuid); foundRent.IsCancelled() {
+		t.Fatal("a rent that has ended was cancelled")
+	}
+}
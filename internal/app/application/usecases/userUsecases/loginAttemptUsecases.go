@@ -0,0 +1,137 @@
+package userusecases
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/domain/loginattempt"
+	"github.com/rom6n/otello/internal/pkg/logger"
+	"github.com/rom6n/otello/internal/pkg/metrics"
+)
+
+const loginFailureWindow = 1 * time.Hour
+
+// LoginPolicy is how failed logins in loginFailureWindow slow down and then lock the next ones
+type LoginPolicy struct {
+	FreeFailures int // failures allowed before the delays start, then the delay doubles from a second up to MaxDelay
+	LockAfter    int
+	LockDuration time.Duration
+	MaxDelay     time.Duration
+}
+
+// LoginPolicies limit failed logins per email and per IP, whichever blocks longer wins
+type LoginPolicies struct {
+	Email LoginPolicy
+	Ip    LoginPolicy
+}
+
+var DefaultLoginPolicies = LoginPolicies{
+	Email: LoginPolicy{FreeFailures: 3, LockAfter: 10, LockDuration: 15 * time.Minute, MaxDelay: 1 * time.Minute},
+	// one IP may be shared by many users (NAT, office), so it gets a looser limit
+	Ip: LoginPolicy{FreeFailures: 10, LockAfter: 50, LockDuration: 15 * time.Minute, MaxDelay: 1 * time.Minute},
+}
+
+func (p LoginPolicy) block(failures int, now int64) (nextAttemptAt, lockedUntil int64) {
+	if failures >= p.LockAfter {
+		return 0, now + int64(p.LockDuration.Seconds())
+	}
+	if failures <= p.FreeFailures {
+		return 0, 0
+	}
+
+	delay := time.Second << (failures - p.FreeFailures - 1)
+	if delay > p.MaxDelay || delay <= 0 {
+		delay = p.MaxDelay
+	}
+
+	return now + int64(delay.Seconds()), 0
+}
+
+// throttled checks that the failures before the reserved attempt have waited out their block.
+// A refused attempt counts too, so the wait it reports starts from it
+func (p LoginPolicy) throttled(loginAttempt *loginattempt.LoginAttempt, now int64) *loginattempt.ThrottledError {
+	nextAttemptAt, lockedUntil := p.block(loginAttempt.Failures-1, loginAttempt.PrevFailureAt)
+	if nextAttemptAt <= now && lockedUntil <= now {
+		return nil
+	}
+
+	nextAttemptAt, lockedUntil = p.block(loginAttempt.Failures, now)
+	if lockedUntil != 0 {
+		return &loginattempt.ThrottledError{RetryAfter: time.Duration(lockedUntil-now) * time.Second, Locked: true}
+	}
+	return &loginattempt.ThrottledError{RetryAfter: time.Duration(nextAttemptAt-now) * time.Second}
+}
+
+// reserveLoginAttempt counts the attempt as failed before the password is checked, so concurrent attempts
+// get different counts and can't all slip through before the first failure is stored.
+// An attempt with the right password must be given back with releaseLoginAttempt
+func (v *userUsecase) reserveLoginAttempt(ctx context.Context, email, ip string) error {
+	now := time.Now().Unix()
+
+	var throttledErr *loginattempt.ThrottledError
+	for key, policy := range map[string]LoginPolicy{
+		loginattempt.EmailKey(email): v.loginPolicies.Email,
+		loginattempt.IpKey(ip):       v.loginPolicies.Ip,
+	} {
+		loginAttempt, registerErr := v.loginAttemptRepo.RegisterLoginFailure(ctx, key, loginFailureWindow)
+		if registerErr != nil {
+			return registerErr
+		}
+
+		err := policy.throttled(loginAttempt, now)
+		if err != nil && (throttledErr == nil || err.RetryAfter > throttledErr.RetryAfter) {
+			throttledErr = err
+		}
+		if err != nil && err.Locked && loginAttempt.Failures-1 == policy.LockAfter {
+			logger.FromContext(ctx).Warn("login locked", "key", key, "failures", loginAttempt.Failures)
+		}
+	}
+
+	if throttledErr != nil {
+		metrics.LoginFailures.Inc()
+		return throttledErr
+	}
+
+	return nil
+}
+
+// releaseLoginAttempt never fails the login itself, a broken counter must not hide the result from the user
+func (v *userUsecase) releaseLoginAttempt(ctx context.Context, email, ip string) {
+	for _, key := range []string{loginattempt.EmailKey(email), loginattempt.IpKey(ip)} {
+		if err := v.loginAttemptRepo.ReleaseLoginFailure(ctx, key); err != nil {
+			logger.FromContext(ctx).Warn("failed to release login attempt", "key", key, "error", err)
+		}
+	}
+}
+
+// registerLoginFailure counts a failure after the password was right, a wrong 2FA code for example
+func (v *userUsecase) registerLoginFailure(ctx context.Context, email, ip string) {
+	metrics.LoginFailures.Inc()
+
+	for _, key := range []string{loginattempt.EmailKey(email), loginattempt.IpKey(ip)} {
+		if _, err := v.loginAttemptRepo.RegisterLoginFailure(ctx, key, loginFailureWindow); err != nil {
+			logger.FromContext(ctx).Warn("failed to register login failure", "key", key, "error", err)
+		}
+	}
+}
+
+func (v *userUsecase) UnlockLogin(ctx context.Context, userId uuid.UUID, ip string) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "UnlockLogin")
+	defer end(&err)
+
+	foundUser, getErr := v.userRepo.GetUserByUuid(usecaseCtx, userId)
+	if getErr != nil {
+		return getErr
+	}
+
+	if err := v.loginAttemptRepo.ResetLoginAttempts(usecaseCtx, loginattempt.EmailKey(foundUser.Email)); err != nil {
+		return err
+	}
+
+	if ip != "" {
+		return v.loginAttemptRepo.ResetLoginAttempts(usecaseCtx, loginattempt.IpKey(ip))
+	}
+
+	return nil
+}
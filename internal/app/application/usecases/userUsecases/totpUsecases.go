@@ -0,0 +1,243 @@
+package userusecases
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/domain/user"
+	"github.com/rom6n/otello/internal/pkg/logger"
+	"github.com/rom6n/otello/internal/utils/hashutils"
+	"github.com/rom6n/otello/internal/utils/totputils"
+)
+
+const (
+	totpIssuer         = "Otello"
+	recoveryCodesCount = 10
+)
+
+type TotpSetup struct {
+	Secret          string `json:"secret"`
+	ProvisioningUri string `json:"provisioning_uri"`
+}
+
+type TotpEnabled struct {
+	RecoveryCodes []string   `json:"recovery_codes"`
+	Tokens        *TokenPair `json:"tokens"`
+}
+
+// SetupTotp starts enrollment. 2FA is enabled only after EnableTotp confirms a code from the new secret
+func (v *userUsecase) SetupTotp(ctx context.Context, userId uuid.UUID, password, ip string) (_ *TotpSetup, err error) {
+	usecaseCtx, end := v.getContext(ctx, "SetupTotp")
+	defer end(&err)
+
+	foundUser, getErr := v.userRepo.GetUserByUuid(usecaseCtx, userId)
+	if getErr != nil {
+		return nil, getErr
+	}
+
+	if foundUser.TotpEnabled {
+		return nil, fmt.Errorf("two-factor authentication is already enabled")
+	}
+
+	if err := v.verifyUserPasswordLimited(usecaseCtx, foundUser, password, ip); err != nil {
+		return nil, err
+	}
+
+	secret, genErr := totputils.GenerateSecret()
+	if genErr != nil {
+		return nil, genErr
+	}
+
+	encryptedSecret, encryptErr := v.totpCipher.Encrypt(secret)
+	if encryptErr != nil {
+		return nil, encryptErr
+	}
+
+	if err := v.userRepo.UpdateUserTotpPendingSecret(usecaseCtx, userId, encryptedSecret); err != nil {
+		return nil, err
+	}
+
+	return &TotpSetup{
+		Secret:          secret,
+		ProvisioningUri: totputils.ProvisioningURI(secret, totpIssuer, foundUser.Email),
+	}, nil
+}
+
+// EnableTotp returns the recovery codes, they are shown only once. Sessions started before 2FA are ended,
+// the current one gets new tokens marked as a 2FA session
+func (v *userUsecase) EnableTotp(ctx context.Context, userId uuid.UUID, code string) (_ *TotpEnabled, err error) {
+	usecaseCtx, end := v.getContext(ctx, "EnableTotp")
+	defer end(&err)
+
+	foundUser, getErr := v.userRepo.GetUserByUuid(usecaseCtx, userId)
+	if getErr != nil {
+		return nil, getErr
+	}
+
+	if foundUser.TotpEnabled {
+		return nil, fmt.Errorf("two-factor authentication is already enabled")
+	}
+
+	if foundUser.TotpPendingSecret == "" {
+		return nil, fmt.Errorf("start two-factor authentication setup first")
+	}
+
+	pendingSecret, legacy, decryptErr := v.totpCipher.Decrypt(foundUser.TotpPendingSecret)
+	if decryptErr != nil {
+		return nil, decryptErr
+	}
+
+	counter, ok := totputils.ValidateCode(pendingSecret, code, time.Now())
+	if !ok {
+		return nil, user.ErrInvalidTwoFactorCode
+	}
+
+	encryptedSecret := foundUser.TotpPendingSecret
+	if legacy {
+		var encryptErr error
+		if encryptedSecret, encryptErr = v.totpCipher.Encrypt(pendingSecret); encryptErr != nil {
+			return nil, encryptErr
+		}
+	}
+
+	recoveryCodes, recoveryCodeHashes, genErr := generateRecoveryCodes()
+	if genErr != nil {
+		return nil, genErr
+	}
+
+	if err := v.userRepo.EnableUserTotp(usecaseCtx, userId, encryptedSecret, counter, recoveryCodeHashes); err != nil {
+		return nil, err
+	}
+
+	newTokenVersion, incErr := v.userRepo.IncrementUserTokenVersion(usecaseCtx, userId)
+	if incErr != nil {
+		return nil, incErr
+	}
+	foundUser.TokenVersion = newTokenVersion
+
+	jwtRefreshToken, jwtAccessToken, jwtErr := v.buildSessionTokens(foundUser, true)
+	if jwtErr != nil {
+		return nil, jwtErr
+	}
+
+	return &TotpEnabled{
+		RecoveryCodes: recoveryCodes,
+		Tokens:        newTokenPair(jwtAccessToken, jwtRefreshToken),
+	}, nil
+}
+
+func (v *userUsecase) DisableTotp(ctx context.Context, userId uuid.UUID, password, code, ip string) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "DisableTotp")
+	defer end(&err)
+
+	foundUser, getErr := v.userRepo.GetUserByUuid(usecaseCtx, userId)
+	if getErr != nil {
+		return getErr
+	}
+
+	if !foundUser.TotpEnabled {
+		return fmt.Errorf("two-factor authentication is not enabled")
+	}
+
+	if err := v.verifyUserPasswordLimited(usecaseCtx, foundUser, password, ip); err != nil {
+		return err
+	}
+
+	if err := v.verifySecondFactor(usecaseCtx, foundUser, code, true); err != nil {
+		return err
+	}
+
+	return v.userRepo.DisableUserTotp(usecaseCtx, userId)
+}
+
+func (v *userUsecase) RegenerateRecoveryCodes(ctx context.Context, userId uuid.UUID, code string) (_ []string, err error) {
+	usecaseCtx, end := v.getContext(ctx, "RegenerateRecoveryCodes")
+	defer end(&err)
+
+	foundUser, getErr := v.userRepo.GetUserByUuid(usecaseCtx, userId)
+	if getErr != nil {
+		return nil, getErr
+	}
+
+	if !foundUser.TotpEnabled {
+		return nil, fmt.Errorf("two-factor authentication is not enabled")
+	}
+
+	if err := v.verifySecondFactor(usecaseCtx, foundUser, code, false); err != nil {
+		return nil, err
+	}
+
+	recoveryCodes, recoveryCodeHashes, genErr := generateRecoveryCodes()
+	if genErr != nil {
+		return nil, genErr
+	}
+
+	if err := v.userRepo.UpdateUserRecoveryCodes(usecaseCtx, userId, recoveryCodeHashes); err != nil {
+		return nil, err
+	}
+
+	return recoveryCodes, nil
+}
+
+// verifySecondFactor accepts a TOTP code and, if allowRecoveryCode is set, a one-time recovery code
+func (v *userUsecase) verifySecondFactor(ctx context.Context, foundUser *user.User, code string, allowRecoveryCode bool) error {
+	secret, legacy, decryptErr := v.totpCipher.Decrypt(foundUser.TotpSecret)
+	if decryptErr != nil {
+		return decryptErr
+	}
+
+	if counter, ok := totputils.ValidateCode(secret, code, time.Now()); ok {
+		used, useErr := v.userRepo.UseUserTotpCounter(ctx, foundUser.Uuid, counter)
+		if useErr != nil {
+			return useErr
+		}
+		if !used {
+			return user.ErrInvalidTwoFactorCode
+		}
+		if legacy {
+			v.encryptLegacyTotpSecret(ctx, foundUser.Uuid, secret)
+		}
+		return nil
+	}
+
+	if !allowRecoveryCode {
+		return user.ErrInvalidTwoFactorCode
+	}
+
+	used, useErr := v.userRepo.UseUserRecoveryCode(ctx, foundUser.Uuid, hashutils.HashToken(totputils.NormalizeRecoveryCode(code)))
+	if useErr != nil {
+		return useErr
+	}
+	if !used {
+		return user.ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+// encryptLegacyTotpSecret upgrades a secret stored before the encryption, failing it must not fail the login
+func (v *userUsecase) encryptLegacyTotpSecret(ctx context.Context, userId uuid.UUID, secret string) {
+	encryptedSecret, encryptErr := v.totpCipher.Encrypt(secret)
+	if encryptErr == nil {
+		encryptErr = v.userRepo.UpdateUserTotpSecret(ctx, userId, encryptedSecret)
+	}
+	if encryptErr != nil {
+		logger.FromContext(ctx).Warn("failed to encrypt legacy totp secret", "user", userId, "error", encryptErr)
+	}
+}
+
+func generateRecoveryCodes() ([]string, []string, error) {
+	recoveryCodes, genErr := totputils.GenerateRecoveryCodes(recoveryCodesCount)
+	if genErr != nil {
+		return nil, nil, genErr
+	}
+
+	recoveryCodeHashes := make([]string, len(recoveryCodes))
+	for i, code := range recoveryCodes {
+		recoveryCodeHashes[i] = hashutils.HashToken(code)
+	}
+
+	return recoveryCodes, recoveryCodeHashes, nil
+}
@@ -0,0 +1,149 @@
+package userusecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/domain/user"
+	"github.com/rom6n/otello/internal/app/domain/usertoken"
+	"github.com/rom6n/otello/internal/pkg/logger"
+	"github.com/rom6n/otello/internal/pkg/metrics"
+)
+
+// ChangePassword revokes every other session and unused reset links and returns new tokens for the current one
+func (v *userUsecase) ChangePassword(ctx context.Context, userId uuid.UUID, oldPassword, newPassword, ip string, mfa bool) (_ *TokenPair, err error) {
+	usecaseCtx, end := v.getContext(ctx, "ChangePassword")
+	defer end(&err)
+
+	foundUser, getErr := v.userRepo.GetUserByUuid(usecaseCtx, userId)
+	if getErr != nil {
+		return nil, getErr
+	}
+
+	if err := v.verifyUserPasswordLimited(usecaseCtx, foundUser, oldPassword, ip); err != nil {
+		return nil, err
+	}
+
+	if err := v.passwordChecker.CheckPassword(newPassword, foundUser.Email, foundUser.Name); err != nil {
+		return nil, err
+	}
+
+	// a reset link leaked before the change must not overwrite the new password
+	if err := v.userTokenRepo.InvalidateUserTokens(usecaseCtx, userId, usertoken.PurposePasswordReset); err != nil {
+		return nil, err
+	}
+
+	if err := v.setPassword(usecaseCtx, userId, newPassword); err != nil {
+		return nil, err
+	}
+
+	newTokenVersion, incErr := v.userRepo.IncrementUserTokenVersion(usecaseCtx, userId)
+	if incErr != nil {
+		return nil, incErr
+	}
+	foundUser.TokenVersion = newTokenVersion
+
+	// bearer clients can't pick up cookies, so the new pair is also returned to them
+	jwtRefreshToken, jwtAccessToken, jwtErr := v.buildSessionTokens(foundUser, mfa)
+	if jwtErr != nil {
+		return nil, jwtErr
+	}
+
+	return newTokenPair(jwtAccessToken, jwtRefreshToken), nil
+}
+
+// DeleteAccount needs the 2FA code too when it is enabled, a stolen password alone must not erase the account
+func (v *userUsecase) DeleteAccount(ctx context.Context, userId uuid.UUID, password, code, ip string) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "DeleteAccount")
+	defer end(&err)
+
+	foundUser, getErr := v.userRepo.GetUserByUuid(usecaseCtx, userId)
+	if getErr != nil {
+		return getErr
+	}
+
+	if err := v.verifyUserPasswordLimited(usecaseCtx, foundUser, password, ip); err != nil {
+		return err
+	}
+
+	if foundUser.TotpEnabled {
+		if code == "" {
+			return user.ErrTwoFactorRequired
+		}
+
+		if err := v.verifySecondFactor(usecaseCtx, foundUser, code, true); err != nil {
+			if errors.Is(err, user.ErrInvalidTwoFactorCode) {
+				v.registerLoginFailure(usecaseCtx, foundUser.Email, ip)
+			}
+			return err
+		}
+	}
+
+	if err := v.userRepo.AnonymizeUser(usecaseCtx, userId); err != nil {
+		return err
+	}
+
+	for _, purpose := range []usertoken.TokenPurpose{usertoken.PurposeEmailVerification, usertoken.PurposePasswordReset, usertoken.PurposeEmailChange} {
+		if err := v.userTokenRepo.InvalidateUserTokens(usecaseCtx, userId, purpose); err != nil {
+			logger.FromContext(ctx).Warn("failed to invalidate tokens of deleted user", "purpose", purpose, "user", userId, "error", err)
+		}
+	}
+
+	return nil
+}
+
+// CheckSession rejects jwt issued before the last password change or to a deleted or disabled account
+func (v *userUsecase) CheckSession(ctx context.Context, userId uuid.UUID, tokenVersion int64) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "CheckSession")
+	defer end(&err)
+
+	foundUser, getErr := v.userRepo.GetUserByUuid(usecaseCtx, userId)
+	if getErr != nil {
+		return getErr
+	}
+
+	if foundUser.IsDeleted() || foundUser.TokenVersion != tokenVersion {
+		return user.ErrSessionRevoked
+	}
+
+	if foundUser.IsDisabled() {
+		return user.ErrAccountDisabled
+	}
+
+	return nil
+}
+
+func (v *userUsecase) setPassword(ctx context.Context, userId uuid.UUID, newPassword string) error {
+	hashedPassword, hashErr := v.passwordHasher.HashPassword(newPassword)
+	if hashErr != nil {
+		return fmt.Errorf("failed to hash password: %v", hashErr)
+	}
+
+	return v.userRepo.UpdateUserPassword(ctx, userId, hashedPassword)
+}
+
+func (v *userUsecase) verifyUserPassword(foundUser *user.User, password string) error {
+	if _, err := v.passwordHasher.VerifyPassword(password, foundUser.Password); err != nil {
+		return user.ErrWrongPassword
+	}
+
+	return nil
+}
+
+// verifyUserPasswordLimited counts wrong passwords like failed logins, so a stolen session can't be used
+// to guess the current password
+func (v *userUsecase) verifyUserPasswordLimited(ctx context.Context, foundUser *user.User, password, ip string) error {
+	if err := v.reserveLoginAttempt(ctx, foundUser.Email, ip); err != nil {
+		return err
+	}
+
+	if err := v.verifyUserPassword(foundUser, password); err != nil {
+		metrics.LoginFailures.Inc()
+		return err
+	}
+
+	v.releaseLoginAttempt(ctx, foundUser.Email, ip)
+	return nil
+}
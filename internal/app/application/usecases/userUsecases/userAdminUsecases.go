@@ -0,0 +1,98 @@
+package userusecases
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/domain/flightticket"
+	"github.com/rom6n/otello/internal/app/domain/rent"
+	"github.com/rom6n/otello/internal/app/domain/user"
+)
+
+type UserPage struct {
+	Users  []user.User `json:"users"`
+	Total  int64       `json:"total"`
+	Offset int64       `json:"offset"`
+	Limit  int64       `json:"limit"`
+}
+
+type UserDetails struct {
+	User      user.User               `json:"user"`
+	Rents     []rent.Rent             `json:"rents"`
+	Purchases []flightticket.Purchase `json:"flight_ticket_purchases"`
+}
+
+func (v *userUsecase) FindUsers(ctx context.Context, filter *user.FindUserFilterDTO) (_ *UserPage, err error) {
+	usecaseCtx, end := v.getContext(ctx, "FindUsers")
+	defer end(&err)
+
+	users, total, findErr := v.userRepo.FindUsers(usecaseCtx, filter)
+	if findErr != nil {
+		return nil, findErr
+	}
+
+	return &UserPage{
+		Users:  users,
+		Total:  total,
+		Offset: filter.Offset,
+		Limit:  filter.Limit,
+	}, nil
+}
+
+func (v *userUsecase) GetUserDetails(ctx context.Context, userId uuid.UUID) (_ *UserDetails, err error) {
+	usecaseCtx, end := v.getContext(ctx, "GetUserDetails")
+	defer end(&err)
+
+	foundUser, getErr := v.userRepo.GetUserByUuid(usecaseCtx, userId)
+	if getErr != nil {
+		return nil, getErr
+	}
+
+	rents, rentsErr := v.rentRepo.GetRentsWithParams(usecaseCtx, &rent.FindRentFilterDTO{RenterUuid: userId})
+	if rentsErr != nil {
+		return nil, rentsErr
+	}
+
+	purchases, purchasesErr := v.purchaseRepo.GetPurchasesByBuyerUuid(usecaseCtx, userId)
+	if purchasesErr != nil {
+		return nil, purchasesErr
+	}
+
+	return &UserDetails{
+		User:      *foundUser,
+		Rents:     rents,
+		Purchases: purchases,
+	}, nil
+}
+
+// DisableUser ends every session of the user and rejects their logins and api keys until EnableUser
+func (v *userUsecase) DisableUser(ctx context.Context, adminId, userId uuid.UUID) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "DisableUser")
+	defer end(&err)
+
+	if adminId == userId {
+		return fmt.Errorf("you can not disable your own account")
+	}
+
+	return v.userRepo.UpdateUserDisabled(usecaseCtx, userId, true)
+}
+
+func (v *userUsecase) EnableUser(ctx context.Context, userId uuid.UUID) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "EnableUser")
+	defer end(&err)
+
+	return v.userRepo.UpdateUserDisabled(usecaseCtx, userId, false)
+}
+
+// ForceLogout ends every session of the user, they can login again right away
+func (v *userUsecase) ForceLogout(ctx context.Context, userId uuid.UUID) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "ForceLogout")
+	defer end(&err)
+
+	if _, err := v.userRepo.IncrementUserTokenVersion(usecaseCtx, userId); err != nil {
+		return err
+	}
+
+	return nil
+}
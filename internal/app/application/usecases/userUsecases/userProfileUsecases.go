@@ -0,0 +1,62 @@
+package userusecases
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/domain/user"
+)
+
+type Profile struct {
+	user.User
+	Bookings BookingCounts `json:"bookings"`
+}
+
+type BookingCounts struct {
+	Rents         int64 `json:"rents"`
+	UpcomingRents int64 `json:"upcoming_rents"` // not ended yet
+}
+
+func (v *userUsecase) GetProfile(ctx context.Context, userId uuid.UUID) (_ *Profile, err error) {
+	usecaseCtx, end := v.getContext(ctx, "GetProfile")
+	defer end(&err)
+
+	return v.getProfile(usecaseCtx, userId)
+}
+
+func (v *userUsecase) UpdateProfile(ctx context.Context, userId uuid.UUID, update user.ProfileUpdate) (_ *Profile, err error) {
+	usecaseCtx, end := v.getContext(ctx, "UpdateProfile")
+	defer end(&err)
+
+	if err := v.userRepo.UpdateUserProfile(usecaseCtx, userId, update); err != nil {
+		return nil, err
+	}
+
+	return v.getProfile(usecaseCtx, userId)
+}
+
+func (v *userUsecase) getProfile(ctx context.Context, userId uuid.UUID) (*Profile, error) {
+	foundUser, getErr := v.userRepo.GetUserByUuid(ctx, userId)
+	if getErr != nil {
+		return nil, getErr
+	}
+
+	rents, countErr := v.rentRepo.CountRentsByRenterUuid(ctx, userId, 0)
+	if countErr != nil {
+		return nil, countErr
+	}
+
+	upcomingRents, countErr := v.rentRepo.CountRentsByRenterUuid(ctx, userId, time.Now().Unix())
+	if countErr != nil {
+		return nil, countErr
+	}
+
+	return &Profile{
+		User: *foundUser,
+		Bookings: BookingCounts{
+			Rents:         rents,
+			UpcomingRents: upcomingRents,
+		},
+	}, nil
+}
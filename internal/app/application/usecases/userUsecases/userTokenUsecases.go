@@ -0,0 +1,243 @@
+package userusecases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/userrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/usertokenrepository"
+	"github.com/rom6n/otello/internal/app/domain/user"
+	"github.com/rom6n/otello/internal/app/domain/usertoken"
+	"github.com/rom6n/otello/internal/pkg/logger"
+	"github.com/rom6n/otello/internal/pkg/mailer"
+	"github.com/rom6n/otello/internal/utils/hashutils"
+)
+
+const (
+	emailVerificationTokenTTL = 24 * time.Hour
+	passwordResetTokenTTL     = 1 * time.Hour
+	emailChangeTokenTTL       = 24 * time.Hour
+)
+
+func (v *userUsecase) SendEmailVerification(ctx context.Context, userId uuid.UUID) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "SendEmailVerification")
+	defer end(&err)
+
+	foundUser, getErr := v.userRepo.GetUserByUuid(usecaseCtx, userId)
+	if getErr != nil {
+		return getErr
+	}
+
+	if foundUser.EmailVerified {
+		return fmt.Errorf("email is already verified")
+	}
+
+	return v.sendEmailVerification(usecaseCtx, foundUser)
+}
+
+func (v *userUsecase) VerifyEmail(ctx context.Context, token string) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "VerifyEmail")
+	defer end(&err)
+
+	userToken, useErr := v.userTokenRepo.UseUserToken(usecaseCtx, hashutils.HashToken(token), usertoken.PurposeEmailVerification)
+	if useErr != nil {
+		return useErr
+	}
+
+	return v.userRepo.UpdateUserEmailVerified(usecaseCtx, userToken.UserUuid, true)
+}
+
+// RequestPasswordReset does not report unknown emails so the route can't be used to enumerate accounts
+func (v *userUsecase) RequestPasswordReset(ctx context.Context, email string) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "RequestPasswordReset")
+	defer end(&err)
+
+	foundUser, getErr := v.userRepo.GetUser(usecaseCtx, user.NormalizeEmail(email))
+	if errors.Is(getErr, userrepository.ErrUserNotFound) {
+		return nil
+	}
+	if getErr != nil {
+		return getErr
+	}
+
+	token, issueErr := v.issueUserToken(usecaseCtx, foundUser.Uuid, usertoken.PurposePasswordReset, passwordResetTokenTTL)
+	if issueErr != nil {
+		return issueErr
+	}
+
+	// a failed delivery is not reported either, the answer would be different for registered emails only
+	if err := v.mailer.Send(usecaseCtx, mailer.Message{
+		To:      foundUser.Email,
+		Subject: "Otello: сброс пароля",
+		Body: fmt.Sprintf(
+			"Для сброса пароля отправьте этот код вместе с новым паролем на %s/api/user/password-reset/confirm\n\nКод: %s\n\nКод действует %v. Если вы не запрашивали сброс пароля, проигнорируйте это письмо.",
+			v.appBaseUrl, token, passwordResetTokenTTL,
+		),
+	}); err != nil {
+		logger.FromContext(ctx).Error("failed to send password reset email", "user", foundUser.Uuid, "error", err)
+	}
+
+	return nil
+}
+
+func (v *userUsecase) ResetPassword(ctx context.Context, token, newPassword string) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "ResetPassword")
+	defer end(&err)
+
+	tokenHash := hashutils.HashToken(token)
+
+	// the token is used only after the new password passes the checks, so a rejected password does not burn it
+	userToken, getErr := v.userTokenRepo.GetUserToken(usecaseCtx, tokenHash, usertoken.PurposePasswordReset)
+	if getErr != nil {
+		return getErr
+	}
+
+	foundUser, userErr := v.userRepo.GetUserByUuid(usecaseCtx, userToken.UserUuid)
+	if userErr != nil {
+		return userErr
+	}
+
+	if err := v.passwordChecker.CheckPassword(newPassword, foundUser.Email, foundUser.Name); err != nil {
+		return err
+	}
+
+	if _, useErr := v.userTokenRepo.UseUserToken(usecaseCtx, tokenHash, usertoken.PurposePasswordReset); useErr != nil {
+		return useErr
+	}
+
+	if err := v.setPassword(usecaseCtx, userToken.UserUuid, newPassword); err != nil {
+		return err
+	}
+
+	_, incErr := v.userRepo.IncrementUserTokenVersion(usecaseCtx, userToken.UserUuid)
+	return incErr
+}
+
+// RequestEmailChange keeps the current email until the new one is confirmed by the link sent to it
+func (v *userUsecase) RequestEmailChange(ctx context.Context, userId uuid.UUID, password, newEmail, ip string) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "RequestEmailChange")
+	defer end(&err)
+
+	foundUser, getErr := v.userRepo.GetUserByUuid(usecaseCtx, userId)
+	if getErr != nil {
+		return getErr
+	}
+
+	if err := v.verifyUserPasswordLimited(usecaseCtx, foundUser, password, ip); err != nil {
+		return err
+	}
+
+	newEmail = user.NormalizeEmail(newEmail)
+	if foundUser.Email == newEmail {
+		return fmt.Errorf("new email is the same as the current one")
+	}
+
+	if err := v.checkEmailIsFree(usecaseCtx, newEmail); err != nil {
+		return err
+	}
+
+	if err := v.userRepo.UpdateUserPendingEmail(usecaseCtx, userId, newEmail); err != nil {
+		return err
+	}
+
+	token, issueErr := v.issueUserToken(usecaseCtx, userId, usertoken.PurposeEmailChange, emailChangeTokenTTL)
+	if issueErr != nil {
+		return issueErr
+	}
+
+	if err := v.mailer.Send(usecaseCtx, mailer.Message{
+		To:      newEmail,
+		Subject: "Otello: подтверждение нового email",
+		Body: fmt.Sprintf(
+			"Чтобы сменить email аккаунта на этот адрес, перейдите по ссылке:\n%s/api/user/change-email/confirm?token=%s\n\nСсылка действует %v.",
+			v.appBaseUrl, token, emailChangeTokenTTL,
+		),
+	}); err != nil {
+		return err
+	}
+
+	if err := v.mailer.Send(usecaseCtx, mailer.Message{
+		To:      foundUser.Email,
+		Subject: "Otello: запрошена смена email",
+		Body:    fmt.Sprintf("Для вашего аккаунта запрошена смена email на %s. Если это были не вы, смените пароль.", newEmail),
+	}); err != nil {
+		logger.FromContext(ctx).Warn("failed to notify the old email about email change", "user", userId, "error", err)
+	}
+
+	return nil
+}
+
+func (v *userUsecase) ConfirmEmailChange(ctx context.Context, token string) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "ConfirmEmailChange")
+	defer end(&err)
+
+	userToken, useErr := v.userTokenRepo.UseUserToken(usecaseCtx, hashutils.HashToken(token), usertoken.PurposeEmailChange)
+	if useErr != nil {
+		return useErr
+	}
+
+	foundUser, getErr := v.userRepo.GetUserByUuid(usecaseCtx, userToken.UserUuid)
+	if getErr != nil {
+		return getErr
+	}
+
+	if foundUser.PendingEmail == "" {
+		return usertokenrepository.ErrInvalidToken
+	}
+
+	// the address could have been registered by someone else while the link was waiting
+	if err := v.checkEmailIsFree(usecaseCtx, foundUser.PendingEmail); err != nil {
+		return err
+	}
+
+	return v.userRepo.UpdateUserEmail(usecaseCtx, foundUser.Uuid, foundUser.PendingEmail)
+}
+
+func (v *userUsecase) checkEmailIsFree(ctx context.Context, email string) error {
+	_, getErr := v.userRepo.GetUser(ctx, email)
+	if getErr == nil {
+		return userrepository.ErrEmailTaken
+	}
+	if !errors.Is(getErr, userrepository.ErrUserNotFound) {
+		return getErr
+	}
+
+	return nil
+}
+
+func (v *userUsecase) sendEmailVerification(ctx context.Context, foundUser *user.User) error {
+	token, issueErr := v.issueUserToken(ctx, foundUser.Uuid, usertoken.PurposeEmailVerification, emailVerificationTokenTTL)
+	if issueErr != nil {
+		return issueErr
+	}
+
+	return v.mailer.Send(ctx, mailer.Message{
+		To:      foundUser.Email,
+		Subject: "Otello: подтверждение email",
+		Body: fmt.Sprintf(
+			"Чтобы подтвердить email, перейдите по ссылке:\n%s/api/user/verify-email/confirm?token=%s\n\nСсылка действует %v.",
+			v.appBaseUrl, token, emailVerificationTokenTTL,
+		),
+	})
+}
+
+// issueUserToken invalidates previous unused tokens of the purpose, so only the latest email works
+func (v *userUsecase) issueUserToken(ctx context.Context, userId uuid.UUID, purpose usertoken.TokenPurpose, ttl time.Duration) (string, error) {
+	if err := v.userTokenRepo.InvalidateUserTokens(ctx, userId, purpose); err != nil {
+		return "", err
+	}
+
+	token, genErr := hashutils.GenerateToken()
+	if genErr != nil {
+		return "", genErr
+	}
+
+	if err := v.userTokenRepo.CreateUserToken(ctx, usertoken.NewUserToken(userId, purpose, hashutils.HashToken(token), ttl)); err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
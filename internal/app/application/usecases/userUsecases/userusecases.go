@@ -2,103 +2,273 @@ package userusecases
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/flightticketpurchaserepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/loginattemptrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/rentrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/rolegrantrepository"
 	"github.com/rom6n/otello/internal/app/adapters/repository/userrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/usertokenrepository"
+	"github.com/rom6n/otello/internal/app/domain/loginattempt"
+	"github.com/rom6n/otello/internal/app/domain/rolegrant"
 	"github.com/rom6n/otello/internal/app/domain/user"
+	"github.com/rom6n/otello/internal/pkg/logger"
+	"github.com/rom6n/otello/internal/pkg/mailer"
+	"github.com/rom6n/otello/internal/pkg/metrics"
+	"github.com/rom6n/otello/internal/pkg/tracing"
 	"github.com/rom6n/otello/internal/utils/hashutils"
 	"github.com/rom6n/otello/internal/utils/httputils"
 	"github.com/rom6n/otello/internal/utils/jwtutils"
+	"github.com/rom6n/otello/internal/utils/passwordutils"
+	"github.com/rom6n/otello/internal/utils/totputils"
 )
 
-const AdminPasswordConst = "5SMpOxD4IFrXfCqy.T0jQA4ufiTHnLbLsUJySnL6mgB6u65ZmMjxiTsuXQcI"
-
 type UserUsecases interface {
 	Register(ctx context.Context, user *user.User) (*fiber.Cookie, *fiber.Cookie, error)
-	Login(ctx context.Context, email, password string) (*fiber.Cookie, *fiber.Cookie, *user.User, error)
+	Login(ctx context.Context, email, password, code, ip string) (*fiber.Cookie, *fiber.Cookie, *user.User, error)
+	IssueTokens(ctx context.Context, email, password, code, ip string) (*TokenPair, error)
+	RefreshAccessToken(ctx context.Context, refreshToken string) (*TokenPair, error)
+	UnlockLogin(ctx context.Context, userId uuid.UUID, ip string) error
+	FindUsers(ctx context.Context, filter *user.FindUserFilterDTO) (*UserPage, error)
+	GetUserDetails(ctx context.Context, userId uuid.UUID) (*UserDetails, error)
+	DisableUser(ctx context.Context, adminId, userId uuid.UUID) error
+	EnableUser(ctx context.Context, userId uuid.UUID) error
+	ForceLogout(ctx context.Context, userId uuid.UUID) error
 	ChangeName(ctx context.Context, userId uuid.UUID, newName string) error
-	ChangeRole(ctx context.Context, userId uuid.UUID, newRole user.UserRole, password string) (*fiber.Cookie, *fiber.Cookie, error)
+	GetProfile(ctx context.Context, userId uuid.UUID) (*Profile, error)
+	UpdateProfile(ctx context.Context, userId uuid.UUID, update user.ProfileUpdate) (*Profile, error)
+	GrantRole(ctx context.Context, adminId, userId uuid.UUID, newRole user.UserRole) error
+	RevokeRole(ctx context.Context, adminId, userId uuid.UUID) error
+	GetRoleGrants(ctx context.Context, userId uuid.UUID) ([]rolegrant.RoleGrant, error)
+	BootstrapAdmin(ctx context.Context, email string) error
+	MigrateEmails(ctx context.Context) error
+	FindDuplicateEmails(ctx context.Context) ([]userrepository.DuplicateEmail, error)
+	SendEmailVerification(ctx context.Context, userId uuid.UUID) error
+	VerifyEmail(ctx context.Context, token string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, token, newPassword string) error
+	ChangePassword(ctx context.Context, userId uuid.UUID, oldPassword, newPassword, ip string, mfa bool) (*TokenPair, error)
+	RequestEmailChange(ctx context.Context, userId uuid.UUID, password, newEmail, ip string) error
+	ConfirmEmailChange(ctx context.Context, token string) error
+	DeleteAccount(ctx context.Context, userId uuid.UUID, password, code, ip string) error
+	CheckSession(ctx context.Context, userId uuid.UUID, tokenVersion int64) error
+	SetupTotp(ctx context.Context, userId uuid.UUID, password, ip string) (*TotpSetup, error)
+	EnableTotp(ctx context.Context, userId uuid.UUID, code string) (*TotpEnabled, error)
+	DisableTotp(ctx context.Context, userId uuid.UUID, password, code, ip string) error
+	RegenerateRecoveryCodes(ctx context.Context, userId uuid.UUID, code string) ([]string, error)
+}
+
+var (
+	ErrOwnRole          = errors.New("you can not change your own role")
+	ErrRoleAlreadyGiven = errors.New("user already has role")
+	ErrNoRoleToRevoke   = errors.New("user has no role to revoke")
+)
+
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	ExpiresIn    int    `json:"expires_in" example:"600"`
 }
 
 type userUsecase struct {
-	userRepo     userrepository.UserRepository
-	jwtUtilsRepo jwtutils.JwtRepository
-	timeout      time.Duration
+	userRepo         userrepository.UserRepository
+	roleGrantRepo    rolegrantrepository.RoleGrantRepository
+	userTokenRepo    usertokenrepository.UserTokenRepository
+	loginAttemptRepo loginattemptrepository.LoginAttemptRepository
+	rentRepo         rentrepository.RentRepository
+	purchaseRepo     flightticketpurchaserepository.FlightTicketPurchaseRepository
+	jwtUtilsRepo     jwtutils.JwtRepository
+	mailer           mailer.Mailer
+	passwordHasher   hashutils.PasswordHasher
+	passwordChecker  passwordutils.PasswordChecker
+	totpCipher       *totputils.SecretCipher
+	loginPolicies    LoginPolicies
+	appBaseUrl       string
+	cookies          httputils.Cookies
+	timeout          time.Duration
 }
 
-func New(userRepo userrepository.UserRepository, jwtUtilsRepo jwtutils.JwtRepository, timeout time.Duration) UserUsecases {
+func New(userRepo userrepository.UserRepository, roleGrantRepo rolegrantrepository.RoleGrantRepository, userTokenRepo usertokenrepository.UserTokenRepository, loginAttemptRepo loginattemptrepository.LoginAttemptRepository, rentRepo rentrepository.RentRepository, purchaseRepo flightticketpurchaserepository.FlightTicketPurchaseRepository, jwtUtilsRepo jwtutils.JwtRepository, mailer mailer.Mailer, passwordHasher hashutils.PasswordHasher, passwordChecker passwordutils.PasswordChecker, totpCipher *totputils.SecretCipher, loginPolicies LoginPolicies, appBaseUrl string, cookies httputils.Cookies, timeout time.Duration) UserUsecases {
 	return &userUsecase{
-		userRepo:     userRepo,
-		jwtUtilsRepo: jwtUtilsRepo,
-		timeout:      timeout,
+		userRepo:         userRepo,
+		roleGrantRepo:    roleGrantRepo,
+		userTokenRepo:    userTokenRepo,
+		loginAttemptRepo: loginAttemptRepo,
+		rentRepo:         rentRepo,
+		purchaseRepo:     purchaseRepo,
+		jwtUtilsRepo:     jwtUtilsRepo,
+		mailer:           mailer,
+		passwordHasher:   passwordHasher,
+		passwordChecker:  passwordChecker,
+		totpCipher:       totpCipher,
+		loginPolicies:    loginPolicies,
+		appBaseUrl:       appBaseUrl,
+		cookies:          cookies,
+		timeout:          timeout,
 	}
 }
 
-func (v *userUsecase) getContext(ctx context.Context) (context.Context, context.CancelFunc) {
-	return context.WithTimeout(ctx, v.timeout)
+func (v *userUsecase) getContext(ctx context.Context, method string) (context.Context, func(*error)) {
+	return tracing.OperationContext(ctx, v.timeout, "usecase user."+method)
 }
 
-func (v *userUsecase) Register(ctx context.Context, user *user.User) (*fiber.Cookie, *fiber.Cookie, error) {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *userUsecase) Register(ctx context.Context, user *user.User) (_ *fiber.Cookie, _ *fiber.Cookie, err error) {
+	usecaseCtx, end := v.getContext(ctx, "Register")
+	defer end(&err)
 
-	salt, saltErr := hashutils.GenerateSalt()
-	if saltErr != nil {
-		return nil, nil, fmt.Errorf("failed to generate salt: %v", saltErr)
+	if err := v.passwordChecker.CheckPassword(user.Password, user.Email, user.Name); err != nil {
+		return nil, nil, err
 	}
 
-	hashedPassword := hashutils.HashPassword(user.Password, salt)
+	hashedPassword, hashErr := v.passwordHasher.HashPassword(user.Password)
+	if hashErr != nil {
+		return nil, nil, fmt.Errorf("failed to hash password: %v", hashErr)
+	}
 	user.Password = hashedPassword
 
 	if createErr := v.userRepo.CreateUser(usecaseCtx, user); createErr != nil {
 		return nil, nil, createErr
 	}
 
-	jwtRefreshToken, jwtErr := v.jwtUtilsRepo.NewJwt(user.Uuid, user.Role, httputils.JwtRefreshToken)
+	if sendErr := v.sendEmailVerification(usecaseCtx, user); sendErr != nil {
+		logger.FromContext(ctx).Warn("failed to send verification email after register", "user", user.Uuid, "error", sendErr)
+	}
+
+	return v.buildSessionCookies(user, false)
+}
+
+func (v *userUsecase) Login(ctx context.Context, email, password, code, ip string) (_ *fiber.Cookie, _ *fiber.Cookie, _ *user.User, err error) {
+	usecaseCtx, end := v.getContext(ctx, "Login")
+	defer end(&err)
+
+	foundUser, authErr := v.authenticate(usecaseCtx, email, password, code, ip)
+	if authErr != nil {
+		return nil, nil, nil, authErr
+	}
+
+	jwtRefreshCookie, jwtAccessCookie, cookieErr := v.buildSessionCookies(foundUser, foundUser.TotpEnabled)
+	if cookieErr != nil {
+		return nil, nil, nil, cookieErr
+	}
+
+	return jwtRefreshCookie, jwtAccessCookie, foundUser, nil
+}
+
+// IssueTokens is Login for clients without cookies, the tokens are sent back as 'Authorization: Bearer'
+func (v *userUsecase) IssueTokens(ctx context.Context, email, password, code, ip string) (_ *TokenPair, err error) {
+	usecaseCtx, end := v.getContext(ctx, "IssueTokens")
+	defer end(&err)
+
+	foundUser, authErr := v.authenticate(usecaseCtx, email, password, code, ip)
+	if authErr != nil {
+		return nil, authErr
+	}
+
+	jwtRefreshToken, jwtAccessToken, jwtErr := v.buildSessionTokens(foundUser, foundUser.TotpEnabled)
 	if jwtErr != nil {
-		return nil, nil, fmt.Errorf("failed to create refresh jwt token: %v", jwtErr)
+		return nil, jwtErr
 	}
 
-	jwtAccessToken, jwtErr := v.jwtUtilsRepo.NewJwt(user.Uuid, user.Role, httputils.JwtAccessToken)
+	return newTokenPair(jwtAccessToken, jwtRefreshToken), nil
+}
+
+func (v *userUsecase) RefreshAccessToken(ctx context.Context, refreshToken string) (_ *TokenPair, err error) {
+	usecaseCtx, end := v.getContext(ctx, "RefreshAccessToken")
+	defer end(&err)
+
+	claims, verifyErr := v.jwtUtilsRepo.VerifyJwt(refreshToken)
+	if verifyErr != nil || !jwtutils.IsUsage(claims, httputils.JwtRefreshToken) {
+		return nil, user.ErrSessionRevoked
+	}
+
+	session, parseErr := jwtutils.SessionFromClaims(claims)
+	if parseErr != nil {
+		return nil, user.ErrSessionRevoked
+	}
+
+	if err := v.CheckSession(usecaseCtx, session.UserUuid, session.TokenVersion); err != nil {
+		return nil, err
+	}
+
+	jwtAccessToken, jwtErr := v.jwtUtilsRepo.NewJwt(session, httputils.JwtAccessToken)
 	if jwtErr != nil {
-		return nil, nil, fmt.Errorf("failed to create access jwt token: %v", jwtErr)
+		return nil, fmt.Errorf("failed to create access jwt token: %v", jwtErr)
 	}
 
-	return httputils.BuildCookie(jwtRefreshToken, httputils.JwtRefreshToken), httputils.BuildCookie(jwtAccessToken, httputils.JwtAccessToken), nil
+	return newTokenPair(jwtAccessToken, ""), nil
 }
 
-func (v *userUsecase) Login(ctx context.Context, email, password string) (*fiber.Cookie, *fiber.Cookie, *user.User, error) {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *userUsecase) authenticate(ctx context.Context, email, password, code, ip string) (*user.User, error) {
+	email = user.NormalizeEmail(email)
 
-	foundUser, userErr := v.userRepo.GetUser(usecaseCtx, email)
+	if err := v.reserveLoginAttempt(ctx, email, ip); err != nil {
+		return nil, err
+	}
+
+	foundUser, userErr := v.userRepo.GetUser(ctx, email)
+	if errors.Is(userErr, userrepository.ErrUserNotFound) {
+		v.passwordHasher.VerifyDummy(password)
+		metrics.LoginFailures.Inc()
+		return nil, user.ErrInvalidCredentials
+	}
 	if userErr != nil {
-		return nil, nil, nil, userErr
+		v.releaseLoginAttempt(ctx, email, ip)
+		return nil, userErr
 	}
 
-	if verifyErr := hashutils.VerifyPassword(password, foundUser.Password); verifyErr != nil {
-		return nil, nil, nil, fmt.Errorf("failed to verify password: %v", verifyErr)
+	needsRehash, verifyErr := v.passwordHasher.VerifyPassword(password, foundUser.Password)
+	if verifyErr != nil && !errors.Is(verifyErr, hashutils.ErrPasswordMismatch) {
+		// a broken stored hash is our problem, but the answer must not tell the account exists
+		logger.FromContext(ctx).Error("failed to verify password", "user", foundUser.Uuid, "error", verifyErr)
+		v.passwordHasher.VerifyDummy(password)
+	}
+	if verifyErr != nil {
+		metrics.LoginFailures.Inc()
+		return nil, user.ErrInvalidCredentials
 	}
 
-	jwtRefreshToken, jwtErr := v.jwtUtilsRepo.NewJwt(foundUser.Uuid, foundUser.Role, httputils.JwtRefreshToken)
-	if jwtErr != nil {
-		return nil, nil, nil, fmt.Errorf("failed to create refresh jwt token: %v", jwtErr)
+	v.releaseLoginAttempt(ctx, email, ip)
+
+	if foundUser.IsDisabled() {
+		return nil, user.ErrAccountDisabled
 	}
 
-	jwtAccessToken, jwtErr := v.jwtUtilsRepo.NewJwt(foundUser.Uuid, foundUser.Role, httputils.JwtAccessToken)
-	if jwtErr != nil {
-		return nil, nil, nil, fmt.Errorf("failed to create access jwt token: %v", jwtErr)
+	if foundUser.TotpEnabled {
+		if code == "" {
+			return nil, user.ErrTwoFactorRequired
+		}
+
+		if err := v.verifySecondFactor(ctx, foundUser, code, true); err != nil {
+			if errors.Is(err, user.ErrInvalidTwoFactorCode) {
+				v.registerLoginFailure(ctx, email, ip)
+			}
+			return nil, err
+		}
 	}
 
-	return httputils.BuildCookie(jwtRefreshToken, httputils.JwtRefreshToken), httputils.BuildCookie(jwtAccessToken, httputils.JwtAccessToken), foundUser, nil
+	if err := v.loginAttemptRepo.ResetLoginAttempts(ctx, loginattempt.EmailKey(email)); err != nil {
+		logger.FromContext(ctx).Warn("failed to reset login attempts after successful login", "user", foundUser.Uuid, "error", err)
+	}
+
+	// the password is known only here, so hashes made with old params or in the old format are upgraded on login
+	if needsRehash {
+		if err := v.setPassword(ctx, foundUser.Uuid, password); err != nil {
+			logger.FromContext(ctx).Warn("failed to rehash password on login", "user", foundUser.Uuid, "error", err)
+		}
+	}
+
+	return foundUser, nil
 }
 
-func (v *userUsecase) ChangeName(ctx context.Context, userId uuid.UUID, newName string) error {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *userUsecase) ChangeName(ctx context.Context, userId uuid.UUID, newName string) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "ChangeName")
+	defer end(&err)
 
 	if err := v.userRepo.UpdateUserName(usecaseCtx, userId, newName); err != nil {
 		return err
@@ -107,24 +277,155 @@ func (v *userUsecase) ChangeName(ctx context.Context, userId uuid.UUID, newName
 	return nil
 }
 
-func (v *userUsecase) ChangeRole(ctx context.Context, userId uuid.UUID, newRole user.UserRole, password string) (*fiber.Cookie, *fiber.Cookie, error) {
-	usecaseCtx, cancel := v.getContext(ctx)
-	defer cancel()
+func (v *userUsecase) GrantRole(ctx context.Context, adminId, userId uuid.UUID, newRole user.UserRole) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "GrantRole")
+	defer end(&err)
 
-	if newRole == user.RoleAdmin {
-		if err := hashutils.VerifyPassword(password, AdminPasswordConst); err != nil {
-			return nil, nil, fmt.Errorf("failed to verify password, wrong password: %v", err)
-		}
+	if adminId == userId {
+		return ErrOwnRole
 	}
 
-	if err := v.userRepo.UpdateUserRole(usecaseCtx, userId, string(newRole)); err != nil {
-		return nil, nil, err
+	foundUser, getErr := v.userRepo.GetUserByUuid(usecaseCtx, userId)
+	if getErr != nil {
+		return getErr
 	}
 
-	jwtRefreshToken, jwtErr := v.jwtUtilsRepo.NewJwt(userId, newRole, httputils.JwtRefreshToken)
+	if foundUser.Role == newRole {
+		return fmt.Errorf("%w '%v'", ErrRoleAlreadyGiven, newRole)
+	}
+
+	return v.changeRole(usecaseCtx, foundUser, newRole, rolegrant.ActionGrant, adminId)
+}
+
+func (v *userUsecase) RevokeRole(ctx context.Context, adminId, userId uuid.UUID) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "RevokeRole")
+	defer end(&err)
+
+	if adminId == userId {
+		return ErrOwnRole
+	}
+
+	foundUser, getErr := v.userRepo.GetUserByUuid(usecaseCtx, userId)
+	if getErr != nil {
+		return getErr
+	}
+
+	if foundUser.Role == user.RoleUser {
+		return ErrNoRoleToRevoke
+	}
+
+	return v.changeRole(usecaseCtx, foundUser, user.RoleUser, rolegrant.ActionRevoke, adminId)
+}
+
+func (v *userUsecase) GetRoleGrants(ctx context.Context, userId uuid.UUID) (_ []rolegrant.RoleGrant, err error) {
+	usecaseCtx, end := v.getContext(ctx, "GetRoleGrants")
+	defer end(&err)
+
+	roleGrants, err := v.roleGrantRepo.GetRoleGrantsByUserUuid(usecaseCtx, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	return roleGrants, nil
+}
+
+// BootstrapAdmin promotes a registered user to admin with no acting admin (CLI or ADMIN_EMAIL env)
+func (v *userUsecase) BootstrapAdmin(ctx context.Context, email string) (err error) {
+	usecaseCtx, end := v.getContext(ctx, "BootstrapAdmin")
+	defer end(&err)
+
+	foundUser, getErr := v.userRepo.GetUser(usecaseCtx, user.NormalizeEmail(email))
+	if getErr != nil {
+		return getErr
+	}
+
+	if foundUser.Role == user.RoleAdmin {
+		return nil
+	}
+
+	return v.changeRole(usecaseCtx, foundUser, user.RoleAdmin, rolegrant.ActionBootstrap, uuid.Nil)
+}
+
+// MigrateEmails normalizes stored emails and creates the unique email index. It refuses to touch anything
+// while two accounts share an email, those have to be merged or renamed by hand first
+func (v *userUsecase) MigrateEmails(ctx context.Context) (err error) {
+	duplicates, findErr := v.FindDuplicateEmails(ctx)
+	if findErr != nil {
+		return findErr
+	}
+	if len(duplicates) > 0 {
+		return fmt.Errorf("%v emails are used by more than one account, list them with the 'find-duplicate-emails' command", len(duplicates))
+	}
+
+	usecaseCtx, end := v.getContext(ctx, "MigrateEmails")
+	defer end(&err)
+
+	normalized, normalizeErr := v.userRepo.NormalizeEmails(usecaseCtx)
+	if normalizeErr != nil {
+		return normalizeErr
+	}
+	if normalized > 0 {
+		logger.FromContext(ctx).Info("normalized user emails", "count", normalized)
+	}
+
+	return v.userRepo.EnsureEmailIndex(usecaseCtx)
+}
+
+func (v *userUsecase) FindDuplicateEmails(ctx context.Context) (_ []userrepository.DuplicateEmail, err error) {
+	usecaseCtx, end := v.getContext(ctx, "FindDuplicateEmails")
+	defer end(&err)
+
+	return v.userRepo.FindDuplicateEmails(usecaseCtx)
+}
+
+func (v *userUsecase) buildSessionCookies(foundUser *user.User, mfa bool) (*fiber.Cookie, *fiber.Cookie, error) {
+	jwtRefreshToken, jwtAccessToken, jwtErr := v.buildSessionTokens(foundUser, mfa)
+	if jwtErr != nil {
+		return nil, nil, jwtErr
+	}
+
+	return v.cookies.Build(jwtRefreshToken, httputils.JwtRefreshToken), v.cookies.Build(jwtAccessToken, httputils.JwtAccessToken), nil
+}
+
+func (v *userUsecase) buildSessionTokens(foundUser *user.User, mfa bool) (string, string, error) {
+	session := jwtutils.Session{
+		UserUuid:     foundUser.Uuid,
+		Role:         foundUser.Role,
+		TokenVersion: foundUser.TokenVersion,
+		Mfa:          mfa,
+	}
+
+	jwtRefreshToken, jwtErr := v.jwtUtilsRepo.NewJwt(session, httputils.JwtRefreshToken)
+	if jwtErr != nil {
+		return "", "", fmt.Errorf("failed to create refresh jwt token: %v", jwtErr)
+	}
+
+	jwtAccessToken, jwtErr := v.jwtUtilsRepo.NewJwt(session, httputils.JwtAccessToken)
 	if jwtErr != nil {
-		return nil, nil, fmt.Errorf("failed to create refresh jwt token: %v", jwtErr)
+		return "", "", fmt.Errorf("failed to create access jwt token: %v", jwtErr)
 	}
 
-	return httputils.BuildCookie(jwtRefreshToken, httputils.JwtRefreshToken), httputils.BuildCookie(jwtRefreshToken, httputils.JwtAccessToken), nil
+	return jwtRefreshToken, jwtAccessToken, nil
+}
+
+func newTokenPair(accessToken, refreshToken string) *TokenPair {
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    jwtutils.AccessTokenExpiresInSeconds,
+	}
+}
+
+func (v *userUsecase) changeRole(ctx context.Context, foundUser *user.User, newRole user.UserRole, action rolegrant.GrantAction, grantedBy uuid.UUID) error {
+	if err := v.userRepo.UpdateUserRole(ctx, foundUser.Uuid, string(newRole)); err != nil {
+		return err
+	}
+
+	roleGrant := rolegrant.NewRoleGrant(foundUser.Uuid, action, newRole, foundUser.Role, grantedBy)
+	if err := v.roleGrantRepo.CreateRoleGrant(ctx, roleGrant); err != nil {
+		return fmt.Errorf("role changed but failed to record the grant: %v", err)
+	}
+
+	return nil
 }
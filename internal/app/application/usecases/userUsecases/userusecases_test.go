@@ -0,0 +1,458 @@
+package userusecases
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/adapters/repository/flightticketpurchaserepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/loginattemptrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/rentrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/rolegrantrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/userrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/usertokenrepository"
+	"github.com/rom6n/otello/internal/app/domain/loginattempt"
+	"github.com/rom6n/otello/internal/app/domain/user"
+	"github.com/rom6n/otello/internal/pkg/mailer"
+	"github.com/rom6n/otello/internal/utils/hashutils"
+	"github.com/rom6n/otello/internal/utils/httputils"
+	"github.com/rom6n/otello/internal/utils/jwtutils"
+	"github.com/rom6n/otello/internal/utils/passwordutils"
+	"github.com/rom6n/otello/internal/utils/totputils"
+)
+
+const (
+	testPassword = "correct-horse-battery"
+	testIp       = "203.0.113.7"
+)
+
+// cheap argon2id costs, the real ones would make every test take seconds
+var testArgon2Params = hashutils.Argon2Params{Memory: 64, Time: 1, Threads: 1, SaltLength: 16, KeyLength: 32}
+
+type discardMailer struct{}
+
+func (discardMailer) Send(ctx context.Context, message mailer.Message) error {
+	return nil
+}
+
+type testUserUsecase struct {
+	*userUsecase
+	users userrepository.UserRepository
+}
+
+func newTestUserUsecase(t *testing.T) testUserUsecase {
+	t.Helper()
+
+	jwtRepo, jwtErr := jwtutils.New(jwtutils.Keys{HmacKey: "test-secret"})
+	if jwtErr != nil {
+		t.Fatalf("jwtutils.New: %v", jwtErr)
+	}
+
+	totpCipher, cipherErr := totputils.NewSecretCipher(base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	if cipherErr != nil {
+		t.Fatalf("NewSecretCipher: %v", cipherErr)
+	}
+
+	users := userrepository.NewMemory()
+
+	usecase := New(
+		users,
+		rolegrantrepository.NewMemory(),
+		usertokenrepository.NewMemory(),
+		loginattemptrepository.NewMemory(),
+		rentrepository.NewMemory(),
+		flightticketpurchaserepository.NewMemory(),
+		jwtRepo,
+		discardMailer{},
+		hashutils.NewPasswordHasher(testArgon2Params),
+		passwordutils.NewPasswordChecker(passwordutils.DefaultPolicy, ""),
+		totpCipher,
+		DefaultLoginPolicies,
+		"http://localhost:8080",
+		httputils.Cookies{},
+		5*time.Second,
+	).(*userUsecase)
+
+	return testUserUsecase{userUsecase: usecase, users: users}
+}
+
+func (v testUserUsecase) register(t *testing.T, email string) *user.User {
+	t.Helper()
+
+	newUser := user.NewUser("Ivan", email, testPassword)
+	if _, _, err := v.Register(context.Background(), newUser); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+
+	return newUser
+}
+
+func (v testUserUsecase) getUser(t *testing.T, email string) *user.User {
+	t.Helper()
+
+	found, err := v.users.GetUser(context.Background(), email)
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+
+	return found
+}
+
+// enableTotp goes through setup and enable and returns the plain secret
+func (v testUserUsecase) enableTotp(t *testing.T, registered *user.User) (string, *TotpEnabled) {
+	t.Helper()
+	ctx := context.Background()
+
+	setup, setupErr := v.SetupTotp(ctx, registered.Uuid, testPassword, testIp)
+	if setupErr != nil {
+		t.Fatalf("SetupTotp: %v", setupErr)
+	}
+
+	code, codeErr := totputils.GenerateCode(setup.Secret, time.Now())
+	if codeErr != nil {
+		t.Fatalf("GenerateCode: %v", codeErr)
+	}
+
+	enabled, enableErr := v.EnableTotp(ctx, registered.Uuid, code)
+	if enableErr != nil {
+		t.Fatalf("EnableTotp: %v", enableErr)
+	}
+
+	return setup.Secret, enabled
+}
+
+func TestEnableTotpEncryptsSecretAndEndsOldSessions(t *testing.T) {
+	usecase := newTestUserUsecase(t)
+	ctx := context.Background()
+	registered := usecase.register(t, "ivan@example.com")
+
+	secret, enabled := usecase.enableTotp(t, registered)
+	if len(enabled.RecoveryCodes) != recoveryCodesCount || enabled.Tokens == nil || enabled.Tokens.AccessToken == "" {
+		t.Fatalf("EnableTotp = %+v, want recovery codes and new tokens", enabled)
+	}
+
+	found := usecase.getUser(t, "ivan@example.com")
+	if !found.TotpEnabled || found.TotpSecret == "" || strings.Contains(found.TotpSecret, secret) {
+		t.Fatalf("stored totp secret = %q, want it encrypted", found.TotpSecret)
+	}
+
+	if err := usecase.CheckSession(ctx, registered.Uuid, registered.TokenVersion); !errors.Is(err, user.ErrSessionRevoked) {
+		t.Fatalf("CheckSession of the session before 2FA = %v, want %v", err, user.ErrSessionRevoked)
+	}
+	if err := usecase.CheckSession(ctx, registered.Uuid, found.TokenVersion); err != nil {
+		t.Fatalf("CheckSession of the new session: %v", err)
+	}
+}
+
+func TestTotpCodeOfUsedStepIsRejected(t *testing.T) {
+	usecase := newTestUserUsecase(t)
+	ctx := context.Background()
+	registered := usecase.register(t, "ivan@example.com")
+	secret, _ := usecase.enableTotp(t, registered)
+
+	// the step of the code that enabled 2FA is used already
+	sameStepCode, _ := totputils.GenerateCode(secret, time.Now())
+	if _, _, _, err := usecase.Login(ctx, "ivan@example.com", testPassword, sameStepCode, testIp); !errors.Is(err, user.ErrInvalidTwoFactorCode) {
+		t.Fatalf("Login with the enabling code = %v, want %v", err, user.ErrInvalidTwoFactorCode)
+	}
+
+	nextStepCode, _ := totputils.GenerateCode(secret, time.Now().Add(30*time.Second))
+	if _, _, _, err := usecase.Login(ctx, "ivan@example.com", testPassword, nextStepCode, testIp); err != nil {
+		t.Fatalf("Login with the next step code: %v", err)
+	}
+	if _, _, _, err := usecase.Login(ctx, "ivan@example.com", testPassword, nextStepCode, testIp); !errors.Is(err, user.ErrInvalidTwoFactorCode) {
+		t.Fatalf("Login replaying the code = %v, want %v", err, user.ErrInvalidTwoFactorCode)
+	}
+}
+
+func TestLegacyPlainTotpSecretIsEncryptedOnUse(t *testing.T) {
+	usecase := newTestUserUsecase(t)
+	ctx := context.Background()
+	registered := usecase.register(t, "ivan@example.com")
+
+	secret, _ := totputils.GenerateSecret()
+	if err := usecase.users.EnableUserTotp(ctx, registered.Uuid, secret, 0, nil); err != nil {
+		t.Fatalf("EnableUserTotp: %v", err)
+	}
+
+	code, _ := totputils.GenerateCode(secret, time.Now())
+	if _, _, _, err := usecase.Login(ctx, "ivan@example.com", testPassword, code, testIp); err != nil {
+		t.Fatalf("Login with a legacy secret: %v", err)
+	}
+
+	if found := usecase.getUser(t, "ivan@example.com"); found.TotpSecret == secret {
+		t.Fatal("legacy totp secret is still stored in plain text after use")
+	}
+}
+
+// sessionOf verifies the jwt and checks its token version like the auth middleware does
+func (v testUserUsecase) sessionOf(t *testing.T, token string) error {
+	t.Helper()
+
+	claims, verifyErr := v.jwtUtilsRepo.VerifyJwt(token)
+	if verifyErr != nil {
+		t.Fatalf("VerifyJwt: %v", verifyErr)
+	}
+	session, sessionErr := jwtutils.SessionFromClaims(claims)
+	if sessionErr != nil {
+		t.Fatalf("SessionFromClaims: %v", sessionErr)
+	}
+
+	return v.CheckSession(context.Background(), session.UserUuid, session.TokenVersion)
+}
+
+func TestChangePasswordRejectsTokensOfOldVersion(t *testing.T) {
+	usecase := newTestUserUsecase(t)
+	ctx := context.Background()
+	registered := usecase.register(t, "ivan@example.com")
+
+	accessCookie, _, _, loginErr := usecase.Login(ctx, "ivan@example.com", testPassword, "", testIp)
+	if loginErr != nil {
+		t.Fatalf("Login: %v", loginErr)
+	}
+
+	tokens, changeErr := usecase.ChangePassword(ctx, registered.Uuid, testPassword, "another-horse-battery", testIp, false)
+	if changeErr != nil {
+		t.Fatalf("ChangePassword: %v", changeErr)
+	}
+
+	if err := usecase.sessionOf(t, accessCookie.Value); !errors.Is(err, user.ErrSessionRevoked) {
+		t.Fatalf("session of the token before the change = %v, want %v", err, user.ErrSessionRevoked)
+	}
+	if err := usecase.sessionOf(t, tokens.AccessToken); err != nil {
+		t.Fatalf("session of the new token: %v", err)
+	}
+}
+
+func TestLoginDoesNotTellUnknownEmailFromWrongPassword(t *testing.T) {
+	usecase := newTestUserUsecase(t)
+	ctx := context.Background()
+	usecase.register(t, "ivan@example.com")
+
+	_, accessCookie, found, err := usecase.Login(ctx, " Ivan@Example.com", testPassword, "", testIp)
+	if err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if found.Email != "ivan@example.com" || accessCookie == nil || accessCookie.Value == "" {
+		t.Fatalf("Login = %+v, %+v, want the user and an access cookie", found, accessCookie)
+	}
+
+	for name, tc := range map[string]struct{ email, password string }{
+		"wrong password": {"ivan@example.com", "wrong-horse-battery"},
+		"unknown email":  {"petr@example.com", testPassword},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, _, _, err := usecase.Login(ctx, tc.email, tc.password, "", testIp); !errors.Is(err, user.ErrInvalidCredentials) {
+				t.Fatalf("Login = %v, want %v", err, user.ErrInvalidCredentials)
+			}
+		})
+	}
+}
+
+func TestLoginThrottlesAndLocksAfterFailures(t *testing.T) {
+	usecase := newTestUserUsecase(t)
+	ctx := context.Background()
+	registered := usecase.register(t, "ivan@example.com")
+	policy := LoginPolicy{FreeFailures: 2, LockAfter: 5, LockDuration: 10 * time.Minute, MaxDelay: time.Minute}
+	usecase.loginPolicies.Email = policy
+
+	for i := 0; i <= policy.FreeFailures; i++ {
+		if _, _, _, err := usecase.Login(ctx, "ivan@example.com", "wrong-horse-battery", "", testIp); !errors.Is(err, user.ErrInvalidCredentials) {
+			t.Fatalf("failure %v: Login = %v, want %v", i+1, err, user.ErrInvalidCredentials)
+		}
+	}
+
+	// past the free failures even the right password has to wait
+	var throttledErr *loginattempt.ThrottledError
+	if _, _, _, err := usecase.Login(ctx, "ivan@example.com", testPassword, "", testIp); !errors.As(err, &throttledErr) || throttledErr.Locked {
+		t.Fatalf("Login after %v failures = %v, want a delay", policy.FreeFailures+1, err)
+	}
+
+	// the delayed login counts too, the rest of the failures are registered directly
+	for i := policy.FreeFailures + 2; i < policy.LockAfter; i++ {
+		usecase.registerLoginFailure(ctx, "ivan@example.com", testIp)
+	}
+
+	if _, _, _, err := usecase.Login(ctx, "ivan@example.com", testPassword, "", "198.51.100.1"); !errors.As(err, &throttledErr) || !throttledErr.Locked {
+		t.Fatalf("Login from another ip after %v failures = %v, want the email locked", policy.LockAfter, err)
+	}
+	if throttledErr.RetryAfter <= 0 || throttledErr.RetryAfter > policy.LockDuration {
+		t.Fatalf("RetryAfter = %v, want up to %v", throttledErr.RetryAfter, policy.LockDuration)
+	}
+
+	if err := usecase.UnlockLogin(ctx, registered.Uuid, testIp); err != nil {
+		t.Fatalf("UnlockLogin: %v", err)
+	}
+	if _, _, _, err := usecase.Login(ctx, "ivan@example.com", testPassword, "", testIp); err != nil {
+		t.Fatalf("Login after UnlockLogin: %v", err)
+	}
+}
+
+func TestRequestEmailChangeCountsWrongPasswords(t *testing.T) {
+	usecase := newTestUserUsecase(t)
+	ctx := context.Background()
+	registered := usecase.register(t, "ivan@example.com")
+
+	for i := 0; i <= usecase.loginPolicies.Email.FreeFailures; i++ {
+		if err := usecase.RequestEmailChange(ctx, registered.Uuid, "wrong-horse-battery", "petr@example.com", testIp); !errors.Is(err, user.ErrWrongPassword) {
+			t.Fatalf("failure %v: RequestEmailChange = %v, want %v", i+1, err, user.ErrWrongPassword)
+		}
+	}
+
+	var throttledErr *loginattempt.ThrottledError
+	if err := usecase.RequestEmailChange(ctx, registered.Uuid, "wrong-horse-battery", "petr@example.com", testIp); !errors.As(err, &throttledErr) {
+		t.Fatalf("RequestEmailChange after %v failures = %v, want it throttled", usecase.loginPolicies.Email.FreeFailures+1, err)
+	}
+}
+
+func TestTotpPasswordChecksCountWrongPasswords(t *testing.T) {
+	for name, tc := range map[string]struct {
+		totpEnabled bool
+		call        func(v testUserUsecase, registered *user.User, password string) error
+	}{
+		"setup": {false, func(v testUserUsecase, registered *user.User, password string) error {
+			_, err := v.SetupTotp(context.Background(), registered.Uuid, password, testIp)
+			return err
+		}},
+		"disable": {true, func(v testUserUsecase, registered *user.User, password string) error {
+			return v.DisableTotp(context.Background(), registered.Uuid, password, "000000", testIp)
+		}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			usecase := newTestUserUsecase(t)
+			registered := usecase.register(t, "ivan@example.com")
+			if tc.totpEnabled {
+				usecase.enableTotp(t, registered)
+			}
+
+			for i := 0; i <= usecase.loginPolicies.Email.FreeFailures; i++ {
+				if err := tc.call(usecase, registered, "wrong-horse-battery"); !errors.Is(err, user.ErrWrongPassword) {
+					t.Fatalf("failure %v = %v, want %v", i+1, err, user.ErrWrongPassword)
+				}
+			}
+
+			var throttledErr *loginattempt.ThrottledError
+			if err := tc.call(usecase, registered, testPassword); !errors.As(err, &throttledErr) {
+				t.Fatalf("the right password after %v failures = %v, want it throttled", usecase.loginPolicies.Email.FreeFailures+1, err)
+			}
+		})
+	}
+}
+
+func TestConcurrentLoginsDoNotSkipTheDelay(t *testing.T) {
+	usecase := newTestUserUsecase(t)
+	usecase.register(t, "ivan@example.com")
+
+	const attempts = 20
+	results := make(chan error, attempts)
+	var wg sync.WaitGroup
+	for i := 0; i < attempts; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _, _, err := usecase.Login(context.Background(), "ivan@example.com", "wrong-horse-battery", "", testIp)
+			results <- err
+		}()
+	}
+	wg.Wait()
+	close(results)
+
+	// every attempt is counted before its password is checked, so only the free ones get that far
+	checked := 0
+	for err := range results {
+		var throttledErr *loginattempt.ThrottledError
+		switch {
+		case errors.Is(err, user.ErrInvalidCredentials):
+			checked++
+		case !errors.As(err, &throttledErr):
+			t.Fatalf("Login = %v, want invalid credentials or throttled", err)
+		}
+	}
+	if checked != usecase.loginPolicies.Email.FreeFailures+1 {
+		t.Fatalf("%v of %v concurrent logins checked the password, want %v", checked, attempts, usecase.loginPolicies.Email.FreeFailures+1)
+	}
+}
+
+func TestMigrateEmailsRefusesDuplicates(t *testing.T) {
+	usecase := newTestUserUsecase(t)
+	ctx := context.Background()
+
+	createLegacy := func(email string) *user.User {
+		legacy := user.NewUser("Ivan", "", "hash")
+		legacy.Email = email
+		if err := usecase.users.CreateUser(ctx, legacy); err != nil {
+			t.Fatalf("CreateUser %q: %v", email, err)
+		}
+		return legacy
+	}
+	upper := createLegacy("Ivan@Example.com")
+	spaced := createLegacy(" ivan@example.com")
+
+	if err := usecase.MigrateEmails(ctx); err == nil {
+		t.Fatal("MigrateEmails with duplicates succeeded, want an error")
+	}
+	if found, _ := usecase.users.GetUserByUuid(ctx, upper.Uuid); found.Email != "Ivan@Example.com" {
+		t.Fatalf("email after the refused migration = %q, want it untouched", found.Email)
+	}
+
+	// the duplicate is renamed by hand, then the migration goes through and can be run again
+	if err := usecase.users.UpdateUserEmail(ctx, spaced.Uuid, " Petr@example.com"); err != nil {
+		t.Fatalf("UpdateUserEmail: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := usecase.MigrateEmails(ctx); err != nil {
+			t.Fatalf("MigrateEmails run %v: %v", i+1, err)
+		}
+	}
+
+	for userUuid, want := range map[uuid.UUID]string{upper.Uuid: "ivan@example.com", spaced.Uuid: "petr@example.com"} {
+		if found, _ := usecase.users.GetUserByUuid(ctx, userUuid); found.Email != want {
+			t.Fatalf("email after the migration = %q, want %q", found.Email, want)
+		}
+	}
+}
+
+func TestProfileJsonHidesSecrets(t *testing.T) {
+	usecase := newTestUserUsecase(t)
+	ctx := context.Background()
+	registered := usecase.register(t, "ivan@example.com")
+	usecase.enableTotp(t, registered)
+
+	language := "ru"
+	profile, updateErr := usecase.UpdateProfile(ctx, registered.Uuid, user.ProfileUpdate{Language: &language})
+	if updateErr != nil {
+		t.Fatalf("UpdateProfile: %v", updateErr)
+	}
+	if profile.Language != "ru" || !profile.TotpEnabled {
+		t.Fatalf("UpdateProfile = %+v, want the language set and 2FA on", profile)
+	}
+
+	profileJson, marshalErr := json.Marshal(profile)
+	if marshalErr != nil {
+		t.Fatalf("Marshal: %v", marshalErr)
+	}
+
+	var fields map[string]any
+	if err := json.Unmarshal(profileJson, &fields); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	for _, field := range []string{"password", "token_version", "totp_secret", "totp_pending_secret", "totp_last_counter", "recovery_code_hashes"} {
+		if _, ok := fields[field]; ok {
+			t.Errorf("profile json has %q", field)
+		}
+	}
+
+	stored := usecase.getUser(t, "ivan@example.com")
+	secrets := append([]string{stored.Password, stored.TotpSecret}, stored.RecoveryCodeHashes...)
+	for _, secret := range secrets {
+		if secret != "" && strings.Contains(string(profileJson), secret) {
+			t.Errorf("profile json contains the stored secret %q", secret)
+		}
+	}
+}
@@ -1,55 +1,83 @@
 package config
 
 import (
-	"time"
-
-	"github.com/rom6n/otello/internal/app/adapters/repository/flightticketrepository"
-	"github.com/rom6n/otello/internal/app/adapters/repository/hotelrepository"
-	hotelroomrepository "github.com/rom6n/otello/internal/app/adapters/repository/hotelroomrepository"
-	"github.com/rom6n/otello/internal/app/adapters/repository/rentrepository"
 	"github.com/rom6n/otello/internal/app/adapters/repository/userrepository"
+	"github.com/rom6n/otello/internal/app/application/usecases/apikeyusecases"
 	"github.com/rom6n/otello/internal/app/application/usecases/flightticketusecases"
 	"github.com/rom6n/otello/internal/app/application/usecases/hotelroomusecases"
 	"github.com/rom6n/otello/internal/app/application/usecases/hotelusecases"
 	"github.com/rom6n/otello/internal/app/application/usecases/rentusecases"
 	"github.com/rom6n/otello/internal/app/application/usecases/userusecases"
+	"github.com/rom6n/otello/internal/pkg/mailer"
+	"github.com/rom6n/otello/internal/utils/hashutils"
+	"github.com/rom6n/otello/internal/utils/httputils"
 	"github.com/rom6n/otello/internal/utils/jwtutils"
-	"go.mongodb.org/mongo-driver/v2/mongo"
+	"github.com/rom6n/otello/internal/utils/passwordutils"
+	"github.com/rom6n/otello/internal/utils/totputils"
 )
 
 type Config struct {
+	Settings             Settings
 	UserRepo             userrepository.UserRepository
 	JWTRepo              jwtutils.JwtRepository
+	Cookies              httputils.Cookies
 	UserUsecases         userusecases.UserUsecases
 	HotelUsecases        hotelusecases.HotelUsecases
 	HotelRoomUsecases    hotelroomusecases.HotelRoomUsecases
 	RentUsecases         rentusecases.RentUsecases
 	FlightTicketUsecases flightticketusecases.FlightTicketUsecases
+	ApiKeyUsecases       apikeyusecases.ApiKeyUsecases
 }
 
 const DBName = "otello"
 
-func GetConfig(dbClient *mongo.Client) Config {
-	userRepo := userrepository.New(dbClient, DBName, "users", 30*time.Second)
-	jwtRepo := jwtutils.New()
-	hotelRepo := hotelrepository.New(dbClient, DBName, "hotels", 30*time.Second)
-	hotelRoomRepo := hotelroomrepository.New(dbClient, DBName, "hotelRooms", 30*time.Second)
-	rentRepo := rentrepository.New(dbClient, DBName, "rents", 30*time.Second)
-	flightTicketRepo := flightticketrepository.New(dbClient, DBName, "flightTickets", 30*time.Second)
+// GetConfig wires the app on top of the storage, settings must be validated
+func GetConfig(storage Storage, settings Settings) (Config, error) {
+	repos := newRepositories(storage, settings.Storage.Timeout)
 
-	userUsecase := userusecases.New(userRepo, jwtRepo, 30*time.Second)
-	hotelUsecase := hotelusecases.New(hotelRepo, 30*time.Second)
-	hotelRoomUsecase := hotelroomusecases.New(hotelRoomRepo, rentRepo, 30*time.Second)
-	rentUsecase := rentusecases.New(rentRepo, 30*time.Second)
-	flightTicketUsecase := flightticketusecases.New(flightTicketRepo, 30*time.Second)
+	jwtRepo, jwtErr := jwtutils.New(settings.JwtKeys())
+	if jwtErr != nil {
+		return Config{}, jwtErr
+	}
+
+	totpCipher, totpErr := totputils.NewSecretCipher(settings.Auth.TotpEncryptionKey)
+	if totpErr != nil {
+		return Config{}, totpErr
+	}
+
+	appMailer, mailerErr := mailer.New(settings.Mailer.Driver, settings.Mailer.LogFile, mailer.SMTPConfig{
+		Host:     settings.Mailer.SmtpHost,
+		Port:     settings.Mailer.SmtpPort,
+		From:     settings.Mailer.SmtpFrom,
+		Username: settings.Mailer.SmtpUsername,
+		Password: settings.Mailer.SmtpPassword,
+	})
+	if mailerErr != nil {
+		return Config{}, mailerErr
+	}
+
+	cookies := httputils.Cookies{Secure: settings.Server.SecureCookies}
+	passwordHasher := hashutils.NewPasswordHasher(settings.Argon2Params())
+	passwordChecker := passwordutils.NewPasswordChecker(settings.PasswordPolicy(), settings.Password.BreachedDir)
+	timeout := settings.Server.RequestTimeout
+
+	userUsecase := userusecases.New(repos.user, repos.roleGrant, repos.userToken, repos.loginAttempt, repos.rent, repos.flightTicketPurchase, jwtRepo, appMailer, passwordHasher, passwordChecker, totpCipher, settings.LoginPolicies(), settings.Server.AppBaseUrl, cookies, timeout)
+	rentUsecase := rentusecases.New(repos.rent, repos.hotelRoom, repos.hotel, repos.user, appMailer, timeout)
+	hotelUsecase := hotelusecases.New(repos.hotel, repos.hotelRoom, repos.user, rentUsecase, timeout)
+	hotelRoomUsecase := hotelroomusecases.New(repos.hotelRoom, repos.hotel, repos.rent, rentUsecase, timeout)
+	flightTicketUsecase := flightticketusecases.New(repos.flightTicket, repos.flightTicketPurchase, timeout)
+	apiKeyUsecase := apikeyusecases.New(repos.apiKey, repos.user, timeout)
 
 	return Config{
-		UserRepo:             userRepo,
+		Settings:             settings,
+		UserRepo:             repos.user,
 		JWTRepo:              jwtRepo,
+		Cookies:              cookies,
 		UserUsecases:         userUsecase,
 		HotelUsecases:        hotelUsecase,
 		HotelRoomUsecases:    hotelRoomUsecase,
 		RentUsecases:         rentUsecase,
 		FlightTicketUsecases: flightTicketUsecase,
-	}
+		ApiKeyUsecases:       apiKeyUsecase,
+	}, nil
 }
@@ -0,0 +1,132 @@
+package config
+
+import (
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rom6n/otello/internal/app/adapters/repository/apikeyrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/flightticketpurchaserepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/flightticketrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/hotelrepository"
+	hotelroomrepository "github.com/rom6n/otello/internal/app/adapters/repository/hotelroomrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/loginattemptrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/rentrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/rolegrantrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/userrepository"
+	"github.com/rom6n/otello/internal/app/adapters/repository/usertokenrepository"
+	"github.com/rom6n/otello/internal/pkg/database"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+const (
+	StorageMongo    = "mongo"
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory" // data is lost on restart, for tests and local runs
+)
+
+// Mongo collections of the repositories
+const (
+	usersCollection                 = "users"
+	hotelsCollection                = "hotels"
+	hotelRoomsCollection            = "hotelRooms"
+	rentsCollection                 = "rents"
+	flightTicketsCollection         = "flightTickets"
+	flightTicketPurchasesCollection = "flightTicketPurchases"
+	roleGrantsCollection            = "roleGrants"
+	userTokensCollection            = "userTokens"
+	loginAttemptsCollection         = "loginAttempts"
+	apiKeysCollection               = "apiKeys"
+)
+
+// Storage is the database the repositories work with, only the client of the Driver is set
+type Storage struct {
+	Driver       string
+	MongoClient  *mongo.Client
+	PostgresPool *pgxpool.Pool
+}
+
+type repositories struct {
+	user                 userrepository.UserRepository
+	hotel                hotelrepository.HotelRepository
+	hotelRoom            hotelroomrepository.HotelRoomRepository
+	rent                 rentrepository.RentRepository
+	flightTicket         flightticketrepository.FlightTicketRepository
+	flightTicketPurchase flightticketpurchaserepository.FlightTicketPurchaseRepository
+	roleGrant            rolegrantrepository.RoleGrantRepository
+	userToken            usertokenrepository.UserTokenRepository
+	loginAttempt         loginattemptrepository.LoginAttemptRepository
+	apiKey               apikeyrepository.ApiKeyRepository
+}
+
+func newRepositories(storage Storage, timeout time.Duration) repositories {
+	switch storage.Driver {
+	case StorageMemory:
+		return newMemoryRepositories()
+	case StoragePostgres:
+		return newPostgresRepositories(storage.PostgresPool, timeout)
+	default:
+		return newMongoRepositories(storage.MongoClient, timeout)
+	}
+}
+
+func newMongoRepositories(dbClient *mongo.Client, timeout time.Duration) repositories {
+	return repositories{
+		user:                 userrepository.New(dbClient, DBName, usersCollection, timeout),
+		hotel:                hotelrepository.New(dbClient, DBName, hotelsCollection, timeout),
+		hotelRoom:            hotelroomrepository.New(dbClient, DBName, hotelRoomsCollection, timeout),
+		rent:                 rentrepository.New(dbClient, DBName, rentsCollection, timeout),
+		flightTicket:         flightticketrepository.New(dbClient, DBName, flightTicketsCollection, timeout),
+		flightTicketPurchase: flightticketpurchaserepository.New(dbClient, DBName, flightTicketPurchasesCollection, timeout),
+		roleGrant:            rolegrantrepository.New(dbClient, DBName, roleGrantsCollection, timeout),
+		userToken:            usertokenrepository.New(dbClient, DBName, userTokensCollection, timeout),
+		loginAttempt:         loginattemptrepository.New(dbClient, DBName, loginAttemptsCollection, timeout),
+		apiKey:               apikeyrepository.New(dbClient, DBName, apiKeysCollection, timeout),
+	}
+}
+
+// MongoSchema is what database.EnsureMongoSchema makes of the collections in DBName
+func MongoSchema() []database.MongoCollection {
+	return []database.MongoCollection{
+		userrepository.MongoSchema(usersCollection),
+		hotelrepository.MongoSchema(hotelsCollection),
+		hotelroomrepository.MongoSchema(hotelRoomsCollection),
+		rentrepository.MongoSchema(rentsCollection),
+		rentrepository.MongoRoomLockSchema(rentsCollection),
+		flightticketrepository.MongoSchema(flightTicketsCollection),
+		flightticketpurchaserepository.MongoSchema(flightTicketPurchasesCollection),
+		rolegrantrepository.MongoSchema(roleGrantsCollection),
+		usertokenrepository.MongoSchema(userTokensCollection),
+		loginattemptrepository.MongoSchema(loginAttemptsCollection),
+		apikeyrepository.MongoSchema(apiKeysCollection),
+	}
+}
+
+func newPostgresRepositories(pool *pgxpool.Pool, timeout time.Duration) repositories {
+	return repositories{
+		user:                 userrepository.NewPostgres(pool, timeout),
+		hotel:                hotelrepository.NewPostgres(pool, timeout),
+		hotelRoom:            hotelroomrepository.NewPostgres(pool, timeout),
+		rent:                 rentrepository.NewPostgres(pool, timeout),
+		flightTicket:         flightticketrepository.NewPostgres(pool, timeout),
+		flightTicketPurchase: flightticketpurchaserepository.NewPostgres(pool, timeout),
+		roleGrant:            rolegrantrepository.NewPostgres(pool, timeout),
+		userToken:            usertokenrepository.NewPostgres(pool, timeout),
+		loginAttempt:         loginattemptrepository.NewPostgres(pool, timeout),
+		apiKey:               apikeyrepository.NewPostgres(pool, timeout),
+	}
+}
+
+func newMemoryRepositories() repositories {
+	return repositories{
+		user:                 userrepository.NewMemory(),
+		hotel:                hotelrepository.NewMemory(),
+		hotelRoom:            hotelroomrepository.NewMemory(),
+		rent:                 rentrepository.NewMemory(),
+		flightTicket:         flightticketrepository.NewMemory(),
+		flightTicketPurchase: flightticketpurchaserepository.NewMemory(),
+		roleGrant:            rolegrantrepository.NewMemory(),
+		userToken:            usertokenrepository.NewMemory(),
+		loginAttempt:         loginattemptrepository.NewMemory(),
+		apiKey:               apikeyrepository.NewMemory(),
+	}
+}
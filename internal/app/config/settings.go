@@ -0,0 +1,481 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rom6n/otello/internal/app/application/usecases/userusecases"
+	"github.com/rom6n/otello/internal/pkg/logger"
+	"github.com/rom6n/otello/internal/pkg/tracing"
+	"github.com/rom6n/otello/internal/utils/hashutils"
+	"github.com/rom6n/otello/internal/utils/jwtutils"
+	"github.com/rom6n/otello/internal/utils/passwordutils"
+	"github.com/rom6n/otello/internal/utils/totputils"
+	"go.yaml.in/yaml/v3"
+)
+
+// ConfigFileEnv names the YAML settings file when there is no -config flag
+const ConfigFileEnv = "CONFIG_FILE"
+
+// maxLegacyHs256Window bounds JWT_ACCEPT_LEGACY_HS256, a week of refresh token lifetime is enough for everyone to get a new token
+const maxLegacyHs256Window = 30 * 24 * time.Hour
+
+// Settings are every tunable of the app. LoadSettings takes the defaults and overrides them with the YAML file,
+// then with env and then with command line flags. The env tag names the variable, the flag is the same name
+// in lower case with dashes: PORT is -port, REQUIRE_ADMIN_2FA is -require-admin-2fa
+type Settings struct {
+	Server   ServerSettings   `yaml:"server"`
+	Storage  StorageSettings  `yaml:"storage"`
+	Auth     AuthSettings     `yaml:"auth"`
+	Password PasswordSettings `yaml:"password"`
+	Login    LoginSettings    `yaml:"login"`
+	Mailer   MailerSettings   `yaml:"mailer"`
+	Log      LogSettings      `yaml:"log"`
+	Tracing  TracingSettings  `yaml:"tracing"`
+}
+
+type ServerSettings struct {
+	Port                  int           `yaml:"port" env:"PORT"`
+	MetricsPort           int           `yaml:"metrics_port" env:"METRICS_PORT"`       // serves /metrics, must not be reachable from outside
+	AppBaseUrl            string        `yaml:"app_base_url" env:"APP_BASE_URL"`       // used in links from emails
+	RequestTimeout        time.Duration `yaml:"request_timeout" env:"REQUEST_TIMEOUT"` // of every usecase call
+	ShutdownTimeout       time.Duration `yaml:"shutdown_timeout" env:"SHUTDOWN_TIMEOUT"`
+	MaxBodySize           int           `yaml:"max_body_size" env:"MAX_BODY_SIZE"` // in bytes
+	RateLimit             int           `yaml:"rate_limit" env:"RATE_LIMIT"`       // requests from one IP per RateLimitWindow
+	RateLimitWindow       time.Duration `yaml:"rate_limit_window" env:"RATE_LIMIT_WINDOW"`
+	SecureCookies         bool          `yaml:"secure_cookies" env:"SECURE_COOKIES"` // turn on when the app is served over https
+	AllowQueryCredentials bool          `yaml:"allow_query_credentials" env:"ALLOW_QUERY_CREDENTIALS"`
+}
+
+type StorageSettings struct {
+	Driver      string        `yaml:"driver" env:"STORAGE_DRIVER"`
+	MongoUri    string        `yaml:"mongo_uri" env:"MONGO_URI"`
+	PostgresUrl string        `yaml:"postgres_url" env:"POSTGRES_URL"`
+	Timeout     time.Duration `yaml:"timeout" env:"DB_TIMEOUT"` // of every repository call
+}
+
+type AuthSettings struct {
+	JwtKey                  string   `yaml:"jwt_key" env:"JWT_KEY"`
+	JwtSigningKeyFile       string   `yaml:"jwt_signing_key_file" env:"JWT_SIGNING_KEY_FILE"`
+	JwtVerificationKeyFiles []string `yaml:"jwt_verification_key_files" env:"JWT_VERIFICATION_KEY_FILES"` // comma separated in env and flags
+	JwtAcceptLegacyHs256    string   `yaml:"jwt_accept_legacy_hs256" env:"JWT_ACCEPT_LEGACY_HS256"`       // date, e.g. 2026-11-01, until which JWT_KEY tokens pass with a signing key file
+	TotpEncryptionKey       string   `yaml:"totp_encryption_key" env:"TOTP_ENCRYPTION_KEY"`               // 32 bytes in base64, encrypts 2FA secrets in the database
+	RequireAdminTwoFactor   bool     `yaml:"require_admin_2fa" env:"REQUIRE_ADMIN_2FA"`
+	AdminEmail              string   `yaml:"admin_email" env:"ADMIN_EMAIL"` // gets the admin role on start
+}
+
+type PasswordSettings struct {
+	MinLength      int    `yaml:"min_length" env:"PASSWORD_MIN_LENGTH"`
+	MaxLength      int    `yaml:"max_length" env:"PASSWORD_MAX_LENGTH"`
+	MinCharClasses int    `yaml:"min_char_classes" env:"PASSWORD_MIN_CHAR_CLASSES"`
+	HashMemoryKib  int    `yaml:"hash_memory_kib" env:"PASSWORD_HASH_MEMORY_KIB"`
+	HashTime       int    `yaml:"hash_time" env:"PASSWORD_HASH_TIME"`
+	HashThreads    int    `yaml:"hash_threads" env:"PASSWORD_HASH_THREADS"`
+	BreachedDir    string `yaml:"breached_dir" env:"BREACHED_PASSWORDS_DIR"`
+}
+
+// LoginSettings throttle failed logins, per email and per IP. Past the free failures every next one doubles the delay
+// from a second up to the max delay, the lock after failures blocks logins for the lock duration
+type LoginSettings struct {
+	EmailFreeFailures int           `yaml:"email_free_failures" env:"LOGIN_EMAIL_FREE_FAILURES"`
+	EmailLockAfter    int           `yaml:"email_lock_after" env:"LOGIN_EMAIL_LOCK_AFTER"`
+	EmailLockDuration time.Duration `yaml:"email_lock_duration" env:"LOGIN_EMAIL_LOCK_DURATION"`
+	EmailMaxDelay     time.Duration `yaml:"email_max_delay" env:"LOGIN_EMAIL_MAX_DELAY"`
+	IpFreeFailures    int           `yaml:"ip_free_failures" env:"LOGIN_IP_FREE_FAILURES"`
+	IpLockAfter       int           `yaml:"ip_lock_after" env:"LOGIN_IP_LOCK_AFTER"`
+	IpLockDuration    time.Duration `yaml:"ip_lock_duration" env:"LOGIN_IP_LOCK_DURATION"`
+	IpMaxDelay        time.Duration `yaml:"ip_max_delay" env:"LOGIN_IP_MAX_DELAY"`
+}
+
+type MailerSettings struct {
+	Driver       string `yaml:"driver" env:"MAILER_DRIVER"` // no default, it has to be chosen explicitly
+	LogFile      string `yaml:"log_file" env:"MAILER_LOG_FILE"`
+	SmtpHost     string `yaml:"smtp_host" env:"SMTP_HOST"`
+	SmtpPort     int    `yaml:"smtp_port" env:"SMTP_PORT"`
+	SmtpFrom     string `yaml:"smtp_from" env:"SMTP_FROM"`
+	SmtpUsername string `yaml:"smtp_username" env:"SMTP_USERNAME"`
+	SmtpPassword string `yaml:"smtp_password" env:"SMTP_PASSWORD"`
+}
+
+type LogSettings struct {
+	Level  string `yaml:"level" env:"LOG_LEVEL"`   // debug, info, warn or error
+	Format string `yaml:"format" env:"LOG_FORMAT"` // text or json
+}
+
+type TracingSettings struct {
+	Exporter     string  `yaml:"exporter" env:"TRACING_EXPORTER"`           // none, stdout or otlp
+	OtlpEndpoint string  `yaml:"otlp_endpoint" env:"TRACING_OTLP_ENDPOINT"` // url of the OTLP/HTTP collector, like http://localhost:4318
+	SampleRatio  float64 `yaml:"sample_ratio" env:"TRACING_SAMPLE_RATIO"`   // of traces started here, the incoming ones keep the caller's decision
+	ServiceName  string  `yaml:"service_name" env:"TRACING_SERVICE_NAME"`
+}
+
+const (
+	MailerLog  = "log"
+	MailerSmtp = "smtp"
+)
+
+func DefaultSettings() Settings {
+	return Settings{
+		Server: ServerSettings{
+			Port:            8080,
+			MetricsPort:     9090,
+			AppBaseUrl:      "http://localhost:8080",
+			RequestTimeout:  30 * time.Second,
+			ShutdownTimeout: 35 * time.Second,
+			MaxBodySize:     20 * 1024,
+			RateLimit:       90,
+			RateLimitWindow: 60 * time.Second,
+		},
+		Storage: StorageSettings{
+			Driver:  StorageMongo,
+			Timeout: 30 * time.Second,
+		},
+		Password: PasswordSettings{
+			MinLength:      passwordutils.DefaultPolicy.MinLength,
+			MaxLength:      passwordutils.DefaultPolicy.MaxLength,
+			MinCharClasses: passwordutils.DefaultPolicy.MinCharClasses,
+			HashMemoryKib:  int(hashutils.DefaultArgon2Params.Memory),
+			HashTime:       int(hashutils.DefaultArgon2Params.Time),
+			HashThreads:    int(hashutils.DefaultArgon2Params.Threads),
+		},
+		Login: LoginSettings{
+			EmailFreeFailures: userusecases.DefaultLoginPolicies.Email.FreeFailures,
+			EmailLockAfter:    userusecases.DefaultLoginPolicies.Email.LockAfter,
+			EmailLockDuration: userusecases.DefaultLoginPolicies.Email.LockDuration,
+			EmailMaxDelay:     userusecases.DefaultLoginPolicies.Email.MaxDelay,
+			IpFreeFailures:    userusecases.DefaultLoginPolicies.Ip.FreeFailures,
+			IpLockAfter:       userusecases.DefaultLoginPolicies.Ip.LockAfter,
+			IpLockDuration:    userusecases.DefaultLoginPolicies.Ip.LockDuration,
+			IpMaxDelay:        userusecases.DefaultLoginPolicies.Ip.MaxDelay,
+		},
+		Log: LogSettings{
+			Level:  "info",
+			Format: logger.FormatText,
+		},
+		Tracing: TracingSettings{
+			Exporter:    tracing.ExporterNone,
+			SampleRatio: 1,
+			ServiceName: "otello",
+		},
+	}
+}
+
+// LoadSettings parses the flags in front of args and returns the rest of them, the command to run.
+// An empty env variable counts as not set
+func LoadSettings(args []string, lookupEnv func(key string) (string, bool)) (Settings, []string, error) {
+	settings := DefaultSettings()
+	fields := settingFields(reflect.ValueOf(&settings).Elem())
+
+	flags := flag.NewFlagSet("app", flag.ContinueOnError)
+	configFile := flags.String("config", "", "YAML file with settings, also "+ConfigFileEnv+" in env")
+
+	flagValues := map[string]string{}
+	for _, field := range fields {
+		record := func(value string) error {
+			flagValues[field.env] = value
+			return nil
+		}
+		if field.value.Kind() == reflect.Bool {
+			flags.BoolFunc(field.flagName(), field.env+" in env", record)
+		} else {
+			flags.Func(field.flagName(), field.env+" in env", record)
+		}
+	}
+
+	if err := flags.Parse(args); err != nil {
+		return Settings{}, nil, err
+	}
+
+	if *configFile == "" {
+		*configFile, _ = lookupEnv(ConfigFileEnv)
+	}
+	if *configFile != "" {
+		if err := loadSettingsFile(*configFile, &settings); err != nil {
+			return Settings{}, nil, err
+		}
+	}
+
+	for _, field := range fields {
+		if value, ok := lookupEnv(field.env); ok && value != "" {
+			if err := field.set(value); err != nil {
+				return Settings{}, nil, fmt.Errorf("invalid %v in env: %v", field.env, err)
+			}
+		}
+	}
+
+	for _, field := range fields {
+		if value, ok := flagValues[field.env]; ok {
+			if err := field.set(value); err != nil {
+				return Settings{}, nil, fmt.Errorf("invalid flag -%v: %v", field.flagName(), err)
+			}
+		}
+	}
+
+	return settings, flags.Args(), nil
+}
+
+func loadSettingsFile(path string, settings *Settings) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read settings file: %v", err)
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(settings); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("failed to parse settings file %v: %v", path, err)
+	}
+
+	return nil
+}
+
+// Validate reports every wrong setting at once, so they can be fixed in one go
+func (s Settings) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(s.Server.Port >= 1 && s.Server.Port <= 65535, "server.port (PORT) must be from 1 to 65535, got %v", s.Server.Port)
+	check(s.Server.MetricsPort >= 1 && s.Server.MetricsPort <= 65535 && s.Server.MetricsPort != s.Server.Port,
+		"server.metrics_port (METRICS_PORT) must be from 1 to 65535 and differ from the port, got %v", s.Server.MetricsPort)
+	baseUrl, urlErr := url.Parse(s.Server.AppBaseUrl)
+	check(urlErr == nil && (baseUrl.Scheme == "http" || baseUrl.Scheme == "https") && baseUrl.Host != "",
+		"server.app_base_url (APP_BASE_URL) must be an http or https url, got '%v'", s.Server.AppBaseUrl)
+	check(s.Server.RequestTimeout > 0, "server.request_timeout (REQUEST_TIMEOUT) must be positive, got %v", s.Server.RequestTimeout)
+	check(s.Server.ShutdownTimeout > 0, "server.shutdown_timeout (SHUTDOWN_TIMEOUT) must be positive, got %v", s.Server.ShutdownTimeout)
+	check(s.Server.MaxBodySize > 0, "server.max_body_size (MAX_BODY_SIZE) must be positive, got %v", s.Server.MaxBodySize)
+	check(s.Server.RateLimit > 0, "server.rate_limit (RATE_LIMIT) must be positive, got %v", s.Server.RateLimit)
+	check(s.Server.RateLimitWindow > 0, "server.rate_limit_window (RATE_LIMIT_WINDOW) must be positive, got %v", s.Server.RateLimitWindow)
+
+	switch s.Storage.Driver {
+	case StorageMongo:
+		check(s.Storage.MongoUri != "", "storage.mongo_uri (MONGO_URI) must be set for the %v storage", StorageMongo)
+	case StoragePostgres:
+		check(s.Storage.PostgresUrl != "", "storage.postgres_url (POSTGRES_URL) must be set for the %v storage", StoragePostgres)
+	case StorageMemory:
+	default:
+		check(false, "storage.driver (STORAGE_DRIVER) must be '%v', '%v' or '%v', got '%v'", StorageMongo, StoragePostgres, StorageMemory, s.Storage.Driver)
+	}
+	check(s.Storage.Timeout > 0, "storage.timeout (DB_TIMEOUT) must be positive, got %v", s.Storage.Timeout)
+
+	check(s.Auth.JwtKey != "" || s.Auth.JwtSigningKeyFile != "", "auth.jwt_key (JWT_KEY) or auth.jwt_signing_key_file (JWT_SIGNING_KEY_FILE) must be set")
+	if s.Auth.JwtAcceptLegacyHs256 != "" {
+		legacyUntil, parseErr := parseSettingDate(s.Auth.JwtAcceptLegacyHs256)
+		check(parseErr == nil && legacyUntil.Before(time.Now().Add(maxLegacyHs256Window)),
+			"auth.jwt_accept_legacy_hs256 (JWT_ACCEPT_LEGACY_HS256) must be a date like 2006-01-02 within %v days, got '%v'", int(maxLegacyHs256Window.Hours()/24), s.Auth.JwtAcceptLegacyHs256)
+		check(s.Auth.JwtKey != "" && s.Auth.JwtSigningKeyFile != "",
+			"auth.jwt_accept_legacy_hs256 (JWT_ACCEPT_LEGACY_HS256) needs both auth.jwt_key (JWT_KEY) and auth.jwt_signing_key_file (JWT_SIGNING_KEY_FILE)")
+	}
+	_, totpCipherErr := totputils.NewSecretCipher(s.Auth.TotpEncryptionKey)
+	check(totpCipherErr == nil, "auth.totp_encryption_key (TOTP_ENCRYPTION_KEY) must be 32 bytes in base64, generate it with 'openssl rand -base64 32'")
+
+	password := s.Password
+	check(password.MinLength >= 1 && password.MinLength <= 128, "password.min_length (PASSWORD_MIN_LENGTH) must be from 1 to 128, got %v", password.MinLength)
+	check(password.MaxLength >= password.MinLength && password.MaxLength <= 1024,
+		"password.max_length (PASSWORD_MAX_LENGTH) must be from the min length to 1024, got %v", password.MaxLength)
+	check(password.MinCharClasses >= 1 && password.MinCharClasses <= 4, "password.min_char_classes (PASSWORD_MIN_CHAR_CLASSES) must be from 1 to 4, got %v", password.MinCharClasses)
+	check(password.HashMemoryKib >= 8*1024 && int64(password.HashMemoryKib) <= math.MaxUint32,
+		"password.hash_memory_kib (PASSWORD_HASH_MEMORY_KIB) must be not less than 8192, got %v", password.HashMemoryKib)
+	check(password.HashTime >= 1 && int64(password.HashTime) <= math.MaxUint32, "password.hash_time (PASSWORD_HASH_TIME) must be positive, got %v", password.HashTime)
+	check(password.HashThreads >= 1 && password.HashThreads <= math.MaxUint8, "password.hash_threads (PASSWORD_HASH_THREADS) must be from 1 to 255, got %v", password.HashThreads)
+	if password.BreachedDir != "" {
+		info, statErr := os.Stat(password.BreachedDir)
+		check(statErr == nil && info.IsDir(), "password.breached_dir (BREACHED_PASSWORDS_DIR) '%v' is not a directory", password.BreachedDir)
+	}
+
+	for _, login := range []struct {
+		name, env               string
+		freeFailures, lockAfter int
+		lockDuration, maxDelay  time.Duration
+	}{
+		{"email", "EMAIL", s.Login.EmailFreeFailures, s.Login.EmailLockAfter, s.Login.EmailLockDuration, s.Login.EmailMaxDelay},
+		{"ip", "IP", s.Login.IpFreeFailures, s.Login.IpLockAfter, s.Login.IpLockDuration, s.Login.IpMaxDelay},
+	} {
+		check(login.freeFailures >= 0, "login.%v_free_failures (LOGIN_%v_FREE_FAILURES) must not be negative, got %v", login.name, login.env, login.freeFailures)
+		check(login.lockAfter > login.freeFailures,
+			"login.%v_lock_after (LOGIN_%v_LOCK_AFTER) must be more than the free failures, got %v", login.name, login.env, login.lockAfter)
+		check(login.lockDuration >= time.Second, "login.%v_lock_duration (LOGIN_%v_LOCK_DURATION) must be at least 1s, got %v", login.name, login.env, login.lockDuration)
+		check(login.maxDelay >= time.Second && login.maxDelay <= login.lockDuration,
+			"login.%v_max_delay (LOGIN_%v_MAX_DELAY) must be from 1s to the lock duration, got %v", login.name, login.env, login.maxDelay)
+	}
+
+	switch s.Mailer.Driver {
+	case MailerSmtp:
+		check(s.Mailer.SmtpHost != "" && s.Mailer.SmtpFrom != "", "mailer.smtp_host (SMTP_HOST) and mailer.smtp_from (SMTP_FROM) must be set for the %v mailer", MailerSmtp)
+		check(s.Mailer.SmtpPort >= 1 && s.Mailer.SmtpPort <= 65535, "mailer.smtp_port (SMTP_PORT) must be from 1 to 65535, got %v", s.Mailer.SmtpPort)
+	case MailerLog:
+	case "":
+		// no silent fallback to the log mailer: a deployment that forgot SMTP must not fail to deliver emails quietly
+		check(false, "mailer.driver (MAILER_DRIVER) must be set, '%v' for a real server or '%v' for local development", MailerSmtp, MailerLog)
+	default:
+		check(false, "mailer.driver (MAILER_DRIVER) must be '%v' or '%v', got '%v'", MailerLog, MailerSmtp, s.Mailer.Driver)
+	}
+
+	_, levelErr := logger.ParseLevel(s.Log.Level)
+	check(levelErr == nil, "log.level (LOG_LEVEL) must be debug, info, warn or error, got '%v'", s.Log.Level)
+	check(s.Log.Format == logger.FormatText || s.Log.Format == logger.FormatJson,
+		"log.format (LOG_FORMAT) must be '%v' or '%v', got '%v'", logger.FormatText, logger.FormatJson, s.Log.Format)
+
+	tracingSettings := s.Tracing
+	switch tracingSettings.Exporter {
+	case tracing.ExporterOtlp:
+		if tracingSettings.OtlpEndpoint != "" {
+			endpoint, endpointErr := url.Parse(tracingSettings.OtlpEndpoint)
+			check(endpointErr == nil && (endpoint.Scheme == "http" || endpoint.Scheme == "https") && endpoint.Host != "",
+				"tracing.otlp_endpoint (TRACING_OTLP_ENDPOINT) must be an http or https url, got '%v'", tracingSettings.OtlpEndpoint)
+		}
+	case tracing.ExporterNone, tracing.ExporterStdout:
+	default:
+		check(false, "tracing.exporter (TRACING_EXPORTER) must be '%v', '%v' or '%v', got '%v'",
+			tracing.ExporterNone, tracing.ExporterStdout, tracing.ExporterOtlp, tracingSettings.Exporter)
+	}
+	check(tracingSettings.SampleRatio >= 0 && tracingSettings.SampleRatio <= 1, "tracing.sample_ratio (TRACING_SAMPLE_RATIO) must be from 0 to 1, got %v", tracingSettings.SampleRatio)
+	check(tracingSettings.ServiceName != "", "tracing.service_name (TRACING_SERVICE_NAME) must be set")
+
+	return errors.Join(errs...)
+}
+
+// JwtKeys, PasswordPolicy, Argon2Params and LoginPolicies must be called on validated settings
+func (s Settings) JwtKeys() jwtutils.Keys {
+	keys := jwtutils.Keys{
+		HmacKey:              s.Auth.JwtKey,
+		SigningKeyFile:       s.Auth.JwtSigningKeyFile,
+		VerificationKeyFiles: s.Auth.JwtVerificationKeyFiles,
+	}
+	if s.Auth.JwtAcceptLegacyHs256 != "" {
+		keys.AcceptLegacyHmacUntil, _ = parseSettingDate(s.Auth.JwtAcceptLegacyHs256)
+	}
+	return keys
+}
+
+func (s Settings) PasswordPolicy() passwordutils.Policy {
+	return passwordutils.Policy{
+		MinLength:      s.Password.MinLength,
+		MaxLength:      s.Password.MaxLength,
+		MinCharClasses: s.Password.MinCharClasses,
+	}
+}
+
+func (s Settings) Argon2Params() hashutils.Argon2Params {
+	params := hashutils.DefaultArgon2Params
+	params.Memory = uint32(s.Password.HashMemoryKib)
+	params.Time = uint32(s.Password.HashTime)
+	params.Threads = uint8(s.Password.HashThreads)
+	return params
+}
+
+func (s Settings) LoginPolicies() userusecases.LoginPolicies {
+	return userusecases.LoginPolicies{
+		Email: userusecases.LoginPolicy{
+			FreeFailures: s.Login.EmailFreeFailures,
+			LockAfter:    s.Login.EmailLockAfter,
+			LockDuration: s.Login.EmailLockDuration,
+			MaxDelay:     s.Login.EmailMaxDelay,
+		},
+		Ip: userusecases.LoginPolicy{
+			FreeFailures: s.Login.IpFreeFailures,
+			LockAfter:    s.Login.IpLockAfter,
+			LockDuration: s.Login.IpLockDuration,
+			MaxDelay:     s.Login.IpMaxDelay,
+		},
+	}
+}
+
+func (s Settings) TracingConfig() tracing.Config {
+	return tracing.Config{
+		Exporter:     s.Tracing.Exporter,
+		OtlpEndpoint: s.Tracing.OtlpEndpoint,
+		SampleRatio:  s.Tracing.SampleRatio,
+		ServiceName:  s.Tracing.ServiceName,
+	}
+}
+
+// parseSettingDate takes a date, which means its start in UTC, or an RFC 3339 time
+func parseSettingDate(raw string) (time.Time, error) {
+	if date, err := time.Parse(time.DateOnly, raw); err == nil {
+		return date, nil
+	}
+	return time.Parse(time.RFC3339, raw)
+}
+
+type settingField struct {
+	env   string
+	value reflect.Value
+}
+
+func (f settingField) flagName() string {
+	return strings.ReplaceAll(strings.ToLower(f.env), "_", "-")
+}
+
+func (f settingField) set(raw string) error {
+	if f.value.Type() == reflect.TypeOf(time.Duration(0)) {
+		duration, err := time.ParseDuration(raw)
+		if err != nil {
+			return err
+		}
+		f.value.SetInt(int64(duration))
+		return nil
+	}
+
+	switch f.value.Kind() {
+	case reflect.String:
+		f.value.SetString(raw)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		f.value.SetBool(parsed)
+	case reflect.Int:
+		parsed, err := strconv.Atoi(raw)
+		if err != nil {
+			return err
+		}
+		f.value.SetInt(int64(parsed))
+	case reflect.Float64:
+		parsed, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return err
+		}
+		f.value.SetFloat(parsed)
+	case reflect.Slice:
+		var items []string
+		for _, item := range strings.Split(raw, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+		f.value.Set(reflect.ValueOf(items))
+	default:
+		return fmt.Errorf("unsupported setting type %v", f.value.Type())
+	}
+
+	return nil
+}
+
+// settingFields lists the fields with an env tag, nested settings structs included
+func settingFields(value reflect.Value) []settingField {
+	var fields []settingField
+	for i := 0; i < value.NumField(); i++ {
+		field := value.Type().Field(i)
+		if env := field.Tag.Get("env"); env != "" {
+			fields = append(fields, settingField{env: env, value: value.Field(i)})
+		} else if field.Type.Kind() == reflect.Struct {
+			fields = append(fields, settingFields(value.Field(i))...)
+		}
+	}
+	return fields
+}
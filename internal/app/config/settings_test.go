@@ -0,0 +1,201 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func envLookup(env map[string]string) func(key string) (string, bool) {
+	return func(key string) (string, bool) {
+		value, ok := env[key]
+		return value, ok
+	}
+}
+
+func writeSettingsFile(t *testing.T, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatalf("failed to write settings file: %v", err)
+	}
+	return path
+}
+
+func TestLoadSettingsPrecedence(t *testing.T) {
+	path := writeSettingsFile(t, `
+server:
+  port: 7000
+  rate_limit: 10
+  request_timeout: 5s
+storage:
+  driver: memory
+mailer:
+  driver: log
+auth:
+  jwt_key: from-file
+  totp_encryption_key: 7B1OHmh4P+dt0BOqeaDnXXH4/9IzCn+R0IrMKJVl2V0=
+  jwt_verification_key_files: [a.pem, b.pem]
+`)
+	env := map[string]string{
+		ConfigFileEnv:          path,
+		"PORT":                 "7001",
+		"RATE_LIMIT":           "20",
+		"SECURE_COOKIES":       "true",
+		"REQUEST_TIMEOUT":      "",
+		"TRACING_SAMPLE_RATIO": "0.25",
+	}
+
+	settings, args, err := LoadSettings([]string{"-port", "7002", "-require-admin-2fa", "migrate", "up"}, envLookup(env))
+	if err != nil {
+		t.Fatalf("LoadSettings: %v", err)
+	}
+
+	if settings.Server.Port != 7002 {
+		t.Errorf("port = %v, want the flag value 7002", settings.Server.Port)
+	}
+	if settings.Server.RateLimit != 20 {
+		t.Errorf("rate limit = %v, want the env value 20", settings.Server.RateLimit)
+	}
+	if settings.Server.RequestTimeout != 5*time.Second {
+		t.Errorf("request timeout = %v, want the file value 5s as empty env is ignored", settings.Server.RequestTimeout)
+	}
+	if settings.Server.ShutdownTimeout != DefaultSettings().Server.ShutdownTimeout {
+		t.Errorf("shutdown timeout = %v, want the default", settings.Server.ShutdownTimeout)
+	}
+	if !settings.Server.SecureCookies || !settings.Auth.RequireAdminTwoFactor {
+		t.Errorf("secure cookies = %v, require admin 2fa = %v, want both on", settings.Server.SecureCookies, settings.Auth.RequireAdminTwoFactor)
+	}
+	if settings.Storage.Driver != StorageMemory || settings.Auth.JwtKey != "from-file" {
+		t.Errorf("storage driver = %v, jwt key = %v, want the file values", settings.Storage.Driver, settings.Auth.JwtKey)
+	}
+	if settings.Tracing.SampleRatio != 0.25 {
+		t.Errorf("tracing sample ratio = %v, want the env value 0.25", settings.Tracing.SampleRatio)
+	}
+	if want := []string{"a.pem", "b.pem"}; !reflect.DeepEqual(settings.Auth.JwtVerificationKeyFiles, want) {
+		t.Errorf("verification key files = %v, want %v", settings.Auth.JwtVerificationKeyFiles, want)
+	}
+	if want := []string{"migrate", "up"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("args = %v, want %v", args, want)
+	}
+	if err := settings.Validate(); err != nil {
+		t.Errorf("Validate: %v", err)
+	}
+}
+
+func TestLoadSettingsConfigFlagOverridesEnv(t *testing.T) {
+	path := writeSettingsFile(t, "server:\n  port: 7100\n")
+	env := map[string]string{ConfigFileEnv: filepath.Join(t.TempDir(), "missing.yaml")}
+
+	settings, _, err := LoadSettings([]string{"-config", path}, envLookup(env))
+	if err != nil {
+		t.Fatalf("LoadSettings: %v", err)
+	}
+	if settings.Server.Port != 7100 {
+		t.Fatalf("port = %v, want 7100 from the -config file", settings.Server.Port)
+	}
+}
+
+func TestLoadSettingsRejectsBadInput(t *testing.T) {
+	cases := map[string]struct {
+		args []string
+		env  map[string]string
+	}{
+		"unknown file key": {env: map[string]string{ConfigFileEnv: writeSettingsFile(t, "server:\n  prot: 8080\n")}},
+		"bad env value":    {env: map[string]string{"PORT": "eighty"}},
+		"bad flag value":   {args: []string{"-request-timeout", "soon"}},
+		"unknown flag":     {args: []string{"-no-such-flag", "1"}},
+	}
+
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := LoadSettings(tc.args, envLookup(tc.env)); err == nil {
+				t.Fatal("LoadSettings succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestValidateDefaults(t *testing.T) {
+	settings := DefaultSettings()
+	settings.Storage.MongoUri = "mongodb://localhost:27017"
+	settings.Auth.JwtKey = "secret"
+	settings.Auth.TotpEncryptionKey = "7B1OHmh4P+dt0BOqeaDnXXH4/9IzCn+R0IrMKJVl2V0="
+	settings.Mailer.Driver = MailerSmtp
+	settings.Mailer.SmtpHost = "smtp.example.com"
+	settings.Mailer.SmtpPort = 587
+	settings.Mailer.SmtpFrom = "no-reply@example.com"
+
+	if err := settings.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
+
+func TestValidateReportsEveryError(t *testing.T) {
+	settings := DefaultSettings()
+	settings.Server.Port = 0
+	settings.Server.MetricsPort = 0
+	settings.Server.AppBaseUrl = "localhost"
+	settings.Storage.Driver = "sqlite"
+	settings.Login.EmailLockAfter = settings.Login.EmailFreeFailures
+	settings.Login.IpMaxDelay = settings.Login.IpLockDuration + time.Second
+	settings.Mailer.Driver = MailerSmtp
+
+	err := settings.Validate()
+	if err == nil {
+		t.Fatal("Validate succeeded, want errors")
+	}
+	for _, want := range []string{"PORT", "METRICS_PORT", "APP_BASE_URL", "STORAGE_DRIVER", "JWT_KEY", "TOTP_ENCRYPTION_KEY", "LOGIN_EMAIL_LOCK_AFTER", "LOGIN_IP_MAX_DELAY", "SMTP_HOST", "SMTP_PORT"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Validate error does not mention %v: %v", want, err)
+		}
+	}
+}
+
+func TestValidateLegacyHs256(t *testing.T) {
+	soon := time.Now().AddDate(0, 0, 7).Format(time.DateOnly)
+
+	for name, tc := range map[string]struct {
+		until, signingKeyFile string
+		ok                    bool
+	}{
+		"date within the window": {soon, "keys/jwt.pem", true},
+		"rfc 3339 time":          {time.Now().Add(time.Hour).Format(time.RFC3339), "keys/jwt.pem", true},
+		"without key file":       {soon, "", false},
+		"too far":                {time.Now().AddDate(1, 0, 0).Format(time.DateOnly), "keys/jwt.pem", false},
+		"not a date":             {"true", "keys/jwt.pem", false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			settings := DefaultSettings()
+			settings.Storage.Driver = StorageMemory
+			settings.Auth.JwtKey = "secret"
+			settings.Auth.JwtSigningKeyFile = tc.signingKeyFile
+			settings.Auth.JwtAcceptLegacyHs256 = tc.until
+			settings.Auth.TotpEncryptionKey = "7B1OHmh4P+dt0BOqeaDnXXH4/9IzCn+R0IrMKJVl2V0="
+			settings.Mailer.Driver = MailerLog
+
+			err := settings.Validate()
+			if (err == nil) != tc.ok {
+				t.Fatalf("Validate error = %v, want ok %v", err, tc.ok)
+			}
+			if tc.ok && settings.JwtKeys().AcceptLegacyHmacUntil.IsZero() {
+				t.Fatal("JwtKeys has no legacy HS256 date")
+			}
+		})
+	}
+}
+
+func TestExampleSettingsFile(t *testing.T) {
+	env := map[string]string{ConfigFileEnv: filepath.Join("..", "..", "..", "config.example.yaml")}
+
+	settings, _, err := LoadSettings(nil, envLookup(env))
+	if err != nil {
+		t.Fatalf("LoadSettings: %v", err)
+	}
+	if err := settings.Validate(); err != nil {
+		t.Fatalf("Validate: %v", err)
+	}
+}
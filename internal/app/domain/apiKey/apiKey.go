@@ -0,0 +1,53 @@
+package apikey
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/domain/user"
+)
+
+// KeyPrefix marks API keys so they are easy to tell from jwt and to find by secret scanners
+const KeyPrefix = "otk_"
+
+var ErrInvalidApiKey = errors.New("api key is invalid or revoked")
+
+// ApiKey is a long-lived credential of a user for server-to-server integrations.
+// It acts as its owner but only with the listed permissions. Only the hash of the key is stored
+type ApiKey struct {
+	Uuid        uuid.UUID         `json:"id" bson:"_id"`
+	OwnerUuid   uuid.UUID         `json:"owner_id" bson:"owner_id"`
+	Name        string            `json:"name" bson:"name"`
+	Prefix      string            `json:"prefix" bson:"prefix"` // first characters of the key, to recognize it in the list
+	KeyHash     string            `json:"-" bson:"key_hash"`
+	Permissions []user.Permission `json:"permissions" bson:"permissions"`
+	CreatedAt   int64             `json:"created_at" bson:"created_at"`
+	LastUsedAt  int64             `json:"last_used_at" bson:"last_used_at"`
+	RevokedAt   int64             `json:"revoked_at,omitempty" bson:"revoked_at,omitempty"`
+}
+
+func NewApiKey(ownerUuid uuid.UUID, name, prefix, keyHash string, permissions []user.Permission) *ApiKey {
+	return &ApiKey{
+		Uuid:        uuid.New(),
+		OwnerUuid:   ownerUuid,
+		Name:        name,
+		Prefix:      prefix,
+		KeyHash:     keyHash,
+		Permissions: permissions,
+		CreatedAt:   time.Now().Unix(),
+	}
+}
+
+func (k *ApiKey) IsRevoked() bool {
+	return k.RevokedAt != 0
+}
+
+func (k *ApiKey) HasPermission(permission user.Permission) bool {
+	for _, p := range k.Permissions {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
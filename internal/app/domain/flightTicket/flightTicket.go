@@ -14,25 +14,27 @@ const (
 )
 
 type FlightTicket struct {
-	Uuid     uuid.UUID              `json:"id" bson:"_id"`
-	CityFrom string                 `json:"city_from" bson:"city_from"`
-	CityTo   string                 `json:"city_to" bson:"city_to"`
-	Quantity uint32                 `json:"quantity" bson:"quantity"`
-	Value    *uint32                `json:"value" bson:"value"`
-	TakeOff  *int64                 `json:"take_off" bson:"take_off"`
-	Arrival  int64                  `json:"arrival" bson:"arrival"`
-	Category FlightTicketCategories `json:"category" bson:"category"`
+	Uuid         uuid.UUID              `json:"id" bson:"_id"`
+	CityFrom     string                 `json:"city_from" bson:"city_from"`
+	CityTo       string                 `json:"city_to" bson:"city_to"`
+	Quantity     uint32                 `json:"quantity" bson:"quantity"`
+	Value        *uint32                `json:"value" bson:"value"`
+	TakeOff      *int64                 `json:"take_off" bson:"take_off"`
+	Arrival      int64                  `json:"arrival" bson:"arrival"`
+	Category     FlightTicketCategories `json:"category" bson:"category"`
+	OperatorUuid uuid.UUID              `json:"operator_id" bson:"operator_id"` // airline operator, uuid.Nil if managed only by admins
 }
 
-func NewFlightTicket(cityFrom, cityTo string, quantity uint32, value *uint32, takeOff *int64, arrival int64) *FlightTicket {
+func NewFlightTicket(cityFrom, cityTo string, quantity uint32, value *uint32, takeOff *int64, arrival int64, operatorUuid uuid.UUID) *FlightTicket {
 	return &FlightTicket{
-		Uuid:     uuid.New(),
-		CityFrom: cityFrom,
-		CityTo:   cityTo,
-		Quantity: quantity,
-		Value:    value,
-		TakeOff:  takeOff,
-		Arrival:  arrival,
-		Category: None,
+		Uuid:         uuid.New(),
+		CityFrom:     cityFrom,
+		CityTo:       cityTo,
+		Quantity:     quantity,
+		Value:        value,
+		TakeOff:      takeOff,
+		Arrival:      arrival,
+		Category:     None,
+		OperatorUuid: operatorUuid,
 	}
 }
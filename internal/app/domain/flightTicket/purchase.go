@@ -0,0 +1,27 @@
+package flightticket
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type Purchase struct {
+	Uuid             uuid.UUID `json:"id" bson:"_id"`
+	FlightTicketUuid uuid.UUID `json:"flight_ticket_id" bson:"flight_ticket_id"`
+	BuyerUuid        uuid.UUID `json:"buyer_id" bson:"buyer_id"`
+	Quantity         uint32    `json:"quantity" bson:"quantity"`
+	Value            *uint32   `json:"value,omitempty" bson:"value,omitempty"` // total for all passengers
+	CreatedAt        int64     `json:"created_at" bson:"created_at"`
+}
+
+func NewPurchase(flightTicketUuid, buyerUuid uuid.UUID, quantity uint32, value *uint32) *Purchase {
+	return &Purchase{
+		Uuid:             uuid.New(),
+		FlightTicketUuid: flightTicketUuid,
+		BuyerUuid:        buyerUuid,
+		Quantity:         quantity,
+		Value:            value,
+		CreatedAt:        time.Now().Unix(),
+	}
+}
@@ -1,21 +1,33 @@
 package hotel
 
 import (
+	"errors"
+
 	"github.com/google/uuid"
 )
 
+var ErrDeleted = errors.New("hotel is deleted")
+
 type Hotel struct {
-	Uuid  uuid.UUID `json:"id" bson:"_id"`
-	Name  string    `json:"name" bson:"name"`
-	City  string    `json:"city" bson:"city"`
-	Stars int32     `json:"stars" bson:"stars"`
+	Uuid      uuid.UUID `json:"id" bson:"_id"`
+	Name      string    `json:"name" bson:"name"`
+	City      string    `json:"city" bson:"city"`
+	Stars     int32     `json:"stars" bson:"stars"`
+	OwnerUuid uuid.UUID `json:"owner_id" bson:"owner_id"` // hotel manager, uuid.Nil if managed only by admins
+	// set when deleted, the record is kept for the history of rents
+	DeletedAt int64 `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
 }
 
-func NewHotel(name, city string, stars int32) *Hotel {
+func NewHotel(name, city string, stars int32, ownerUuid uuid.UUID) *Hotel {
 	return &Hotel{
-		Uuid:  uuid.New(),
-		Name:  name,
-		City:  city,
-		Stars: stars,
+		Uuid:      uuid.New(),
+		Name:      name,
+		City:      city,
+		Stars:     stars,
+		OwnerUuid: ownerUuid,
 	}
 }
+
+func (h *Hotel) IsDeleted() bool {
+	return h.DeletedAt != 0
+}
@@ -1,9 +1,13 @@
 package hotelroom
 
 import (
+	"errors"
+
 	"github.com/google/uuid"
 )
 
+var ErrDeleted = errors.New("hotel room is deleted")
+
 type HotelType string
 
 const (
@@ -19,6 +23,8 @@ type HotelRoom struct {
 	Type         HotelType `json:"type" bson:"type"`                   // can have 2 values (first, second)
 	AmountPeople uint32    `json:"amount_people" bson:"amount_people"` // can have 2 values (from, to)
 	Value        *int64    `json:"value" bson:"value"`                 // can have 2 values (from, to)
+	// set when deleted, the record is kept for the history of rents
+	DeletedAt int64 `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
 }
 
 type FindHotelRoomDTO struct {
@@ -42,6 +48,7 @@ type FindHotelRoomFilterDTO struct {
 	ValueFrom        *int64
 	ValueTo          *int64
 	Arrange          string
+	IncludeDeleted   bool // deleted rooms are found only for the history of rents
 }
 
 func NewHotelRoom(hotelUuid uuid.UUID, rooms uint32, Type HotelType, amountPeople uint32, value *int64) *HotelRoom {
@@ -54,3 +61,7 @@ func NewHotelRoom(hotelUuid uuid.UUID, rooms uint32, Type HotelType, amountPeopl
 		Value:        value,
 	}
 }
+
+func (r *HotelRoom) IsDeleted() bool {
+	return r.DeletedAt != 0
+}
@@ -0,0 +1,37 @@
+package loginattempt
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// LoginAttempt counts failed logins for one email or one IP. Key is "email:<email>" or "ip:<ip>".
+// An attempt is counted as failed before the password is checked and released when it turns out right,
+// so concurrent attempts always see each other
+type LoginAttempt struct {
+	Key           string `json:"key" bson:"_id"`
+	Failures      int    `json:"failures" bson:"failures"`
+	LastFailureAt int64  `json:"last_failure_at" bson:"last_failure_at"`
+	PrevFailureAt int64  `json:"prev_failure_at" bson:"prev_failure_at"` // the failure before the last one
+}
+
+type ThrottledError struct {
+	RetryAfter time.Duration
+	Locked     bool
+}
+
+func (e *ThrottledError) Error() string {
+	if e.Locked {
+		return fmt.Sprintf("too many failed login attempts, account is temporarily locked. try again in %v", e.RetryAfter)
+	}
+	return fmt.Sprintf("too many failed login attempts. try again in %v", e.RetryAfter)
+}
+
+func EmailKey(email string) string {
+	return "email:" + strings.ToLower(email)
+}
+
+func IpKey(ip string) string {
+	return "ip:" + ip
+}
@@ -1,15 +1,34 @@
 package rent
 
 import (
+	"errors"
+
 	"github.com/google/uuid"
 )
 
+var (
+	// ErrAlreadyRented is returned when a new rent overlaps an existing rent of the same hotel room
+	ErrAlreadyRented = errors.New("this hotel room is already rented for this time")
+	// ErrHasActiveRents blocks deleting hotels and hotel rooms until their rents end or are cancelled
+	ErrHasActiveRents = errors.New("there are rents that haven't ended yet, delete with force to cancel them")
+)
+
+const (
+	// CancelReasonRequest is a cancellation by the renter, a hotel manager or support
+	CancelReasonRequest = "request"
+	// CancelReasonForced is a cancellation by the forced deletion of the room or its hotel
+	CancelReasonForced = "forced"
+)
+
 type Rent struct {
 	Uuid       uuid.UUID `json:"id" bson:"_id"`
 	RoomUuid   uuid.UUID `json:"hotel_room_id" bson:"hotel_room_id"`
 	RenterUuid uuid.UUID `json:"renter_id" bson:"renter_id"`
 	DateFrom   int64     `json:"date_from" bson:"date_from"`
 	DateTo     int64     `json:"date_to" bson:"date_to"`
+	// set when cancelled, the record is kept for the history and its dates are free again
+	CancelledAt  int64  `json:"cancelled_at,omitempty" bson:"cancelled_at,omitempty"`
+	CancelReason string `json:"cancel_reason,omitempty" bson:"cancel_reason,omitempty"`
 }
 
 type DeleteDTO struct {
@@ -18,6 +37,12 @@ type DeleteDTO struct {
 	UserRole string
 }
 
+type FindRentFilterDTO struct {
+	HotelUuid  uuid.UUID
+	RoomUuids  []uuid.UUID
+	RenterUuid uuid.UUID
+}
+
 func NewRent(roomUuid, renterUuid uuid.UUID, dateFrom int64, dateTo int64) *Rent {
 	return &Rent{
 		Uuid:       uuid.New(),
@@ -27,3 +52,7 @@ func NewRent(roomUuid, renterUuid uuid.UUID, dateFrom int64, dateTo int64) *Rent
 		DateTo:     dateTo,
 	}
 }
+
+func (r *Rent) IsCancelled() bool {
+	return r.CancelledAt != 0
+}
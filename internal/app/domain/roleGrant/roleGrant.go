@@ -0,0 +1,38 @@
+package rolegrant
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/domain/user"
+)
+
+type GrantAction string
+
+const (
+	ActionGrant     GrantAction = "grant"
+	ActionRevoke    GrantAction = "revoke"
+	ActionBootstrap GrantAction = "bootstrap"
+)
+
+type RoleGrant struct {
+	Uuid         uuid.UUID     `json:"id" bson:"_id"`
+	UserUuid     uuid.UUID     `json:"user_id" bson:"user_id"`
+	Action       GrantAction   `json:"action" bson:"action"`
+	Role         user.UserRole `json:"role" bson:"role"`
+	PreviousRole user.UserRole `json:"previous_role" bson:"previous_role"`
+	GrantedBy    uuid.UUID     `json:"granted_by" bson:"granted_by"` // uuid.Nil when bootstrapped from CLI or env
+	CreatedAt    int64         `json:"created_at" bson:"created_at"`
+}
+
+func NewRoleGrant(userUuid uuid.UUID, action GrantAction, role, previousRole user.UserRole, grantedBy uuid.UUID) *RoleGrant {
+	return &RoleGrant{
+		Uuid:         uuid.New(),
+		UserUuid:     userUuid,
+		Action:       action,
+		Role:         role,
+		PreviousRole: previousRole,
+		GrantedBy:    grantedBy,
+		CreatedAt:    time.Now().Unix(),
+	}
+}
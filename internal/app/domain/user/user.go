@@ -1,30 +1,183 @@
 package user
 
 import (
+	"errors"
+	"slices"
+	"strings"
+
 	"github.com/google/uuid"
 )
 
 type UserRole string
 
 const (
-	RoleAdmin UserRole = "admin"
-	RoleUser  UserRole = "user"
+	RoleAdmin           UserRole = "admin"
+	RoleUser            UserRole = "user"
+	RoleHotelManager    UserRole = "hotel_manager"
+	RoleAirlineOperator UserRole = "airline_operator"
+	RoleSupportAgent    UserRole = "support_agent"
+)
+
+type Permission string
+
+const (
+	PermManageUsers      Permission = "users:manage"
+	PermManageHotels     Permission = "hotels:manage"
+	PermManageHotelRooms Permission = "hotel_rooms:manage"
+	PermManageFlights    Permission = "flights:manage"
+	PermReadRents        Permission = "rents:read"
+	PermCancelRents      Permission = "rents:cancel"
 )
 
+type PermissionScope int
+
+const (
+	ScopeNone PermissionScope = iota
+	ScopeOwn                  // only resources owned by the user (hotels they manage, flights they operate)
+	ScopeAll
+)
+
+var (
+	ErrNoPermission    = errors.New("you dont have permission")
+	ErrWrongPassword   = errors.New("wrong password")
+	ErrSessionRevoked  = errors.New("session has been revoked, login again")
+	ErrAccountDisabled = errors.New("account is disabled")
+	// ErrInvalidCredentials does not tell an unknown email from a wrong password
+	ErrInvalidCredentials = errors.New("invalid email or password")
+
+	ErrTwoFactorRequired    = errors.New("two-factor authentication code is required")
+	ErrInvalidTwoFactorCode = errors.New("invalid two-factor authentication code")
+
+	ErrWeakPassword     = errors.New("password does not meet the password policy")
+	ErrBreachedPassword = errors.New("password has appeared in a data breach, choose another one")
+)
+
+var rolePermissions = map[UserRole]map[Permission]PermissionScope{
+	RoleAdmin: {
+		PermManageUsers:      ScopeAll,
+		PermManageHotels:     ScopeAll,
+		PermManageHotelRooms: ScopeAll,
+		PermManageFlights:    ScopeAll,
+		PermReadRents:        ScopeAll,
+		PermCancelRents:      ScopeAll,
+	},
+	RoleHotelManager: {
+		PermManageHotels:     ScopeOwn,
+		PermManageHotelRooms: ScopeOwn,
+		PermReadRents:        ScopeOwn,
+		PermCancelRents:      ScopeOwn,
+	},
+	RoleAirlineOperator: {
+		PermManageFlights: ScopeOwn,
+	},
+	RoleSupportAgent: {
+		PermReadRents:   ScopeAll,
+		PermCancelRents: ScopeAll,
+	},
+	RoleUser: {},
+}
+
 type User struct {
-	Uuid     uuid.UUID `json:"id" bson:"_id"`
-	Name     string    `json:"name" bson:"name"`
-	Email    string    `json:"email" bson:"email"`
-	Password string    `json:"password" bson:"password"`
-	Role     UserRole  `json:"role" bson:"role"`
+	Uuid          uuid.UUID `json:"id" bson:"_id"`
+	Name          string    `json:"name" bson:"name"`
+	Email         string    `json:"email" bson:"email"`
+	EmailVerified bool      `json:"email_verified" bson:"email_verified"`
+	Password      string    `json:"-" bson:"password"`
+	Role          UserRole  `json:"role" bson:"role"`
+	PendingEmail  string    `json:"pending_email,omitempty" bson:"pending_email,omitempty"`
+	TokenVersion  int64     `json:"-" bson:"token_version"`
+	DeletedAt     int64     `json:"deleted_at,omitempty" bson:"deleted_at,omitempty"`
+	DisabledAt    int64     `json:"disabled_at,omitempty" bson:"disabled_at,omitempty"` // disabled by an admin, login and sessions are rejected
+
+	Phone    string `json:"phone,omitempty" bson:"phone,omitempty"`       // E.164, e.g. +79991234567
+	Language string `json:"language,omitempty" bson:"language,omitempty"` // ISO 639-1, e.g. ru
+	Currency string `json:"currency,omitempty" bson:"currency,omitempty"` // ISO 4217, e.g. RUB
+
+	TotpEnabled        bool     `json:"totp_enabled" bson:"totp_enabled"`
+	TotpSecret         string   `json:"-" bson:"totp_secret,omitempty"`
+	TotpPendingSecret  string   `json:"-" bson:"totp_pending_secret,omitempty"` // set during enrollment until the first code is confirmed
+	TotpLastCounter    int64    `json:"-" bson:"totp_last_counter,omitempty"`
+	RecoveryCodeHashes []string `json:"-" bson:"recovery_code_hashes,omitempty"`
+}
+
+// ProfileUpdate changes only the non-nil fields, an empty string clears the field
+type ProfileUpdate struct {
+	Name     *string
+	Phone    *string
+	Language *string
+	Currency *string
+}
+
+// FindUserFilterDTO is an admin search. Query matches a part of the email or name, ignoring case
+type FindUserFilterDTO struct {
+	Query    string
+	Role     UserRole
+	Disabled *bool
+	Offset   int64
+	Limit    int64
+}
+
+type Actor struct {
+	Uuid uuid.UUID
+	Role UserRole
+	// KeyPermissions limits the role of a caller with an API key to the key's permissions, nil for sessions
+	KeyPermissions []Permission
 }
 
 func NewUser(name string, email string, password string) *User {
 	return &User{
 		Uuid:     uuid.New(),
 		Name:     name,
-		Email:    email,
+		Email:    NormalizeEmail(email),
 		Password: password,
 		Role:     "user",
 	}
 }
+
+// NormalizeEmail makes "Ivan@Example.com " and "ivan@example.com" the same account
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+func (u *User) IsDeleted() bool {
+	return u.DeletedAt != 0
+}
+
+func (u *User) IsDisabled() bool {
+	return u.DisabledAt != 0
+}
+
+func ParseUserRole(role string) (UserRole, bool) {
+	if _, ok := rolePermissions[UserRole(role)]; !ok {
+		return RoleUser, false
+	}
+	return UserRole(role), true
+}
+
+func (r UserRole) PermissionScope(permission Permission) PermissionScope {
+	return rolePermissions[r][permission]
+}
+
+// PermissionScope is the scope of the role, none when the actor uses an API key without the permission
+func (a Actor) PermissionScope(permission Permission) PermissionScope {
+	if a.KeyPermissions != nil && !slices.Contains(a.KeyPermissions, permission) {
+		return ScopeNone
+	}
+	return a.Role.PermissionScope(permission)
+}
+
+func (a Actor) Can(permission Permission) bool {
+	return a.PermissionScope(permission) != ScopeNone
+}
+
+// CanAccess reports whether the actor may use the permission on a resource owned by ownerUuid
+func (a Actor) CanAccess(permission Permission, ownerUuid uuid.UUID) bool {
+	switch a.PermissionScope(permission) {
+	case ScopeAll:
+		return true
+	case ScopeOwn:
+		return ownerUuid != uuid.Nil && ownerUuid == a.Uuid
+	default:
+		return false
+	}
+}
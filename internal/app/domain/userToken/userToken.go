@@ -0,0 +1,38 @@
+package usertoken
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type TokenPurpose string
+
+const (
+	PurposeEmailVerification TokenPurpose = "email_verification"
+	PurposePasswordReset     TokenPurpose = "password_reset"
+	PurposeEmailChange       TokenPurpose = "email_change"
+)
+
+// UserToken is a single-use token sent by email. Only the hash of the token is stored
+type UserToken struct {
+	Uuid      uuid.UUID    `json:"id" bson:"_id"`
+	UserUuid  uuid.UUID    `json:"user_id" bson:"user_id"`
+	Purpose   TokenPurpose `json:"purpose" bson:"purpose"`
+	TokenHash string       `json:"-" bson:"token_hash"`
+	CreatedAt int64        `json:"created_at" bson:"created_at"`
+	ExpiresAt int64        `json:"expires_at" bson:"expires_at"`
+	UsedAt    *int64       `json:"used_at" bson:"used_at"`
+}
+
+func NewUserToken(userUuid uuid.UUID, purpose TokenPurpose, tokenHash string, ttl time.Duration) *UserToken {
+	now := time.Now()
+	return &UserToken{
+		Uuid:      uuid.New(),
+		UserUuid:  userUuid,
+		Purpose:   purpose,
+		TokenHash: tokenHash,
+		CreatedAt: now.Unix(),
+		ExpiresAt: now.Add(ttl).Unix(),
+	}
+}
@@ -0,0 +1,45 @@
+package mongomigrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/pkg/migrations"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+// fieldDefault is a field added after the first release, documents created before it don't have it
+type fieldDefault struct {
+	collection string
+	field      string
+	value      any
+}
+
+var backfilledFields = []fieldDefault{
+	{collection: "hotels", field: "owner_id", value: uuid.Nil},
+	{collection: "flightTickets", field: "operator_id", value: uuid.Nil},
+	{collection: "users", field: "email_verified", value: false},
+	{collection: "users", field: "token_version", value: int64(0)},
+	{collection: "users", field: "totp_enabled", value: false},
+}
+
+// backfillFields sets the fields missing in old documents to the values the app writes for new ones,
+// so the documents pass the schema validators. It has no Down, removing the fields would break the validators again
+var backfillFields = migrations.Migration{
+	Version: 1,
+	Name:    "backfill_fields",
+	Up: func(ctx context.Context, db *mongo.Database) error {
+		for _, field := range backfilledFields {
+			filter := bson.D{{Key: field.field, Value: bson.D{{Key: "$exists", Value: false}}}}
+			update := bson.D{{Key: "$set", Value: bson.D{{Key: field.field, Value: field.value}}}}
+
+			if _, err := db.Collection(field.collection).UpdateMany(ctx, filter, update); err != nil {
+				return fmt.Errorf("failed to backfill %v.%v: %v", field.collection, field.field, err)
+			}
+		}
+
+		return nil
+	},
+}
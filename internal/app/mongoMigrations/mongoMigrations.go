@@ -0,0 +1,11 @@
+package mongomigrations
+
+import "github.com/rom6n/otello/internal/pkg/migrations"
+
+// All is every migration of the app, a new one gets the next version and is never changed after release.
+// Migrations use literal collection names, so renaming a collection later doesn't change old migrations
+func All() []migrations.Migration {
+	return []migrations.Migration{
+		backfillFields,
+	}
+}
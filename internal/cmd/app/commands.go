@@ -0,0 +1,152 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rom6n/otello/internal/app/config"
+	"github.com/rom6n/otello/internal/app/mongomigrations"
+	"github.com/rom6n/otello/internal/pkg/database"
+	"github.com/rom6n/otello/internal/pkg/migrations"
+)
+
+const usage = `usage: app [flags] [command]
+
+commands:
+  bootstrap-admin <email>   give the admin role to an already registered user
+  find-duplicate-emails     list accounts sharing an email, they block the unique email index
+  plan-mongo-schema         print the collections, validators and indexes the server would create or change on start
+  migrate up                apply pending mongo migrations, the server also does it on start
+  migrate down [steps]      revert the last applied mongo migrations, 1 by default
+  migrate status            list mongo migrations and when they were applied
+
+without a command the HTTP server is started. Flags override env and the settings file,
+run with -h to list them`
+
+func runCommand(ctx context.Context, storage config.Storage, configs config.Config, args []string) error {
+	switch args[0] {
+	case "bootstrap-admin":
+		if len(args) != 2 {
+			return fmt.Errorf("bootstrap-admin requires exactly one email argument")
+		}
+		if err := configs.UserUsecases.BootstrapAdmin(ctx, args[1]); err != nil {
+			return fmt.Errorf("failed to bootstrap admin: %v", err)
+		}
+		slog.Info("user is admin now", "email", args[1])
+		return nil
+	case "find-duplicate-emails":
+		duplicates, findErr := configs.UserUsecases.FindDuplicateEmails(ctx)
+		if findErr != nil {
+			return findErr
+		}
+		if len(duplicates) == 0 {
+			slog.Info("no duplicate emails found")
+			return nil
+		}
+		for _, duplicate := range duplicates {
+			fmt.Printf("%v\n", duplicate.Email)
+			for i, userUuid := range duplicate.UserUuids {
+				fmt.Printf("  %v  %q\n", userUuid, duplicate.Emails[i])
+			}
+		}
+		return fmt.Errorf("found %v duplicate emails", len(duplicates))
+	case "plan-mongo-schema":
+		if storage.Driver != config.StorageMongo {
+			return fmt.Errorf("plan-mongo-schema needs STORAGE_DRIVER=%v", config.StorageMongo)
+		}
+		changes, planErr := database.PlanMongoSchema(ctx, storage.MongoClient.Database(config.DBName), config.MongoSchema())
+		if planErr != nil {
+			return planErr
+		}
+		if len(changes) == 0 {
+			slog.Info("mongo schema is up to date")
+			return nil
+		}
+		for _, change := range changes {
+			fmt.Println(change)
+		}
+		return nil
+	case "migrate":
+		return runMigrate(ctx, storage, args[1:])
+	default:
+		fmt.Fprintln(os.Stderr, usage)
+		return fmt.Errorf("unknown command: %v", args[0])
+	}
+}
+
+func bootstrapAdminFromSettings(ctx context.Context, configs config.Config) {
+	email := configs.Settings.Auth.AdminEmail
+	if email == "" {
+		return
+	}
+
+	if err := configs.UserUsecases.BootstrapAdmin(ctx, email); err != nil {
+		slog.Error("failed to bootstrap admin from ADMIN_EMAIL", "email", email, "error", err)
+	}
+}
+
+func newMongoMigrator(storage config.Storage) migrations.Migrator {
+	return migrations.New(storage.MongoClient.Database(config.DBName), mongomigrations.All())
+}
+
+func runMigrate(ctx context.Context, storage config.Storage, args []string) error {
+	if storage.Driver != config.StorageMongo {
+		return fmt.Errorf("migrate needs STORAGE_DRIVER=%v, postgres migrations are applied on start", config.StorageMongo)
+	}
+	if len(args) == 0 {
+		return fmt.Errorf("migrate requires up, down or status")
+	}
+
+	migrator := newMongoMigrator(storage)
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			return err
+		}
+		if len(applied) == 0 {
+			slog.Info("no pending migrations")
+		}
+		return nil
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			parsed, parseErr := strconv.Atoi(args[1])
+			if parseErr != nil || parsed < 1 {
+				return fmt.Errorf("steps must be a positive number, got '%v'", args[1])
+			}
+			steps = parsed
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		if err != nil {
+			return err
+		}
+		if len(reverted) == 0 {
+			slog.Info("no applied migrations")
+		}
+		return nil
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			return err
+		}
+		for _, status := range statuses {
+			state := "pending"
+			if status.AppliedAt != 0 {
+				state = "applied " + time.Unix(status.AppliedAt, 0).UTC().Format(time.RFC3339)
+			}
+			if status.Unknown {
+				state += ", unknown to this version"
+			}
+			fmt.Printf("%04d_%-30v %v\n", status.Version, status.Name, state)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown migrate command: %v", args[0])
+	}
+}
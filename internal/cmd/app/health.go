@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/rom6n/otello/internal/app/config"
+	"github.com/rom6n/otello/internal/pkg/health"
+)
+
+// readinessChecks reach the storage the app was started with, the memory one is always ready
+func readinessChecks(storage config.Storage) []health.Check {
+	switch storage.Driver {
+	case config.StorageMongo:
+		migrator := newMongoMigrator(storage)
+		return []health.Check{
+			{Name: "mongo", Run: func(ctx context.Context) error {
+				return storage.MongoClient.Ping(ctx, nil)
+			}},
+			{Name: "migrations", Run: func(ctx context.Context) error {
+				statuses, err := migrator.Status(ctx)
+				if err != nil {
+					return err
+				}
+				pending := 0
+				for _, status := range statuses {
+					if status.AppliedAt == 0 {
+						pending++
+					}
+				}
+				if pending > 0 {
+					return fmt.Errorf("%v pending migrations", pending)
+				}
+				return nil
+			}},
+		}
+	case config.StoragePostgres:
+		return []health.Check{
+			{Name: "postgres", Run: storage.PostgresPool.Ping},
+		}
+	}
+	return nil
+}
+
+// closeStorage disconnects from the database, it goes after the requests are drained
+func closeStorage(storage config.Storage, timeout time.Duration) {
+	switch storage.Driver {
+	case config.StorageMongo:
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		if err := storage.MongoClient.Disconnect(ctx); err != nil {
+			slog.Error("failed to disconnect from mongodb", "error", err)
+		}
+	case config.StoragePostgres:
+		storage.PostgresPool.Close()
+	}
+}
@@ -2,64 +2,165 @@ package main
 
 import (
 	"context"
-	"log"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"log/slog"
 	"os"
 	"os/signal"
+	"strconv"
 	"syscall"
-	"time"
 
 	"github.com/joho/godotenv"
 	_ "github.com/rom6n/otello/docs"
 	"github.com/rom6n/otello/internal/app/config"
 	"github.com/rom6n/otello/internal/pkg/database"
+	"github.com/rom6n/otello/internal/pkg/health"
 	"github.com/rom6n/otello/internal/pkg/http"
-)
-
-const (
-	defaultPort          = "8080"
-	shutdownDelaySeconds = 35
+	"github.com/rom6n/otello/internal/pkg/logger"
+	"github.com/rom6n/otello/internal/pkg/metrics"
+	"github.com/rom6n/otello/internal/pkg/tracing"
 )
 
 func main() {
+	if err := run(); err != nil {
+		slog.Error("app stopped", "error", err)
+		os.Exit(1)
+	}
+}
+
+// run returns instead of exiting, so its deferred closing of the storage and flushing of traces always happen
+func run() error {
 	ctx := context.Background()
-	if err := godotenv.Load(); err != nil {
-		log.Fatalf("failed to load environment: %v", err)
+	// .env is optional, settings can also come from a file, the environment and flags
+	if err := godotenv.Load(); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to load environment: %v", err)
 	}
 
-	dbClient := database.NewClient()
-	configs := config.GetConfig(dbClient)
+	settings, args, loadErr := config.LoadSettings(os.Args[1:], os.LookupEnv)
+	if errors.Is(loadErr, flag.ErrHelp) {
+		fmt.Fprintln(os.Stderr, usage)
+		return nil
+	}
+	if loadErr != nil {
+		return fmt.Errorf("failed to load settings: %v", loadErr)
+	}
+	if err := settings.Validate(); err != nil {
+		return fmt.Errorf("invalid settings: %v", err)
+	}
 
-	app := http.NewFiberApp(configs)
+	appLogger, loggerErr := logger.New(os.Stderr, settings.Log.Level, settings.Log.Format)
+	if loggerErr != nil {
+		return fmt.Errorf("failed to create logger: %v", loggerErr)
+	}
+	// log.Printf of the packages that do not take a context goes through the same handler
+	slog.SetDefault(appLogger)
 
-	go func() {
-		port := os.Getenv("PORT")
-		if port == "" {
-			port = defaultPort
+	shutdownTracing, tracingErr := tracing.Setup(ctx, settings.TracingConfig())
+	if tracingErr != nil {
+		return fmt.Errorf("failed to set up tracing: %v", tracingErr)
+	}
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), settings.Server.ShutdownTimeout)
+		defer cancel()
+		if err := shutdownTracing(flushCtx); err != nil {
+			slog.Error("failed to flush traces", "error", err)
+		}
+	}()
+
+	storage := config.Storage{Driver: settings.Storage.Driver}
+
+	switch storage.Driver {
+	case config.StorageMongo:
+		client, err := database.NewClient(ctx, settings.Storage.MongoUri, settings.Storage.Timeout)
+		if err != nil {
+			return fmt.Errorf("failed to connect to mongodb: %v", err)
+		}
+		storage.MongoClient = client
+	case config.StoragePostgres:
+		pool, err := database.NewPostgresPool(settings.Storage.PostgresUrl)
+		if err != nil {
+			return fmt.Errorf("failed to connect to postgres: %v", err)
+		}
+		storage.PostgresPool = pool
+	default:
+		slog.Warn("data will be lost on restart", "storage", storage.Driver)
+	}
+	// runs before the traces are flushed, so the spans of the last commands get exported
+	defer closeStorage(storage, settings.Server.ShutdownTimeout)
+
+	if storage.Driver == config.StoragePostgres {
+		if err := database.MigratePostgres(ctx, storage.PostgresPool); err != nil {
+			return fmt.Errorf("failed to migrate postgres: %v", err)
+		}
+	}
+
+	configs, configErr := config.GetConfig(storage, settings)
+	if configErr != nil {
+		return fmt.Errorf("failed to configure app: %v", configErr)
+	}
+
+	if len(args) > 0 {
+		if err := runCommand(ctx, storage, configs, args); err != nil {
+			return fmt.Errorf("command failed: %v", err)
+		}
+		return nil
+	}
+
+	if storage.Driver == config.StorageMongo {
+		if _, err := newMongoMigrator(storage).Up(ctx); err != nil {
+			return fmt.Errorf("failed to migrate mongo: %v", err)
 		}
-		if err := app.Listen(":" + port); err != nil {
-			log.Fatalf("error starting app: %v", err)
+		if err := database.EnsureMongoSchema(ctx, storage.MongoClient.Database(config.DBName), config.MongoSchema()); err != nil {
+			return fmt.Errorf("failed to ensure mongo schema: %v", err)
 		}
+	}
+
+	if err := configs.UserUsecases.MigrateEmails(ctx); err != nil {
+		return fmt.Errorf("failed to migrate user emails: %v", err)
+	}
+
+	bootstrapAdminFromSettings(ctx, configs)
+
+	readiness := health.New(readinessChecks(storage)...)
+	app := http.NewFiberApp(configs, appLogger, readiness)
+
+	metricsServer := metrics.NewServer(":" + strconv.Itoa(settings.Server.MetricsPort))
+
+	listenErr := make(chan error, 2)
+	go func() {
+		listenErr <- app.Listen(":" + strconv.Itoa(settings.Server.Port))
+	}()
+	go func() {
+		listenErr <- metricsServer.ListenAndServe()
 	}()
 
 	stop := make(chan os.Signal, 1)
 
 	signal.Notify(stop, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT, os.Interrupt)
 
-	<-stop
-	shutdownTimeout := shutdownDelaySeconds * time.Second
-	shutdownTime := shutdownDelaySeconds + 2
+	select {
+	case err := <-listenErr:
+		return fmt.Errorf("failed to start app: %v", err)
+	case <-stop:
+	}
 
-	ctxShutdown, cancel := context.WithTimeout(ctx, shutdownTimeout)
+	// /readyz fails from now on, while the listener closes and the requests in flight are waited for
+	readiness.Drain()
+	slog.Info("shutting down, draining requests", "timeout", settings.Server.ShutdownTimeout)
+
+	ctxShutdown, cancel := context.WithTimeout(ctx, settings.Server.ShutdownTimeout)
 	defer cancel()
 
-	if shotdownErr := app.ShutdownWithContext(ctxShutdown); shotdownErr != nil {
-		log.Fatalf("error shutting down server: %v. forced shutdown", shotdownErr)
+	if shutdownErr := app.ShutdownWithContext(ctxShutdown); shutdownErr != nil {
+		return fmt.Errorf("failed to drain requests, forced shutdown: %v", shutdownErr)
 	}
-
-	for i := shutdownTime; i > 0; i -= 1 {
-		log.Printf("shutting down in %v seconds...\n", i)
-		time.Sleep(1 * time.Second)
+	// metrics are served until the API is drained, so the last scrape sees the requests in flight
+	if shutdownErr := metricsServer.Shutdown(ctxShutdown); shutdownErr != nil {
+		return fmt.Errorf("failed to shut down metrics server: %v", shutdownErr)
 	}
 
-	log.Println("server shutdown successfully")
+	slog.Info("server shutdown successfully")
+	return nil
 }
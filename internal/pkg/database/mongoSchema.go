@@ -0,0 +1,283 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/rom6n/otello/internal/pkg/logger"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+// Documents that were already invalid can still be updated, so old data doesn't block the app
+const (
+	mongoValidationLevel  = "moderate"
+	mongoValidationAction = "error"
+)
+
+// MongoCollection is the indexes and the $jsonSchema validator a collection should have
+type MongoCollection struct {
+	Name      string
+	Validator bson.D // the $jsonSchema document, see MongoSchemaObject
+	Indexes   []MongoIndex
+}
+
+// MongoIndex is compared with the existing indexes by name
+type MongoIndex struct {
+	Name   string
+	Keys   bson.D
+	Unique bool
+}
+
+// MongoSchemaChange is a single step EnsureMongoSchema makes
+type MongoSchemaChange struct {
+	Collection  string
+	Description string
+	apply       func(ctx context.Context, db *mongo.Database) error
+}
+
+func (v MongoSchemaChange) String() string {
+	return fmt.Sprintf("%v: %v", v.Collection, v.Description)
+}
+
+var (
+	MongoUuid    = MongoType("binData")
+	MongoString  = MongoType("string")
+	MongoBool    = MongoType("bool")
+	MongoInteger = MongoType("int", "long")
+)
+
+// MongoType allows any of the bson types, add "null" for pointers and slices without omitempty
+func MongoType(bsonTypes ...string) bson.D {
+	if len(bsonTypes) == 1 {
+		return bson.D{{Key: "bsonType", Value: bsonTypes[0]}}
+	}
+	return bson.D{{Key: "bsonType", Value: bsonTypes}}
+}
+
+// MongoArray is a nullable array of items
+func MongoArray(items bson.D) bson.D {
+	return bson.D{{Key: "bsonType", Value: []string{"array", "null"}}, {Key: "items", Value: items}}
+}
+
+// MongoSchemaObject is a $jsonSchema object, fields with omitempty must not be required
+func MongoSchemaObject(required []string, properties bson.D) bson.D {
+	return bson.D{
+		{Key: "bsonType", Value: "object"},
+		{Key: "required", Value: required},
+		{Key: "properties", Value: properties},
+	}
+}
+
+// PlanMongoSchema compares the collections with the database and returns what EnsureMongoSchema would change
+func PlanMongoSchema(ctx context.Context, db *mongo.Database, collections []MongoCollection) ([]MongoSchemaChange, error) {
+	specifications, err := db.ListCollectionSpecifications(ctx, bson.D{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %v", err)
+	}
+
+	existing := make(map[string]mongo.CollectionSpecification, len(specifications))
+	for _, specification := range specifications {
+		existing[specification.Name] = specification
+	}
+
+	var changes []MongoSchemaChange
+	for _, collection := range collections {
+		validator := bson.D{{Key: "$jsonSchema", Value: collection.Validator}}
+
+		specification, exists := existing[collection.Name]
+		if !exists {
+			changes = append(changes, createCollectionChange(collection.Name, validator))
+			for _, index := range collection.Indexes {
+				changes = append(changes, createIndexChange(collection.Name, index))
+			}
+			continue
+		}
+
+		validatorChanged, validatorErr := isValidatorChanged(specification.Options, validator)
+		if validatorErr != nil {
+			return nil, fmt.Errorf("failed to read validator of %v: %v", collection.Name, validatorErr)
+		}
+		if validatorChanged {
+			changes = append(changes, updateValidatorChange(collection.Name, validator))
+		}
+
+		indexChanges, indexErr := planMongoIndexes(ctx, db.Collection(collection.Name), collection.Indexes)
+		if indexErr != nil {
+			return nil, indexErr
+		}
+		changes = append(changes, indexChanges...)
+	}
+
+	return changes, nil
+}
+
+// EnsureMongoSchema creates the missing collections and indexes and updates changed validators, it does nothing on a second run
+func EnsureMongoSchema(ctx context.Context, db *mongo.Database, collections []MongoCollection) error {
+	changes, err := PlanMongoSchema(ctx, db, collections)
+	if err != nil {
+		return err
+	}
+
+	for _, change := range changes {
+		if err := change.apply(ctx, db); err != nil {
+			return fmt.Errorf("failed to apply '%v': %v", change, err)
+		}
+		logger.FromContext(ctx).Info("applied mongo schema change", "change", change.String())
+	}
+
+	return nil
+}
+
+func planMongoIndexes(ctx context.Context, collection *mongo.Collection, indexes []MongoIndex) ([]MongoSchemaChange, error) {
+	specifications, err := collection.Indexes().ListSpecifications(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list indexes of %v: %v", collection.Name(), err)
+	}
+
+	existing := make(map[string]mongo.IndexSpecification, len(specifications))
+	for _, specification := range specifications {
+		existing[specification.Name] = specification
+	}
+
+	var changes []MongoSchemaChange
+	for _, index := range indexes {
+		specification, exists := existing[index.Name]
+		switch {
+		case !exists:
+			changes = append(changes, createIndexChange(collection.Name(), index))
+		case !isSameIndex(specification, index):
+			changes = append(changes, replaceIndexChange(collection.Name(), index))
+		}
+	}
+
+	return changes, nil
+}
+
+func isSameIndex(specification mongo.IndexSpecification, index MongoIndex) bool {
+	unique := specification.Unique != nil && *specification.Unique
+	return unique == index.Unique && formatRawKeys(specification.KeysDocument) == formatKeys(index.Keys)
+}
+
+// formatKeys ignores the number types, the server may return 1 as a double
+func formatKeys(keys bson.D) string {
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		parts = append(parts, fmt.Sprintf("%v: %v", key.Key, key.Value))
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func formatRawKeys(keys bson.Raw) string {
+	elements, err := keys.Elements()
+	if err != nil {
+		return keys.String()
+	}
+
+	parts := make([]string, 0, len(elements))
+	for _, element := range elements {
+		value := element.Value()
+		if number, ok := value.AsInt64OK(); ok {
+			parts = append(parts, fmt.Sprintf("%v: %v", element.Key(), number))
+		} else {
+			parts = append(parts, fmt.Sprintf("%v: %v", element.Key(), value.String()))
+		}
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
+
+func isValidatorChanged(collectionOptions bson.Raw, validator bson.D) (bool, error) {
+	expected, err := bson.Marshal(validator)
+	if err != nil {
+		return false, err
+	}
+
+	if level, levelErr := collectionOptions.LookupErr("validationLevel"); levelErr != nil || level.StringValue() != mongoValidationLevel {
+		return true, nil
+	}
+	if action, actionErr := collectionOptions.LookupErr("validationAction"); actionErr != nil || action.StringValue() != mongoValidationAction {
+		return true, nil
+	}
+
+	current, lookupErr := collectionOptions.LookupErr("validator")
+	if lookupErr != nil {
+		return true, nil
+	}
+
+	return !bytes.Equal(current.Value, expected), nil
+}
+
+func createCollectionChange(name string, validator bson.D) MongoSchemaChange {
+	return MongoSchemaChange{
+		Collection:  name,
+		Description: "create collection with validator",
+		apply: func(ctx context.Context, db *mongo.Database) error {
+			opts := options.CreateCollection().
+				SetValidator(validator).
+				SetValidationLevel(mongoValidationLevel).
+				SetValidationAction(mongoValidationAction)
+			return db.CreateCollection(ctx, name, opts)
+		},
+	}
+}
+
+func updateValidatorChange(name string, validator bson.D) MongoSchemaChange {
+	return MongoSchemaChange{
+		Collection:  name,
+		Description: "update validator",
+		apply: func(ctx context.Context, db *mongo.Database) error {
+			command := bson.D{
+				{Key: "collMod", Value: name},
+				{Key: "validator", Value: validator},
+				{Key: "validationLevel", Value: mongoValidationLevel},
+				{Key: "validationAction", Value: mongoValidationAction},
+			}
+			return db.RunCommand(ctx, command).Err()
+		},
+	}
+}
+
+func createIndexChange(name string, index MongoIndex) MongoSchemaChange {
+	return MongoSchemaChange{
+		Collection:  name,
+		Description: fmt.Sprintf("create index %v %v%v", index.Name, formatKeys(index.Keys), uniqueSuffix(index)),
+		apply: func(ctx context.Context, db *mongo.Database) error {
+			return createMongoIndex(ctx, db.Collection(name), index)
+		},
+	}
+}
+
+// replaceIndexChange drops the index first, Mongo doesn't allow two indexes with the same name
+func replaceIndexChange(name string, index MongoIndex) MongoSchemaChange {
+	return MongoSchemaChange{
+		Collection:  name,
+		Description: fmt.Sprintf("replace index %v with %v%v", index.Name, formatKeys(index.Keys), uniqueSuffix(index)),
+		apply: func(ctx context.Context, db *mongo.Database) error {
+			collection := db.Collection(name)
+			if err := collection.Indexes().DropOne(ctx, index.Name); err != nil {
+				return err
+			}
+			return createMongoIndex(ctx, collection, index)
+		},
+	}
+}
+
+func createMongoIndex(ctx context.Context, collection *mongo.Collection, index MongoIndex) error {
+	model := mongo.IndexModel{
+		Keys:    index.Keys,
+		Options: options.Index().SetName(index.Name).SetUnique(index.Unique),
+	}
+
+	_, err := collection.Indexes().CreateOne(ctx, model)
+	return err
+}
+
+func uniqueSuffix(index MongoIndex) string {
+	if index.Unique {
+		return " unique"
+	}
+	return ""
+}
@@ -0,0 +1,44 @@
+package database
+
+import (
+	"context"
+	"sync"
+
+	"github.com/rom6n/otello/internal/pkg/tracing"
+	"go.mongodb.org/mongo-driver/v2/event"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// newTracingMonitor gives every Mongo command a span under the repository method that sent it.
+// Commands are not put into spans as they carry user data
+func newTracingMonitor() *event.CommandMonitor {
+	var spans sync.Map
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, started *event.CommandStartedEvent) {
+			attributes := []trace.SpanStartOption{
+				trace.WithSpanKind(trace.SpanKindClient),
+				trace.WithAttributes(semconv.DBSystemNameMongoDB, semconv.DBNamespace(started.DatabaseName), semconv.DBOperationName(started.CommandName)),
+			}
+			if element, err := started.Command.IndexErr(0); err == nil {
+				if collection, ok := element.Value().StringValueOK(); ok {
+					attributes = append(attributes, trace.WithAttributes(semconv.DBCollectionName(collection)))
+				}
+			}
+
+			_, span := tracing.Start(ctx, "mongodb "+started.CommandName, attributes...)
+			spans.Store(started.RequestID, span)
+		},
+		Succeeded: func(ctx context.Context, succeeded *event.CommandSucceededEvent) {
+			if span, ok := spans.LoadAndDelete(succeeded.RequestID); ok {
+				tracing.End(span.(trace.Span), nil)
+			}
+		},
+		Failed: func(ctx context.Context, failed *event.CommandFailedEvent) {
+			if span, ok := spans.LoadAndDelete(failed.RequestID); ok {
+				tracing.End(span.(trace.Span), failed.Failure)
+			}
+		},
+	}
+}
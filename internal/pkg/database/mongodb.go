@@ -1,23 +1,28 @@
 package database
 
 import (
-	"log"
-	"os"
+	"context"
+	"fmt"
+	"time"
 
 	"go.mongodb.org/mongo-driver/v2/mongo"
 	"go.mongodb.org/mongo-driver/v2/mongo/options"
 )
 
-func NewClient() *mongo.Client {
-	uri := os.Getenv("MONGO_URI")
-	if uri == "" {
-		log.Fatalln("MONGO_URI in env is not set")
+// NewClient connects and pings the server, mongo.Connect alone does not reach it
+func NewClient(ctx context.Context, uri string, timeout time.Duration) (*mongo.Client, error) {
+	client, err := mongo.Connect(options.Client().ApplyURI(uri).SetMonitor(newTracingMonitor()))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to mongodb: %v", err)
 	}
 
-	client, err := mongo.Connect(options.Client().ApplyURI(uri))
-	if err != nil {
-		log.Fatalf("failed to connect to mongodb: %v", err)
+	pingCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := client.Ping(pingCtx, nil); err != nil {
+		_ = client.Disconnect(ctx)
+		return nil, fmt.Errorf("failed to ping mongodb: %v", err)
 	}
 
-	return client
+	return client, nil
 }
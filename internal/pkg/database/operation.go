@@ -0,0 +1,33 @@
+package database
+
+import (
+	"context"
+	"time"
+
+	"github.com/rom6n/otello/internal/pkg/metrics"
+	"github.com/rom6n/otello/internal/pkg/tracing"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+)
+
+const (
+	StorageMongo    = "mongo"
+	StoragePostgres = "postgres"
+)
+
+var storageSystems = map[string]attribute.KeyValue{
+	StorageMongo:    semconv.DBSystemNameMongoDB,
+	StoragePostgres: semconv.DBSystemNamePostgreSQL,
+}
+
+// OperationContext bounds a repository method by timeout inside its span, deferred end(&err)
+// observes the latency and ends the span, failed when the method returned an error
+func OperationContext(ctx context.Context, timeout time.Duration, storage, repository, method string) (context.Context, func(*error)) {
+	dbCtx, end := tracing.OperationContext(ctx, timeout, "repository "+repository+"."+method, storageSystems[storage])
+	done := metrics.ObserveRepository(storage, repository, method)
+
+	return dbCtx, func(err *error) {
+		end(err)
+		done()
+	}
+}
@@ -0,0 +1,133 @@
+package database
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/rom6n/otello/internal/pkg/logger"
+)
+
+// postgresMigrationLock is the pg_advisory_lock key held while migrating, so instances starting together migrate once
+const postgresMigrationLock = 4_300_421
+
+//go:embed postgres/*.sql
+var postgresMigrations embed.FS
+
+func NewPostgresPool(url string) (*pgxpool.Pool, error) {
+	pool, err := pgxpool.New(context.Background(), url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to postgres: %v", err)
+	}
+
+	return pool, nil
+}
+
+type postgresMigration struct {
+	version int64
+	name    string
+}
+
+// MigratePostgres applies the postgres/<version>_<name>.sql files that are not in schema_migrations yet,
+// in version order and each in its own transaction
+func MigratePostgres(ctx context.Context, pool *pgxpool.Pool) error {
+	migrations, err := readPostgresMigrations()
+	if err != nil {
+		return err
+	}
+
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire postgres connection: %v", err)
+	}
+	defer conn.Release()
+
+	if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", postgresMigrationLock); err != nil {
+		return fmt.Errorf("failed to lock migrations: %v", err)
+	}
+	defer conn.Exec(context.Background(), "SELECT pg_advisory_unlock($1)", postgresMigrationLock)
+
+	_, err = conn.Exec(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    bigint PRIMARY KEY,
+		name       text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %v", err)
+	}
+
+	applied := map[int64]bool{}
+	rows, err := conn.Query(ctx, "SELECT version FROM schema_migrations")
+	if err != nil {
+		return fmt.Errorf("failed to get applied migrations: %v", err)
+	}
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to get applied migrations: %v", err)
+		}
+		applied[version] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get applied migrations: %v", err)
+	}
+
+	for _, migration := range migrations {
+		if applied[migration.version] {
+			continue
+		}
+
+		script, err := postgresMigrations.ReadFile(path.Join("postgres", migration.name))
+		if err != nil {
+			return fmt.Errorf("failed to read migration %v: %v", migration.name, err)
+		}
+
+		tx, err := conn.Begin(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to begin migration %v: %v", migration.name, err)
+		}
+		if _, err := tx.Exec(ctx, string(script)); err != nil {
+			tx.Rollback(ctx)
+			return fmt.Errorf("failed to apply migration %v: %v", migration.name, err)
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.version, migration.name); err != nil {
+			tx.Rollback(ctx)
+			return fmt.Errorf("failed to record migration %v: %v", migration.name, err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return fmt.Errorf("failed to commit migration %v: %v", migration.name, err)
+		}
+
+		logger.FromContext(ctx).Info("applied postgres migration", "migration", migration.name)
+	}
+
+	return nil
+}
+
+func readPostgresMigrations() ([]postgresMigration, error) {
+	entries, err := postgresMigrations.ReadDir("postgres")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %v", err)
+	}
+
+	var migrations []postgresMigration
+	for _, entry := range entries {
+		versionStr, _, _ := strings.Cut(entry.Name(), "_")
+		version, err := strconv.ParseInt(versionStr, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %v must start with a version number: %v", entry.Name(), err)
+		}
+		migrations = append(migrations, postgresMigration{version: version, name: entry.Name()})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+
+	return migrations, nil
+}
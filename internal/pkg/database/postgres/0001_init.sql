@@ -0,0 +1,135 @@
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+-- empty strings and zeros stand for the fields Mongo omits (omitempty)
+CREATE TABLE users (
+    id                   uuid PRIMARY KEY,
+    name                 text    NOT NULL DEFAULT '',
+    email                text    NOT NULL,
+    email_verified       boolean NOT NULL DEFAULT false,
+    password             text    NOT NULL DEFAULT '',
+    role                 text    NOT NULL,
+    pending_email        text    NOT NULL DEFAULT '',
+    token_version        bigint  NOT NULL DEFAULT 0,
+    deleted_at           bigint  NOT NULL DEFAULT 0,
+    disabled_at          bigint  NOT NULL DEFAULT 0,
+    phone                text    NOT NULL DEFAULT '',
+    language             text    NOT NULL DEFAULT '',
+    currency             text    NOT NULL DEFAULT '',
+    totp_enabled         boolean NOT NULL DEFAULT false,
+    totp_secret          text    NOT NULL DEFAULT '',
+    totp_pending_secret  text    NOT NULL DEFAULT '',
+    totp_last_counter    bigint  NOT NULL DEFAULT 0,
+    recovery_code_hashes text[],
+    CONSTRAINT users_email_unique UNIQUE (email)
+);
+
+-- owner_id is NULL for hotels managed only by admins
+CREATE TABLE hotels (
+    id       uuid PRIMARY KEY,
+    name     text    NOT NULL,
+    city     text    NOT NULL,
+    stars    integer NOT NULL,
+    owner_id uuid REFERENCES users (id)
+);
+
+CREATE INDEX hotels_city_idx ON hotels (city);
+CREATE INDEX hotels_owner_id_idx ON hotels (owner_id);
+
+CREATE TABLE hotel_rooms (
+    id            uuid PRIMARY KEY,
+    hotel_uuid    uuid   NOT NULL REFERENCES hotels (id),
+    rooms         bigint NOT NULL,
+    type          text   NOT NULL,
+    amount_people bigint NOT NULL,
+    value         bigint
+);
+
+CREATE INDEX hotel_rooms_hotel_uuid_idx ON hotel_rooms (hotel_uuid);
+
+-- dates are unix seconds, a rent covers [date_from, date_to) so back to back rents don't overlap
+CREATE TABLE rents (
+    id            uuid PRIMARY KEY,
+    hotel_room_id uuid   NOT NULL REFERENCES hotel_rooms (id),
+    renter_id     uuid   NOT NULL REFERENCES users (id),
+    date_from     bigint NOT NULL,
+    date_to       bigint NOT NULL,
+    CONSTRAINT rents_dates_check CHECK (date_from < date_to),
+    CONSTRAINT rents_no_overlap EXCLUDE USING gist (hotel_room_id WITH =, int8range(date_from, date_to) WITH &&)
+);
+
+CREATE INDEX rents_renter_id_idx ON rents (renter_id);
+
+-- operator_id is NULL for flights managed only by admins
+CREATE TABLE flight_tickets (
+    id          uuid PRIMARY KEY,
+    city_from   text   NOT NULL,
+    city_to     text   NOT NULL,
+    quantity    bigint NOT NULL CHECK (quantity >= 0),
+    value       bigint,
+    take_off    bigint,
+    arrival     bigint NOT NULL,
+    category    text   NOT NULL DEFAULT '',
+    operator_id uuid REFERENCES users (id)
+);
+
+CREATE INDEX flight_tickets_cities_idx ON flight_tickets (city_from, city_to);
+
+CREATE TABLE flight_ticket_purchases (
+    id               uuid PRIMARY KEY,
+    flight_ticket_id uuid   NOT NULL REFERENCES flight_tickets (id),
+    buyer_id         uuid   NOT NULL REFERENCES users (id),
+    quantity         bigint NOT NULL,
+    value            bigint,
+    created_at       bigint NOT NULL
+);
+
+CREATE INDEX flight_ticket_purchases_buyer_id_idx ON flight_ticket_purchases (buyer_id, created_at DESC);
+
+-- granted_by is NULL when the role was bootstrapped from CLI or env
+CREATE TABLE role_grants (
+    id            uuid PRIMARY KEY,
+    user_id       uuid   NOT NULL REFERENCES users (id),
+    action        text   NOT NULL,
+    role          text   NOT NULL,
+    previous_role text   NOT NULL,
+    granted_by    uuid REFERENCES users (id),
+    created_at    bigint NOT NULL
+);
+
+CREATE INDEX role_grants_user_id_idx ON role_grants (user_id, created_at DESC);
+
+CREATE TABLE user_tokens (
+    id         uuid PRIMARY KEY,
+    user_id    uuid   NOT NULL REFERENCES users (id),
+    purpose    text   NOT NULL,
+    token_hash text   NOT NULL,
+    created_at bigint NOT NULL,
+    expires_at bigint NOT NULL,
+    used_at    bigint
+);
+
+CREATE INDEX user_tokens_token_hash_idx ON user_tokens (token_hash);
+CREATE INDEX user_tokens_user_id_idx ON user_tokens (user_id, purpose);
+
+CREATE TABLE login_attempts (
+    key             text PRIMARY KEY,
+    failures        integer NOT NULL DEFAULT 0,
+    last_failure_at bigint  NOT NULL DEFAULT 0,
+    next_attempt_at bigint  NOT NULL DEFAULT 0,
+    locked_until    bigint  NOT NULL DEFAULT 0
+);
+
+CREATE TABLE api_keys (
+    id           uuid PRIMARY KEY,
+    owner_id     uuid   NOT NULL REFERENCES users (id),
+    name         text   NOT NULL,
+    prefix       text   NOT NULL,
+    key_hash     text   NOT NULL,
+    permissions  text[],
+    created_at   bigint NOT NULL,
+    last_used_at bigint NOT NULL DEFAULT 0,
+    revoked_at   bigint NOT NULL DEFAULT 0,
+    CONSTRAINT api_keys_key_hash_unique UNIQUE (key_hash)
+);
+
+CREATE INDEX api_keys_owner_id_idx ON api_keys (owner_id, created_at DESC);
@@ -0,0 +1,3 @@
+-- deleted hotels and rooms are kept for the history of rents, 0 while not deleted
+ALTER TABLE hotels ADD COLUMN deleted_at bigint NOT NULL DEFAULT 0;
+ALTER TABLE hotel_rooms ADD COLUMN deleted_at bigint NOT NULL DEFAULT 0;
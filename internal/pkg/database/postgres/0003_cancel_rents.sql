@@ -0,0 +1,5 @@
+-- cancelled rents are kept for the history, 0 while not cancelled, and no longer take the dates of the room
+ALTER TABLE rents ADD COLUMN cancelled_at bigint NOT NULL DEFAULT 0;
+ALTER TABLE rents ADD COLUMN cancel_reason text NOT NULL DEFAULT '';
+ALTER TABLE rents DROP CONSTRAINT rents_no_overlap;
+ALTER TABLE rents ADD CONSTRAINT rents_no_overlap EXCLUDE USING gist (hotel_room_id WITH =, int8range(date_from, date_to) WITH &&) WHERE (cancelled_at = 0);
@@ -0,0 +1,5 @@
+-- an attempt is counted before the password is checked, the delay is decided from the failure before it,
+-- so the block is no longer stored
+ALTER TABLE login_attempts ADD COLUMN prev_failure_at bigint NOT NULL DEFAULT 0;
+ALTER TABLE login_attempts DROP COLUMN next_attempt_at;
+ALTER TABLE login_attempts DROP COLUMN locked_until;
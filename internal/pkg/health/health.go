@@ -0,0 +1,85 @@
+package health
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rom6n/otello/internal/pkg/logger"
+)
+
+// CheckTimeout bounds every readiness check, a probe must not hang on a stuck database
+const CheckTimeout = 2 * time.Second
+
+const (
+	StatusOk       = "ok"
+	StatusFail     = "fail"
+	StatusDraining = "draining"
+)
+
+// Check reports why a dependency the app needs is not ready, nil means ready.
+// The reason only goes to the log, the probe is public and gets StatusFail
+type Check struct {
+	Name string
+	Run  func(ctx context.Context) error
+}
+
+type Report struct {
+	Ready  bool              `json:"ready"`
+	Checks map[string]string `json:"checks"`
+}
+
+type Readiness interface {
+	// Check runs every check at once, a draining app is not ready without running them
+	Check(ctx context.Context) Report
+	// Drain marks the app as shutting down, it stays not ready from then on
+	Drain()
+}
+
+type readiness struct {
+	checks   []Check
+	draining atomic.Bool
+}
+
+func New(checks ...Check) Readiness {
+	return &readiness{checks: checks}
+}
+
+func (v *readiness) Check(ctx context.Context) Report {
+	if v.draining.Load() {
+		return Report{Ready: false, Checks: map[string]string{"shutdown": StatusDraining}}
+	}
+
+	results := make([]string, len(v.checks))
+	var wg sync.WaitGroup
+	for i, check := range v.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			checkCtx, cancel := context.WithTimeout(ctx, CheckTimeout)
+			defer cancel()
+
+			results[i] = StatusOk
+			if err := check.Run(checkCtx); err != nil {
+				results[i] = StatusFail
+				logger.FromContext(ctx).Warn("readiness check failed", "check", check.Name, "error", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Ready: true, Checks: make(map[string]string, len(v.checks))}
+	for i, check := range v.checks {
+		report.Checks[check.Name] = results[i]
+		if results[i] != StatusOk {
+			report.Ready = false
+		}
+	}
+
+	return report
+}
+
+func (v *readiness) Drain() {
+	v.draining.Store(true)
+}
@@ -0,0 +1,69 @@
+package health
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/rom6n/otello/internal/pkg/logger"
+)
+
+func TestCheckReportsEveryCheck(t *testing.T) {
+	var logs bytes.Buffer
+	ctx := logger.WithContext(context.Background(), slog.New(slog.NewTextHandler(&logs, nil)))
+
+	readiness := New(
+		Check{Name: "mongo", Run: func(context.Context) error { return nil }},
+		Check{Name: "migrations", Run: func(context.Context) error { return errors.New("2 pending migrations") }},
+	)
+
+	report := readiness.Check(ctx)
+	if report.Ready {
+		t.Fatal("report is ready, want not ready with a failing check")
+	}
+	if report.Checks["mongo"] != StatusOk {
+		t.Errorf("mongo = %q, want %q", report.Checks["mongo"], StatusOk)
+	}
+	// the error may name hosts or schema details, it goes to the log and not to the public probe
+	if report.Checks["migrations"] != StatusFail {
+		t.Errorf("migrations = %q, want %q", report.Checks["migrations"], StatusFail)
+	}
+	if !strings.Contains(logs.String(), "2 pending migrations") {
+		t.Errorf("logs = %q, want the check error", logs.String())
+	}
+}
+
+func TestCheckIsBoundedByTimeout(t *testing.T) {
+	readiness := New(Check{Name: "stuck", Run: func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}})
+
+	if report := readiness.Check(context.Background()); report.Ready {
+		t.Fatal("report is ready, want the stuck check to time out")
+	}
+}
+
+func TestDrainFailsReadiness(t *testing.T) {
+	runs := 0
+	readiness := New(Check{Name: "mongo", Run: func(context.Context) error {
+		runs++
+		return nil
+	}})
+
+	if report := readiness.Check(context.Background()); !report.Ready {
+		t.Fatalf("report = %+v, want ready before drain", report)
+	}
+
+	readiness.Drain()
+	report := readiness.Check(context.Background())
+	if report.Ready || report.Checks["shutdown"] != StatusDraining {
+		t.Fatalf("report = %+v, want not ready while draining", report)
+	}
+	if runs != 1 {
+		t.Errorf("checks ran %v times, want them skipped while draining", runs)
+	}
+}
@@ -1,28 +1,35 @@
 package http
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
-	flog "github.com/gofiber/fiber/v2/log"
 	"github.com/gofiber/fiber/v2/middleware/limiter"
-	"github.com/gofiber/fiber/v2/middleware/logger"
 	"github.com/gofiber/swagger"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 	"github.com/rom6n/otello/internal/app/adapters/handler"
+	"github.com/rom6n/otello/internal/app/adapters/repository/userrepository"
+	"github.com/rom6n/otello/internal/app/application/usecases/apikeyusecases"
+	"github.com/rom6n/otello/internal/app/application/usecases/userusecases"
 	"github.com/rom6n/otello/internal/app/config"
+	"github.com/rom6n/otello/internal/app/domain/apikey"
 	"github.com/rom6n/otello/internal/app/domain/user"
+	"github.com/rom6n/otello/internal/pkg/health"
+	"github.com/rom6n/otello/internal/pkg/logger"
+	"github.com/rom6n/otello/internal/pkg/metrics"
+	"github.com/rom6n/otello/internal/pkg/tracing"
 	"github.com/rom6n/otello/internal/utils/httputils"
 	"github.com/rom6n/otello/internal/utils/jwtutils"
-)
-
-const (
-	maxBodySize = 1024 * 20 // 20 KB
-
-	maxRequestsPerWindow = 90
-	timeWindow           = 60 * time.Second
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
 )
 
 type handlers struct {
@@ -31,14 +38,24 @@ type handlers struct {
 	hotelRoomHandler    handler.HotelRoomHandler
 	rentHandler         handler.RentHandler
 	flightTicketHandler handler.FlightTicketHandler
+	apiKeyHandler       handler.ApiKeyHandler
+	jwksHandler         handler.JwksHandler
 }
 
 type adminAPIs struct {
+	userApi         fiber.Router
 	hotelApi        fiber.Router
 	hotelRoomApi    fiber.Router
+	rentApi         fiber.Router
 	flightTicketApi fiber.Router
 }
 
+type partnerAPIs struct {
+	hotelApi     fiber.Router
+	hotelRoomApi fiber.Router
+	rentApi      fiber.Router
+}
+
 type userAPIs struct {
 	userApi         fiber.Router
 	hotelApi        fiber.Router
@@ -46,26 +63,36 @@ type userAPIs struct {
 	flightTicketApi fiber.Router
 }
 
-func NewFiberApp(cfg config.Config) *fiber.App {
+func NewFiberApp(cfg config.Config, appLogger *slog.Logger, readiness health.Readiness) *fiber.App {
+	serverSettings := cfg.Settings.Server
+
 	app := fiber.New()
-	app.Use(logger.New())
+	// probes skip the logs, metrics and rate limit of the API, /metrics has its own port, see metrics.NewServer
+	app.Get("/healthz", livenessHandler())
+	app.Get("/readyz", readinessHandler(readiness))
+	app.Use(tracingMiddleware())
+	app.Use(requestLoggerMiddleware(appLogger))
+	app.Use(metricsMiddleware())
 	app.Use(limiter.New(limiter.Config{
-		Max:               maxRequestsPerWindow,
-		Expiration:        timeWindow,
+		Max:               serverSettings.RateLimit,
+		Expiration:        serverSettings.RateLimitWindow,
 		LimiterMiddleware: limiter.SlidingWindow{},
 	}))
-	app.Use(maxBodyLimitMiddleware)
-	CheckAuthorized := checkJwtMiddleware(cfg.JWTRepo, false)
+	app.Use(maxBodyLimitMiddleware(serverSettings.MaxBodySize))
+	CheckAuthorized := checkJwtMiddleware(cfg.JWTRepo, cfg.UserUsecases, cfg.Cookies)
+	CheckAuthorizedOrApiKey := checkApiKeyOrJwtMiddleware(cfg.ApiKeyUsecases, CheckAuthorized)
+	RequireAdminTwoFactor := requireAdminTwoFactor(cfg.Settings.Auth.RequireAdminTwoFactor)
 
-	createdAdminAPI, createdUserAPI := createAPIGroups(app, cfg)
+	createdAdminAPI, createdPartnerAPI, createdUserAPI := createAPIGroups(app, CheckAuthorizedOrApiKey, RequireAdminTwoFactor)
 	createdHandlers := createHandlers(cfg)
 	connectUserRoutes(app, createdHandlers, createdUserAPI, CheckAuthorized)
 	connectAdminRoutes(createdHandlers, createdAdminAPI)
+	connectPartnerRoutes(createdHandlers, createdPartnerAPI)
 
 	return app
 }
 
-func createAPIGroups(app *fiber.App, cfg config.Config) (adminAPIs, userAPIs) {
+func createAPIGroups(app *fiber.App, CheckAuthorizedOrApiKey, RequireAdminTwoFactor fiber.Handler) (adminAPIs, partnerAPIs, userAPIs) {
 	api := app.Group("/api")
 
 	userApi := api.Group("/user")
@@ -73,15 +100,29 @@ func createAPIGroups(app *fiber.App, cfg config.Config) (adminAPIs, userAPIs) {
 	hotelRoomApi := api.Group("/hotel-room")
 	flightTicketApi := api.Group("/flight-ticket")
 
-	adminApi := api.Group("/admin", checkJwtMiddleware(cfg.JWTRepo, true))
+	// Back-office group: every route checks its own permission, ownership is checked in usecases
+	adminApi := api.Group("/admin", CheckAuthorizedOrApiKey, RequireAdminTwoFactor)
+
+	adminUserApi := adminApi.Group("/user", requirePermission(user.PermManageUsers))
+	adminHotelApi := adminApi.Group("/hotel", requirePermission(user.PermManageHotels))
+	adminHotelRoomApi := adminApi.Group("/hotel-room", requirePermission(user.PermManageHotelRooms))
+	adminRentApi := adminApi.Group("/rent", requirePermission(user.PermReadRents))
+	adminFlightTicketApi := adminApi.Group("/flight-ticket", requirePermission(user.PermManageFlights))
 
-	adminHotelApi := adminApi.Group("/hotel")
-	adminHotelRoomApi := adminApi.Group("/hotel-room")
-	adminFlightTicketApi := adminApi.Group("/flight-ticket")
+	// Extranet for hotel partners, usecases reject access to hotels the partner does not own
+	partnerApi := api.Group("/partner", CheckAuthorizedOrApiKey, RequireAdminTwoFactor, requirePermission(user.PermManageHotelRooms))
+
+	newPartnerApi := partnerAPIs{
+		hotelApi:     partnerApi.Group("/hotel"),
+		hotelRoomApi: partnerApi.Group("/hotel-room"),
+		rentApi:      partnerApi.Group("/rent", requirePermission(user.PermReadRents)),
+	}
 
 	newAdminApi := adminAPIs{
+		userApi:         adminUserApi,
 		hotelApi:        adminHotelApi,
 		hotelRoomApi:    adminHotelRoomApi,
+		rentApi:         adminRentApi,
 		flightTicketApi: adminFlightTicketApi,
 	}
 
@@ -92,10 +133,20 @@ func createAPIGroups(app *fiber.App, cfg config.Config) (adminAPIs, userAPIs) {
 		flightTicketApi: flightTicketApi,
 	}
 
-	return newAdminApi, newUserApi
+	return newAdminApi, newPartnerApi, newUserApi
 }
 
 func connectAdminRoutes(handlers handlers, createdAdminApi adminAPIs) {
+	createdAdminApi.userApi.Post("/grant-role", handlers.userHandler.GrantRole())
+	createdAdminApi.userApi.Post("/revoke-role", handlers.userHandler.RevokeRole())
+	createdAdminApi.userApi.Get("/role-grants", handlers.userHandler.GetRoleGrants())
+	createdAdminApi.userApi.Post("/unlock", handlers.userHandler.UnlockLogin())
+	createdAdminApi.userApi.Get("/find", handlers.userHandler.FindUsers())
+	createdAdminApi.userApi.Get("/view", handlers.userHandler.GetUserDetails())
+	createdAdminApi.userApi.Post("/disable", handlers.userHandler.DisableUser())
+	createdAdminApi.userApi.Post("/enable", handlers.userHandler.EnableUser())
+	createdAdminApi.userApi.Post("/logout", handlers.userHandler.ForceLogout())
+
 	createdAdminApi.hotelApi.Post("/create", handlers.hotelHandler.Create())
 	createdAdminApi.hotelApi.Put("/update", handlers.hotelHandler.Update())
 	createdAdminApi.hotelApi.Delete("/delete", handlers.hotelHandler.Delete())
@@ -104,20 +155,51 @@ func connectAdminRoutes(handlers handlers, createdAdminApi adminAPIs) {
 	createdAdminApi.hotelRoomApi.Put("/update", handlers.hotelRoomHandler.Update())
 	createdAdminApi.hotelRoomApi.Delete("/delete", handlers.hotelRoomHandler.Delete())
 
+	createdAdminApi.rentApi.Get("/find", handlers.rentHandler.Find())
+
 	createdAdminApi.flightTicketApi.Post("/create", handlers.flightTicketHandler.Create())
 	createdAdminApi.flightTicketApi.Put("/update", handlers.flightTicketHandler.Update())
 	createdAdminApi.flightTicketApi.Delete("/delete", handlers.flightTicketHandler.Delete())
 }
 
+func connectPartnerRoutes(handlers handlers, createdPartnerApi partnerAPIs) {
+	createdPartnerApi.hotelApi.Get("/list", handlers.hotelHandler.FindOwned())
+
+	createdPartnerApi.hotelRoomApi.Post("/create", handlers.hotelRoomHandler.Create())
+	createdPartnerApi.hotelRoomApi.Put("/update", handlers.hotelRoomHandler.Update())
+	createdPartnerApi.hotelRoomApi.Put("/price", handlers.hotelRoomHandler.UpdatePrice())
+	createdPartnerApi.hotelRoomApi.Delete("/delete", handlers.hotelRoomHandler.Delete())
+
+	createdPartnerApi.rentApi.Get("/find", handlers.rentHandler.Find())
+}
+
 func connectUserRoutes(app *fiber.App, handlers handlers, createdUserApi userAPIs, CheckAuthorized fiber.Handler) {
 	// Swagger docs route
 	app.Get("/docs/*", swagger.HandlerDefault)
+	app.Get("/.well-known/jwks.json", handlers.jwksHandler.Jwks())
 
 	createdUserApi.userApi.Post("/register", handlers.userHandler.Register())
 	createdUserApi.userApi.Post("/login", handlers.userHandler.Login())
-	createdUserApi.userApi.Post("/get-admin", CheckAuthorized, handlers.userHandler.GetAdminRole())
-	createdUserApi.userApi.Post("/get-user", CheckAuthorized, handlers.userHandler.GetUserRole())
+	createdUserApi.userApi.Post("/token", handlers.userHandler.Token())
+	createdUserApi.userApi.Post("/token/refresh", handlers.userHandler.RefreshToken())
 	createdUserApi.userApi.Put("/rename", CheckAuthorized, handlers.userHandler.ChangeName())
+	createdUserApi.userApi.Get("/me", CheckAuthorized, handlers.userHandler.GetProfile())
+	createdUserApi.userApi.Put("/me", CheckAuthorized, handlers.userHandler.UpdateProfile())
+	createdUserApi.userApi.Post("/verify-email/send", CheckAuthorized, handlers.userHandler.SendEmailVerification())
+	createdUserApi.userApi.Get("/verify-email/confirm", handlers.userHandler.VerifyEmail())
+	createdUserApi.userApi.Post("/password-reset/request", handlers.userHandler.RequestPasswordReset())
+	createdUserApi.userApi.Post("/password-reset/confirm", handlers.userHandler.ResetPassword())
+	createdUserApi.userApi.Put("/change-password", CheckAuthorized, handlers.userHandler.ChangePassword())
+	createdUserApi.userApi.Post("/change-email", CheckAuthorized, handlers.userHandler.RequestEmailChange())
+	createdUserApi.userApi.Get("/change-email/confirm", handlers.userHandler.ConfirmEmailChange())
+	createdUserApi.userApi.Delete("/delete", CheckAuthorized, handlers.userHandler.DeleteAccount())
+	createdUserApi.userApi.Post("/2fa/setup", CheckAuthorized, handlers.userHandler.SetupTotp())
+	createdUserApi.userApi.Post("/2fa/enable", CheckAuthorized, handlers.userHandler.EnableTotp())
+	createdUserApi.userApi.Post("/2fa/disable", CheckAuthorized, handlers.userHandler.DisableTotp())
+	createdUserApi.userApi.Post("/2fa/recovery-codes", CheckAuthorized, handlers.userHandler.RegenerateRecoveryCodes())
+	createdUserApi.userApi.Post("/api-key/create", CheckAuthorized, handlers.apiKeyHandler.Create())
+	createdUserApi.userApi.Get("/api-key/list", CheckAuthorized, handlers.apiKeyHandler.FindOwned())
+	createdUserApi.userApi.Post("/api-key/revoke", CheckAuthorized, handlers.apiKeyHandler.Revoke())
 
 	createdUserApi.hotelApi.Get("/find", handlers.hotelHandler.Find())
 
@@ -126,12 +208,14 @@ func connectUserRoutes(app *fiber.App, handlers handlers, createdUserApi userAPI
 	createdUserApi.hotelRoomApi.Post("/unrent", CheckAuthorized, handlers.rentHandler.Delete())
 
 	createdUserApi.flightTicketApi.Get("/find", handlers.flightTicketHandler.Find())
-	createdUserApi.flightTicketApi.Post("/buy", handlers.flightTicketHandler.Buy())
+	createdUserApi.flightTicketApi.Post("/buy", CheckAuthorized, handlers.flightTicketHandler.Buy())
 }
 
 func createHandlers(cfg config.Config) handlers {
 	userHandler := handler.UserHandler{
-		UserUsecase: cfg.UserUsecases,
+		UserUsecase:           cfg.UserUsecases,
+		Cookies:               cfg.Cookies,
+		AllowQueryCredentials: cfg.Settings.Server.AllowQueryCredentials,
 	}
 
 	hotelHandler := handler.HotelHandler{
@@ -149,124 +233,372 @@ func createHandlers(cfg config.Config) handlers {
 	flightTicketHandler := handler.FlightTicketHandler{
 		FlightTicketUsecase: cfg.FlightTicketUsecases,
 	}
+	apiKeyHandler := handler.ApiKeyHandler{
+		ApiKeyUsecase: cfg.ApiKeyUsecases,
+	}
+
+	jwksHandler := handler.JwksHandler{
+		JwtRepo: cfg.JWTRepo,
+	}
+
 	return handlers{
 		userHandler:         userHandler,
 		hotelHandler:        hotelHandler,
 		hotelRoomHandler:    hotelRoomHandler,
 		rentHandler:         rentHandler,
 		flightTicketHandler: flightTicketHandler,
+		apiKeyHandler:       apiKeyHandler,
+		jwksHandler:         jwksHandler,
 	}
 }
 
-func checkJwtMiddleware(jwtRepo jwtutils.JwtRepository, adminOnly bool) fiber.Handler {
+// checkJwtMiddleware takes the access token from 'Authorization: Bearer' or, for browsers, from cookies
+func checkJwtMiddleware(jwtRepo jwtutils.JwtRepository, userUsecase userusecases.UserUsecases, cookies httputils.Cookies) fiber.Handler {
 	return func(c *fiber.Ctx) error {
-		errLoginBeforeBeProcessed := httputils.HandleUnsuccess(c, "login before be processed", "unauthorized", nil, fiber.StatusUnauthorized)
+		bearerToken := getBearerToken(c)
+		if strings.HasPrefix(bearerToken, apikey.KeyPrefix) {
+			return httputils.HandleUnsuccess(c, "api keys are not accepted on this route", "unauthorized", nil, fiber.StatusUnauthorized)
+		}
 
-		jwtToken := c.Cookies("jwtToken")
+		jwtToken := bearerToken
+		if jwtToken == "" {
+			jwtToken = c.Cookies("jwtToken")
+		}
 
-		var userUuid string
-		var userRole string
+		var session jwtutils.Session
 		if jwtToken == "" {
-			gottenUserUuid, gottenUserRole, buildErr := buildAccessJwtTokenByRefreshJwtToken(c, jwtRepo)
-			if buildErr != nil {
+			gottenSession, buildErr := buildAccessJwtTokenByRefreshJwtToken(c, jwtRepo, userUsecase, cookies)
+			if buildErr != nil || gottenSession.UserUuid == uuid.Nil {
 				return buildErr
 			}
-			userUuid = gottenUserUuid
-			userRole = string(gottenUserRole)
-		}
-
-		if userUuid == "" || userRole == "" {
+			session = gottenSession
+		} else {
 			claims, err := jwtRepo.VerifyJwt(jwtToken)
 			if err != nil {
 				return httputils.HandleUnsuccess(c, "jwt token not verified or accepted", fmt.Sprintf("%v-%v", err, claims["iss"]), nil, fiber.StatusForbidden)
 			}
 
-			var ok bool
-			if userUuid, ok = claims["user_id"].(string); !ok {
-				flog.Warnf("User has not string-type user_id in claims")
-				return errLoginBeforeBeProcessed
+			if !jwtutils.IsUsage(claims, httputils.JwtAccessToken) {
+				return httputils.HandleUnsuccess(c, "jwt token not verified or accepted", "refresh token can not be used as access token", nil, fiber.StatusForbidden)
+			}
+
+			gottenSession, parseErr := parseSessionFromClaims(c, claims)
+			if parseErr != nil || gottenSession.UserUuid == uuid.Nil {
+				return parseErr
 			}
 
-			if userRole, ok = claims["role"].(string); !ok {
-				flog.Warnf("User has not string-type role")
-				return errLoginBeforeBeProcessed
+			if sessionErr := userUsecase.CheckSession(c.UserContext(), gottenSession.UserUuid, gottenSession.TokenVersion); sessionErr != nil {
+				return handleSessionError(c, sessionErr, cookies)
 			}
+			session = gottenSession
 		}
 
-		if adminOnly && userRole != string(user.RoleAdmin) {
+		c.Locals("id", session.UserUuid.String())
+		c.Locals("role", string(session.Role))
+		c.Locals("mfa", session.Mfa)
+		setRequestLogger(c, logger.FromContext(c.UserContext()).With("user", session.UserUuid))
+
+		return c.Next()
+	}
+}
+
+// checkApiKeyOrJwtMiddleware lets server-to-server integrations in with an API key from 'X-API-Key' or 'Authorization: Bearer',
+// everyone else goes through checkJwt
+func checkApiKeyOrJwtMiddleware(apiKeyUsecase apikeyusecases.ApiKeyUsecases, checkJwt fiber.Handler) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		rawKey := c.Get("X-API-Key")
+		if bearerToken := getBearerToken(c); rawKey == "" && strings.HasPrefix(bearerToken, apikey.KeyPrefix) {
+			rawKey = bearerToken
+		}
+
+		if rawKey == "" {
+			return checkJwt(c)
+		}
+
+		apiKey, actor, err := apiKeyUsecase.Authenticate(c.UserContext(), rawKey)
+		if errors.Is(err, apikey.ErrInvalidApiKey) || errors.Is(err, userrepository.ErrUserNotFound) {
+			return httputils.HandleUnsuccess(c, "api key not accepted", fmt.Sprintf("%v", apikey.ErrInvalidApiKey), nil, fiber.StatusUnauthorized)
+		}
+		if err != nil {
+			logger.FromContext(c.UserContext()).Error("failed to authenticate api key", "error", err)
+			return httputils.HandleUnsuccess(c, "failed to check api key", "internal error", nil, fiber.StatusInternalServerError)
+		}
+
+		c.Locals("id", actor.Uuid.String())
+		c.Locals("role", string(actor.Role))
+		c.Locals("mfa", false)
+		c.Locals("apiKey", apiKey)
+		c.Locals("keyPermissions", actor.KeyPermissions)
+		setRequestLogger(c, logger.FromContext(c.UserContext()).With("user", actor.Uuid, "key_prefix", apiKey.Prefix))
+
+		return c.Next()
+	}
+}
+
+func getBearerToken(c *fiber.Ctx) string {
+	authorization := c.Get(fiber.HeaderAuthorization)
+	if len(authorization) > len("Bearer ") && strings.EqualFold(authorization[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(authorization[len("Bearer "):])
+	}
+	return ""
+}
+
+// requirePermission must run after checkJwtMiddleware
+func requirePermission(permission user.Permission) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userRole, _ := c.Locals("role").(string)
+		if user.UserRole(userRole).PermissionScope(permission) == user.ScopeNone {
 			return httputils.HandleUnsuccess(c, "no permission", "", nil, fiber.StatusForbidden)
 		}
 
-		c.Locals("id", userUuid)
-		c.Locals("role", userRole)
-		flog.Info("MIDDLEWARE PASSED IP: ", c.IP())
+		if apiKey, ok := c.Locals("apiKey").(*apikey.ApiKey); ok && !apiKey.HasPermission(permission) {
+			return httputils.HandleUnsuccess(c, "no permission", fmt.Sprintf("api key has no '%v' permission", permission), nil, fiber.StatusForbidden)
+		}
 
 		return c.Next()
 	}
 }
 
-func buildAccessJwtTokenByRefreshJwtToken(c *fiber.Ctx, jwtRepo jwtutils.JwtRepository) (string, user.UserRole, error) {
-	errLoginBeforeBeProcessed := httputils.HandleUnsuccess(c, "login before be processed", "unauthorized", nil, fiber.StatusUnauthorized)
+// requireAdminTwoFactor must run after checkJwtMiddleware. When required, admins need a session opened with a second factor
+func requireAdminTwoFactor(required bool) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if !required || c.Locals("role") != string(user.RoleAdmin) {
+			return c.Next()
+		}
+
+		if mfa, _ := c.Locals("mfa").(bool); !mfa {
+			return httputils.HandleUnsuccess(c, "two-factor authentication required", "enable it via /api/user/2fa/setup and login with a code", nil, fiber.StatusForbidden)
+		}
 
+		return c.Next()
+	}
+}
+
+func buildAccessJwtTokenByRefreshJwtToken(c *fiber.Ctx, jwtRepo jwtutils.JwtRepository, userUsecase userusecases.UserUsecases, cookies httputils.Cookies) (jwtutils.Session, error) {
 	jwtRefreshToken := c.Cookies("jwtRefreshToken")
 	if jwtRefreshToken == "" {
-		return "", user.RoleUser, errLoginBeforeBeProcessed
+		return jwtutils.Session{}, handleLoginBeforeBeProcessed(c)
 	}
 
 	claims, err := jwtRepo.VerifyJwt(jwtRefreshToken)
 	if err != nil {
-		return "", user.RoleUser, httputils.HandleUnsuccess(c, "jwt refresh token not verified or accepted", fmt.Sprintf("%v-%v", err, claims["iss"]), nil, fiber.StatusForbidden)
+		return jwtutils.Session{}, httputils.HandleUnsuccess(c, "jwt refresh token not verified or accepted", fmt.Sprintf("%v-%v", err, claims["iss"]), nil, fiber.StatusForbidden)
+	}
+
+	if !jwtutils.IsUsage(claims, httputils.JwtRefreshToken) {
+		return jwtutils.Session{}, httputils.HandleUnsuccess(c, "jwt refresh token not verified or accepted", "access token can not be used as refresh token", nil, fiber.StatusForbidden)
 	}
 
-	userUuid, userRole, parseErr := parseUserDataFromClaims(c, claims)
-	if parseErr != nil {
-		return "", user.RoleUser, parseErr
+	session, parseErr := parseSessionFromClaims(c, claims)
+	if parseErr != nil || session.UserUuid == uuid.Nil {
+		return jwtutils.Session{}, parseErr
 	}
 
-	jwtAccessToken, jwtErr := jwtRepo.NewJwt(userUuid, userRole, httputils.JwtAccessToken)
+	if sessionErr := userUsecase.CheckSession(c.UserContext(), session.UserUuid, session.TokenVersion); sessionErr != nil {
+		return jwtutils.Session{}, handleSessionError(c, sessionErr, cookies)
+	}
+
+	jwtAccessToken, jwtErr := jwtRepo.NewJwt(session, httputils.JwtAccessToken)
 	if jwtErr != nil {
-		flog.Warnf("Failed to create jwt access token: %v", jwtErr)
-		return "", user.RoleUser, errLoginBeforeBeProcessed
+		logger.FromContext(c.UserContext()).Error("failed to create jwt access token", "error", jwtErr)
+		return jwtutils.Session{}, handleLoginBeforeBeProcessed(c)
 	}
 
-	jwtAccessCookie := httputils.BuildCookie(jwtAccessToken, httputils.JwtAccessToken)
+	jwtAccessCookie := cookies.Build(jwtAccessToken, httputils.JwtAccessToken)
 	c.Cookie(jwtAccessCookie)
 
-	return userUuid.String(), userRole, nil
+	return session, nil
 }
 
-func parseUserDataFromClaims(c *fiber.Ctx, claims jwt.MapClaims) (uuid.UUID, user.UserRole, error) {
-	errLoginBeforeBeProcessed := httputils.HandleUnsuccess(c, "login before be processed", "unauthorized", nil, fiber.StatusUnauthorized)
+// parseSessionFromClaims writes the 401 response itself and returns an empty session on failure
+func parseSessionFromClaims(c *fiber.Ctx, claims jwt.MapClaims) (jwtutils.Session, error) {
+	session, err := jwtutils.SessionFromClaims(claims)
+	if err != nil {
+		logger.FromContext(c.UserContext()).Warn("failed to parse session from claims", "error", err)
+		return jwtutils.Session{}, handleLoginBeforeBeProcessed(c)
+	}
+
+	return session, nil
+}
+
+func handleLoginBeforeBeProcessed(c *fiber.Ctx) error {
+	return httputils.HandleUnsuccess(c, "login before be processed", "unauthorized", nil, fiber.StatusUnauthorized)
+}
+
+func handleSessionError(c *fiber.Ctx, sessionErr error, cookies httputils.Cookies) error {
+	if errors.Is(sessionErr, user.ErrSessionRevoked) || errors.Is(sessionErr, userrepository.ErrUserNotFound) {
+		c.Cookie(cookies.BuildExpired(httputils.JwtAccessToken))
+		c.Cookie(cookies.BuildExpired(httputils.JwtRefreshToken))
+		return httputils.HandleUnsuccess(c, "login before be processed", fmt.Sprintf("%v", sessionErr), nil, fiber.StatusUnauthorized)
+	}
+
+	if errors.Is(sessionErr, user.ErrAccountDisabled) {
+		c.Cookie(cookies.BuildExpired(httputils.JwtAccessToken))
+		c.Cookie(cookies.BuildExpired(httputils.JwtRefreshToken))
+		return httputils.HandleUnsuccess(c, "account is disabled", fmt.Sprintf("%v", sessionErr), nil, fiber.StatusForbidden)
+	}
+
+	logger.FromContext(c.UserContext()).Error("failed to check session", "error", sessionErr)
+	return httputils.HandleUnsuccess(c, "failed to check session", "internal error", nil, fiber.StatusInternalServerError)
+}
+
+// requestLoggerMiddleware gives every request an id, the client's 'X-Request-ID' when it looks sane, and a logger with it,
+// which usecases and repositories take from the context. When the request is done it is logged, without the query
+// as it may carry credentials
+func requestLoggerMiddleware(appLogger *slog.Logger) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		requestId := c.Get(fiber.HeaderXRequestID)
+		if !isValidRequestId(requestId) {
+			requestId = uuid.NewString()
+		}
+		c.Set(fiber.HeaderXRequestID, requestId)
+		requestLogger := appLogger.With("request_id", requestId)
+		if spanContext := trace.SpanContextFromContext(c.UserContext()); spanContext.IsValid() {
+			requestLogger = requestLogger.With("trace_id", spanContext.TraceID().String())
+		}
+		setRequestLogger(c, requestLogger)
+
+		start := time.Now()
+		err := c.Next()
+
+		status := responseStatus(c, err)
+		level := slog.LevelInfo
+		attrs := []any{"method", c.Method(), "path", c.Path(), "ip", c.IP()}
+		if err != nil {
+			attrs = append(attrs, "error", err)
+		}
+		if status >= fiber.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		attrs = append(attrs, "status", status, "duration", time.Since(start))
+
+		logger.FromContext(c.UserContext()).Log(c.UserContext(), level, "request", attrs...)
+
+		return err
+	}
+}
+
+// tracingMiddleware starts the server span of a request, continuing the trace of the 'traceparent' header when there is one.
+// The span is named after the route pattern once the route is known
+func tracingMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		ctx := otel.GetTextMapPropagator().Extract(c.UserContext(), requestHeaderCarrier{c})
+		// fiber reuses the method and path bytes between requests, while the exporter sends the spans later
+		method := strings.Clone(c.Method())
+		ctx, span := tracing.Start(ctx, method, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(
+			semconv.HTTPRequestMethodKey.String(method),
+			semconv.URLPath(strings.Clone(c.Path())),
+			semconv.ClientAddress(strings.Clone(c.IP())),
+		))
+		c.SetUserContext(ctx)
+
+		err := c.Next()
+
+		status := responseStatus(c, err)
+		span.SetName(method + " " + c.Route().Path)
+		span.SetAttributes(semconv.HTTPRoute(c.Route().Path), semconv.HTTPResponseStatusCode(status))
+		if status >= fiber.StatusInternalServerError {
+			span.SetStatus(codes.Error, "")
+		}
+		span.End()
+
+		return err
+	}
+}
+
+type requestHeaderCarrier struct {
+	c *fiber.Ctx
+}
 
-	userUuidStr, ok := claims["user_id"].(string)
-	if !ok {
-		flog.Warnf("User has not string-type user_id in claims")
-		return uuid.Nil, user.RoleUser, errLoginBeforeBeProcessed
+func (v requestHeaderCarrier) Get(key string) string {
+	return v.c.Get(key)
+}
+
+func (v requestHeaderCarrier) Set(key, value string) {
+	v.c.Request().Header.Set(key, value)
+}
+
+func (v requestHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(v.c.GetReqHeaders()))
+	for key := range v.c.GetReqHeaders() {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// metricsMiddleware labels requests with the route pattern, not the path, so ids in paths do not blow up the series
+func metricsMiddleware() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		start := time.Now()
+		err := c.Next()
+
+		status := responseStatus(c, err)
+		route := c.Route().Path
+		var fiberErr *fiber.Error
+		if errors.As(err, &fiberErr) && fiberErr.Code == fiber.StatusNotFound {
+			route = "unmatched"
+		}
+
+		// fiber reuses the method bytes between requests, while the registry keeps label values
+		labels := []string{strings.Clone(c.Method()), route, strconv.Itoa(status)}
+		metrics.HttpRequests.WithLabelValues(labels...).Inc()
+		metrics.HttpRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+
+		return err
 	}
-	userUuid, parseErr := uuid.Parse(userUuidStr)
-	if parseErr != nil {
-		flog.Warnf("Failed to parse user UUID: %v", parseErr)
-		return uuid.Nil, user.RoleUser, errLoginBeforeBeProcessed
+}
+
+// responseStatus is the status the error handler is going to send when a handler returned err
+func responseStatus(c *fiber.Ctx, err error) int {
+	if err == nil {
+		return c.Response().StatusCode()
 	}
 
-	var userRole user.UserRole
-	switch claims["role"] {
-	case "user":
-		userRole = user.RoleUser
-	case "admin":
-		userRole = user.RoleAdmin
-	default:
-		flog.Warnf("User has not existed role: %v", claims["role"])
-		return uuid.Nil, user.RoleUser, errLoginBeforeBeProcessed
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		return fiberErr.Code
 	}
+	return fiber.StatusInternalServerError
+}
 
-	return userUuid, userRole, nil
+func setRequestLogger(c *fiber.Ctx, requestLogger *slog.Logger) {
+	c.SetUserContext(logger.WithContext(c.UserContext(), requestLogger))
 }
 
-func maxBodyLimitMiddleware(c *fiber.Ctx) error {
-	maxSize := maxBodySize
-	if len(c.Body()) > maxSize {
-		return httputils.HandleUnsuccess(c, "payload too large", "", nil, fiber.StatusRequestEntityTooLarge)
+func isValidRequestId(requestId string) bool {
+	if requestId == "" || len(requestId) > 128 {
+		return false
+	}
+	for _, char := range requestId {
+		if !(char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char >= '0' && char <= '9' || char == '-' || char == '_' || char == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+func maxBodyLimitMiddleware(maxSize int) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if len(c.Body()) > maxSize {
+			return httputils.HandleUnsuccess(c, "payload too large", "", nil, fiber.StatusRequestEntityTooLarge)
+		}
+		return c.Next()
+	}
+}
+
+// livenessHandler only tells the process serves requests, the dependencies are up to readinessHandler
+func livenessHandler() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return httputils.HandleSuccess(c, "alive", nil)
+	}
+}
+
+func readinessHandler(readiness health.Readiness) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		report := readiness.Check(c.UserContext())
+		if !report.Ready {
+			return httputils.HandleUnsuccess(c, "not ready", "", report.Checks, fiber.StatusServiceUnavailable)
+		}
+		return httputils.HandleSuccess(c, "ready", report.Checks)
 	}
-	return c.Next()
 }
@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestNewRedactsSensitiveAttributes(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := New(&out, "info", FormatJson)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	logger.With("refresh_token", "rt-secret").Info("login",
+		"email", "user@example.com", "password", "hunter2", "newPassword", "hunter3", "Authorization", "Bearer abc",
+		slog.Group("request", "api_key", "otl_abc", "path", "/api/user/login"))
+
+	var entry map[string]any
+	if err := json.Unmarshal(out.Bytes(), &entry); err != nil {
+		t.Fatalf("failed to parse log entry %q: %v", out.String(), err)
+	}
+
+	for _, key := range []string{"refresh_token", "password", "newPassword", "Authorization"} {
+		if entry[key] != Redacted {
+			t.Errorf("%v = %v, want %v", key, entry[key], Redacted)
+		}
+	}
+	request, _ := entry["request"].(map[string]any)
+	if request["api_key"] != Redacted || request["path"] != "/api/user/login" {
+		t.Errorf("request group = %v, want the api key redacted and the path kept", request)
+	}
+	if entry["email"] != "user@example.com" {
+		t.Errorf("email = %v, want it kept", entry["email"])
+	}
+	for _, secret := range []string{"rt-secret", "hunter2", "hunter3", "Bearer abc", "otl_abc"} {
+		if strings.Contains(out.String(), secret) {
+			t.Errorf("log entry contains %q: %v", secret, out.String())
+		}
+	}
+}
+
+func TestNewLevelAndFormat(t *testing.T) {
+	var out bytes.Buffer
+	logger, err := New(&out, "warn", FormatText)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	logger.Info("skipped")
+	logger.Warn("kept", "key", "value")
+	if got := out.String(); strings.Contains(got, "skipped") || !strings.Contains(got, "level=WARN msg=kept key=value") {
+		t.Fatalf("log output = %q, want only the warning in the text format", got)
+	}
+
+	if _, err := New(&out, "loud", FormatText); err == nil {
+		t.Error("New with an unknown level succeeded")
+	}
+	if _, err := New(&out, "info", "xml"); err == nil {
+		t.Error("New with an unknown format succeeded")
+	}
+}
+
+func TestFromContext(t *testing.T) {
+	if FromContext(context.Background()) != slog.Default() {
+		t.Error("FromContext without a logger did not return the default one")
+	}
+
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	ctx, cancel := context.WithCancel(WithContext(context.Background(), logger))
+	defer cancel()
+	if FromContext(ctx) != logger {
+		t.Error("FromContext did not return the logger of the parent context")
+	}
+}
@@ -0,0 +1,48 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/rom6n/otello/internal/pkg/logger"
+)
+
+// LogMailer does not deliver emails, it appends them to a file or writes them to the app log.
+// The app log gets only the recipient and the subject, the body carries reset codes and confirmation links
+type LogMailer struct {
+	filePath string
+	mu       sync.Mutex
+}
+
+func NewLogMailer(filePath string) *LogMailer {
+	return &LogMailer{
+		filePath: filePath,
+	}
+}
+
+func (v *LogMailer) Send(ctx context.Context, message Message) error {
+	if v.filePath == "" {
+		logger.FromContext(ctx).Info("email", "to", message.To, "subject", message.Subject)
+		return nil
+	}
+
+	entry := fmt.Sprintf("[%s] to: %s\nsubject: %s\n\n%s\n\n", time.Now().Format(time.RFC3339), message.To, message.Subject, message.Body)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	file, err := os.OpenFile(v.filePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("failed to open mailer log file: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(entry); err != nil {
+		return fmt.Errorf("failed to write email to log file: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+)
+
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+type Mailer interface {
+	Send(ctx context.Context, message Message) error
+}
+
+// New picks the mailer by driver: "smtp" or "log" (for local development and tests), logFile is used by the log mailer
+func New(driver, logFile string, smtpConfig SMTPConfig) (Mailer, error) {
+	switch driver {
+	case "smtp":
+		return NewSMTPMailer(smtpConfig), nil
+	case "log":
+		return NewLogMailer(logFile), nil
+	default:
+		return nil, fmt.Errorf("unknown mailer driver: '%v'", driver)
+	}
+}
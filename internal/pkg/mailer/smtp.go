@@ -0,0 +1,122 @@
+package mailer
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strconv"
+	"strings"
+)
+
+type SMTPConfig struct {
+	Host     string
+	Port     int
+	From     string
+	Username string // no auth when empty
+	Password string
+}
+
+type SMTPMailer struct {
+	host string
+	addr string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPMailer(config SMTPConfig) *SMTPMailer {
+	var auth smtp.Auth
+	if config.Username != "" {
+		auth = smtp.PlainAuth("", config.Username, config.Password, config.Host)
+	}
+
+	return &SMTPMailer{
+		host: config.Host,
+		addr: net.JoinHostPort(config.Host, strconv.Itoa(config.Port)),
+		auth: auth,
+		from: config.From,
+	}
+}
+
+func (v *SMTPMailer) Send(ctx context.Context, message Message) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to send email: %v", err)
+	}
+
+	if strings.ContainsAny(message.To, "\r\n") || strings.ContainsAny(message.Subject, "\r\n") {
+		return fmt.Errorf("failed to send email: headers must not contain line breaks")
+	}
+
+	// headers must be ASCII, so a non-latin subject is encoded by RFC 2047
+	rawMessage := fmt.Sprintf(
+		"From: %s\r\nTo: %s\r\nSubject: %s\r\nMIME-Version: 1.0\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s\r\n",
+		v.from, message.To, mime.QEncoding.Encode("utf-8", message.Subject), message.Body,
+	)
+
+	if err := v.send(ctx, message.To, []byte(rawMessage)); err != nil {
+		return fmt.Errorf("failed to send email: %v", err)
+	}
+
+	return nil
+}
+
+// send is smtp.SendMail bound to ctx: a hung server is dropped when the request runs out of time
+func (v *SMTPMailer) send(ctx context.Context, to string, rawMessage []byte) error {
+	var dialer net.Dialer
+	conn, dialErr := dialer.DialContext(ctx, "tcp", v.addr)
+	if dialErr != nil {
+		return dialErr
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+	}
+	stopClosing := context.AfterFunc(ctx, func() { conn.Close() })
+	defer stopClosing()
+
+	client, clientErr := smtp.NewClient(conn, v.host)
+	if clientErr != nil {
+		return clientErr
+	}
+	defer client.Close()
+
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		if err := client.StartTLS(&tls.Config{ServerName: v.host}); err != nil {
+			return err
+		}
+	}
+
+	if v.auth != nil {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			return fmt.Errorf("server does not support AUTH")
+		}
+		if err := client.Auth(v.auth); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(v.from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+
+	writer, dataErr := client.Data()
+	if dataErr != nil {
+		return dataErr
+	}
+	if _, err := writer.Write(rawMessage); err != nil {
+		return err
+	}
+	if err := writer.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
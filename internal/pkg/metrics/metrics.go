@@ -0,0 +1,116 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "otello"
+
+// Registry holds every metric of the app, it is served by Handler
+var Registry = prometheus.NewRegistry()
+
+var factory = promauto.With(Registry)
+
+var (
+	HttpRequests = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "HTTP requests by route pattern and status.",
+	}, []string{"method", "route", "status"})
+
+	HttpRequestDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "HTTP request latency by route pattern and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	RepositoryOperationDuration = factory.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "repository",
+		Name:      "operation_duration_seconds",
+		Help:      "Latency of repository methods by storage.",
+		Buckets:   []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"storage", "repository", "method"})
+
+	RentsCreated = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "rents",
+		Name:      "created_total",
+		Help:      "Rents created.",
+	})
+
+	RentsCancelled = factory.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "rents",
+		Name:      "cancelled_total",
+		Help:      "Rents cancelled by a renter or manager request, or by a forced deletion of the room or hotel.",
+	}, []string{"reason"})
+
+	FlightSeatsSold = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "flights",
+		Name:      "seats_sold_total",
+		Help:      "Flight seats sold.",
+	})
+
+	FlightSearchHeapSize = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "flights",
+		Name:      "search_heap_size",
+		Help:      "The largest size of the candidate heap during one flight search.",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 10),
+	})
+
+	FlightSearchExpansions = factory.NewHistogram(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "flights",
+		Name:      "search_expansions",
+		Help:      "Paths taken from the candidate heap during one flight search.",
+		Buckets:   prometheus.ExponentialBuckets(1, 4, 10),
+	})
+
+	LoginFailures = factory.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "auth",
+		Name:      "login_failures_total",
+		Help:      "Failed login attempts.",
+	})
+)
+
+const (
+	CancelReasonRequest = "request"
+	CancelReasonForced  = "forced"
+)
+
+func init() {
+	Registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+}
+
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// NewServer serves /metrics apart from the API, so the port can be kept away from the public
+func NewServer(addr string) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("GET /metrics", Handler())
+
+	return &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+}
+
+// ObserveRepository starts timing a repository method, the returned func records it
+func ObserveRepository(storage, repository, method string) func() {
+	start := time.Now()
+	return func() {
+		RepositoryOperationDuration.WithLabelValues(storage, repository, method).Observe(time.Since(start).Seconds())
+	}
+}
@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestObserveRepository(t *testing.T) {
+	done := ObserveRepository("mongo", "hotel", "GetHotel")
+	done()
+
+	if count := testutil.CollectAndCount(RepositoryOperationDuration); count != 1 {
+		t.Fatalf("collected %v series, want 1", count)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	RentsCreated.Inc()
+	FlightSeatsSold.Add(3)
+
+	recorder := httptest.NewRecorder()
+	Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+
+	body := recorder.Body.String()
+	for _, want := range []string{"otello_rents_created_total 1", "otello_flights_seats_sold_total 3", "go_goroutines"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics do not contain %q", want)
+		}
+	}
+}
+
+func TestNewServerServesOnlyMetrics(t *testing.T) {
+	handler := NewServer(":0").Handler
+
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	if recorder.Code != 200 || !strings.Contains(recorder.Body.String(), "go_goroutines") {
+		t.Fatalf("GET /metrics = %v, want the metrics", recorder.Code)
+	}
+
+	recorder = httptest.NewRecorder()
+	handler.ServeHTTP(recorder, httptest.NewRequest("GET", "/api/user/me", nil))
+	if recorder.Code != 404 {
+		t.Fatalf("GET /api/user/me = %v, want 404", recorder.Code)
+	}
+}
@@ -0,0 +1,280 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/pkg/logger"
+	"go.mongodb.org/mongo-driver/v2/bson"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+	"go.mongodb.org/mongo-driver/v2/mongo/options"
+)
+
+const (
+	migrationsCollection = "schema_migrations"
+	lockCollection       = "schema_migrations_lock"
+	lockId               = "lock"
+
+	// a crashed instance blocks the others at most for lockTtl, migrations must finish faster
+	lockTtl          = 15 * time.Minute
+	lockPollInterval = time.Second
+)
+
+// ErrLocked is returned when another instance keeps migrating until the context is done
+var ErrLocked = errors.New("migrations are locked by another instance")
+
+// Migration changes the stored documents to the shape the app expects.
+// Mongo has no transactions on standalone servers, so Up and Down should be safe to run again after a failure
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(ctx context.Context, db *mongo.Database) error
+	Down    func(ctx context.Context, db *mongo.Database) error // nil if the migration can't be reverted
+}
+
+func (v Migration) String() string {
+	return fmt.Sprintf("%04d_%v", v.Version, v.Name)
+}
+
+type Status struct {
+	Version   int64
+	Name      string
+	AppliedAt int64 // 0 while pending
+	Unknown   bool  // applied by another version of the app, this one has no such migration
+}
+
+type Migrator interface {
+	Up(ctx context.Context) ([]Migration, error)
+	Down(ctx context.Context, steps int) ([]Migration, error)
+	Status(ctx context.Context) ([]Status, error)
+}
+
+type appliedMigration struct {
+	Version   int64  `bson:"_id"`
+	Name      string `bson:"name"`
+	AppliedAt int64  `bson:"applied_at"`
+}
+
+type migrator struct {
+	db         *mongo.Database
+	migrations []Migration
+}
+
+// New records applied migrations in the schema_migrations collection of db
+func New(db *mongo.Database, migrations []Migration) Migrator {
+	sorted := append([]Migration(nil), migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+
+	return &migrator{
+		db:         db,
+		migrations: sorted,
+	}
+}
+
+// Up applies all pending migrations in order and returns them
+func (v *migrator) Up(ctx context.Context) ([]Migration, error) {
+	if err := v.validate(); err != nil {
+		return nil, err
+	}
+
+	release, lockErr := v.lock(ctx)
+	if lockErr != nil {
+		return nil, lockErr
+	}
+	defer release()
+
+	applied, err := v.getApplied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	appliedVersions := make(map[int64]bool, len(applied))
+	for _, migration := range applied {
+		appliedVersions[migration.Version] = true
+	}
+
+	var done []Migration
+	for _, migration := range v.migrations {
+		if appliedVersions[migration.Version] {
+			continue
+		}
+
+		if err := migration.Up(ctx, v.db); err != nil {
+			return done, fmt.Errorf("failed to apply migration %v: %v", migration, err)
+		}
+
+		record := appliedMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now().Unix()}
+		if _, err := v.db.Collection(migrationsCollection).InsertOne(ctx, record); err != nil {
+			return done, fmt.Errorf("failed to record migration %v: %v", migration, err)
+		}
+
+		logger.FromContext(ctx).Info("applied mongo migration", "migration", migration.String())
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns them
+func (v *migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	if err := v.validate(); err != nil {
+		return nil, err
+	}
+	if steps < 1 {
+		return nil, fmt.Errorf("steps must be at least 1")
+	}
+
+	release, lockErr := v.lock(ctx)
+	if lockErr != nil {
+		return nil, lockErr
+	}
+	defer release()
+
+	applied, err := v.getApplied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	known := make(map[int64]Migration, len(v.migrations))
+	for _, migration := range v.migrations {
+		known[migration.Version] = migration
+	}
+
+	var done []Migration
+	for i := len(applied) - 1; i >= 0 && len(done) < steps; i-- {
+		migration, exists := known[applied[i].Version]
+		if !exists {
+			return done, fmt.Errorf("migration %04d_%v is not known to this version of the app", applied[i].Version, applied[i].Name)
+		}
+		if migration.Down == nil {
+			return done, fmt.Errorf("migration %v can't be reverted", migration)
+		}
+
+		if err := migration.Down(ctx, v.db); err != nil {
+			return done, fmt.Errorf("failed to revert migration %v: %v", migration, err)
+		}
+
+		if _, err := v.db.Collection(migrationsCollection).DeleteOne(ctx, bson.D{{Key: "_id", Value: migration.Version}}); err != nil {
+			return done, fmt.Errorf("failed to remove the record of migration %v: %v", migration, err)
+		}
+
+		logger.FromContext(ctx).Info("reverted mongo migration", "migration", migration.String())
+		done = append(done, migration)
+	}
+
+	return done, nil
+}
+
+// Status lists known and applied migrations by version
+func (v *migrator) Status(ctx context.Context) ([]Status, error) {
+	if err := v.validate(); err != nil {
+		return nil, err
+	}
+
+	applied, err := v.getApplied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[int64]*Status, len(v.migrations)+len(applied))
+	for _, migration := range v.migrations {
+		statuses[migration.Version] = &Status{Version: migration.Version, Name: migration.Name}
+	}
+	for _, migration := range applied {
+		status, exists := statuses[migration.Version]
+		if !exists {
+			status = &Status{Version: migration.Version, Name: migration.Name, Unknown: true}
+			statuses[migration.Version] = status
+		}
+		status.AppliedAt = migration.AppliedAt
+	}
+
+	result := make([]Status, 0, len(statuses))
+	for _, status := range statuses {
+		result = append(result, *status)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+
+	return result, nil
+}
+
+func (v *migrator) validate() error {
+	for i, migration := range v.migrations {
+		if migration.Version < 1 || migration.Name == "" || migration.Up == nil {
+			return fmt.Errorf("migration %v needs a positive version, a name and Up", migration)
+		}
+		if i > 0 && v.migrations[i-1].Version == migration.Version {
+			return fmt.Errorf("migrations %v and %v have the same version", v.migrations[i-1], migration)
+		}
+	}
+
+	return nil
+}
+
+func (v *migrator) getApplied(ctx context.Context) ([]appliedMigration, error) {
+	opts := options.Find().SetSort(bson.D{{Key: "_id", Value: 1}})
+
+	cursor, err := v.db.Collection(migrationsCollection).Find(ctx, bson.D{}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find applied migrations: %v", err)
+	}
+	defer cursor.Close(ctx)
+
+	var applied []appliedMigration
+	if err := cursor.All(ctx, &applied); err != nil {
+		return nil, fmt.Errorf("failed to decode applied migrations: %v", err)
+	}
+
+	return applied, nil
+}
+
+// lock waits until no other instance migrates, an expired lock is taken over
+func (v *migrator) lock(ctx context.Context) (func(), error) {
+	collection := v.db.Collection(lockCollection)
+	owner := uuid.NewString()
+
+	for {
+		now := time.Now().Unix()
+		filter := bson.D{
+			{Key: "_id", Value: lockId},
+			{Key: "expires_at", Value: bson.D{{Key: "$lt", Value: now}}},
+		}
+		update := bson.D{{Key: "$set", Value: bson.D{
+			{Key: "owner", Value: owner},
+			{Key: "expires_at", Value: now + int64(lockTtl.Seconds())},
+		}}}
+
+		// a held lock doesn't match the filter, so the upsert collides with its _id
+		_, err := collection.UpdateOne(ctx, filter, update, options.UpdateOne().SetUpsert(true))
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return nil, ErrLocked
+		}
+		if !mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("failed to lock migrations: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ErrLocked
+		case <-time.After(lockPollInterval):
+		}
+	}
+
+	release := func() {
+		releaseCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 10*time.Second)
+		defer cancel()
+
+		if _, err := collection.DeleteOne(releaseCtx, bson.D{{Key: "_id", Value: lockId}, {Key: "owner", Value: owner}}); err != nil {
+			logger.FromContext(ctx).Error("failed to unlock migrations", "error", err)
+		}
+	}
+
+	return release, nil
+}
@@ -0,0 +1,183 @@
+package migrations
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rom6n/otello/internal/app/adapters/repository/repositorytest"
+	"go.mongodb.org/mongo-driver/v2/mongo"
+)
+
+func TestMongoMigrator(t *testing.T) {
+	ctx := context.Background()
+
+	newDatabase := func(t *testing.T) *mongo.Database {
+		client, dbName := repositorytest.MongoDatabase(t)
+		return client.Database(dbName)
+	}
+
+	// every migration appends its version to calls, so the order of runs can be checked
+	newMigrations := func(calls *[]int64) []Migration {
+		step := func(version int64, down bool) Migration {
+			migration := Migration{
+				Version: version,
+				Name:    "step",
+				Up: func(ctx context.Context, db *mongo.Database) error {
+					*calls = append(*calls, version)
+					return nil
+				},
+			}
+			if down {
+				migration.Down = func(ctx context.Context, db *mongo.Database) error {
+					*calls = append(*calls, -version)
+					return nil
+				}
+			}
+			return migration
+		}
+
+		return []Migration{step(3, true), step(1, false), step(2, true)}
+	}
+
+	versions := func(migrations []Migration) []int64 {
+		result := make([]int64, 0, len(migrations))
+		for _, migration := range migrations {
+			result = append(result, migration.Version)
+		}
+		return result
+	}
+
+	t.Run("up applies pending migrations in order once", func(t *testing.T) {
+		db := newDatabase(t)
+		var calls []int64
+		migrator := New(db, newMigrations(&calls))
+
+		applied, err := migrator.Up(ctx)
+		if err != nil {
+			t.Fatalf("Up: %v", err)
+		}
+		if got := versions(applied); !reflect.DeepEqual(got, []int64{1, 2, 3}) {
+			t.Fatalf("applied = %v, want [1 2 3]", got)
+		}
+
+		applied, err = migrator.Up(ctx)
+		if err != nil {
+			t.Fatalf("second Up: %v", err)
+		}
+		if len(applied) != 0 || !reflect.DeepEqual(calls, []int64{1, 2, 3}) {
+			t.Fatalf("second Up applied %v, calls = %v", versions(applied), calls)
+		}
+
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			t.Fatalf("Status: %v", err)
+		}
+		for _, status := range statuses {
+			if status.AppliedAt == 0 || status.Unknown {
+				t.Fatalf("status = %+v, want applied", status)
+			}
+		}
+	})
+
+	t.Run("down reverts the newest migrations", func(t *testing.T) {
+		db := newDatabase(t)
+		var calls []int64
+		migrator := New(db, newMigrations(&calls))
+
+		if _, err := migrator.Up(ctx); err != nil {
+			t.Fatalf("Up: %v", err)
+		}
+
+		reverted, err := migrator.Down(ctx, 2)
+		if err != nil {
+			t.Fatalf("Down: %v", err)
+		}
+		if got := versions(reverted); !reflect.DeepEqual(got, []int64{3, 2}) {
+			t.Fatalf("reverted = %v, want [3 2]", got)
+		}
+
+		if _, err := migrator.Down(ctx, 1); err == nil {
+			t.Fatalf("Down of a migration without Down succeeded")
+		}
+
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			t.Fatalf("Status: %v", err)
+		}
+		applied := map[int64]bool{}
+		for _, status := range statuses {
+			applied[status.Version] = status.AppliedAt != 0
+		}
+		if !reflect.DeepEqual(applied, map[int64]bool{1: true, 2: false, 3: false}) {
+			t.Fatalf("applied after Down = %v", applied)
+		}
+
+		if _, err := migrator.Up(ctx); err != nil {
+			t.Fatalf("Up after Down: %v", err)
+		}
+		if want := []int64{1, 2, 3, -3, -2, 2, 3}; !reflect.DeepEqual(calls, want) {
+			t.Fatalf("calls = %v, want %v", calls, want)
+		}
+	})
+
+	t.Run("status shows migrations unknown to this version", func(t *testing.T) {
+		db := newDatabase(t)
+		var calls []int64
+
+		if _, err := New(db, newMigrations(&calls)).Up(ctx); err != nil {
+			t.Fatalf("Up: %v", err)
+		}
+
+		older := New(db, newMigrations(&calls)[1:])
+		statuses, err := older.Status(ctx)
+		if err != nil {
+			t.Fatalf("Status: %v", err)
+		}
+		if len(statuses) != 3 || statuses[2].Version != 3 || !statuses[2].Unknown {
+			t.Fatalf("statuses = %+v, want the third unknown", statuses)
+		}
+
+		if _, err := older.Down(ctx, 1); err == nil {
+			t.Fatalf("Down of an unknown migration succeeded")
+		}
+	})
+
+	t.Run("a held lock blocks other instances", func(t *testing.T) {
+		db := newDatabase(t)
+		var calls []int64
+		holder := New(db, newMigrations(&calls)).(*migrator)
+
+		release, err := holder.lock(ctx)
+		if err != nil {
+			t.Fatalf("lock: %v", err)
+		}
+
+		waitCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+		defer cancel()
+
+		if _, err := New(db, newMigrations(&calls)).Up(waitCtx); !errors.Is(err, ErrLocked) {
+			t.Fatalf("Up while locked error = %v, want ErrLocked", err)
+		}
+		if len(calls) != 0 {
+			t.Fatalf("migrations ran while locked: %v", calls)
+		}
+
+		release()
+		if _, err := New(db, newMigrations(&calls)).Up(ctx); err != nil {
+			t.Fatalf("Up after release: %v", err)
+		}
+	})
+
+	t.Run("duplicate versions are rejected", func(t *testing.T) {
+		db := newDatabase(t)
+		var calls []int64
+		duplicated := append(newMigrations(&calls), newMigrations(&calls)[0])
+
+		if _, err := New(db, duplicated).Up(ctx); err == nil {
+			t.Fatalf("Up with duplicate versions succeeded")
+		}
+	})
+}
@@ -0,0 +1,94 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.34.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOtlp   = "otlp"
+)
+
+const tracerName = "github.com/rom6n/otello"
+
+type Config struct {
+	Exporter string
+	// OtlpEndpoint is the collector url, when empty the exporter reads the standard OTEL_EXPORTER_OTLP_* env
+	OtlpEndpoint string
+	SampleRatio  float64
+	ServiceName  string
+}
+
+// Setup installs the global tracer provider and the W3C trace context propagator. With the none exporter spans are
+// not recorded, but the incoming trace context is still passed on. The returned shutdown flushes the spans left
+func Setup(ctx context.Context, config Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch config.Exporter {
+	case ExporterNone:
+		return func(context.Context) error { return nil }, nil
+	case ExporterStdout:
+		exporter, err = stdouttrace.New()
+	case ExporterOtlp:
+		var options []otlptracehttp.Option
+		if config.OtlpEndpoint != "" {
+			options = append(options, otlptracehttp.WithEndpointURL(config.OtlpEndpoint))
+		}
+		exporter, err = otlptracehttp.New(ctx, options...)
+	default:
+		return nil, fmt.Errorf("unknown tracing exporter '%v'", config.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to create %v tracing exporter: %v", config.Exporter, err)
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(semconv.ServiceName(config.ServiceName))),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+func Start(ctx context.Context, name string, options ...trace.SpanStartOption) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, options...)
+}
+
+// End marks the span failed when err is set and ends it
+func End(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// OperationContext bounds a usecase or repository method by timeout inside its own span. The returned end takes
+// the address of the method's error result, so it is deferred as end(&err) and the span is failed when err is set
+func OperationContext(ctx context.Context, timeout time.Duration, name string, attributes ...attribute.KeyValue) (context.Context, func(*error)) {
+	spanCtx, span := Start(ctx, name, trace.WithAttributes(attributes...))
+	timeoutCtx, cancel := context.WithTimeout(spanCtx, timeout)
+
+	return timeoutCtx, func(err *error) {
+		cancel()
+		End(span, *err)
+	}
+}
@@ -0,0 +1,55 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestOperationContext(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter)))
+
+	parentCtx, parent := Start(context.Background(), "GET /api/hotel-room/find")
+	usecaseCtx, endUsecase := OperationContext(parentCtx, time.Second, "usecase hotel_room.GetWithParams")
+	if _, ok := usecaseCtx.Deadline(); !ok {
+		t.Error("OperationContext has no deadline")
+	}
+
+	_, endRepository := OperationContext(usecaseCtx, time.Second, "repository hotel_room.GetHotelRoomsWithParams")
+	repositoryErr := errors.New("timeout")
+	endRepository(&repositoryErr)
+	var usecaseErr error
+	endUsecase(&usecaseErr)
+	parent.End()
+
+	spans := exporter.GetSpans()
+	if len(spans) != 3 {
+		t.Fatalf("exported %v spans, want 3", len(spans))
+	}
+	repository, usecase := spans[0], spans[1]
+	if usecase.Parent.SpanID() != parent.SpanContext().SpanID() || repository.Parent.SpanID() != usecase.SpanContext.SpanID() {
+		t.Error("spans are not nested as handler > usecase > repository")
+	}
+	if repository.Status.Code != codes.Error || len(repository.Events) == 0 {
+		t.Errorf("repository span status = %v with %v events, want an error recorded", repository.Status.Code, len(repository.Events))
+	}
+	if usecase.Status.Code == codes.Error {
+		t.Error("usecase span is failed, want it unset")
+	}
+	if usecaseCtx.Err() == nil {
+		t.Error("usecase context is not cancelled after end")
+	}
+}
+
+func TestSetupRejectsUnknownExporter(t *testing.T) {
+	if _, err := Setup(context.Background(), Config{Exporter: "zipkin", SampleRatio: 1, ServiceName: "otello"}); err == nil {
+		t.Fatal("Setup with an unknown exporter succeeded")
+	}
+}
@@ -0,0 +1,176 @@
+package hashutils
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+var ErrPasswordMismatch = errors.New("wrong password")
+
+// Argon2Params are the argon2id costs, Memory is in KiB
+type Argon2Params struct {
+	Memory     uint32
+	Time       uint32
+	Threads    uint8
+	SaltLength uint32
+	KeyLength  uint32
+}
+
+var DefaultArgon2Params = Argon2Params{
+	Memory:     64 * 1024,
+	Time:       2,
+	Threads:    4,
+	SaltLength: 16,
+	KeyLength:  32,
+}
+
+// legacyArgon2Params were hard-coded for the old "salt.hash" format
+var legacyArgon2Params = Argon2Params{
+	Memory:     64 * 1024,
+	Time:       2,
+	Threads:    4,
+	SaltLength: 12,
+	KeyLength:  32,
+}
+
+type PasswordHasher interface {
+	HashPassword(password string) (string, error)
+	// VerifyPassword returns needsRehash = true when the hash was made with other params or in the legacy format
+	VerifyPassword(password, encodedHash string) (needsRehash bool, err error)
+	// VerifyDummy takes as long as VerifyPassword, so a login of an unknown user can not be told by the response time
+	VerifyDummy(password string)
+}
+
+type passwordHasher struct {
+	params Argon2Params
+	// dummyHash is made with the current params and matches no password
+	dummyHash string
+}
+
+func NewPasswordHasher(params Argon2Params) PasswordHasher {
+	return &passwordHasher{
+		params:    params,
+		dummyHash: encodePasswordHash(params, make([]byte, params.SaltLength), make([]byte, params.KeyLength)),
+	}
+}
+
+// HashPassword encodes the hash in PHC format: $argon2id$v=19$m=65536,t=2,p=4$salt$hash
+func (v *passwordHasher) HashPassword(password string) (string, error) {
+	salt := make([]byte, v.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %v", err)
+	}
+
+	hash := argon2.IDKey([]byte(password), salt, v.params.Time, v.params.Memory, v.params.Threads, v.params.KeyLength)
+
+	return encodePasswordHash(v.params, salt, hash), nil
+}
+
+func encodePasswordHash(params Argon2Params, salt, hash []byte) string {
+	return fmt.Sprintf(
+		"$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version,
+		params.Memory,
+		params.Time,
+		params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(hash),
+	)
+}
+
+func (v *passwordHasher) VerifyPassword(password, encodedHash string) (bool, error) {
+	params, salt, hash, legacy, decodeErr := decodePasswordHash(encodedHash)
+	if decodeErr != nil {
+		return false, decodeErr
+	}
+
+	if len(hash) == 0 {
+		return false, fmt.Errorf("incorrect hash string")
+	}
+
+	newHash := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, uint32(len(hash)))
+	if subtle.ConstantTimeCompare(newHash, hash) != 1 {
+		return false, ErrPasswordMismatch
+	}
+
+	needsRehash := legacy ||
+		params.Memory != v.params.Memory ||
+		params.Time != v.params.Time ||
+		params.Threads != v.params.Threads ||
+		uint32(len(salt)) != v.params.SaltLength ||
+		uint32(len(hash)) != v.params.KeyLength
+
+	return needsRehash, nil
+}
+
+func (v *passwordHasher) VerifyDummy(password string) {
+	_, _ = v.VerifyPassword(password, v.dummyHash)
+}
+
+func decodePasswordHash(encodedHash string) (params Argon2Params, salt, hash []byte, legacy bool, err error) {
+	if !strings.HasPrefix(encodedHash, "$") {
+		salt, hash, err = decodeLegacyPasswordHash(encodedHash)
+		return legacyArgon2Params, salt, hash, true, err
+	}
+
+	// "", "argon2id", "v=19", "m=...,t=...,p=...", salt, hash
+	parts := strings.Split(encodedHash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return params, nil, nil, false, fmt.Errorf("incorrect hash string")
+	}
+
+	var version int
+	if _, scanErr := fmt.Sscanf(parts[2], "v=%d", &version); scanErr != nil {
+		return params, nil, nil, false, fmt.Errorf("failed to parse hash version: %v", scanErr)
+	}
+	if version != argon2.Version {
+		return params, nil, nil, false, fmt.Errorf("unsupported argon2 version %v", version)
+	}
+
+	if _, scanErr := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); scanErr != nil {
+		return params, nil, nil, false, fmt.Errorf("failed to parse hash params: %v", scanErr)
+	}
+	if params.Time < 1 || params.Threads < 1 {
+		return params, nil, nil, false, fmt.Errorf("incorrect hash params")
+	}
+
+	salt, err = base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return params, nil, nil, false, fmt.Errorf("failed to decode salt string: %v", err)
+	}
+
+	hash, err = base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return params, nil, nil, false, fmt.Errorf("failed to decode hash string: %v", err)
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(hash))
+
+	return params, salt, hash, false, nil
+}
+
+func decodeLegacyPasswordHash(encodedHash string) ([]byte, []byte, error) {
+	data := strings.Split(encodedHash, ".")
+	if len(data) != 2 {
+		return nil, nil, fmt.Errorf("incorrect hash string")
+	}
+
+	salt, decodeErr := base64.RawStdEncoding.DecodeString(data[0])
+	if decodeErr != nil {
+		return nil, nil, fmt.Errorf("failed to decode salt string: %v", decodeErr)
+	}
+
+	hash, decodeErr := base64.RawStdEncoding.DecodeString(data[1])
+	if decodeErr != nil {
+		return nil, nil, fmt.Errorf("failed to decode hash string: %v", decodeErr)
+	}
+
+	return salt, hash, nil
+}
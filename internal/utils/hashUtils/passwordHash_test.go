@@ -0,0 +1,128 @@
+package hashutils
+
+import (
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// cheap costs keep the tests fast, only the legacy format uses the real ones
+var testParams = Argon2Params{Memory: 64, Time: 1, Threads: 1, SaltLength: 16, KeyLength: 32}
+
+func TestHashPasswordRoundTrip(t *testing.T) {
+	hasher := NewPasswordHasher(testParams)
+
+	encodedHash, err := hasher.HashPassword("correct-horse")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	if !strings.HasPrefix(encodedHash, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("HashPassword = %q, want PHC format with the params", encodedHash)
+	}
+
+	again, _ := hasher.HashPassword("correct-horse")
+	if again == encodedHash {
+		t.Fatal("the same password hashed twice gives the same hash, want a random salt")
+	}
+
+	needsRehash, err := hasher.VerifyPassword("correct-horse", encodedHash)
+	if err != nil || needsRehash {
+		t.Fatalf("VerifyPassword = %v, %v, want a match without rehash", needsRehash, err)
+	}
+
+	if _, err := hasher.VerifyPassword("wrong-horse", encodedHash); !errors.Is(err, ErrPasswordMismatch) {
+		t.Fatalf("VerifyPassword of a wrong password = %v, want %v", err, ErrPasswordMismatch)
+	}
+}
+
+func TestVerifyPasswordNeedsRehashWhenParamsChange(t *testing.T) {
+	encodedHash, err := NewPasswordHasher(testParams).HashPassword("correct-horse")
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+
+	for name, change := range map[string]func(*Argon2Params){
+		"memory":      func(p *Argon2Params) { p.Memory = 128 },
+		"time":        func(p *Argon2Params) { p.Time = 2 },
+		"threads":     func(p *Argon2Params) { p.Threads = 2 },
+		"salt length": func(p *Argon2Params) { p.SaltLength = 32 },
+		"key length":  func(p *Argon2Params) { p.KeyLength = 64 },
+	} {
+		t.Run(name, func(t *testing.T) {
+			params := testParams
+			change(&params)
+
+			needsRehash, err := NewPasswordHasher(params).VerifyPassword("correct-horse", encodedHash)
+			if err != nil || !needsRehash {
+				t.Fatalf("VerifyPassword = %v, %v, want a match that needs rehash", needsRehash, err)
+			}
+		})
+	}
+}
+
+func TestVerifyPasswordLegacyFormat(t *testing.T) {
+	salt := []byte("legacy-salt!")
+	hash := argon2.IDKey([]byte("correct-horse"), salt, legacyArgon2Params.Time, legacyArgon2Params.Memory, legacyArgon2Params.Threads, legacyArgon2Params.KeyLength)
+	legacyHash := base64.RawStdEncoding.EncodeToString(salt) + "." + base64.RawStdEncoding.EncodeToString(hash)
+
+	// even with the very same params the legacy format is rehashed into PHC
+	hasher := NewPasswordHasher(legacyArgon2Params)
+
+	needsRehash, err := hasher.VerifyPassword("correct-horse", legacyHash)
+	if err != nil || !needsRehash {
+		t.Fatalf("VerifyPassword = %v, %v, want a match that needs rehash", needsRehash, err)
+	}
+
+	if _, err := hasher.VerifyPassword("wrong-horse", legacyHash); !errors.Is(err, ErrPasswordMismatch) {
+		t.Fatalf("VerifyPassword of a wrong password = %v, want %v", err, ErrPasswordMismatch)
+	}
+}
+
+func TestVerifyPasswordRejectsMalformedHashes(t *testing.T) {
+	hasher := NewPasswordHasher(testParams)
+	validHash, _ := hasher.HashPassword("correct-horse")
+	parts := strings.Split(validHash, "$")
+
+	for name, encodedHash := range map[string]string{
+		"empty":              "",
+		"legacy without dot": "c2FsdA",
+		"legacy bad base64":  "%%%.%%%",
+		"legacy empty hash":  "c2FsdA.",
+		"other algorithm":    strings.Replace(validHash, "argon2id", "argon2i", 1),
+		"other version":      strings.Replace(validHash, "v=19", "v=16", 1),
+		"missing part":       strings.Join(parts[:5], "$"),
+		"bad params":         strings.Replace(validHash, "m=64,t=1,p=1", "m=64,t=x,p=1", 1),
+		"zero time":          strings.Replace(validHash, "t=1", "t=0", 1),
+		"zero threads":       strings.Replace(validHash, "p=1", "p=0", 1),
+		"bad salt":           strings.Join([]string{parts[0], parts[1], parts[2], parts[3], "%%%", parts[5]}, "$"),
+		"bad hash":           strings.Join([]string{parts[0], parts[1], parts[2], parts[3], parts[4], "%%%"}, "$"),
+		"empty hash":         strings.Join([]string{parts[0], parts[1], parts[2], parts[3], parts[4], ""}, "$"),
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := hasher.VerifyPassword("correct-horse", encodedHash)
+			if err == nil || errors.Is(err, ErrPasswordMismatch) {
+				t.Fatalf("VerifyPassword = %v, want a malformed hash error", err)
+			}
+		})
+	}
+}
+
+func TestVerifyDummyHashesWithCurrentParams(t *testing.T) {
+	hasher := NewPasswordHasher(testParams).(*passwordHasher)
+
+	params, _, _, legacy, err := decodePasswordHash(hasher.dummyHash)
+	if err != nil || legacy || params != testParams {
+		t.Fatalf("dummy hash params = %+v, legacy %v, %v, want %+v", params, legacy, err, testParams)
+	}
+
+	// the dummy hash must go through the whole argon2 computation and then match nothing
+	for _, password := range []string{"", "correct-horse", strings.Repeat("a", 64)} {
+		if _, err := hasher.VerifyPassword(password, hasher.dummyHash); !errors.Is(err, ErrPasswordMismatch) {
+			t.Fatalf("VerifyPassword(%q, dummy) = %v, want %v", password, err, ErrPasswordMismatch)
+		}
+		hasher.VerifyDummy(password)
+	}
+}
@@ -15,12 +15,15 @@ const (
 	jwtAccessTokenMaxAgeSeconds  = 600
 	jwtRefreshTokenMaxAgeSeconds = 7 * 24 * 3600
 
-	SecureNeed   = false
 	httpOnlyNeed = true
 )
 
-func BuildCookie(value string, usage CookieUsage) *fiber.Cookie {
-	var name string
+// Cookies builds the jwt cookies, Secure must be on when the app is served over https
+type Cookies struct {
+	Secure bool
+}
+
+func (v Cookies) Build(value string, usage CookieUsage) *fiber.Cookie {
 	var maxAge int
 	var expires time.Time
 
@@ -28,20 +31,40 @@ func BuildCookie(value string, usage CookieUsage) *fiber.Cookie {
 	case JwtAccessToken:
 		maxAge = jwtAccessTokenMaxAgeSeconds
 		expires = time.Now().Add(jwtAccessTokenMaxAgeSeconds * time.Second)
-		name = "jwtToken"
 	case JwtRefreshToken:
 		maxAge = jwtRefreshTokenMaxAgeSeconds
 		expires = time.Now().Add(jwtRefreshTokenMaxAgeSeconds * time.Second)
-		name = "jwtRefreshToken"
 	}
 
 	return &fiber.Cookie{
-		Name:     name,
+		Name:     cookieName(usage),
 		Value:    value,
 		MaxAge:   maxAge,
 		Expires:  expires,
-		Secure:   SecureNeed,
+		Secure:   v.Secure,
+		SameSite: fiber.CookieSameSiteStrictMode,
+		HTTPOnly: httpOnlyNeed,
+	}
+}
+
+func (v Cookies) BuildExpired(usage CookieUsage) *fiber.Cookie {
+	return &fiber.Cookie{
+		Name:     cookieName(usage),
+		Value:    "",
+		MaxAge:   -1,
+		Expires:  time.Unix(0, 0),
+		Secure:   v.Secure,
 		SameSite: fiber.CookieSameSiteStrictMode,
 		HTTPOnly: httpOnlyNeed,
 	}
 }
+
+func cookieName(usage CookieUsage) string {
+	switch usage {
+	case JwtAccessToken:
+		return "jwtToken"
+	case JwtRefreshToken:
+		return "jwtRefreshToken"
+	}
+	return ""
+}
@@ -0,0 +1,145 @@
+package jwtutils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type Jwk struct {
+	Kty string `json:"kty"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JwkSet struct {
+	Keys []Jwk `json:"keys"`
+}
+
+type verificationKey struct {
+	method    jwt.SigningMethod
+	publicKey crypto.PublicKey
+	jwk       Jwk
+}
+
+// loadPrivateKeyFile reads a PEM RSA or Ed25519 private key (PKCS#8, or PKCS#1 for RSA)
+func loadPrivateKeyFile(path string) (crypto.Signer, error) {
+	block, readErr := readPemFile(path)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PRIVATE KEY":
+		key, parseErr := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if parseErr != nil {
+			return nil, fmt.Errorf("failed to parse private key %v: %v", path, parseErr)
+		}
+		signer, ok := key.(crypto.Signer)
+		if !ok {
+			return nil, fmt.Errorf("unsupported private key type in %v", path)
+		}
+		return signer, nil
+	}
+
+	return nil, fmt.Errorf("unsupported pem block '%v' in %v", block.Type, path)
+}
+
+// loadPublicKeyFile also accepts private key files, so the signing key file can be listed as a verification key
+func loadPublicKeyFile(path string) (crypto.PublicKey, error) {
+	block, readErr := readPemFile(path)
+	if readErr != nil {
+		return nil, readErr
+	}
+
+	switch block.Type {
+	case "PUBLIC KEY":
+		key, parseErr := x509.ParsePKIXPublicKey(block.Bytes)
+		if parseErr != nil {
+			return nil, fmt.Errorf("failed to parse public key %v: %v", path, parseErr)
+		}
+		return key, nil
+	case "RSA PUBLIC KEY":
+		return x509.ParsePKCS1PublicKey(block.Bytes)
+	}
+
+	signer, loadErr := loadPrivateKeyFile(path)
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	return signer.Public(), nil
+}
+
+func readPemFile(path string) (*pem.Block, error) {
+	data, readErr := os.ReadFile(path)
+	if readErr != nil {
+		return nil, fmt.Errorf("failed to read key file: %v", readErr)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("no pem data in %v", path)
+	}
+
+	return block, nil
+}
+
+// newVerificationKey picks the algorithm by key type: RS256 for RSA and EdDSA for Ed25519.
+// The kid is the RFC 7638 thumbprint, so the same key always gets the same kid on every instance
+func newVerificationKey(publicKey crypto.PublicKey) (*verificationKey, error) {
+	switch key := publicKey.(type) {
+	case *rsa.PublicKey:
+		n := base64.RawURLEncoding.EncodeToString(key.N.Bytes())
+		e := base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())
+		thumbprint, err := jwkThumbprint(map[string]string{"e": e, "kty": "RSA", "n": n})
+		if err != nil {
+			return nil, err
+		}
+		return &verificationKey{
+			method:    jwt.SigningMethodRS256,
+			publicKey: key,
+			jwk:       Jwk{Kty: "RSA", Use: "sig", Alg: jwt.SigningMethodRS256.Alg(), Kid: thumbprint, N: n, E: e},
+		}, nil
+	case ed25519.PublicKey:
+		x := base64.RawURLEncoding.EncodeToString(key)
+		thumbprint, err := jwkThumbprint(map[string]string{"crv": "Ed25519", "kty": "OKP", "x": x})
+		if err != nil {
+			return nil, err
+		}
+		return &verificationKey{
+			method:    jwt.SigningMethodEdDSA,
+			publicKey: key,
+			jwk:       Jwk{Kty: "OKP", Use: "sig", Alg: jwt.SigningMethodEdDSA.Alg(), Kid: thumbprint, Crv: "Ed25519", X: x},
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unsupported public key type %T, use RSA or Ed25519", publicKey)
+}
+
+func jwkThumbprint(members map[string]string) (string, error) {
+	// json.Marshal sorts map keys, which is the member order RFC 7638 requires
+	data, err := json.Marshal(members)
+	if err != nil {
+		return "", fmt.Errorf("failed to build jwk thumbprint: %v", err)
+	}
+
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:]), nil
+}
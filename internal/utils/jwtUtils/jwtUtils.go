@@ -2,8 +2,6 @@ package jwtutils
 
 import (
 	"fmt"
-	"log"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -15,57 +13,137 @@ import (
 const (
 	jwtAccessTokenExpirationMinutes  = 10
 	jwtRefreshTokenExpirationMinutes = 10080
+
+	AccessTokenExpiresInSeconds = jwtAccessTokenExpirationMinutes * 60
+
+	tokenTypeAccess  = "access"
+	tokenTypeRefresh = "refresh"
 )
 
+// Session is what a jwt says about its owner
+type Session struct {
+	UserUuid     uuid.UUID
+	Role         user.UserRole
+	TokenVersion int64
+	Mfa          bool // the session was opened with a second factor
+}
+
 type JwtRepository interface {
-	NewJwt(uuid uuid.UUID, role user.UserRole, usage httputils.CookieUsage) (string, error)
+	NewJwt(session Session, usage httputils.CookieUsage) (string, error)
 	VerifyJwt(tokenStr string) (jwt.MapClaims, error)
+	Jwks() JwkSet
 }
 
 type JwtRepo struct {
-	signKey []byte
+	signingMethod    jwt.SigningMethod
+	signingKey       interface{}
+	signingKid       string
+	verificationKeys map[string]*verificationKey
+	hmacKey          []byte    // HS256 JWT_KEY, the signing key or a legacy one accepted until hmacUntil
+	hmacUntil        time.Time // zero when HS256 is the signing method
 }
 
-func New() JwtRepository {
-	signKey := os.Getenv("JWT_KEY")
-	if signKey == "" {
-		log.Fatalln("JWT_KEY in env is not set")
+// Keys are the files and the secret the tokens are signed and verified with
+type Keys struct {
+	HmacKey              string
+	SigningKeyFile       string   // RSA or Ed25519 PEM
+	VerificationKeyFiles []string // extra public keys, e.g. the previous signing key during rotation
+	// AcceptLegacyHmacUntil keeps HS256 tokens of the HmacKey valid until then after switching to the SigningKeyFile,
+	// so the switch does not log everyone out. Zero rejects them right away
+	AcceptLegacyHmacUntil time.Time
+}
+
+// New signs with the SigningKeyFile when it is set, otherwise with HS256 and the HmacKey
+func New(keys Keys) (JwtRepository, error) {
+	repo := &JwtRepo{
+		verificationKeys: map[string]*verificationKey{},
+	}
+
+	if keys.SigningKeyFile != "" {
+		signingKey, loadErr := loadPrivateKeyFile(keys.SigningKeyFile)
+		if loadErr != nil {
+			return nil, fmt.Errorf("failed to load jwt signing key: %v", loadErr)
+		}
+
+		key, keyErr := repo.addVerificationKey(signingKey.Public())
+		if keyErr != nil {
+			return nil, fmt.Errorf("failed to use jwt signing key: %v", keyErr)
+		}
+
+		repo.signingMethod = key.method
+		repo.signingKey = signingKey
+		repo.signingKid = key.jwk.Kid
+
+		if keys.HmacKey != "" && !keys.AcceptLegacyHmacUntil.IsZero() {
+			repo.hmacKey = []byte(keys.HmacKey)
+			repo.hmacUntil = keys.AcceptLegacyHmacUntil
+		}
+	} else {
+		if keys.HmacKey == "" {
+			return nil, fmt.Errorf("neither jwt signing key file nor jwt key is set")
+		}
+		repo.hmacKey = []byte(keys.HmacKey)
+		repo.signingMethod = jwt.SigningMethodHS256
+		repo.signingKey = repo.hmacKey
+	}
+
+	for _, keyFile := range keys.VerificationKeyFiles {
+		publicKey, loadErr := loadPublicKeyFile(keyFile)
+		if loadErr != nil {
+			return nil, fmt.Errorf("failed to load jwt verification key: %v", loadErr)
+		}
+
+		if _, keyErr := repo.addVerificationKey(publicKey); keyErr != nil {
+			return nil, fmt.Errorf("failed to use verification key %v: %v", keyFile, keyErr)
+		}
 	}
 
-	return &JwtRepo{
-		signKey: []byte(signKey),
+	return repo, nil
+}
+
+func (v *JwtRepo) addVerificationKey(publicKey interface{}) (*verificationKey, error) {
+	key, keyErr := newVerificationKey(publicKey)
+	if keyErr != nil {
+		return nil, keyErr
 	}
+
+	v.verificationKeys[key.jwk.Kid] = key
+	return key, nil
 }
 
-func (v *JwtRepo) NewJwt(uuid uuid.UUID, role user.UserRole, usage httputils.CookieUsage) (string, error) {
+func (v *JwtRepo) NewJwt(session Session, usage httputils.CookieUsage) (string, error) {
 	var exp int64
+	var tokenType string
 	switch usage {
 	case httputils.JwtAccessToken:
 		exp = time.Now().Add(jwtAccessTokenExpirationMinutes * time.Minute).Unix()
+		tokenType = tokenTypeAccess
 	case httputils.JwtRefreshToken:
 		exp = time.Now().Add(jwtRefreshTokenExpirationMinutes * time.Minute).Unix()
+		tokenType = tokenTypeRefresh
 	}
 
 	claims := jwt.MapClaims{
-		"user_id": uuid.String(),
+		"user_id": session.UserUuid.String(),
 		"exp":     exp,
 		"iat":     time.Now().Unix(),
 		"iss":     "otello",
 		"aud":     "otello-users",
-		"role":    role,
+		"role":    session.Role,
+		"ver":     session.TokenVersion,
+		"mfa":     session.Mfa,
+		"typ":     tokenType,
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(v.signKey)
+	token := jwt.NewWithClaims(v.signingMethod, claims)
+	if v.signingKid != "" {
+		token.Header["kid"] = v.signingKid
+	}
+	return token.SignedString(v.signingKey)
 }
 
 func (v *JwtRepo) VerifyJwt(tokenStr string) (jwt.MapClaims, error) {
-	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
-		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
-		}
-		return v.signKey, nil
-	})
+	token, err := jwt.Parse(tokenStr, v.getVerificationKey)
 
 	if err != nil {
 		return nil, fmt.Errorf("not authorized")
@@ -88,3 +166,89 @@ func (v *JwtRepo) VerifyJwt(tokenStr string) (jwt.MapClaims, error) {
 
 	return claims, err
 }
+
+// getVerificationKey checks that alg matches the key type of kid, so a public key can never be used as an HMAC secret
+func (v *JwtRepo) getVerificationKey(t *jwt.Token) (interface{}, error) {
+	if _, isHmac := t.Method.(*jwt.SigningMethodHMAC); isHmac {
+		if v.hmacKey == nil || t.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		if !v.hmacUntil.IsZero() && time.Now().After(v.hmacUntil) {
+			return nil, fmt.Errorf("legacy HS256 tokens are not accepted since %v", v.hmacUntil.Format(time.RFC3339))
+		}
+		return v.hmacKey, nil
+	}
+
+	kid, _ := t.Header["kid"].(string)
+	key, ok := v.verificationKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown jwt key id: %v", kid)
+	}
+
+	if t.Method.Alg() != key.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method %v for key %v", t.Header["alg"], kid)
+	}
+
+	return key.publicKey, nil
+}
+
+// Jwks returns the public keys for other services to verify our tokens. HS256 secrets are never published
+func (v *JwtRepo) Jwks() JwkSet {
+	jwkSet := JwkSet{Keys: make([]Jwk, 0, len(v.verificationKeys))}
+
+	if key, ok := v.verificationKeys[v.signingKid]; ok {
+		jwkSet.Keys = append(jwkSet.Keys, key.jwk)
+	}
+	for kid, key := range v.verificationKeys {
+		if kid != v.signingKid {
+			jwkSet.Keys = append(jwkSet.Keys, key.jwk)
+		}
+	}
+
+	return jwkSet
+}
+
+func SessionFromClaims(claims jwt.MapClaims) (Session, error) {
+	userUuidStr, ok := claims["user_id"].(string)
+	if !ok {
+		return Session{}, fmt.Errorf("user has not string-type user_id in claims")
+	}
+	userUuid, parseErr := uuid.Parse(userUuidStr)
+	if parseErr != nil {
+		return Session{}, fmt.Errorf("failed to parse user UUID: %v", parseErr)
+	}
+
+	userRoleStr, _ := claims["role"].(string)
+	userRole, ok := user.ParseUserRole(userRoleStr)
+	if !ok {
+		return Session{}, fmt.Errorf("user has not existed role: %v", claims["role"])
+	}
+
+	// tokens issued before token versions existed have no "ver" and match version 0
+	tokenVersion, _ := claims["ver"].(float64)
+	mfa, _ := claims["mfa"].(bool)
+
+	return Session{
+		UserUuid:     userUuid,
+		Role:         userRole,
+		TokenVersion: int64(tokenVersion),
+		Mfa:          mfa,
+	}, nil
+}
+
+// IsUsage stops refresh tokens from being used as access tokens and vice versa. Refresh tokens without "typ" predate it
+// and still pass, access tokens must have it: a refresh token of that time would pass as one otherwise
+func IsUsage(claims jwt.MapClaims, usage httputils.CookieUsage) bool {
+	tokenType, ok := claims["typ"].(string)
+	if !ok {
+		return usage == httputils.JwtRefreshToken
+	}
+
+	switch usage {
+	case httputils.JwtAccessToken:
+		return tokenType == tokenTypeAccess
+	case httputils.JwtRefreshToken:
+		return tokenType == tokenTypeRefresh
+	}
+	return false
+}
@@ -0,0 +1,264 @@
+package jwtutils
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/rom6n/otello/internal/app/domain/user"
+	"github.com/rom6n/otello/internal/utils/httputils"
+)
+
+const testHmacKey = "test-hmac-secret"
+
+var testSession = Session{UserUuid: uuid.New(), Role: user.RoleAdmin, TokenVersion: 3, Mfa: true}
+
+// writeKeyFile saves the key as PKCS#8 PEM and returns the path
+func writeKeyFile(t *testing.T, key crypto.Signer) string {
+	t.Helper()
+
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatalf("MarshalPKCS8PrivateKey: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	return path
+}
+
+func newEd25519KeyFile(t *testing.T) string {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatalf("ed25519.GenerateKey: %v", err)
+	}
+	return writeKeyFile(t, key)
+}
+
+func newRsaKeyFile(t *testing.T) string {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("rsa.GenerateKey: %v", err)
+	}
+	return writeKeyFile(t, key)
+}
+
+func newTestRepo(t *testing.T, keys Keys) *JwtRepo {
+	t.Helper()
+	repo, err := New(keys)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return repo.(*JwtRepo)
+}
+
+func signClaims(t *testing.T, method jwt.SigningMethod, key interface{}, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("SignedString: %v", err)
+	}
+	return signed
+}
+
+func validClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"user_id": testSession.UserUuid.String(),
+		"exp":     time.Now().Add(time.Minute).Unix(),
+		"iat":     time.Now().Unix(),
+		"iss":     "otello",
+		"aud":     "otello-users",
+		"role":    string(testSession.Role),
+		"ver":     testSession.TokenVersion,
+		"typ":     tokenTypeAccess,
+	}
+}
+
+func TestNewJwtPicksAlgAndKid(t *testing.T) {
+	for _, tt := range []struct {
+		name    string
+		keys    func(t *testing.T) Keys
+		wantAlg string
+		wantKid bool
+	}{
+		{"hmac only", func(t *testing.T) Keys { return Keys{HmacKey: testHmacKey} }, "HS256", false},
+		{"ed25519 key", func(t *testing.T) Keys { return Keys{SigningKeyFile: newEd25519KeyFile(t)} }, "EdDSA", true},
+		{"rsa key", func(t *testing.T) Keys { return Keys{SigningKeyFile: newRsaKeyFile(t)} }, "RS256", true},
+		{"key file wins over hmac", func(t *testing.T) Keys {
+			return Keys{HmacKey: testHmacKey, SigningKeyFile: newEd25519KeyFile(t)}
+		}, "EdDSA", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestRepo(t, tt.keys(t))
+
+			tokenStr, err := repo.NewJwt(testSession, httputils.JwtAccessToken)
+			if err != nil {
+				t.Fatalf("NewJwt: %v", err)
+			}
+
+			token, _, err := jwt.NewParser().ParseUnverified(tokenStr, jwt.MapClaims{})
+			if err != nil {
+				t.Fatalf("ParseUnverified: %v", err)
+			}
+			if alg := token.Header["alg"]; alg != tt.wantAlg {
+				t.Fatalf("alg = %v, want %v", alg, tt.wantAlg)
+			}
+			if kid, _ := token.Header["kid"].(string); (kid != "") != tt.wantKid || (tt.wantKid && kid != repo.signingKid) {
+				t.Fatalf("kid = %q, want the signing key kid %q", kid, repo.signingKid)
+			}
+
+			claims, err := repo.VerifyJwt(tokenStr)
+			if err != nil {
+				t.Fatalf("VerifyJwt: %v", err)
+			}
+			session, err := SessionFromClaims(claims)
+			if err != nil || session != testSession {
+				t.Fatalf("SessionFromClaims = %+v, %v, want %+v", session, err, testSession)
+			}
+		})
+	}
+}
+
+func TestNewRequiresAKey(t *testing.T) {
+	if _, err := New(Keys{}); err == nil {
+		t.Fatal("New without keys succeeded, want an error")
+	}
+}
+
+func TestJwksListsPublicKeysOnly(t *testing.T) {
+	oldKeyFile := newRsaKeyFile(t)
+	repo := newTestRepo(t, Keys{
+		HmacKey:               testHmacKey,
+		SigningKeyFile:        newEd25519KeyFile(t),
+		VerificationKeyFiles:  []string{oldKeyFile},
+		AcceptLegacyHmacUntil: time.Now().Add(time.Hour),
+	})
+
+	jwks := repo.Jwks()
+	if len(jwks.Keys) != 2 {
+		t.Fatalf("jwks has %d keys, want the signing and the verification key", len(jwks.Keys))
+	}
+
+	signing, old := jwks.Keys[0], jwks.Keys[1]
+	if signing.Kid != repo.signingKid || signing.Alg != "EdDSA" || signing.Kty != "OKP" || signing.X == "" {
+		t.Fatalf("first jwk = %+v, want the Ed25519 signing key", signing)
+	}
+	if old.Alg != "RS256" || old.Kty != "RSA" || old.N == "" || old.E == "" {
+		t.Fatalf("second jwk = %+v, want the RSA verification key", old)
+	}
+
+	if hmacOnly := newTestRepo(t, Keys{HmacKey: testHmacKey}).Jwks(); len(hmacOnly.Keys) != 0 {
+		t.Fatalf("jwks of an HS256 repo = %+v, want no keys", hmacOnly)
+	}
+}
+
+func TestVerifyJwtRejects(t *testing.T) {
+	ed25519File := newEd25519KeyFile(t)
+	repo := newTestRepo(t, Keys{HmacKey: testHmacKey, SigningKeyFile: ed25519File})
+	signingKey := repo.signingKey
+
+	expired := validClaims()
+	expired["exp"] = time.Now().Add(-time.Minute).Unix()
+	otherIssuer := validClaims()
+	otherIssuer["iss"] = "someone-else"
+
+	_, otherKey, _ := ed25519.GenerateKey(rand.Reader)
+	publicKeyPem := x509PublicKeyPem(t, repo.verificationKeys[repo.signingKid].publicKey)
+
+	for _, tt := range []struct {
+		name  string
+		token string
+	}{
+		{"expired", signClaims(t, jwt.SigningMethodEdDSA, signingKey, repo.signingKid, expired)},
+		{"other issuer", signClaims(t, jwt.SigningMethodEdDSA, signingKey, repo.signingKid, otherIssuer)},
+		{"unknown kid", signClaims(t, jwt.SigningMethodEdDSA, signingKey, "unknown", validClaims())},
+		{"other key with our kid", signClaims(t, jwt.SigningMethodEdDSA, otherKey, repo.signingKid, validClaims())},
+		{"legacy hs256 without the flag", signClaims(t, jwt.SigningMethodHS256, []byte(testHmacKey), "", validClaims())},
+		{"public key as hmac secret", signClaims(t, jwt.SigningMethodHS256, publicKeyPem, repo.signingKid, validClaims())},
+		{"hs512", signClaims(t, jwt.SigningMethodHS512, []byte(testHmacKey), "", validClaims())},
+		{"alg none", signClaims(t, jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, "", validClaims())},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := repo.VerifyJwt(tt.token); err == nil {
+				t.Fatal("VerifyJwt accepted the token")
+			}
+		})
+	}
+}
+
+func TestLegacyHmacIsTimeBoxed(t *testing.T) {
+	keyFile := newEd25519KeyFile(t)
+	legacyToken := signClaims(t, jwt.SigningMethodHS256, []byte(testHmacKey), "", validClaims())
+
+	for _, tt := range []struct {
+		name  string
+		until time.Time
+		ok    bool
+	}{
+		{"not set", time.Time{}, false},
+		{"before the date", time.Now().Add(time.Hour), true},
+		{"after the date", time.Now().Add(-time.Hour), false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := newTestRepo(t, Keys{HmacKey: testHmacKey, SigningKeyFile: keyFile, AcceptLegacyHmacUntil: tt.until})
+			if _, err := repo.VerifyJwt(legacyToken); (err == nil) != tt.ok {
+				t.Fatalf("VerifyJwt error = %v, want accepted %v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestIsUsage(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		typ      any
+		usage    httputils.CookieUsage
+		accepted bool
+	}{
+		{"access as access", tokenTypeAccess, httputils.JwtAccessToken, true},
+		{"refresh as refresh", tokenTypeRefresh, httputils.JwtRefreshToken, true},
+		{"refresh as access", tokenTypeRefresh, httputils.JwtAccessToken, false},
+		{"access as refresh", tokenTypeAccess, httputils.JwtRefreshToken, false},
+		{"no typ as access", nil, httputils.JwtAccessToken, false},
+		{"no typ as refresh", nil, httputils.JwtRefreshToken, true},
+		{"unknown typ", "id", httputils.JwtAccessToken, false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := validClaims()
+			delete(claims, "typ")
+			if tt.typ != nil {
+				claims["typ"] = tt.typ
+			}
+
+			if accepted := IsUsage(claims, tt.usage); accepted != tt.accepted {
+				t.Fatalf("IsUsage = %v, want %v", accepted, tt.accepted)
+			}
+		})
+	}
+}
+
+func x509PublicKeyPem(t *testing.T, publicKey crypto.PublicKey) []byte {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		t.Fatalf("MarshalPKIXPublicKey: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+}
@@ -0,0 +1,66 @@
+package passwordutils
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const breachedHashPrefixLength = 5
+
+// breachedList looks passwords up in a directory laid out like the Pwned Passwords range API:
+// a file per first 5 hex chars of the SHA-1 (e.g. "5BAA6" or "5BAA6.txt") with "SUFFIX:COUNT" lines.
+// Only the one small file for the prefix is read, the list itself can be tens of gigabytes
+type breachedList struct {
+	dir string
+}
+
+func (v *breachedList) contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password))
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:breachedHashPrefixLength], hash[breachedHashPrefixLength:]
+
+	file, openErr := v.openRangeFile(prefix)
+	if errors.Is(openErr, fs.ErrNotExist) {
+		return false, nil
+	}
+	if openErr != nil {
+		return false, fmt.Errorf("failed to open breached passwords file: %v", openErr)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lineSuffix, countStr, _ := strings.Cut(strings.TrimSpace(scanner.Text()), ":")
+		if !strings.EqualFold(lineSuffix, suffix) {
+			continue
+		}
+
+		// padding entries of the range API have count 0 and are not real passwords
+		if count, parseErr := strconv.Atoi(countStr); parseErr == nil && count == 0 {
+			return false, nil
+		}
+		return true, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("failed to read breached passwords file: %v", err)
+	}
+
+	return false, nil
+}
+
+func (v *breachedList) openRangeFile(prefix string) (*os.File, error) {
+	file, openErr := os.Open(filepath.Join(v.dir, prefix))
+	if errors.Is(openErr, fs.ErrNotExist) {
+		return os.Open(filepath.Join(v.dir, prefix+".txt"))
+	}
+
+	return file, openErr
+}
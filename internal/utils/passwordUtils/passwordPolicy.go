@@ -0,0 +1,76 @@
+package passwordutils
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/rom6n/otello/internal/app/domain/user"
+)
+
+// minPersonalInfoLength keeps short names like "Al" from rejecting half of all passwords
+const minPersonalInfoLength = 3
+
+type Policy struct {
+	MinLength      int
+	MaxLength      int // argon2 hashes any length, the limit only stops huge request bodies from being hashed
+	MinCharClasses int // of lowercase, uppercase, digits and symbols
+}
+
+var DefaultPolicy = Policy{
+	MinLength:      8,
+	MaxLength:      128,
+	MinCharClasses: 2,
+}
+
+// Validate checks the password against the policy. email and name are the owner's, the password must not contain them
+func (p Policy) Validate(password, email, name string) error {
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		return fmt.Errorf("%w: it must be at least %v characters long", user.ErrWeakPassword, p.MinLength)
+	}
+	if length > p.MaxLength {
+		return fmt.Errorf("%w: it must be at most %v characters long", user.ErrWeakPassword, p.MaxLength)
+	}
+
+	if classes := countCharClasses(password); classes < p.MinCharClasses {
+		return fmt.Errorf("%w: it must contain at least %v of: lowercase letters, uppercase letters, digits, symbols", user.ErrWeakPassword, p.MinCharClasses)
+	}
+
+	lowerPassword := strings.ToLower(password)
+	emailLocalPart, _, _ := strings.Cut(email, "@")
+	for _, info := range []string{emailLocalPart, name} {
+		info = strings.ToLower(strings.TrimSpace(info))
+		if utf8.RuneCountInString(info) >= minPersonalInfoLength && strings.Contains(lowerPassword, info) {
+			return fmt.Errorf("%w: it must not contain your email or name", user.ErrWeakPassword)
+		}
+	}
+
+	return nil
+}
+
+func countCharClasses(password string) int {
+	var hasLower, hasUpper, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		default:
+			hasSymbol = true
+		}
+	}
+
+	classes := 0
+	for _, has := range []bool{hasLower, hasUpper, hasDigit, hasSymbol} {
+		if has {
+			classes++
+		}
+	}
+
+	return classes
+}
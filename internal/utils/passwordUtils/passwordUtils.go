@@ -0,0 +1,51 @@
+package passwordutils
+
+import (
+	"log/slog"
+
+	"github.com/rom6n/otello/internal/app/domain/user"
+)
+
+type PasswordChecker interface {
+	// CheckPassword is called for every new password, email and name are the owner's
+	CheckPassword(password, email, name string) error
+}
+
+type passwordChecker struct {
+	policy   Policy
+	breached *breachedList
+}
+
+func NewPasswordChecker(policy Policy, breachedDir string) PasswordChecker {
+	checker := &passwordChecker{
+		policy: policy,
+	}
+
+	if breachedDir != "" {
+		checker.breached = &breachedList{dir: breachedDir}
+	}
+
+	return checker
+}
+
+func (v *passwordChecker) CheckPassword(password, email, name string) error {
+	if err := v.policy.Validate(password, email, name); err != nil {
+		return err
+	}
+
+	if v.breached == nil {
+		return nil
+	}
+
+	breached, lookupErr := v.breached.contains(password)
+	if lookupErr != nil {
+		// a broken list must not block registrations and password changes
+		slog.Warn("failed to check password against the breached list", "error", lookupErr)
+		return nil
+	}
+	if breached {
+		return user.ErrBreachedPassword
+	}
+
+	return nil
+}
@@ -0,0 +1,118 @@
+package passwordutils
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rom6n/otello/internal/app/domain/user"
+)
+
+// breachedDir has range files for a few passwords, see the comments of TestCheckPasswordBreachedList
+const breachedDir = "testdata/breached"
+
+func TestPolicyValidate(t *testing.T) {
+	for _, tt := range []struct {
+		name     string
+		password string
+		ok       bool
+	}{
+		{"two classes", "horsebattery7", true},
+		{"too short", "hors3", false},
+		{"min length", "horseba7", true},
+		{"too long", strings.Repeat("a1", 65), false},
+		{"max length", strings.Repeat("a1", 64), true},
+		{"one class", "horsebatterystaple", false},
+		{"letters of both cases", "HorseBattery", true},
+		{"symbols", "horse battery", true},
+		{"runes are counted, not bytes", "лошадь7", false},
+		{"cyrillic", "лошадьБатарея", true},
+		{"contains email", "Ivan.Petrov2024", false},
+		{"contains name in other case", "Super-IVAN-9", false},
+		{"short name is allowed", "Alpha-beta-9", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			err := DefaultPolicy.Validate(tt.password, "ivan.petrov@example.com", "Ivan")
+			if tt.ok && err != nil {
+				t.Fatalf("Validate(%q) = %v, want ok", tt.password, err)
+			}
+			if !tt.ok && !errors.Is(err, user.ErrWeakPassword) {
+				t.Fatalf("Validate(%q) = %v, want %v", tt.password, err, user.ErrWeakPassword)
+			}
+		})
+	}
+}
+
+func TestPolicyValidateCharClasses(t *testing.T) {
+	for _, tt := range []struct {
+		password string
+		classes  int
+	}{
+		{"horsebattery", 1},
+		{"horseBattery", 2},
+		{"horseBattery7", 3},
+		{"horseBattery7!", 4},
+	} {
+		if classes := countCharClasses(tt.password); classes != tt.classes {
+			t.Errorf("countCharClasses(%q) = %v, want %v", tt.password, classes, tt.classes)
+		}
+
+		policy := Policy{MinLength: 1, MaxLength: 128, MinCharClasses: tt.classes + 1}
+		if err := policy.Validate(tt.password, "", ""); !errors.Is(err, user.ErrWeakPassword) {
+			t.Errorf("Validate(%q) with %v classes required = %v, want %v", tt.password, tt.classes+1, err, user.ErrWeakPassword)
+		}
+	}
+}
+
+// The fixture keeps the range files of:
+//   - "P@ssw0rd", SHA-1 21BD1..., in a file without extension
+//   - "Summer2024", SHA-1 6EA16..., in a .txt file with the suffix in lowercase
+//   - "Passw0rd!", SHA-1 F4A69..., as a padding entry with count 0
+func TestCheckPasswordBreachedList(t *testing.T) {
+	checker := NewPasswordChecker(DefaultPolicy, breachedDir)
+
+	for _, tt := range []struct {
+		password string
+		breached bool
+	}{
+		{"P@ssw0rd", true},
+		{"Summer2024", true},
+		{"Passw0rd!", false},
+		{"P@ssw0rd-but-longer", false},
+		{"correct-horse-battery-7", false},
+	} {
+		err := checker.CheckPassword(tt.password, "ivan@example.com", "Ivan")
+		if tt.breached && !errors.Is(err, user.ErrBreachedPassword) {
+			t.Errorf("CheckPassword(%q) = %v, want %v", tt.password, err, user.ErrBreachedPassword)
+		}
+		if !tt.breached && err != nil {
+			t.Errorf("CheckPassword(%q) = %v, want ok", tt.password, err)
+		}
+	}
+}
+
+func TestCheckPasswordPolicyGoesFirst(t *testing.T) {
+	checker := NewPasswordChecker(Policy{MinLength: 12, MaxLength: 128, MinCharClasses: 2}, breachedDir)
+
+	// a breached password that is also too short reports the policy, it needs no lookup
+	if err := checker.CheckPassword("P@ssw0rd", "", ""); !errors.Is(err, user.ErrWeakPassword) {
+		t.Fatalf("CheckPassword = %v, want %v", err, user.ErrWeakPassword)
+	}
+}
+
+func TestCheckPasswordWithoutOrWithBrokenList(t *testing.T) {
+	if err := NewPasswordChecker(DefaultPolicy, "").CheckPassword("P@ssw0rd", "", ""); err != nil {
+		t.Fatalf("CheckPassword without a list = %v, want ok", err)
+	}
+
+	// the prefix of "P@ssw0rd" is a directory here, the read fails and the password is let through
+	brokenDir := t.TempDir()
+	if err := os.Mkdir(filepath.Join(brokenDir, "21BD1"), 0o755); err != nil {
+		t.Fatalf("Mkdir: %v", err)
+	}
+	if err := NewPasswordChecker(DefaultPolicy, brokenDir).CheckPassword("P@ssw0rd", "", ""); err != nil {
+		t.Fatalf("CheckPassword with a broken list = %v, want ok", err)
+	}
+}
@@ -0,0 +1,68 @@
+package postgresutils
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+const (
+	uniqueViolationCode    = "23505"
+	exclusionViolationCode = "23P01"
+)
+
+// NullableUuid stores uuid.Nil as NULL, for optional references like the owner of a hotel
+func NullableUuid(id uuid.UUID) *uuid.UUID {
+	if id == uuid.Nil {
+		return nil
+	}
+	return &id
+}
+
+// UuidOrNil reads a column written with NullableUuid
+func UuidOrNil(id *uuid.UUID) uuid.UUID {
+	if id == nil {
+		return uuid.Nil
+	}
+	return *id
+}
+
+func IsUniqueViolation(err error) bool {
+	return hasCode(err, uniqueViolationCode)
+}
+
+func IsExclusionViolation(err error) bool {
+	return hasCode(err, exclusionViolationCode)
+}
+
+func hasCode(err error, code string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == code
+}
+
+// ContainsPattern is a LIKE pattern matching value anywhere, with its wildcards escaped
+func ContainsPattern(value string) string {
+	escaped := strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(value)
+	return "%" + escaped + "%"
+}
+
+// Filter builds a WHERE clause from AND-ed conditions, the %v in a condition becomes the placeholder of its arg
+type Filter struct {
+	conditions []string
+	Args       []any
+}
+
+func (f *Filter) Add(condition string, arg any) {
+	f.Args = append(f.Args, arg)
+	f.conditions = append(f.conditions, fmt.Sprintf(condition, fmt.Sprintf("$%v", len(f.Args))))
+}
+
+func (f *Filter) Where() string {
+	if len(f.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(f.conditions, " AND ")
+}
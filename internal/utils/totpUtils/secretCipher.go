@@ -0,0 +1,72 @@
+package totputils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+)
+
+const (
+	secretKeyLength = 32 // AES-256
+	// base32 secrets never contain ':', so a stored value with the prefix is always an encrypted one
+	encryptedSecretPrefix = "v1:"
+)
+
+// SecretCipher keeps TOTP secrets encrypted at rest with AES-256-GCM, a database dump alone can't generate codes
+type SecretCipher struct {
+	aead cipher.AEAD
+}
+
+// NewSecretCipher takes a base64 encoded 32 byte key, like the output of 'openssl rand -base64 32'
+func NewSecretCipher(key string) (*SecretCipher, error) {
+	rawKey, decodeErr := base64.StdEncoding.DecodeString(key)
+	if decodeErr != nil || len(rawKey) != secretKeyLength {
+		return nil, fmt.Errorf("totp encryption key must be %d bytes encoded in base64", secretKeyLength)
+	}
+
+	block, blockErr := aes.NewCipher(rawKey)
+	if blockErr != nil {
+		return nil, fmt.Errorf("failed to create totp cipher: %v", blockErr)
+	}
+
+	aead, aeadErr := cipher.NewGCM(block)
+	if aeadErr != nil {
+		return nil, fmt.Errorf("failed to create totp cipher: %v", aeadErr)
+	}
+
+	return &SecretCipher{aead: aead}, nil
+}
+
+func (c *SecretCipher) Encrypt(secret string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate totp secret nonce: %v", err)
+	}
+
+	sealed := c.aead.Seal(nonce, nonce, []byte(secret), nil)
+	return encryptedSecretPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt also accepts secrets stored before they were encrypted, legacy tells the caller to store them again encrypted
+func (c *SecretCipher) Decrypt(stored string) (secret string, legacy bool, err error) {
+	encoded, encrypted := strings.CutPrefix(stored, encryptedSecretPrefix)
+	if !encrypted {
+		return stored, true, nil
+	}
+
+	sealed, decodeErr := base64.RawStdEncoding.DecodeString(encoded)
+	if decodeErr != nil || len(sealed) < c.aead.NonceSize() {
+		return "", false, fmt.Errorf("failed to decrypt totp secret: malformed value")
+	}
+
+	nonceSize := c.aead.NonceSize()
+	plain, openErr := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], nil)
+	if openErr != nil {
+		return "", false, fmt.Errorf("failed to decrypt totp secret: %v", openErr)
+	}
+
+	return string(plain), false, nil
+}
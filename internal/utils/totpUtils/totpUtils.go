@@ -0,0 +1,131 @@
+package totputils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	secretLength = 20 // 160 bits, as recommended by RFC 4226
+	codeDigits   = 6
+	period       = 30 * time.Second
+	// codes from one step before and after are accepted to tolerate clock drift
+	allowedSkewSteps = 1
+
+	recoveryCodeLength = 10
+)
+
+var secretEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func GenerateSecret() (string, error) {
+	secret := make([]byte, secretLength)
+	if _, err := rand.Read(secret); err != nil {
+		return "", fmt.Errorf("failed to generate totp secret: %v", err)
+	}
+
+	return secretEncoding.EncodeToString(secret), nil
+}
+
+// ProvisioningURI builds the otpauth:// link that authenticator apps read from a QR code
+func ProvisioningURI(secret, issuer, accountName string) string {
+	query := url.Values{}
+	query.Set("secret", secret)
+	query.Set("issuer", issuer)
+	query.Set("algorithm", "SHA1")
+	query.Set("digits", fmt.Sprintf("%d", codeDigits))
+	query.Set("period", fmt.Sprintf("%d", int(period.Seconds())))
+
+	label := url.PathEscape(issuer + ":" + accountName)
+	return fmt.Sprintf("otpauth://totp/%s?%s", label, query.Encode())
+}
+
+func GenerateCode(secret string, t time.Time) (string, error) {
+	key, decodeErr := decodeSecret(secret)
+	if decodeErr != nil {
+		return "", decodeErr
+	}
+
+	return hotp(key, counterAt(t)), nil
+}
+
+// ValidateCode returns the time step of the matched code so callers can reject its reuse
+func ValidateCode(secret, code string, t time.Time) (int64, bool) {
+	key, decodeErr := decodeSecret(secret)
+	if decodeErr != nil || len(code) != codeDigits {
+		return 0, false
+	}
+
+	counter := counterAt(t)
+	for step := -allowedSkewSteps; step <= allowedSkewSteps; step++ {
+		expected := hotp(key, counter+int64(step))
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return counter + int64(step), true
+		}
+	}
+
+	return 0, false
+}
+
+func GenerateRecoveryCodes(count int) ([]string, error) {
+	codes := make([]string, count)
+	for i := range codes {
+		raw := make([]byte, recoveryCodeLength)
+		if _, err := rand.Read(raw); err != nil {
+			return nil, fmt.Errorf("failed to generate recovery code: %v", err)
+		}
+
+		code := strings.ToLower(secretEncoding.EncodeToString(raw))[:recoveryCodeLength]
+		codes[i] = code[:recoveryCodeLength/2] + "-" + code[recoveryCodeLength/2:]
+	}
+
+	return codes, nil
+}
+
+// NormalizeRecoveryCode lets users type recovery codes in any case, with or without the dash
+func NormalizeRecoveryCode(code string) string {
+	code = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(code), "-", ""))
+	if len(code) != recoveryCodeLength {
+		return code
+	}
+	return code[:recoveryCodeLength/2] + "-" + code[recoveryCodeLength/2:]
+}
+
+func decodeSecret(secret string) ([]byte, error) {
+	key, err := secretEncoding.DecodeString(strings.ToUpper(strings.TrimRight(secret, "=")))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode totp secret: %v", err)
+	}
+	return key, nil
+}
+
+func counterAt(t time.Time) int64 {
+	return t.Unix() / int64(period.Seconds())
+}
+
+// hotp is the HMAC-SHA1 one-time password from RFC 4226, TOTP uses the time step as the counter
+func hotp(key []byte, counter int64) string {
+	var message [8]byte
+	binary.BigEndian.PutUint64(message[:], uint64(counter))
+
+	mac := hmac.New(sha1.New, key)
+	mac.Write(message[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	binaryCode := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < codeDigits; i++ {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", codeDigits, binaryCode%modulo)
+}
@@ -0,0 +1,176 @@
+package totputils
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfc6238Secret is the SHA1 seed of RFC 6238 Appendix B, "12345678901234567890" in base32
+const rfc6238Secret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+// The appendix lists 8 digit codes, ours are their last 6 digits
+func TestGenerateCodeRFC6238Vectors(t *testing.T) {
+	for _, tt := range []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	} {
+		code, err := GenerateCode(rfc6238Secret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatalf("GenerateCode(%v): %v", tt.unix, err)
+		}
+		if code != tt.want {
+			t.Errorf("GenerateCode(%v) = %v, want %v", tt.unix, code, tt.want)
+		}
+	}
+}
+
+func TestValidateCodeSkewWindow(t *testing.T) {
+	now := time.Unix(1111111111, 0)
+	counter := counterAt(now)
+
+	for _, tt := range []struct {
+		name string
+		at   time.Time
+		ok   bool
+	}{
+		{"two steps before", now.Add(-2 * period), false},
+		{"one step before", now.Add(-period), true},
+		{"current step", now, true},
+		{"one step after", now.Add(period), true},
+		{"two steps after", now.Add(2 * period), false},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			code, err := GenerateCode(rfc6238Secret, tt.at)
+			if err != nil {
+				t.Fatalf("GenerateCode: %v", err)
+			}
+
+			matched, ok := ValidateCode(rfc6238Secret, code, now)
+			if ok != tt.ok {
+				t.Fatalf("ValidateCode ok = %v, want %v", ok, tt.ok)
+			}
+			if ok && matched != counterAt(tt.at) {
+				t.Fatalf("ValidateCode step = %v, want %v of the code, not %v of now", matched, counterAt(tt.at), counter)
+			}
+		})
+	}
+}
+
+func TestValidateCodeRejectsMalformedInput(t *testing.T) {
+	now := time.Unix(59, 0)
+
+	for name, tc := range map[string]struct{ secret, code string }{
+		"short code":     {rfc6238Secret, "28708"},
+		"8 digit code":   {rfc6238Secret, "94287082"},
+		"broken secret":  {"not base32!", "287082"},
+		"another secret": {"JBSWY3DPEHPK3PXP", "287082"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, ok := ValidateCode(tc.secret, tc.code, now); ok {
+				t.Fatal("ValidateCode accepted the code")
+			}
+		})
+	}
+}
+
+func TestNormalizeRecoveryCode(t *testing.T) {
+	codes, err := GenerateRecoveryCodes(3)
+	if err != nil {
+		t.Fatalf("GenerateRecoveryCodes: %v", err)
+	}
+
+	for _, code := range codes {
+		typed := strings.ToUpper(strings.ReplaceAll(code, "-", "")) + " "
+		if normalized := NormalizeRecoveryCode(typed); normalized != code {
+			t.Errorf("NormalizeRecoveryCode(%q) = %q, want %q", typed, normalized, code)
+		}
+	}
+}
+
+func newTestCipher(t *testing.T, keyByte byte) *SecretCipher {
+	t.Helper()
+	key := base64.StdEncoding.EncodeToString([]byte(strings.Repeat(string(rune(keyByte)), secretKeyLength)))
+	secretCipher, err := NewSecretCipher(key)
+	if err != nil {
+		t.Fatalf("NewSecretCipher: %v", err)
+	}
+	return secretCipher
+}
+
+func TestSecretCipherRoundTrip(t *testing.T) {
+	secretCipher := newTestCipher(t, 'k')
+
+	encrypted, err := secretCipher.Encrypt(rfc6238Secret)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+	if strings.Contains(encrypted, rfc6238Secret) {
+		t.Fatalf("encrypted secret %q contains the plain one", encrypted)
+	}
+
+	again, _ := secretCipher.Encrypt(rfc6238Secret)
+	if again == encrypted {
+		t.Fatal("the same secret encrypted twice gives the same value, want a random nonce")
+	}
+
+	secret, legacy, err := secretCipher.Decrypt(encrypted)
+	if err != nil {
+		t.Fatalf("Decrypt: %v", err)
+	}
+	if secret != rfc6238Secret || legacy {
+		t.Fatalf("Decrypt = %q, legacy %v, want %q and not legacy", secret, legacy, rfc6238Secret)
+	}
+}
+
+func TestSecretCipherLegacyAndTampered(t *testing.T) {
+	secretCipher := newTestCipher(t, 'k')
+
+	secret, legacy, err := secretCipher.Decrypt(rfc6238Secret)
+	if err != nil || secret != rfc6238Secret || !legacy {
+		t.Fatalf("Decrypt(plain) = %q, %v, %v, want the plain secret back as legacy", secret, legacy, err)
+	}
+
+	encrypted, err := secretCipher.Encrypt(rfc6238Secret)
+	if err != nil {
+		t.Fatalf("Encrypt: %v", err)
+	}
+
+	otherCipher := newTestCipher(t, 'o')
+	for name, tc := range map[string]struct {
+		cipher *SecretCipher
+		stored string
+	}{
+		"other key":  {otherCipher, encrypted},
+		"truncated":  {secretCipher, encryptedSecretPrefix + "AAAA"},
+		"not base64": {secretCipher, encryptedSecretPrefix + "%%%"},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := tc.cipher.Decrypt(tc.stored); err == nil {
+				t.Fatal("Decrypt succeeded, want an error")
+			}
+		})
+	}
+}
+
+func TestNewSecretCipherRejectsBadKeys(t *testing.T) {
+	for name, key := range map[string]string{
+		"empty":      "",
+		"short":      base64.StdEncoding.EncodeToString([]byte("short")),
+		"not base64": strings.Repeat("!", 44),
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewSecretCipher(key); err == nil {
+				t.Fatal("NewSecretCipher succeeded, want an error")
+			}
+		})
+	}
+}